@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS product_category_id_idx;
+ALTER TABLE public."product" DROP COLUMN IF EXISTS category_id;
+
+DROP TABLE IF EXISTS "category" CASCADE;
+
+DROP SEQUENCE IF EXISTS category_id_seq;
@@ -0,0 +1,38 @@
+CREATE SEQUENCE IF NOT EXISTS category_id_seq;
+
+CREATE TABLE IF NOT EXISTS public."category"
+(
+    id              BIGINT                   DEFAULT NEXTVAL('category_id_seq'::regclass) NOT NULL PRIMARY KEY,
+    parent_id       BIGINT                                                                REFERENCES public."category" (id),
+    slug            TEXT UNIQUE                                                           NOT NULL CHECK (slug <> '')
+    CONSTRAINT max_len_slug CHECK (LENGTH(slug) <= 64),
+    name            TEXT                                                                  NOT NULL CHECK (name <> '')
+    CONSTRAINT max_len_name CHECK (LENGTH(name) <= 256)
+);
+
+CREATE INDEX IF NOT EXISTS category_parent_id_idx ON public."category" (parent_id);
+
+INSERT INTO public."category" (parent_id, slug, name) VALUES
+    (NULL, 'other', 'Другое'),
+    (NULL, 'transport', 'Транспорт'),
+    (NULL, 'electronics', 'Электроника'),
+    (NULL, 'home', 'Для дома и дачи'),
+    (NULL, 'clothes', 'Одежда и обувь');
+
+INSERT INTO public."category" (parent_id, slug, name) VALUES
+    ((SELECT id FROM public."category" WHERE slug = 'transport'), 'cars', 'Автомобили'),
+    ((SELECT id FROM public."category" WHERE slug = 'transport'), 'motorcycles', 'Мотоциклы'),
+    ((SELECT id FROM public."category" WHERE slug = 'electronics'), 'phones', 'Телефоны'),
+    ((SELECT id FROM public."category" WHERE slug = 'electronics'), 'computers', 'Компьютеры'),
+    ((SELECT id FROM public."category" WHERE slug = 'home'), 'furniture', 'Мебель'),
+    ((SELECT id FROM public."category" WHERE slug = 'clothes'), 'mens_clothes', 'Мужская одежда'),
+    ((SELECT id FROM public."category" WHERE slug = 'clothes'), 'womens_clothes', 'Женская одежда');
+
+ALTER TABLE public."product" ADD COLUMN IF NOT EXISTS category_id BIGINT REFERENCES public."category" (id);
+
+UPDATE public."product" SET category_id = (SELECT id FROM public."category" WHERE slug = 'other')
+WHERE category_id IS NULL;
+
+ALTER TABLE public."product" ALTER COLUMN category_id SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS product_category_id_idx ON public."product" (category_id);
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS product_attributes_idx;
+ALTER TABLE public."product" DROP COLUMN IF EXISTS attributes;
+
+DROP TABLE IF EXISTS "category_attribute" CASCADE;
+
+DROP SEQUENCE IF EXISTS category_attribute_id_seq;
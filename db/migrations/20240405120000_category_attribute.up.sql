@@ -0,0 +1,26 @@
+CREATE SEQUENCE IF NOT EXISTS category_attribute_id_seq;
+
+CREATE TABLE IF NOT EXISTS public."category_attribute"
+(
+    id              BIGINT                   DEFAULT NEXTVAL('category_attribute_id_seq'::regclass) NOT NULL PRIMARY KEY,
+    category_id     BIGINT                                                                          NOT NULL REFERENCES public."category" (id),
+    name            TEXT                                                                            NOT NULL CHECK (name ~ '^[a-z_][a-z0-9_]*$')
+    CONSTRAINT max_len_name CHECK (LENGTH(name) <= 64),
+    type            TEXT                                                                            NOT NULL
+    CONSTRAINT allowed_type CHECK (type IN ('int', 'string', 'enum', 'bool')),
+    enum_values     TEXT[]                   DEFAULT '{}'                                           NOT NULL,
+    min_value       BIGINT,
+    max_value       BIGINT,
+    required        BOOLEAN                  DEFAULT FALSE                                          NOT NULL,
+    UNIQUE (category_id, name)
+);
+
+INSERT INTO public."category_attribute" (category_id, name, type, enum_values, min_value, max_value, required) VALUES
+    ((SELECT id FROM public."category" WHERE slug = 'cars'), 'year', 'int', '{}', 1900, 2100, TRUE),
+    ((SELECT id FROM public."category" WHERE slug = 'cars'), 'mileage', 'int', '{}', 0, 10000000, TRUE),
+    ((SELECT id FROM public."category" WHERE slug = 'phones'), 'memory', 'int', '{}', 1, 4096, FALSE),
+    ((SELECT id FROM public."category" WHERE slug = 'phones'), 'condition', 'enum', '{new,used,broken}', NULL, NULL, TRUE);
+
+ALTER TABLE public."product" ADD COLUMN IF NOT EXISTS attributes JSONB DEFAULT '{}'::jsonb NOT NULL;
+
+CREATE INDEX IF NOT EXISTS product_attributes_idx ON public."product" USING GIN (attributes jsonb_path_ops);
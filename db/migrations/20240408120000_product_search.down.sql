@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS product_search_vector_idx;
+ALTER TABLE public."product" DROP COLUMN IF EXISTS search_vector;
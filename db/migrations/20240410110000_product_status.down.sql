@@ -0,0 +1 @@
+ALTER TABLE public."product" DROP COLUMN IF EXISTS status;
@@ -0,0 +1,2 @@
+ALTER TABLE public."product" ADD COLUMN IF NOT EXISTS status TEXT DEFAULT 'active' NOT NULL
+    CONSTRAINT allowed_status CHECK (status IN ('draft', 'active', 'reserved', 'sold'));
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS category_name_trgm_idx;
+DROP INDEX IF EXISTS product_title_trgm_idx;
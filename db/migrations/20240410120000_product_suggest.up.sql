@@ -0,0 +1,6 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX IF NOT EXISTS product_title_trgm_idx ON public."product"
+    USING GIN (LOWER(title) gin_trgm_ops) WHERE status = 'active';
+
+CREATE INDEX IF NOT EXISTS category_name_trgm_idx ON public."category" USING GIN (LOWER(name) gin_trgm_ops);
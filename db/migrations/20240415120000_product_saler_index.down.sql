@@ -0,0 +1 @@
+DROP INDEX IF EXISTS product_saler_id_created_at_idx;
@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS product_saler_id_created_at_idx ON public."product" (saler_id, created_at DESC);
@@ -0,0 +1,3 @@
+ALTER TABLE public."product" DROP COLUMN IF EXISTS favorites_count;
+
+DROP TABLE IF EXISTS "favorite" CASCADE;
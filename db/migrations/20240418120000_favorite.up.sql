@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS public."favorite"
+(
+    user_id         BIGINT                                                               NOT NULL REFERENCES public."user" (id) ON DELETE CASCADE,
+    product_id      BIGINT                                                               NOT NULL REFERENCES public."product" (id) ON DELETE CASCADE,
+    created_at      TIMESTAMP WITH TIME ZONE DEFAULT NOW()                               NOT NULL,
+    PRIMARY KEY (user_id, product_id)
+);
+
+CREATE INDEX IF NOT EXISTS favorite_product_id_idx ON public."favorite" (product_id);
+
+ALTER TABLE public."product" ADD COLUMN IF NOT EXISTS favorites_count BIGINT DEFAULT 0 NOT NULL
+    CONSTRAINT not_negative_favorites_count CHECK (favorites_count >= 0);
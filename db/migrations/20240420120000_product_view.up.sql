@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS public."product_view_daily"
+(
+    product_id      BIGINT                                                               NOT NULL REFERENCES public."product" (id) ON DELETE CASCADE,
+    day             DATE                                                                 NOT NULL,
+    views           BIGINT DEFAULT 0                                                     NOT NULL CHECK (views >= 0),
+    PRIMARY KEY (product_id, day)
+);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS "product_price_history" CASCADE;
+
+DROP SEQUENCE IF EXISTS product_price_history_id_seq;
@@ -0,0 +1,13 @@
+CREATE SEQUENCE IF NOT EXISTS product_price_history_id_seq;
+
+CREATE TABLE IF NOT EXISTS public."product_price_history"
+(
+    id              BIGINT                   DEFAULT NEXTVAL('product_price_history_id_seq'::regclass) NOT NULL PRIMARY KEY,
+    product_id      BIGINT                                                                NOT NULL REFERENCES public."product" (id) ON DELETE CASCADE,
+    old_price       BIGINT                                                                NOT NULL CHECK (old_price >= 0),
+    new_price       BIGINT                                                                NOT NULL CHECK (new_price >= 0),
+    changed_at      TIMESTAMP WITH TIME ZONE DEFAULT NOW()                                NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS product_price_history_product_id_changed_at_idx
+    ON public."product_price_history" (product_id, changed_at);
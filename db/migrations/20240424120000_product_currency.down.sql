@@ -0,0 +1,9 @@
+ALTER TABLE public."product_price_history" DROP COLUMN IF EXISTS old_currency, DROP COLUMN IF EXISTS new_currency;
+
+UPDATE public."product_price_history" SET old_price = old_price / 100, new_price = new_price / 100;
+
+DROP INDEX IF EXISTS product_currency_price_idx;
+
+ALTER TABLE public."product" DROP COLUMN IF EXISTS currency;
+
+UPDATE public."product" SET price = price / 100;
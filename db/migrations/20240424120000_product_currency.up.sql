@@ -0,0 +1,15 @@
+-- prices were stored in whole rubles, now they are amounts in minor units of currency
+UPDATE public."product" SET price = price * 100;
+
+ALTER TABLE public."product" ADD COLUMN IF NOT EXISTS currency TEXT DEFAULT 'RUB' NOT NULL
+    CONSTRAINT currency_iso_4217 CHECK (currency ~ '^[A-Z]{3}$');
+
+CREATE INDEX IF NOT EXISTS product_currency_price_idx ON public."product" (currency, price);
+
+UPDATE public."product_price_history" SET old_price = old_price * 100, new_price = new_price * 100;
+
+ALTER TABLE public."product_price_history"
+    ADD COLUMN IF NOT EXISTS old_currency TEXT DEFAULT 'RUB' NOT NULL
+        CONSTRAINT old_currency_iso_4217 CHECK (old_currency ~ '^[A-Z]{3}$'),
+    ADD COLUMN IF NOT EXISTS new_currency TEXT DEFAULT 'RUB' NOT NULL
+        CONSTRAINT new_currency_iso_4217 CHECK (new_currency ~ '^[A-Z]{3}$');
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS product_latitude_longitude_idx;
+
+ALTER TABLE public."product"
+    DROP CONSTRAINT IF EXISTS latitude_with_longitude,
+    DROP COLUMN IF EXISTS latitude,
+    DROP COLUMN IF EXISTS longitude,
+    DROP COLUMN IF EXISTS city;
@@ -0,0 +1,9 @@
+ALTER TABLE public."product"
+    ADD COLUMN IF NOT EXISTS latitude  DOUBLE PRECISION CONSTRAINT latitude_range CHECK (latitude BETWEEN -90 AND 90),
+    ADD COLUMN IF NOT EXISTS longitude DOUBLE PRECISION CONSTRAINT longitude_range CHECK (longitude BETWEEN -180 AND 180),
+    ADD COLUMN IF NOT EXISTS city      TEXT DEFAULT '' NOT NULL CONSTRAINT max_len_city CHECK (LENGTH(city) <= 128),
+    ADD CONSTRAINT latitude_with_longitude CHECK ((latitude IS NULL) = (longitude IS NULL));
+
+-- bounding box prefilter of near search
+CREATE INDEX IF NOT EXISTS product_latitude_longitude_idx ON public."product" (latitude, longitude)
+    WHERE latitude IS NOT NULL;
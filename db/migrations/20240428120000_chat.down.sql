@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS "message" CASCADE;
+DROP TABLE IF EXISTS "conversation" CASCADE;
+
+DROP SEQUENCE IF EXISTS message_id_seq;
+DROP SEQUENCE IF EXISTS conversation_id_seq;
@@ -0,0 +1,33 @@
+CREATE SEQUENCE IF NOT EXISTS conversation_id_seq;
+CREATE SEQUENCE IF NOT EXISTS message_id_seq;
+
+CREATE TABLE IF NOT EXISTS public."conversation"
+(
+    id              BIGINT                   DEFAULT NEXTVAL('conversation_id_seq'::regclass) NOT NULL PRIMARY KEY,
+    product_id      BIGINT                                                                    NOT NULL REFERENCES public."product" (id) ON DELETE CASCADE,
+    buyer_id        BIGINT                                                                    NOT NULL REFERENCES public."user" (id) ON DELETE CASCADE,
+    saler_id        BIGINT                                                                    NOT NULL REFERENCES public."user" (id) ON DELETE CASCADE,
+    created_at      TIMESTAMP WITH TIME ZONE DEFAULT NOW()                                    NOT NULL,
+    last_message_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()                                    NOT NULL,
+    CONSTRAINT buyer_is_not_saler CHECK (buyer_id <> saler_id),
+    UNIQUE (product_id, buyer_id)
+);
+
+CREATE INDEX IF NOT EXISTS conversation_buyer_id_last_message_at_idx
+    ON public."conversation" (buyer_id, last_message_at DESC);
+CREATE INDEX IF NOT EXISTS conversation_saler_id_last_message_at_idx
+    ON public."conversation" (saler_id, last_message_at DESC);
+
+CREATE TABLE IF NOT EXISTS public."message"
+(
+    id              BIGINT                   DEFAULT NEXTVAL('message_id_seq'::regclass) NOT NULL PRIMARY KEY,
+    conversation_id BIGINT                                                               NOT NULL REFERENCES public."conversation" (id) ON DELETE CASCADE,
+    sender_id       BIGINT                                                               NOT NULL REFERENCES public."user" (id) ON DELETE CASCADE,
+    text            TEXT                                                                 NOT NULL CHECK (text <> '')
+    CONSTRAINT max_len_text CHECK (LENGTH(text) <= 4000),
+    created_at      TIMESTAMP WITH TIME ZONE DEFAULT NOW()                               NOT NULL,
+    read_at         TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS message_conversation_id_id_idx ON public."message" (conversation_id, id);
+CREATE INDEX IF NOT EXISTS message_unread_idx ON public."message" (conversation_id, sender_id) WHERE read_at IS NULL;
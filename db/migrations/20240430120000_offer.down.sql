@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS "offer" CASCADE;
+
+DROP SEQUENCE IF EXISTS offer_id_seq;
@@ -0,0 +1,27 @@
+CREATE SEQUENCE IF NOT EXISTS offer_id_seq;
+
+CREATE TABLE IF NOT EXISTS public."offer"
+(
+    id            BIGINT                   DEFAULT NEXTVAL('offer_id_seq'::regclass) NOT NULL PRIMARY KEY,
+    product_id    BIGINT                                                             NOT NULL REFERENCES public."product" (id) ON DELETE CASCADE,
+    buyer_id      BIGINT                                                             NOT NULL REFERENCES public."user" (id) ON DELETE CASCADE,
+    saler_id      BIGINT                                                             NOT NULL REFERENCES public."user" (id) ON DELETE CASCADE,
+    price         BIGINT                                                             NOT NULL CHECK (price > 0),
+    counter_price BIGINT CHECK (counter_price > 0),
+    currency      TEXT                                                               NOT NULL,
+    status        TEXT                     DEFAULT 'pending'                         NOT NULL
+        CONSTRAINT offer_status CHECK (status IN ('pending', 'accepted', 'declined', 'countered', 'expired', 'withdrawn')),
+    created_at    TIMESTAMP WITH TIME ZONE DEFAULT NOW()                             NOT NULL,
+    updated_at    TIMESTAMP WITH TIME ZONE DEFAULT NOW()                             NOT NULL,
+    expires_at    TIMESTAMP WITH TIME ZONE                                           NOT NULL,
+    CONSTRAINT buyer_is_not_saler CHECK (buyer_id <> saler_id)
+);
+
+-- buyer has at most one open offer on product
+CREATE UNIQUE INDEX IF NOT EXISTS offer_open_product_id_buyer_id_idx ON public."offer" (product_id, buyer_id)
+    WHERE status IN ('pending', 'countered');
+-- open offers expire without answer, accepted ones release product if buyer doesn't buy it in time
+CREATE INDEX IF NOT EXISTS offer_expiring_expires_at_idx ON public."offer" (expires_at)
+    WHERE status IN ('pending', 'countered', 'accepted');
+CREATE INDEX IF NOT EXISTS offer_buyer_id_idx ON public."offer" (buyer_id);
+CREATE INDEX IF NOT EXISTS offer_saler_id_idx ON public."offer" (saler_id);
@@ -0,0 +1,8 @@
+UPDATE public."offer" SET status = 'expired' WHERE status = 'closed';
+ALTER TABLE public."offer" DROP CONSTRAINT IF EXISTS offer_status;
+ALTER TABLE public."offer" ADD CONSTRAINT offer_status
+    CHECK (status IN ('pending', 'accepted', 'declined', 'countered', 'expired', 'withdrawn'));
+
+DROP TABLE IF EXISTS "order" CASCADE;
+
+DROP SEQUENCE IF EXISTS order_id_seq;
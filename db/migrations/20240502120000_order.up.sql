@@ -0,0 +1,34 @@
+CREATE SEQUENCE IF NOT EXISTS order_id_seq;
+
+CREATE TABLE IF NOT EXISTS public."order"
+(
+    id              BIGINT                   DEFAULT NEXTVAL('order_id_seq'::regclass) NOT NULL PRIMARY KEY,
+    product_id      BIGINT                                                             NOT NULL REFERENCES public."product" (id) ON DELETE CASCADE,
+    buyer_id        BIGINT                                                             NOT NULL REFERENCES public."user" (id) ON DELETE CASCADE,
+    saler_id        BIGINT                                                             NOT NULL REFERENCES public."user" (id) ON DELETE CASCADE,
+    price           BIGINT                                                             NOT NULL CHECK (price > 0),
+    currency        TEXT                                                               NOT NULL,
+    status          TEXT                     DEFAULT 'created'                         NOT NULL
+        CONSTRAINT order_status CHECK (status IN ('created', 'paid', 'shipped', 'completed', 'cancelled', 'refunding', 'refunded')),
+    payment_id      TEXT                     DEFAULT ''                                NOT NULL,
+    payment_status  TEXT                     DEFAULT 'none'                            NOT NULL
+        CONSTRAINT order_payment_status CHECK (payment_status IN ('none', 'pending', 'succeeded', 'failed')),
+    payment_error   TEXT                     DEFAULT ''                                NOT NULL,
+    payment_attempt INT                      DEFAULT 0                                 NOT NULL,
+    offer_id        BIGINT REFERENCES public."offer" (id) ON DELETE SET NULL,
+    created_at      TIMESTAMP WITH TIME ZONE DEFAULT NOW()                             NOT NULL,
+    updated_at      TIMESTAMP WITH TIME ZONE DEFAULT NOW()                             NOT NULL,
+    CONSTRAINT buyer_is_not_saler CHECK (buyer_id <> saler_id)
+);
+
+-- product is held by at most one order which is not finished
+CREATE UNIQUE INDEX IF NOT EXISTS order_live_product_id_idx ON public."order" (product_id)
+    WHERE status IN ('created', 'paid', 'shipped', 'refunding');
+CREATE INDEX IF NOT EXISTS order_buyer_id_idx ON public."order" (buyer_id);
+CREATE INDEX IF NOT EXISTS order_saler_id_idx ON public."order" (saler_id);
+-- accepted offer is bought by at most one order, it is closed when order is cancelled
+CREATE UNIQUE INDEX IF NOT EXISTS order_offer_id_idx ON public."order" (offer_id) WHERE offer_id IS NOT NULL;
+
+ALTER TABLE public."offer" DROP CONSTRAINT IF EXISTS offer_status;
+ALTER TABLE public."offer" ADD CONSTRAINT offer_status
+    CHECK (status IN ('pending', 'accepted', 'declined', 'countered', 'expired', 'withdrawn', 'closed'));
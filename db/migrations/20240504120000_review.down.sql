@@ -0,0 +1,7 @@
+ALTER TABLE public."user"
+    DROP COLUMN IF EXISTS rating_sum,
+    DROP COLUMN IF EXISTS reviews_count;
+
+DROP TABLE IF EXISTS "review" CASCADE;
+
+DROP SEQUENCE IF EXISTS review_id_seq;
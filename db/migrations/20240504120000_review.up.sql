@@ -0,0 +1,24 @@
+CREATE SEQUENCE IF NOT EXISTS review_id_seq;
+
+CREATE TABLE IF NOT EXISTS public."review"
+(
+    id         BIGINT                   DEFAULT NEXTVAL('review_id_seq'::regclass) NOT NULL PRIMARY KEY,
+    order_id   BIGINT UNIQUE                                                       NOT NULL REFERENCES public."order" (id) ON DELETE CASCADE,
+    product_id BIGINT                                                              NOT NULL REFERENCES public."product" (id) ON DELETE CASCADE,
+    author_id  BIGINT                                                              NOT NULL REFERENCES public."user" (id) ON DELETE CASCADE,
+    saler_id   BIGINT                                                              NOT NULL REFERENCES public."user" (id) ON DELETE CASCADE,
+    rating     SMALLINT                                                            NOT NULL CHECK (rating BETWEEN 1 AND 5),
+    text       TEXT                     DEFAULT ''                                 NOT NULL
+        CONSTRAINT max_len_text CHECK (LENGTH(text) <= 2000),
+    reply      TEXT                     DEFAULT ''                                 NOT NULL
+        CONSTRAINT max_len_reply CHECK (LENGTH(reply) <= 2000),
+    replied_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()                              NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS review_saler_id_id_idx ON public."review" (saler_id, id DESC);
+
+-- aggregates of reviews about user as seller, they are changed with every new review
+ALTER TABLE public."user"
+    ADD COLUMN IF NOT EXISTS rating_sum    BIGINT DEFAULT 0 NOT NULL,
+    ADD COLUMN IF NOT EXISTS reviews_count BIGINT DEFAULT 0 NOT NULL;
@@ -0,0 +1,17 @@
+DROP TABLE IF EXISTS "moderation_action" CASCADE;
+
+DROP SEQUENCE IF EXISTS moderation_action_id_seq;
+
+DROP TABLE IF EXISTS "report" CASCADE;
+
+DROP SEQUENCE IF EXISTS report_id_seq;
+
+ALTER TABLE public."user"
+    DROP COLUMN IF EXISTS is_moderator,
+    DROP COLUMN IF EXISTS banned_at;
+
+UPDATE public."product" SET status = 'active' WHERE status = 'hidden';
+
+ALTER TABLE public."product" DROP CONSTRAINT IF EXISTS allowed_status;
+ALTER TABLE public."product" ADD CONSTRAINT allowed_status
+    CHECK (status IN ('draft', 'active', 'reserved', 'sold'));
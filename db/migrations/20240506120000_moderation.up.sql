@@ -0,0 +1,45 @@
+ALTER TABLE public."product" DROP CONSTRAINT IF EXISTS allowed_status;
+ALTER TABLE public."product" ADD CONSTRAINT allowed_status
+    CHECK (status IN ('draft', 'active', 'reserved', 'sold', 'hidden'));
+
+ALTER TABLE public."user"
+    ADD COLUMN IF NOT EXISTS is_moderator BOOLEAN DEFAULT FALSE NOT NULL,
+    ADD COLUMN IF NOT EXISTS banned_at    TIMESTAMP WITH TIME ZONE;
+
+CREATE SEQUENCE IF NOT EXISTS report_id_seq;
+
+CREATE TABLE IF NOT EXISTS public."report"
+(
+    id          BIGINT                   DEFAULT NEXTVAL('report_id_seq'::regclass) NOT NULL PRIMARY KEY,
+    product_id  BIGINT                                                              NOT NULL REFERENCES public."product" (id) ON DELETE CASCADE,
+    reporter_id BIGINT                                                              NOT NULL REFERENCES public."user" (id) ON DELETE CASCADE,
+    reason      TEXT                                                                NOT NULL
+        CONSTRAINT report_reason CHECK (reason IN ('scam', 'prohibited', 'wrong_category', 'duplicate', 'spam', 'offensive', 'other')),
+    comment     TEXT                     DEFAULT ''                                 NOT NULL
+        CONSTRAINT max_len_comment CHECK (LENGTH(comment) <= 1000),
+    is_resolved BOOLEAN                  DEFAULT FALSE                              NOT NULL,
+    created_at  TIMESTAMP WITH TIME ZONE DEFAULT NOW()                              NOT NULL
+);
+
+-- reporter has at most one open report about product, repeated reports are not counted
+CREATE UNIQUE INDEX IF NOT EXISTS report_open_product_id_reporter_id_idx ON public."report" (product_id, reporter_id)
+    WHERE NOT is_resolved;
+
+CREATE SEQUENCE IF NOT EXISTS moderation_action_id_seq;
+
+-- audit trail of moderation, moderator_id is NULL for automatic actions
+CREATE TABLE IF NOT EXISTS public."moderation_action"
+(
+    id           BIGINT                   DEFAULT NEXTVAL('moderation_action_id_seq'::regclass) NOT NULL PRIMARY KEY,
+    moderator_id BIGINT REFERENCES public."user" (id) ON DELETE SET NULL,
+    action       TEXT                                                                           NOT NULL
+        CONSTRAINT moderation_action_action CHECK (action IN ('hide', 'restore', 'ban', 'auto_hide')),
+    product_id   BIGINT REFERENCES public."product" (id) ON DELETE CASCADE,
+    user_id      BIGINT REFERENCES public."user" (id) ON DELETE CASCADE,
+    comment      TEXT                     DEFAULT ''                                            NOT NULL
+        CONSTRAINT max_len_comment CHECK (LENGTH(comment) <= 1000),
+    created_at   TIMESTAMP WITH TIME ZONE DEFAULT NOW()                                         NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS moderation_action_product_id_idx ON public."moderation_action" (product_id);
+CREATE INDEX IF NOT EXISTS moderation_action_user_id_idx ON public."moderation_action" (user_id);
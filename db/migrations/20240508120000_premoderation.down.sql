@@ -0,0 +1,12 @@
+DROP INDEX IF EXISTS product_category_id_currency_price_idx;
+DROP INDEX IF EXISTS product_on_review_idx;
+
+ALTER TABLE public."product"
+    DROP COLUMN IF EXISTS review_reason,
+    DROP COLUMN IF EXISTS review_requested_at;
+
+UPDATE public."product" SET status = 'hidden' WHERE status = 'on_review';
+
+ALTER TABLE public."product" DROP CONSTRAINT IF EXISTS allowed_status;
+ALTER TABLE public."product" ADD CONSTRAINT allowed_status
+    CHECK (status IN ('draft', 'active', 'reserved', 'sold', 'hidden'));
@@ -0,0 +1,12 @@
+ALTER TABLE public."product" DROP CONSTRAINT IF EXISTS allowed_status;
+ALTER TABLE public."product" ADD CONSTRAINT allowed_status
+    CHECK (status IN ('draft', 'active', 'reserved', 'sold', 'hidden', 'on_review'));
+
+-- why premoderation sent product to manual review and when
+ALTER TABLE public."product"
+    ADD COLUMN IF NOT EXISTS review_reason       TEXT DEFAULT '' NOT NULL,
+    ADD COLUMN IF NOT EXISTS review_requested_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS product_on_review_idx ON public."product" (review_requested_at) WHERE status = 'on_review';
+CREATE INDEX IF NOT EXISTS product_category_id_currency_price_idx ON public."product" (category_id, currency, price)
+    WHERE status = 'active';
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS product_image_hash_idx;
+DROP INDEX IF EXISTS product_fingerprint_trgm_idx;
+
+ALTER TABLE public."product"
+    DROP COLUMN IF EXISTS image_hash,
+    DROP COLUMN IF EXISTS fingerprint;
@@ -0,0 +1,10 @@
+-- normalized text of product for trigram search of near duplicates, FindDuplicate normalizes input the same way
+ALTER TABLE public."product" ADD COLUMN IF NOT EXISTS fingerprint TEXT GENERATED ALWAYS AS (
+    TRIM(REGEXP_REPLACE(LOWER(title || ' ' || description), '[^[:alnum:]]+', ' ', 'g'))
+) STORED;
+
+-- perceptual hash of ingested image, NULL for image which was not ingested
+ALTER TABLE public."product" ADD COLUMN IF NOT EXISTS image_hash BIGINT;
+
+CREATE INDEX IF NOT EXISTS product_fingerprint_trgm_idx ON public."product" USING GIN (fingerprint gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS product_image_hash_idx ON public."product" (image_hash) WHERE image_hash IS NOT NULL;
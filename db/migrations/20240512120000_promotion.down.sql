@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS "promotion" CASCADE;
+
+DROP SEQUENCE IF EXISTS promotion_id_seq;
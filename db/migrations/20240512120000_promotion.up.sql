@@ -0,0 +1,24 @@
+CREATE SEQUENCE IF NOT EXISTS promotion_id_seq;
+
+CREATE TABLE IF NOT EXISTS public."promotion"
+(
+    id             BIGINT                   DEFAULT NEXTVAL('promotion_id_seq'::regclass) NOT NULL PRIMARY KEY,
+    product_id     BIGINT                                                                 NOT NULL REFERENCES public."product" (id) ON DELETE CASCADE,
+    saler_id       BIGINT                                                                 NOT NULL REFERENCES public."user" (id) ON DELETE CASCADE,
+    type           TEXT                                                                   NOT NULL
+        CONSTRAINT allowed_type CHECK (type IN ('bump', 'highlight', 'top_of_category')),
+    started_at     TIMESTAMP WITH TIME ZONE                                               NOT NULL,
+    ended_at       TIMESTAMP WITH TIME ZONE                                               NOT NULL,
+    -- promotion granted by moderator is free, its payment status is none
+    price          BIGINT                   DEFAULT 0                                     NOT NULL,
+    currency       TEXT                     DEFAULT ''                                    NOT NULL,
+    payment_id     TEXT                     DEFAULT ''                                    NOT NULL,
+    payment_status TEXT                     DEFAULT 'none'                                NOT NULL
+        CONSTRAINT promotion_payment_status CHECK (payment_status IN ('none', 'pending', 'succeeded', 'failed')),
+    payment_error  TEXT                     DEFAULT ''                                    NOT NULL,
+    created_at     TIMESTAMP WITH TIME ZONE DEFAULT NOW()                                 NOT NULL,
+    CONSTRAINT ended_after_started CHECK (ended_at > started_at)
+);
+
+-- running promotions of product are looked up for every product of feed
+CREATE INDEX IF NOT EXISTS promotion_product_id_ended_at_idx ON public."promotion" (product_id, ended_at);
@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS "saved_search_match" CASCADE;
+DROP TABLE IF EXISTS "saved_search_queue" CASCADE;
+DROP TABLE IF EXISTS "saved_search" CASCADE;
+
+DROP SEQUENCE IF EXISTS saved_search_match_id_seq;
+DROP SEQUENCE IF EXISTS saved_search_id_seq;
+
+ALTER TABLE public."product" DROP COLUMN IF EXISTS published_at;
@@ -0,0 +1,63 @@
+-- first activation of product, only then it is matched with saved searches
+ALTER TABLE public."product" ADD COLUMN IF NOT EXISTS published_at TIMESTAMP WITH TIME ZONE;
+
+UPDATE public."product" SET published_at = created_at
+    WHERE published_at IS NULL AND status NOT IN ('draft', 'on_review');
+
+CREATE SEQUENCE IF NOT EXISTS saved_search_id_seq;
+
+-- filter of feed saved by user. Fields are the same as in product filter, 0, '' and NULL mean that field
+-- is not applied. Attribute filters are kept as one jsonpath predicate
+CREATE TABLE IF NOT EXISTS public."saved_search"
+(
+    id               BIGINT                   DEFAULT NEXTVAL('saved_search_id_seq'::regclass) NOT NULL PRIMARY KEY,
+    user_id          BIGINT                                                                    NOT NULL REFERENCES public."user" (id) ON DELETE CASCADE,
+    name             TEXT                                                                      NOT NULL CHECK (name <> '')
+        CONSTRAINT max_len_name CHECK (LENGTH(name) <= 256),
+    query            TEXT                     DEFAULT ''                                       NOT NULL,
+    notify           TEXT                     DEFAULT 'instant'                                NOT NULL
+        CONSTRAINT allowed_notify CHECK (notify IN ('instant', 'hourly', 'daily')),
+    category_id      BIGINT                   DEFAULT 0                                        NOT NULL,
+    currency         TEXT                     DEFAULT ''                                       NOT NULL,
+    min_price        BIGINT,
+    max_price        BIGINT,
+    saler_id         BIGINT                   DEFAULT 0                                        NOT NULL,
+    has_image        BOOLEAN,
+    search_query     TEXT                     DEFAULT ''                                       NOT NULL,
+    latitude         DOUBLE PRECISION,
+    longitude        DOUBLE PRECISION,
+    radius_km        DOUBLE PRECISION,
+    attributes_path  JSONPATH,
+    last_notified_at TIMESTAMP WITH TIME ZONE,
+    created_at       TIMESTAMP WITH TIME ZONE DEFAULT NOW()                                    NOT NULL,
+    CONSTRAINT near_with_radius CHECK ((latitude IS NULL) = (radius_km IS NULL))
+);
+
+-- new product is matched only with searches of its category, of categories above it and without category
+CREATE INDEX IF NOT EXISTS saved_search_category_id_idx ON public."saved_search" (category_id);
+CREATE INDEX IF NOT EXISTS saved_search_user_id_idx ON public."saved_search" (user_id);
+
+-- published products waiting for matcher
+CREATE TABLE IF NOT EXISTS public."saved_search_queue"
+(
+    product_id BIGINT                                 NOT NULL PRIMARY KEY REFERENCES public."product" (id) ON DELETE CASCADE,
+    queued_at  TIMESTAMP WITH TIME ZONE DEFAULT NOW() NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS saved_search_queue_queued_at_idx ON public."saved_search_queue" (queued_at);
+
+CREATE SEQUENCE IF NOT EXISTS saved_search_match_id_seq;
+
+-- products found by saved search, notified_at is set when user is notified about them
+CREATE TABLE IF NOT EXISTS public."saved_search_match"
+(
+    id              BIGINT                   DEFAULT NEXTVAL('saved_search_match_id_seq'::regclass) NOT NULL PRIMARY KEY,
+    saved_search_id BIGINT                                                                          NOT NULL REFERENCES public."saved_search" (id) ON DELETE CASCADE,
+    product_id      BIGINT                                                                          NOT NULL REFERENCES public."product" (id) ON DELETE CASCADE,
+    created_at      TIMESTAMP WITH TIME ZONE DEFAULT NOW()                                          NOT NULL,
+    notified_at     TIMESTAMP WITH TIME ZONE,
+    CONSTRAINT uniq_saved_search_product UNIQUE (saved_search_id, product_id)
+);
+
+CREATE INDEX IF NOT EXISTS saved_search_match_pending_idx ON public."saved_search_match" (saved_search_id)
+    WHERE notified_at IS NULL;
@@ -0,0 +1,67 @@
+package delivery
+
+import (
+	"context"
+	"fmt"
+	"github.com/SanExpett/marketplace-backend/internal/category/usecases"
+	"github.com/SanExpett/marketplace-backend/internal/server/delivery"
+	"github.com/SanExpett/marketplace-backend/pkg/models"
+	myerrors "github.com/SanExpett/marketplace-backend/pkg/my_errors"
+	"github.com/SanExpett/marketplace-backend/pkg/my_logger"
+	"go.uber.org/zap"
+	"net/http"
+)
+
+var _ ICategoryService = (*usecases.CategoryService)(nil)
+
+type ICategoryService interface {
+	GetCategoriesTree(ctx context.Context) ([]*models.Category, error)
+}
+
+type CategoryHandler struct {
+	service ICategoryService
+	logger  *zap.SugaredLogger
+}
+
+func NewCategoryHandler(categoryService ICategoryService) (*CategoryHandler, error) {
+	logger, err := my_logger.Get()
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return &CategoryHandler{
+		service: categoryService,
+		logger:  logger,
+	}, nil
+}
+
+// GetCategoriesHandler godoc
+//
+//	@Summary    get categories
+//	@Description  get tree of categories
+//	@Tags category
+//	@Produce    json
+//	@Success    200  {object} CategoryListResponse
+//	@Failure    405  {string} string
+//	@Failure    500  {string} string
+//	@Failure    222  {object} delivery.ErrorResponse "Error"
+//	@Router      /categories [get]
+func (c *CategoryHandler) GetCategoriesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, `Method not allowed`, http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	ctx := r.Context()
+
+	categories, err := c.service.GetCategoriesTree(ctx)
+	if err != nil {
+		delivery.HandleErr(w, c.logger, err)
+
+		return
+	}
+
+	delivery.SendOkResponse(w, c.logger, NewCategoryListResponse(delivery.StatusResponseSuccessful, categories))
+	c.logger.Infof("in GetCategoriesHandler: get categories: %+v", categories)
+}
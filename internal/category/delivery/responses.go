@@ -0,0 +1,15 @@
+package delivery
+
+import "github.com/SanExpett/marketplace-backend/pkg/models"
+
+type CategoryListResponse struct {
+	Status int                `json:"status"`
+	Body   []*models.Category `json:"body"`
+}
+
+func NewCategoryListResponse(status int, body []*models.Category) *CategoryListResponse {
+	return &CategoryListResponse{
+		Status: status,
+		Body:   body,
+	}
+}
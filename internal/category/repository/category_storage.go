@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"github.com/SanExpett/marketplace-backend/pkg/models"
+	myerrors "github.com/SanExpett/marketplace-backend/pkg/my_errors"
+	"github.com/SanExpett/marketplace-backend/pkg/my_logger"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+type CategoryStorage struct {
+	pool   *pgxpool.Pool
+	logger *zap.SugaredLogger
+}
+
+func NewCategoryStorage(pool *pgxpool.Pool) (*CategoryStorage, error) {
+	logger, err := my_logger.Get()
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return &CategoryStorage{
+		pool:   pool,
+		logger: logger,
+	}, nil
+}
+
+func (c *CategoryStorage) selectCategories(ctx context.Context, tx pgx.Tx) ([]*models.Category, error) {
+	SQLSelectCategories := `SELECT id, COALESCE(parent_id, 0), slug, name FROM public."category" ORDER BY id`
+
+	rowsCategories, err := tx.Query(ctx, SQLSelectCategories)
+	if err != nil {
+		c.logger.Errorln(err)
+
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	curCategory := new(models.Category)
+
+	var slCategory []*models.Category
+
+	_, err = pgx.ForEachRow(rowsCategories, []any{
+		&curCategory.ID, &curCategory.ParentID, &curCategory.Slug, &curCategory.Name,
+	}, func() error {
+		slCategory = append(slCategory, &models.Category{ //nolint:exhaustruct
+			ID:       curCategory.ID,
+			ParentID: curCategory.ParentID,
+			Slug:     curCategory.Slug,
+			Name:     curCategory.Name,
+		})
+
+		return nil
+	})
+	if err != nil {
+		c.logger.Errorln(err)
+
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return slCategory, nil
+}
+
+func (c *CategoryStorage) GetCategories(ctx context.Context) ([]*models.Category, error) {
+	var slCategory []*models.Category
+
+	err := pgx.BeginFunc(ctx, c.pool, func(tx pgx.Tx) error {
+		var err error
+
+		slCategory, err = c.selectCategories(ctx, tx)
+
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return slCategory, nil
+}
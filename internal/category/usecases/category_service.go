@@ -0,0 +1,69 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+	categoryrepo "github.com/SanExpett/marketplace-backend/internal/category/repository"
+	"github.com/SanExpett/marketplace-backend/pkg/models"
+	myerrors "github.com/SanExpett/marketplace-backend/pkg/my_errors"
+	"github.com/SanExpett/marketplace-backend/pkg/my_logger"
+	"go.uber.org/zap"
+)
+
+var _ ICategoryStorage = (*categoryrepo.CategoryStorage)(nil)
+
+type ICategoryStorage interface {
+	GetCategories(ctx context.Context) ([]*models.Category, error)
+}
+
+type CategoryService struct {
+	storage ICategoryStorage
+	logger  *zap.SugaredLogger
+}
+
+func NewCategoryService(categoryStorage ICategoryStorage) (*CategoryService, error) {
+	logger, err := my_logger.Get()
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return &CategoryService{storage: categoryStorage, logger: logger}, nil
+}
+
+// buildTree links flat categories by parent_id and returns roots.
+func buildTree(categories []*models.Category) []*models.Category {
+	categoryByID := make(map[uint64]*models.Category, len(categories))
+	for _, category := range categories {
+		category.Children = []*models.Category{}
+		categoryByID[category.ID] = category
+	}
+
+	roots := make([]*models.Category, 0)
+
+	for _, category := range categories {
+		parent, ok := categoryByID[category.ParentID]
+		if category.ParentID == 0 || !ok {
+			roots = append(roots, category)
+
+			continue
+		}
+
+		parent.Children = append(parent.Children, category)
+	}
+
+	return roots
+}
+
+func (c *CategoryService) GetCategoriesTree(ctx context.Context) ([]*models.Category, error) {
+	categories, err := c.storage.GetCategories(ctx)
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	tree := buildTree(categories)
+	for _, category := range tree {
+		category.Sanitize()
+	}
+
+	return tree, nil
+}
@@ -0,0 +1,343 @@
+package delivery
+
+import (
+	"context"
+	"fmt"
+	"github.com/SanExpett/marketplace-backend/internal/chat/usecases"
+	"github.com/SanExpett/marketplace-backend/internal/server/delivery"
+	"github.com/SanExpett/marketplace-backend/pkg/models"
+	myerrors "github.com/SanExpett/marketplace-backend/pkg/my_errors"
+	"github.com/SanExpett/marketplace-backend/pkg/my_logger"
+	"github.com/SanExpett/marketplace-backend/pkg/utils"
+	"go.uber.org/zap"
+	"io"
+	"net/http"
+)
+
+const (
+	ResponseSuccessfulMarkRead = "Successful mark messages as read"
+)
+
+var _ IChatService = (*usecases.ChatService)(nil)
+
+type IChatService interface {
+	StartConversation(ctx context.Context, productID uint64, userID uint64) (*models.Conversation, error)
+	SendMessage(ctx context.Context, r io.Reader, conversationID uint64, userID uint64) (*models.Message, error)
+	GetConversations(ctx context.Context, userID uint64, limit uint64, offset uint64) ([]*models.Conversation, error)
+	GetMessages(ctx context.Context, conversationID uint64, userID uint64, beforeID uint64,
+		limit uint64) ([]*models.Message, error)
+	MarkRead(ctx context.Context, conversationID uint64, userID uint64, upToID uint64) error
+	GetUnreadCount(ctx context.Context, userID uint64) (*models.UnreadCount, error)
+}
+
+type ChatHandler struct {
+	service IChatService
+	logger  *zap.SugaredLogger
+}
+
+func NewChatHandler(chatService IChatService) (*ChatHandler, error) {
+	logger, err := my_logger.Get()
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return &ChatHandler{
+		service: chatService,
+		logger:  logger,
+	}, nil
+}
+
+// StartConversationHandler godoc
+//
+//	@Summary    start conversation
+//	@Description  start conversation with saler about product, returns existing conversation if it was started before
+//	@Tags chat
+//	@Accept      json
+//	@Produce    json
+//	@Param      product_id  query uint64 true  "product id"
+//	@Success    200  {object} ConversationResponse
+//	@Failure    405  {string} string
+//	@Failure    500  {string} string
+//	@Failure    222  {object} delivery.ErrorResponse "Error"
+//	@Router      /chat/start [post]
+func (c *ChatHandler) StartConversationHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, `Method not allowed`, http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	ctx := r.Context()
+
+	userID, err := delivery.GetUserIDFromCookie(r)
+	if err != nil {
+		delivery.HandleErr(w, c.logger, err)
+
+		return
+	}
+
+	productID, err := utils.ParseUint64FromRequest(r, "product_id")
+	if err != nil {
+		delivery.HandleErr(w, c.logger, err)
+
+		return
+	}
+
+	conversation, err := c.service.StartConversation(ctx, productID, userID)
+	if err != nil {
+		delivery.HandleErr(w, c.logger, err)
+
+		return
+	}
+
+	delivery.SendOkResponse(w, c.logger, NewConversationResponse(delivery.StatusResponseSuccessful, conversation))
+	c.logger.Infof("in StartConversationHandler: user %d started conversation %d", userID, conversation.ID)
+}
+
+// SendMessageHandler godoc
+//
+//	@Summary    send message
+//	@Description  send message to conversation, only buyer and saler of conversation can send messages
+//	@Tags chat
+//	@Accept      json
+//	@Produce    json
+//	@Param      conversation_id  query uint64 true  "conversation id"
+//	@Param      preMessage  body models.PreMessage true  "message data for sending"
+//	@Success    200  {object} MessageResponse
+//	@Failure    405  {string} string
+//	@Failure    500  {string} string
+//	@Failure    222  {object} delivery.ErrorResponse "Error"
+//	@Router      /chat/send [post]
+func (c *ChatHandler) SendMessageHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, `Method not allowed`, http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	ctx := r.Context()
+
+	userID, err := delivery.GetUserIDFromCookie(r)
+	if err != nil {
+		delivery.HandleErr(w, c.logger, err)
+
+		return
+	}
+
+	conversationID, err := utils.ParseUint64FromRequest(r, "conversation_id")
+	if err != nil {
+		delivery.HandleErr(w, c.logger, err)
+
+		return
+	}
+
+	message, err := c.service.SendMessage(ctx, r.Body, conversationID, userID)
+	if err != nil {
+		delivery.HandleErr(w, c.logger, err)
+
+		return
+	}
+
+	delivery.SendOkResponse(w, c.logger, NewMessageResponse(delivery.StatusResponseSuccessful, message))
+	c.logger.Infof("in SendMessageHandler: user %d sent message %d", userID, message.ID)
+}
+
+// GetConversationsHandler godoc
+//
+//	@Summary    get conversations
+//	@Description  get conversations of current user with last message and unread count, recently active first
+//	@Tags chat
+//	@Produce    json
+//	@Param      limit  query uint64 false  "limit of conversations, 20 by default, can't be bigger than configured maximum"
+//	@Param      offset  query uint64 false  "offset of conversations"
+//	@Success    200  {object} ConversationListResponse
+//	@Failure    405  {string} string
+//	@Failure    500  {string} string
+//	@Failure    222  {object} delivery.ErrorResponse "Error"
+//	@Router      /chat/get_list [get]
+func (c *ChatHandler) GetConversationsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, `Method not allowed`, http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	ctx := r.Context()
+
+	userID, err := delivery.GetUserIDFromCookie(r)
+	if err != nil {
+		delivery.HandleErr(w, c.logger, err)
+
+		return
+	}
+
+	limit, err := utils.ParseUint64FromRequest(r, "limit")
+	if err != nil {
+		limit = 0
+	}
+
+	offset, err := utils.ParseUint64FromRequest(r, "offset")
+	if err != nil {
+		offset = 0
+	}
+
+	conversations, err := c.service.GetConversations(ctx, userID, limit, offset)
+	if err != nil {
+		delivery.HandleErr(w, c.logger, err)
+
+		return
+	}
+
+	delivery.SendOkResponse(w, c.logger,
+		NewConversationListResponse(delivery.StatusResponseSuccessful, conversations))
+	c.logger.Infof("in GetConversationsHandler: get %d conversations of user %d", len(conversations), userID)
+}
+
+// GetMessagesHandler godoc
+//
+//	@Summary    get messages
+//	@Description  get messages of conversation, newest first; pass id of the oldest received message as before_id for next page
+//	@Tags chat
+//	@Produce    json
+//	@Param      conversation_id  query uint64 true  "conversation id"
+//	@Param      before_id  query uint64 false  "return messages older than this message"
+//	@Param      limit  query uint64 false  "limit of messages, 20 by default, can't be bigger than configured maximum"
+//	@Success    200  {object} MessageListResponse
+//	@Failure    405  {string} string
+//	@Failure    500  {string} string
+//	@Failure    222  {object} delivery.ErrorResponse "Error"
+//	@Router      /chat/messages [get]
+func (c *ChatHandler) GetMessagesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, `Method not allowed`, http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	ctx := r.Context()
+
+	userID, err := delivery.GetUserIDFromCookie(r)
+	if err != nil {
+		delivery.HandleErr(w, c.logger, err)
+
+		return
+	}
+
+	conversationID, err := utils.ParseUint64FromRequest(r, "conversation_id")
+	if err != nil {
+		delivery.HandleErr(w, c.logger, err)
+
+		return
+	}
+
+	beforeID, err := utils.ParseUint64FromRequest(r, "before_id")
+	if err != nil {
+		beforeID = 0
+	}
+
+	limit, err := utils.ParseUint64FromRequest(r, "limit")
+	if err != nil {
+		limit = 0
+	}
+
+	messages, err := c.service.GetMessages(ctx, conversationID, userID, beforeID, limit)
+	if err != nil {
+		delivery.HandleErr(w, c.logger, err)
+
+		return
+	}
+
+	delivery.SendOkResponse(w, c.logger, NewMessageListResponse(delivery.StatusResponseSuccessful, messages))
+	c.logger.Infof("in GetMessagesHandler: get %d messages of conversation %d", len(messages), conversationID)
+}
+
+// MarkReadHandler godoc
+//
+//	@Summary    mark messages as read
+//	@Description  mark messages of interlocutor in conversation as read
+//	@Tags chat
+//	@Produce    json
+//	@Param      conversation_id  query uint64 true  "conversation id"
+//	@Param      up_to_id  query uint64 false  "mark messages up to this message inclusive, all messages by default"
+//	@Success    200  {object} delivery.Response
+//	@Failure    405  {string} string
+//	@Failure    500  {string} string
+//	@Failure    222  {object} delivery.ErrorResponse "Error"
+//	@Router      /chat/read [post]
+func (c *ChatHandler) MarkReadHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, `Method not allowed`, http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	ctx := r.Context()
+
+	userID, err := delivery.GetUserIDFromCookie(r)
+	if err != nil {
+		delivery.HandleErr(w, c.logger, err)
+
+		return
+	}
+
+	conversationID, err := utils.ParseUint64FromRequest(r, "conversation_id")
+	if err != nil {
+		delivery.HandleErr(w, c.logger, err)
+
+		return
+	}
+
+	upToID, err := utils.ParseUint64FromRequest(r, "up_to_id")
+	if err != nil {
+		upToID = 0
+	}
+
+	err = c.service.MarkRead(ctx, conversationID, userID, upToID)
+	if err != nil {
+		delivery.HandleErr(w, c.logger, err)
+
+		return
+	}
+
+	delivery.SendOkResponse(w, c.logger,
+		delivery.NewResponse(delivery.StatusResponseSuccessful, ResponseSuccessfulMarkRead))
+	c.logger.Infof("in MarkReadHandler: user %d read conversation %d", userID, conversationID)
+}
+
+// GetUnreadCountHandler godoc
+//
+//	@Summary    get unread count
+//	@Description  get count of unread messages in all conversations of current user
+//	@Tags chat
+//	@Produce    json
+//	@Success    200  {object} UnreadCountResponse
+//	@Failure    405  {string} string
+//	@Failure    500  {string} string
+//	@Failure    222  {object} delivery.ErrorResponse "Error"
+//	@Router      /chat/unread [get]
+func (c *ChatHandler) GetUnreadCountHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, `Method not allowed`, http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	ctx := r.Context()
+
+	userID, err := delivery.GetUserIDFromCookie(r)
+	if err != nil {
+		delivery.HandleErr(w, c.logger, err)
+
+		return
+	}
+
+	unreadCount, err := c.service.GetUnreadCount(ctx, userID)
+	if err != nil {
+		delivery.HandleErr(w, c.logger, err)
+
+		return
+	}
+
+	delivery.SendOkResponse(w, c.logger, NewUnreadCountResponse(delivery.StatusResponseSuccessful, unreadCount))
+	c.logger.Infof("in GetUnreadCountHandler: user %d has %d unread messages", userID, unreadCount.Count)
+}
@@ -0,0 +1,63 @@
+package delivery
+
+import "github.com/SanExpett/marketplace-backend/pkg/models"
+
+type ConversationResponse struct {
+	Status int                  `json:"status"`
+	Body   *models.Conversation `json:"body"`
+}
+
+func NewConversationResponse(status int, body *models.Conversation) *ConversationResponse {
+	return &ConversationResponse{
+		Status: status,
+		Body:   body,
+	}
+}
+
+type ConversationListResponse struct {
+	Status int                    `json:"status"`
+	Body   []*models.Conversation `json:"body"`
+}
+
+func NewConversationListResponse(status int, body []*models.Conversation) *ConversationListResponse {
+	return &ConversationListResponse{
+		Status: status,
+		Body:   body,
+	}
+}
+
+type MessageResponse struct {
+	Status int             `json:"status"`
+	Body   *models.Message `json:"body"`
+}
+
+func NewMessageResponse(status int, body *models.Message) *MessageResponse {
+	return &MessageResponse{
+		Status: status,
+		Body:   body,
+	}
+}
+
+type MessageListResponse struct {
+	Status int               `json:"status"`
+	Body   []*models.Message `json:"body"`
+}
+
+func NewMessageListResponse(status int, body []*models.Message) *MessageListResponse {
+	return &MessageListResponse{
+		Status: status,
+		Body:   body,
+	}
+}
+
+type UnreadCountResponse struct {
+	Status int                 `json:"status"`
+	Body   *models.UnreadCount `json:"body"`
+}
+
+func NewUnreadCountResponse(status int, body *models.UnreadCount) *UnreadCountResponse {
+	return &UnreadCountResponse{
+		Status: status,
+		Body:   body,
+	}
+}
@@ -0,0 +1,389 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/SanExpett/marketplace-backend/internal/server/repository"
+	"github.com/SanExpett/marketplace-backend/pkg/models"
+	myerrors "github.com/SanExpett/marketplace-backend/pkg/my_errors"
+	"github.com/SanExpett/marketplace-backend/pkg/my_logger"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+	"time"
+)
+
+var (
+	ErrProductNotFound      = myerrors.NewError("Этот товар не найден")
+	ErrConversationNotFound = myerrors.NewError("Этот диалог не найден")
+	ErrSelfConversation     = myerrors.NewError("Нельзя начать диалог о своем объявлении")
+)
+
+type ChatStorage struct {
+	pool   *pgxpool.Pool
+	logger *zap.SugaredLogger
+}
+
+func NewChatStorage(pool *pgxpool.Pool) (*ChatStorage, error) {
+	logger, err := my_logger.Get()
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return &ChatStorage{
+		pool:   pool,
+		logger: logger,
+	}, nil
+}
+
+// selectProductSaler returns saler of product, drafts and hidden products are visible only to saler.
+func (c *ChatStorage) selectProductSaler(ctx context.Context, tx pgx.Tx, productID uint64, userID uint64,
+) (uint64, error) {
+	SQLSelectProductSaler := `SELECT saler_id FROM public."product"
+		WHERE id=$1 AND (status <> ALL($2) OR saler_id = $3)`
+
+	var salerID uint64
+
+	err := tx.QueryRow(ctx, SQLSelectProductSaler, productID, models.PrivateProductStatuses(), userID).Scan(&salerID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, fmt.Errorf(myerrors.ErrTemplate, ErrProductNotFound)
+		}
+
+		c.logger.Errorf("error with productID=%d: %+v", productID, err)
+
+		return 0, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return salerID, nil
+}
+
+// selectInterlocutorID returns other participant of conversation. Strangers get ErrConversationNotFound,
+// so they can't learn that conversation exists.
+func (c *ChatStorage) selectInterlocutorID(ctx context.Context, tx pgx.Tx, conversationID uint64, userID uint64,
+) (uint64, error) {
+	SQLSelectInterlocutorID := `SELECT CASE WHEN buyer_id = $2 THEN saler_id ELSE buyer_id END
+		FROM public."conversation" WHERE id=$1 AND (buyer_id=$2 OR saler_id=$2)`
+
+	var interlocutorID uint64
+
+	err := tx.QueryRow(ctx, SQLSelectInterlocutorID, conversationID, userID).Scan(&interlocutorID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, fmt.Errorf(myerrors.ErrTemplate, ErrConversationNotFound)
+		}
+
+		c.logger.Errorf("error with conversationID=%d: %+v", conversationID, err)
+
+		return 0, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return interlocutorID, nil
+}
+
+const SQLSelectConversations = `SELECT c.id, c.product_id, p.title, c.buyer_id, c.saler_id,
+	c.created_at, c.last_message_at,
+	m.id, m.sender_id, m.text, m.created_at, m.read_at,
+	(SELECT COUNT(*) FROM public."message" um
+		WHERE um.conversation_id = c.id AND um.sender_id <> $1 AND um.read_at IS NULL)
+	FROM public."conversation" c
+	INNER JOIN public."product" p ON p.id = c.product_id
+	LEFT JOIN LATERAL (SELECT id, sender_id, text, created_at, read_at FROM public."message"
+		WHERE conversation_id = c.id ORDER BY id DESC LIMIT 1) m ON TRUE
+	WHERE (c.buyer_id = $1 OR c.saler_id = $1)`
+
+func scanConversation(row pgx.Row, userID uint64) (*models.Conversation, error) {
+	conversation := &models.Conversation{} //nolint:exhaustruct
+
+	var (
+		lastMessageID        *uint64
+		lastMessageSenderID  *uint64
+		lastMessageText      *string
+		lastMessageCreatedAt *time.Time
+		lastMessageReadAt    *time.Time
+	)
+
+	err := row.Scan(&conversation.ID, &conversation.ProductID, &conversation.ProductTitle,
+		&conversation.BuyerID, &conversation.SalerID, &conversation.CreatedAt, &conversation.LastMessageAt,
+		&lastMessageID, &lastMessageSenderID, &lastMessageText, &lastMessageCreatedAt, &lastMessageReadAt,
+		&conversation.UnreadCount)
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	if lastMessageID != nil {
+		conversation.LastMessage = &models.Message{
+			ID:             *lastMessageID,
+			ConversationID: conversation.ID,
+			SenderID:       *lastMessageSenderID,
+			Text:           *lastMessageText,
+			IsMy:           *lastMessageSenderID == userID,
+			CreatedAt:      *lastMessageCreatedAt,
+			ReadAt:         lastMessageReadAt,
+		}
+	}
+
+	return conversation, nil
+}
+
+func (c *ChatStorage) selectConversationByID(ctx context.Context, tx pgx.Tx, conversationID uint64,
+	userID uint64,
+) (*models.Conversation, error) {
+	row := tx.QueryRow(ctx, SQLSelectConversations+` AND c.id = $2`, userID, conversationID)
+
+	conversation, err := scanConversation(row, userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf(myerrors.ErrTemplate, ErrConversationNotFound)
+		}
+
+		c.logger.Errorf("error with conversationID=%d: %+v", conversationID, err)
+
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return conversation, nil
+}
+
+// CreateConversation is idempotent: buyer has one conversation about product and gets it back on repeated start.
+func (c *ChatStorage) CreateConversation(ctx context.Context, productID uint64, buyerID uint64,
+) (*models.Conversation, error) {
+	SQLInsertConversation := `INSERT INTO public."conversation"(product_id, buyer_id, saler_id) VALUES($1, $2, $3)
+		ON CONFLICT (product_id, buyer_id) DO NOTHING`
+	SQLSelectConversationID := `SELECT id FROM public."conversation" WHERE product_id=$1 AND buyer_id=$2`
+
+	var conversation *models.Conversation
+
+	err := pgx.BeginFunc(ctx, c.pool, func(tx pgx.Tx) error {
+		salerID, err := c.selectProductSaler(ctx, tx, productID, buyerID)
+		if err != nil {
+			return err
+		}
+
+		if salerID == buyerID {
+			return fmt.Errorf(myerrors.ErrTemplate, ErrSelfConversation)
+		}
+
+		_, err = tx.Exec(ctx, SQLInsertConversation, productID, buyerID, salerID)
+		if err != nil {
+			c.logger.Errorln(err)
+
+			return fmt.Errorf(myerrors.ErrTemplate, err)
+		}
+
+		var conversationID uint64
+
+		err = tx.QueryRow(ctx, SQLSelectConversationID, productID, buyerID).Scan(&conversationID)
+		if err != nil {
+			c.logger.Errorln(err)
+
+			return fmt.Errorf(myerrors.ErrTemplate, err)
+		}
+
+		conversation, err = c.selectConversationByID(ctx, tx, conversationID, buyerID)
+
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return conversation, nil
+}
+
+func (c *ChatStorage) selectMessageByID(ctx context.Context, tx pgx.Tx, messageID uint64, userID uint64,
+) (*models.Message, error) {
+	SQLSelectMessage := `SELECT id, conversation_id, sender_id, text, created_at, read_at
+		FROM public."message" WHERE id=$1`
+
+	message := &models.Message{} //nolint:exhaustruct
+
+	err := tx.QueryRow(ctx, SQLSelectMessage, messageID).Scan(&message.ID, &message.ConversationID,
+		&message.SenderID, &message.Text, &message.CreatedAt, &message.ReadAt)
+	if err != nil {
+		c.logger.Errorf("error with messageID=%d: %+v", messageID, err)
+
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	message.IsMy = message.SenderID == userID
+
+	return message, nil
+}
+
+// AddMessage returns added message and id of its recipient.
+func (c *ChatStorage) AddMessage(ctx context.Context, conversationID uint64, senderID uint64,
+	preMessage *models.PreMessage,
+) (*models.Message, uint64, error) {
+	SQLInsertMessage := `INSERT INTO public."message"(conversation_id, sender_id, text) VALUES($1, $2, $3)`
+	SQLUpdateLastMessageAt := `UPDATE public."conversation" SET last_message_at = NOW() WHERE id=$1`
+
+	var (
+		message     *models.Message
+		recipientID uint64
+	)
+
+	err := pgx.BeginFunc(ctx, c.pool, func(tx pgx.Tx) error {
+		var err error
+
+		recipientID, err = c.selectInterlocutorID(ctx, tx, conversationID, senderID)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, SQLInsertMessage, conversationID, senderID, preMessage.Text)
+		if err != nil {
+			c.logger.Errorln(err)
+
+			return fmt.Errorf(myerrors.ErrTemplate, err)
+		}
+
+		messageID, err := repository.GetLastValSeq(ctx, tx, pgx.Identifier{"public", "message_id_seq"})
+		if err != nil {
+			return fmt.Errorf(myerrors.ErrTemplate, err)
+		}
+
+		_, err = tx.Exec(ctx, SQLUpdateLastMessageAt, conversationID)
+		if err != nil {
+			c.logger.Errorln(err)
+
+			return fmt.Errorf(myerrors.ErrTemplate, err)
+		}
+
+		message, err = c.selectMessageByID(ctx, tx, messageID, senderID)
+
+		return err
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return message, recipientID, nil
+}
+
+func (c *ChatStorage) GetConversations(ctx context.Context, userID uint64, limit uint64, offset uint64,
+) ([]*models.Conversation, error) {
+	var conversations []*models.Conversation
+
+	err := pgx.BeginFunc(ctx, c.pool, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, SQLSelectConversations+` ORDER BY c.last_message_at DESC, c.id DESC
+			LIMIT $2 OFFSET $3`, userID, limit, offset)
+		if err != nil {
+			c.logger.Errorln(err)
+
+			return fmt.Errorf(myerrors.ErrTemplate, err)
+		}
+
+		conversations, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (*models.Conversation, error) {
+			return scanConversation(row, userID)
+		})
+		if err != nil {
+			c.logger.Errorln(err)
+
+			return fmt.Errorf(myerrors.ErrTemplate, err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return conversations, nil
+}
+
+// GetMessages returns messages newest first, beforeID = 0 means from the newest message.
+func (c *ChatStorage) GetMessages(ctx context.Context, conversationID uint64, userID uint64, beforeID uint64,
+	limit uint64,
+) ([]*models.Message, error) {
+	SQLSelectMessages := `SELECT id, conversation_id, sender_id, text, created_at, read_at
+		FROM public."message" WHERE conversation_id=$1 AND ($2::bigint = 0 OR id < $2::bigint)
+		ORDER BY id DESC LIMIT $3`
+
+	var messages []*models.Message
+
+	err := pgx.BeginFunc(ctx, c.pool, func(tx pgx.Tx) error {
+		if _, err := c.selectInterlocutorID(ctx, tx, conversationID, userID); err != nil {
+			return err
+		}
+
+		rows, err := tx.Query(ctx, SQLSelectMessages, conversationID, beforeID, limit)
+		if err != nil {
+			c.logger.Errorln(err)
+
+			return fmt.Errorf(myerrors.ErrTemplate, err)
+		}
+
+		messages, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (*models.Message, error) {
+			message := &models.Message{} //nolint:exhaustruct
+			err := row.Scan(&message.ID, &message.ConversationID, &message.SenderID, &message.Text,
+				&message.CreatedAt, &message.ReadAt)
+			message.IsMy = message.SenderID == userID
+
+			return message, err //nolint:wrapcheck
+		})
+		if err != nil {
+			c.logger.Errorln(err)
+
+			return fmt.Errorf(myerrors.ErrTemplate, err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return messages, nil
+}
+
+// MarkRead sets read receipts on messages of interlocutor, upToID = 0 means all messages.
+func (c *ChatStorage) MarkRead(ctx context.Context, conversationID uint64, userID uint64, upToID uint64) error {
+	SQLMarkRead := `UPDATE public."message" SET read_at = NOW()
+		WHERE conversation_id=$1 AND sender_id <> $2 AND read_at IS NULL AND ($3::bigint = 0 OR id <= $3::bigint)`
+
+	err := pgx.BeginFunc(ctx, c.pool, func(tx pgx.Tx) error {
+		if _, err := c.selectInterlocutorID(ctx, tx, conversationID, userID); err != nil {
+			return err
+		}
+
+		_, err := tx.Exec(ctx, SQLMarkRead, conversationID, userID, upToID)
+		if err != nil {
+			c.logger.Errorln(err)
+
+			return fmt.Errorf(myerrors.ErrTemplate, err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return nil
+}
+
+func (c *ChatStorage) GetUnreadCount(ctx context.Context, userID uint64) (uint64, error) {
+	SQLSelectUnreadCount := `SELECT COUNT(*) FROM public."message" m
+		INNER JOIN public."conversation" c ON c.id = m.conversation_id
+		WHERE (c.buyer_id = $1 OR c.saler_id = $1) AND m.sender_id <> $1 AND m.read_at IS NULL`
+
+	var count uint64
+
+	err := pgx.BeginFunc(ctx, c.pool, func(tx pgx.Tx) error {
+		err := tx.QueryRow(ctx, SQLSelectUnreadCount, userID).Scan(&count)
+		if err != nil {
+			c.logger.Errorln(err)
+
+			return fmt.Errorf(myerrors.ErrTemplate, err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return count, nil
+}
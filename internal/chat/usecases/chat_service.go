@@ -0,0 +1,156 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+	chatrepo "github.com/SanExpett/marketplace-backend/internal/chat/repository"
+	"github.com/SanExpett/marketplace-backend/pkg/event_hub"
+	"github.com/SanExpett/marketplace-backend/pkg/models"
+	myerrors "github.com/SanExpett/marketplace-backend/pkg/my_errors"
+	"github.com/SanExpett/marketplace-backend/pkg/my_logger"
+	"github.com/SanExpett/marketplace-backend/pkg/utils"
+	"go.uber.org/zap"
+	"io"
+)
+
+var _ IChatStorage = (*chatrepo.ChatStorage)(nil)
+
+type IChatStorage interface {
+	CreateConversation(ctx context.Context, productID uint64, buyerID uint64) (*models.Conversation, error)
+	AddMessage(ctx context.Context, conversationID uint64, senderID uint64,
+		preMessage *models.PreMessage) (*models.Message, uint64, error)
+	GetConversations(ctx context.Context, userID uint64, limit uint64, offset uint64) ([]*models.Conversation, error)
+	GetMessages(ctx context.Context, conversationID uint64, userID uint64, beforeID uint64,
+		limit uint64) ([]*models.Message, error)
+	MarkRead(ctx context.Context, conversationID uint64, userID uint64, upToID uint64) error
+	GetUnreadCount(ctx context.Context, userID uint64) (uint64, error)
+}
+
+var _ IEventPublisher = (*event_hub.EventHub)(nil)
+
+type IEventPublisher interface {
+	Publish(ctx context.Context, event *models.Event, recipients ...uint64) error
+}
+
+type ChatService struct {
+	storage      IChatStorage
+	publisher    IEventPublisher
+	maxPageLimit uint64
+	logger       *zap.SugaredLogger
+}
+
+// NewChatService creates service. publisher can be nil, then new messages are not pushed to recipients.
+func NewChatService(chatStorage IChatStorage, publisher IEventPublisher, maxPageLimit uint64,
+) (*ChatService, error) {
+	logger, err := my_logger.Get()
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return &ChatService{storage: chatStorage, publisher: publisher, maxPageLimit: maxPageLimit, logger: logger}, nil
+}
+
+func (c *ChatService) StartConversation(ctx context.Context, productID uint64, userID uint64,
+) (*models.Conversation, error) {
+	conversation, err := c.storage.CreateConversation(ctx, productID, userID)
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	conversation.Sanitize()
+
+	return conversation, nil
+}
+
+func (c *ChatService) SendMessage(ctx context.Context, r io.Reader, conversationID uint64, userID uint64,
+) (*models.Message, error) {
+	preMessage, err := ValidatePreMessage(r)
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	message, recipientID, err := c.storage.AddMessage(ctx, conversationID, userID, preMessage)
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	message.Sanitize()
+	c.publishNewMessage(ctx, message, recipientID)
+
+	return message, nil
+}
+
+// publishNewMessage doesn't fail sending: message is saved and recipient gets it on next fetch anyway.
+func (c *ChatService) publishNewMessage(ctx context.Context, message *models.Message, recipientID uint64) {
+	if c.publisher == nil {
+		return
+	}
+
+	messageOfRecipient := *message
+	messageOfRecipient.IsMy = false
+
+	event, err := models.NewEvent(models.EventTypeNewMessage, &messageOfRecipient)
+	if err == nil {
+		err = c.publisher.Publish(ctx, event, recipientID)
+	}
+
+	if err != nil {
+		c.logger.Errorf("message %d is not published: %+v", message.ID, err)
+	}
+}
+
+func (c *ChatService) GetConversations(ctx context.Context, userID uint64, limit uint64, offset uint64,
+) ([]*models.Conversation, error) {
+	limit, err := utils.ValidateLimit(limit, defaultPageLimit, c.maxPageLimit)
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	conversations, err := c.storage.GetConversations(ctx, userID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	for _, conversation := range conversations {
+		conversation.Sanitize()
+	}
+
+	return conversations, nil
+}
+
+func (c *ChatService) GetMessages(ctx context.Context, conversationID uint64, userID uint64, beforeID uint64,
+	limit uint64,
+) ([]*models.Message, error) {
+	limit, err := utils.ValidateLimit(limit, defaultPageLimit, c.maxPageLimit)
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	messages, err := c.storage.GetMessages(ctx, conversationID, userID, beforeID, limit)
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	for _, message := range messages {
+		message.Sanitize()
+	}
+
+	return messages, nil
+}
+
+func (c *ChatService) MarkRead(ctx context.Context, conversationID uint64, userID uint64, upToID uint64) error {
+	if err := c.storage.MarkRead(ctx, conversationID, userID, upToID); err != nil {
+		return fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return nil
+}
+
+func (c *ChatService) GetUnreadCount(ctx context.Context, userID uint64) (*models.UnreadCount, error) {
+	count, err := c.storage.GetUnreadCount(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return &models.UnreadCount{Count: count}, nil
+}
@@ -0,0 +1,52 @@
+package usecases
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/SanExpett/marketplace-backend/pkg/models"
+	myerrors "github.com/SanExpett/marketplace-backend/pkg/my_errors"
+	"github.com/SanExpett/marketplace-backend/pkg/my_logger"
+	"github.com/asaskevich/govalidator"
+	"io"
+)
+
+const defaultPageLimit = 20
+
+var (
+	ErrDecodePreMessage = myerrors.NewError("Некорректный json сообщения")
+)
+
+func validatePreMessage(r io.Reader) (*models.PreMessage, error) {
+	logger, err := my_logger.Get()
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(r)
+	preMessage := &models.PreMessage{} //nolint:exhaustruct
+	if err := decoder.Decode(preMessage); err != nil {
+		logger.Errorln(err)
+
+		return nil, fmt.Errorf(myerrors.ErrTemplate, ErrDecodePreMessage)
+	}
+
+	preMessage.Trim()
+
+	_, err = govalidator.ValidateStruct(preMessage)
+	if err != nil {
+		logger.Errorln(err)
+
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return preMessage, nil
+}
+
+func ValidatePreMessage(r io.Reader) (*models.PreMessage, error) {
+	preMessage, err := validatePreMessage(r)
+	if err != nil {
+		return nil, myerrors.NewError(err.Error())
+	}
+
+	return preMessage, nil
+}
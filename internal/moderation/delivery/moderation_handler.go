@@ -0,0 +1,299 @@
+package delivery
+
+import (
+	"context"
+	"fmt"
+	"github.com/SanExpett/marketplace-backend/internal/moderation/usecases"
+	"github.com/SanExpett/marketplace-backend/internal/server/delivery"
+	"github.com/SanExpett/marketplace-backend/pkg/models"
+	myerrors "github.com/SanExpett/marketplace-backend/pkg/my_errors"
+	"github.com/SanExpett/marketplace-backend/pkg/my_logger"
+	"github.com/SanExpett/marketplace-backend/pkg/utils"
+	"go.uber.org/zap"
+	"io"
+	"net/http"
+)
+
+var _ IModerationService = (*usecases.ModerationService)(nil)
+
+type IModerationService interface {
+	AddReport(ctx context.Context, r io.Reader, productID uint64, userID uint64) (*models.Report, error)
+	GetQueue(ctx context.Context, userID uint64, limit uint64, offset uint64) ([]*models.ModerationItem, error)
+	HideProduct(ctx context.Context, r io.Reader, productID uint64, userID uint64) (*models.ModerationAction, error)
+	RestoreProduct(ctx context.Context, r io.Reader, productID uint64, userID uint64) (*models.ModerationAction, error)
+	BanUser(ctx context.Context, r io.Reader, bannedUserID uint64, userID uint64) (*models.ModerationAction, error)
+	GetAuditTrail(ctx context.Context, userID uint64, productID uint64, targetUserID uint64, limit uint64,
+		offset uint64) ([]*models.ModerationAction, error)
+}
+
+type ModerationHandler struct {
+	service IModerationService
+	logger  *zap.SugaredLogger
+}
+
+func NewModerationHandler(moderationService IModerationService) (*ModerationHandler, error) {
+	logger, err := my_logger.Get()
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return &ModerationHandler{
+		service: moderationService,
+		logger:  logger,
+	}, nil
+}
+
+// AddReportHandler godoc
+//
+//	@Summary    report product
+//	@Description  report product to moderators, repeated report of the same user is not counted.
+//	@Description  Product with enough reports is hidden until moderator's decision
+//	@Tags moderation
+//	@Accept      json
+//	@Produce    json
+//	@Param      product_id  query uint64 true  "product id"
+//	@Param      preReport  body models.PreReport true  "reason is one of scam, prohibited, wrong_category, duplicate, spam, offensive, other"
+//	@Success    200  {object} ReportResponse
+//	@Failure    405  {string} string
+//	@Failure    500  {string} string
+//	@Failure    222  {object} delivery.ErrorResponse "Error"
+//	@Router      /report/add [post]
+func (m *ModerationHandler) AddReportHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, `Method not allowed`, http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	ctx := r.Context()
+
+	userID, err := delivery.GetUserIDFromCookie(r)
+	if err != nil {
+		delivery.HandleErr(w, m.logger, err)
+
+		return
+	}
+
+	productID, err := utils.ParseUint64FromRequest(r, "product_id")
+	if err != nil {
+		delivery.HandleErr(w, m.logger, err)
+
+		return
+	}
+
+	report, err := m.service.AddReport(ctx, r.Body, productID, userID)
+	if err != nil {
+		delivery.HandleErr(w, m.logger, err)
+
+		return
+	}
+
+	delivery.SendOkResponse(w, m.logger, NewReportResponse(delivery.StatusResponseSuccessful, report))
+	m.logger.Infof("in AddReportHandler: user %d reported product %d", userID, productID)
+}
+
+// GetQueueHandler godoc
+//
+//	@Summary    get moderation queue
+//	@Description  get products with open reports, the most reported first, then the longest waiting. Only for moderators
+//	@Tags moderation
+//	@Produce    json
+//	@Param      limit  query uint64 false  "limit of products, 20 by default, can't be bigger than configured maximum"
+//	@Param      offset  query uint64 false  "offset of products"
+//	@Success    200  {object} ModerationQueueResponse
+//	@Failure    405  {string} string
+//	@Failure    500  {string} string
+//	@Failure    222  {object} delivery.ErrorResponse "Error"
+//	@Router      /moderation/queue [get]
+func (m *ModerationHandler) GetQueueHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, `Method not allowed`, http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	ctx := r.Context()
+
+	userID, err := delivery.GetUserIDFromCookie(r)
+	if err != nil {
+		delivery.HandleErr(w, m.logger, err)
+
+		return
+	}
+
+	limit, err := utils.ParseUint64FromRequest(r, "limit")
+	if err != nil {
+		limit = 0
+	}
+
+	offset, err := utils.ParseUint64FromRequest(r, "offset")
+	if err != nil {
+		offset = 0
+	}
+
+	queue, err := m.service.GetQueue(ctx, userID, limit, offset)
+	if err != nil {
+		delivery.HandleErr(w, m.logger, err)
+
+		return
+	}
+
+	delivery.SendOkResponse(w, m.logger, NewModerationQueueResponse(delivery.StatusResponseSuccessful, queue))
+	m.logger.Infof("in GetQueueHandler: moderator %d got %d products", userID, len(queue))
+}
+
+// handleModerationAction is common part of handlers of moderator's actions with target from query param.
+func (m *ModerationHandler) handleModerationAction(w http.ResponseWriter, r *http.Request, targetParam string,
+	action func(ctx context.Context, r io.Reader, targetID uint64, userID uint64) (*models.ModerationAction, error),
+) {
+	if r.Method != http.MethodPost {
+		http.Error(w, `Method not allowed`, http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	ctx := r.Context()
+
+	userID, err := delivery.GetUserIDFromCookie(r)
+	if err != nil {
+		delivery.HandleErr(w, m.logger, err)
+
+		return
+	}
+
+	targetID, err := utils.ParseUint64FromRequest(r, targetParam)
+	if err != nil {
+		delivery.HandleErr(w, m.logger, err)
+
+		return
+	}
+
+	moderationAction, err := action(ctx, r.Body, targetID, userID)
+	if err != nil {
+		delivery.HandleErr(w, m.logger, err)
+
+		return
+	}
+
+	delivery.SendOkResponse(w, m.logger,
+		NewModerationActionResponse(delivery.StatusResponseSuccessful, moderationAction))
+	m.logger.Infof("in %s: moderator %d did: %+v", r.URL.Path, userID, moderationAction)
+}
+
+// HideProductHandler godoc
+//
+//	@Summary    hide product
+//	@Description  moderator hides product and closes its reports, action is written to audit trail
+//	@Tags moderation
+//	@Accept      json
+//	@Produce    json
+//	@Param      product_id  query uint64 true  "product id"
+//	@Param      preAction  body models.PreModerationAction false  "comment of moderator"
+//	@Success    200  {object} ModerationActionResponse
+//	@Failure    405  {string} string
+//	@Failure    500  {string} string
+//	@Failure    222  {object} delivery.ErrorResponse "Error"
+//	@Router      /moderation/hide [post]
+func (m *ModerationHandler) HideProductHandler(w http.ResponseWriter, r *http.Request) {
+	m.handleModerationAction(w, r, "product_id", m.service.HideProduct)
+}
+
+// RestoreProductHandler godoc
+//
+//	@Description  moderator returns hidden product to feed and dismisses its reports, action is written to audit trail.
+//	@Description  Product held by order or accepted offer is restored as reserved
+//	@Description  moderator returns hidden product to feed and dismisses its reports, action is written to audit trail
+//	@Tags moderation
+//	@Accept      json
+//	@Produce    json
+//	@Param      product_id  query uint64 true  "product id"
+//	@Param      preAction  body models.PreModerationAction false  "comment of moderator"
+//	@Success    200  {object} ModerationActionResponse
+//	@Failure    405  {string} string
+//	@Failure    500  {string} string
+//	@Failure    222  {object} delivery.ErrorResponse "Error"
+//	@Router      /moderation/restore [post]
+func (m *ModerationHandler) RestoreProductHandler(w http.ResponseWriter, r *http.Request) {
+	m.handleModerationAction(w, r, "product_id", m.service.RestoreProduct)
+}
+
+// BanUserHandler godoc
+//
+//	@Summary    ban saler
+//	@Description  moderator bans user: he can't sign in and his products are hidden, action is written to audit trail
+//	@Tags moderation
+//	@Accept      json
+//	@Produce    json
+//	@Param      user_id  query uint64 true  "id of banned user"
+//	@Param      preAction  body models.PreModerationAction false  "comment of moderator"
+//	@Success    200  {object} ModerationActionResponse
+//	@Failure    405  {string} string
+//	@Failure    500  {string} string
+//	@Failure    222  {object} delivery.ErrorResponse "Error"
+//	@Router      /moderation/ban [post]
+func (m *ModerationHandler) BanUserHandler(w http.ResponseWriter, r *http.Request) {
+	m.handleModerationAction(w, r, "user_id", m.service.BanUser)
+}
+
+// GetAuditTrailHandler godoc
+//
+//	@Summary    get audit trail
+//	@Description  get actions of moderators and automatic hiding, newest first. Only for moderators
+//	@Tags moderation
+//	@Produce    json
+//	@Param      product_id  query uint64 false  "only actions with this product"
+//	@Param      user_id  query uint64 false  "only actions with this user"
+//	@Param      limit  query uint64 false  "limit of actions, 20 by default, can't be bigger than configured maximum"
+//	@Param      offset  query uint64 false  "offset of actions"
+//	@Success    200  {object} ModerationActionListResponse
+//	@Failure    405  {string} string
+//	@Failure    500  {string} string
+//	@Failure    222  {object} delivery.ErrorResponse "Error"
+//	@Router      /moderation/audit [get]
+func (m *ModerationHandler) GetAuditTrailHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, `Method not allowed`, http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	ctx := r.Context()
+
+	userID, err := delivery.GetUserIDFromCookie(r)
+	if err != nil {
+		delivery.HandleErr(w, m.logger, err)
+
+		return
+	}
+
+	productID, err := utils.ParseUint64FromRequest(r, "product_id")
+	if err != nil {
+		productID = 0
+	}
+
+	targetUserID, err := utils.ParseUint64FromRequest(r, "user_id")
+	if err != nil {
+		targetUserID = 0
+	}
+
+	limit, err := utils.ParseUint64FromRequest(r, "limit")
+	if err != nil {
+		limit = 0
+	}
+
+	offset, err := utils.ParseUint64FromRequest(r, "offset")
+	if err != nil {
+		offset = 0
+	}
+
+	auditTrail, err := m.service.GetAuditTrail(ctx, userID, productID, targetUserID, limit, offset)
+	if err != nil {
+		delivery.HandleErr(w, m.logger, err)
+
+		return
+	}
+
+	delivery.SendOkResponse(w, m.logger,
+		NewModerationActionListResponse(delivery.StatusResponseSuccessful, auditTrail))
+	m.logger.Infof("in GetAuditTrailHandler: moderator %d got %d actions", userID, len(auditTrail))
+}
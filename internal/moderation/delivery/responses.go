@@ -0,0 +1,51 @@
+package delivery
+
+import "github.com/SanExpett/marketplace-backend/pkg/models"
+
+type ReportResponse struct {
+	Status int            `json:"status"`
+	Body   *models.Report `json:"body"`
+}
+
+func NewReportResponse(status int, body *models.Report) *ReportResponse {
+	return &ReportResponse{
+		Status: status,
+		Body:   body,
+	}
+}
+
+type ModerationQueueResponse struct {
+	Status int                      `json:"status"`
+	Body   []*models.ModerationItem `json:"body"`
+}
+
+func NewModerationQueueResponse(status int, body []*models.ModerationItem) *ModerationQueueResponse {
+	return &ModerationQueueResponse{
+		Status: status,
+		Body:   body,
+	}
+}
+
+type ModerationActionResponse struct {
+	Status int                      `json:"status"`
+	Body   *models.ModerationAction `json:"body"`
+}
+
+func NewModerationActionResponse(status int, body *models.ModerationAction) *ModerationActionResponse {
+	return &ModerationActionResponse{
+		Status: status,
+		Body:   body,
+	}
+}
+
+type ModerationActionListResponse struct {
+	Status int                        `json:"status"`
+	Body   []*models.ModerationAction `json:"body"`
+}
+
+func NewModerationActionListResponse(status int, body []*models.ModerationAction) *ModerationActionListResponse {
+	return &ModerationActionListResponse{
+		Status: status,
+		Body:   body,
+	}
+}
@@ -0,0 +1,550 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/SanExpett/marketplace-backend/internal/server/repository"
+	"github.com/SanExpett/marketplace-backend/pkg/models"
+	myerrors "github.com/SanExpett/marketplace-backend/pkg/my_errors"
+	"github.com/SanExpett/marketplace-backend/pkg/my_logger"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+	"slices"
+)
+
+const MessageAutoHideComment = "Скрыто автоматически после %d жалоб"
+
+var (
+	ErrProductNotFound    = myerrors.NewError("Этот товар не найден")
+	ErrReportOnMyProduct  = myerrors.NewError("Нельзя пожаловаться на свое объявление")
+	ErrNotModerator       = myerrors.NewError("Это действие доступно только модераторам")
+	ErrProductNotHideable = myerrors.NewError("Скрыть можно только опубликованное объявление или объявление на проверке")
+	ErrUserNotFound       = myerrors.NewError("Этот пользователь не найден")
+	ErrUserAlreadyBanned  = myerrors.NewError("Этот пользователь уже заблокирован")
+	ErrBanModerator       = myerrors.NewError("Нельзя заблокировать модератора")
+
+	NameSeqModerationAction = pgx.Identifier{"public", "moderation_action_id_seq"} //nolint:gochecknoglobals
+)
+
+type ConfigModerationStorage struct {
+	// hideThreshold is number of open reports after which product is hidden until moderator's decision,
+	// 0 disables automatic hiding
+	hideThreshold uint64
+}
+
+func NewConfigModerationStorage(hideThreshold uint64) *ConfigModerationStorage {
+	return &ConfigModerationStorage{
+		hideThreshold: hideThreshold,
+	}
+}
+
+type ModerationStorage struct {
+	pool   *pgxpool.Pool
+	config *ConfigModerationStorage
+	logger *zap.SugaredLogger
+}
+
+func NewModerationStorage(pool *pgxpool.Pool, config *ConfigModerationStorage) (*ModerationStorage, error) {
+	logger, err := my_logger.Get()
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return &ModerationStorage{
+		pool:   pool,
+		config: config,
+		logger: logger,
+	}, nil
+}
+
+// hideableProductStatuses are statuses of products which can be hidden by moderation.
+func hideableProductStatuses() []string {
+	return []string{models.ProductStatusActive, models.ProductStatusReserved}
+}
+
+// checkModerator returns ErrNotModerator if user is not moderator.
+func (m *ModerationStorage) checkModerator(ctx context.Context, tx pgx.Tx, userID uint64) error {
+	SQLIsModerator := `SELECT EXISTS(SELECT 1 FROM public."user" WHERE id=$1 AND is_moderator AND banned_at IS NULL)`
+
+	var isModerator bool
+
+	err := tx.QueryRow(ctx, SQLIsModerator, userID).Scan(&isModerator)
+	if err != nil {
+		m.logger.Errorf("error with userID=%d: %+v", userID, err)
+
+		return fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	if !isModerator {
+		return fmt.Errorf(myerrors.ErrTemplate, ErrNotModerator)
+	}
+
+	return nil
+}
+
+// selectProductForUpdate returns saler and status of product and locks it until end of transaction.
+func (m *ModerationStorage) selectProductForUpdate(ctx context.Context, tx pgx.Tx, productID uint64,
+) (uint64, string, error) {
+	SQLSelectProductForUpdate := `SELECT saler_id, status FROM public."product" WHERE id=$1 FOR UPDATE`
+
+	var (
+		salerID uint64
+		status  string
+	)
+
+	err := tx.QueryRow(ctx, SQLSelectProductForUpdate, productID).Scan(&salerID, &status)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, "", fmt.Errorf(myerrors.ErrTemplate, ErrProductNotFound)
+		}
+
+		m.logger.Errorf("error with productID=%d: %+v", productID, err)
+
+		return 0, "", fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return salerID, status, nil
+}
+
+func (m *ModerationStorage) updateProductStatus(ctx context.Context, tx pgx.Tx, productID uint64, status string,
+) error {
+	SQLUpdateProductStatus := `UPDATE public."product" SET status=$1 WHERE id=$2`
+
+	_, err := tx.Exec(ctx, SQLUpdateProductStatus, status, productID)
+	if err != nil {
+		m.logger.Errorln(err)
+
+		return fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return nil
+}
+
+// isProductHeld reports whether hidden product is still held by live order or by accepted offer that has not
+// expired, so it must come back as reserved.
+func (m *ModerationStorage) isProductHeld(ctx context.Context, tx pgx.Tx, productID uint64) (bool, error) {
+	SQLIsProductHeld := `SELECT EXISTS(SELECT 1 FROM public."order" WHERE product_id=$1 AND status = ANY($2))
+		OR EXISTS(SELECT 1 FROM public."offer" WHERE product_id=$1 AND status=$3 AND expires_at > NOW())`
+
+	var isHeld bool
+
+	err := tx.QueryRow(ctx, SQLIsProductHeld, productID, models.LiveOrderStatuses(),
+		models.OfferStatusAccepted).Scan(&isHeld)
+	if err != nil {
+		m.logger.Errorf("error with productID=%d: %+v", productID, err)
+
+		return false, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return isHeld, nil
+}
+
+// resolveReports closes open reports about product, so reporters can report it again later.
+func (m *ModerationStorage) resolveReports(ctx context.Context, tx pgx.Tx, productID uint64) error {
+	SQLResolveReports := `UPDATE public."report" SET is_resolved = TRUE WHERE product_id=$1 AND NOT is_resolved`
+
+	_, err := tx.Exec(ctx, SQLResolveReports, productID)
+	if err != nil {
+		m.logger.Errorln(err)
+
+		return fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return nil
+}
+
+func (m *ModerationStorage) countOpenReports(ctx context.Context, tx pgx.Tx, productID uint64) (uint64, error) {
+	SQLCountOpenReports := `SELECT COUNT(*) FROM public."report" WHERE product_id=$1 AND NOT is_resolved`
+
+	var count uint64
+
+	err := tx.QueryRow(ctx, SQLCountOpenReports, productID).Scan(&count)
+	if err != nil {
+		m.logger.Errorln(err)
+
+		return 0, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return count, nil
+}
+
+const SQLSelectModerationAction = `SELECT id, moderator_id, action, product_id, user_id, comment, created_at
+	FROM public."moderation_action"`
+
+func scanModerationAction(row pgx.Row) (*models.ModerationAction, error) {
+	action := &models.ModerationAction{} //nolint:exhaustruct
+
+	err := row.Scan(&action.ID, &action.ModeratorID, &action.Action, &action.ProductID, &action.UserID,
+		&action.Comment, &action.CreatedAt)
+
+	return action, err //nolint:wrapcheck
+}
+
+// insertModerationAction writes action to audit trail. Zero moderatorID means automatic action,
+// zero productID or userID means action has no such target.
+func (m *ModerationStorage) insertModerationAction(ctx context.Context, tx pgx.Tx, moderatorID uint64,
+	action string, productID uint64, userID uint64, comment string,
+) (*models.ModerationAction, error) {
+	SQLInsertModerationAction := `INSERT INTO public."moderation_action"(moderator_id, action, product_id, user_id,
+		comment) VALUES(NULLIF($1::bigint, 0), $2, NULLIF($3::bigint, 0), NULLIF($4::bigint, 0), $5)`
+
+	_, err := tx.Exec(ctx, SQLInsertModerationAction, moderatorID, action, productID, userID, comment)
+	if err != nil {
+		m.logger.Errorln(err)
+
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	id, err := repository.GetLastValSeq(ctx, tx, NameSeqModerationAction)
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	moderationAction, err := scanModerationAction(tx.QueryRow(ctx, SQLSelectModerationAction+` WHERE id=$1`, id))
+	if err != nil {
+		m.logger.Errorln(err)
+
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return moderationAction, nil
+}
+
+// AddReport is idempotent: reporter has one open report about product and gets it back on repeated report,
+// so one user can't push product over hide threshold. Product with enough open reports is hidden
+// in the same transaction.
+func (m *ModerationStorage) AddReport(ctx context.Context, productID uint64, reporterID uint64,
+	preReport *models.PreReport,
+) (*models.Report, error) {
+	SQLInsertReport := `INSERT INTO public."report"(product_id, reporter_id, reason, comment) VALUES($1, $2, $3, $4)
+		ON CONFLICT (product_id, reporter_id) WHERE NOT is_resolved DO NOTHING`
+	SQLSelectReport := `SELECT id, product_id, reporter_id, reason, comment, is_resolved, created_at
+		FROM public."report" WHERE product_id=$1 AND reporter_id=$2 AND NOT is_resolved`
+
+	report := &models.Report{} //nolint:exhaustruct
+
+	err := pgx.BeginFunc(ctx, m.pool, func(tx pgx.Tx) error {
+		salerID, status, err := m.selectProductForUpdate(ctx, tx, productID)
+		if err != nil {
+			return err
+		}
+
+		if salerID == reporterID {
+			return fmt.Errorf(myerrors.ErrTemplate, ErrReportOnMyProduct)
+		}
+
+		if slices.Contains(models.PrivateProductStatuses(), status) {
+			return fmt.Errorf(myerrors.ErrTemplate, ErrProductNotFound)
+		}
+
+		_, err = tx.Exec(ctx, SQLInsertReport, productID, reporterID, preReport.Reason, preReport.Comment)
+		if err != nil {
+			m.logger.Errorln(err)
+
+			return fmt.Errorf(myerrors.ErrTemplate, err)
+		}
+
+		err = tx.QueryRow(ctx, SQLSelectReport, productID, reporterID).Scan(&report.ID, &report.ProductID,
+			&report.ReporterID, &report.Reason, &report.Comment, &report.IsResolved, &report.CreatedAt)
+		if err != nil {
+			m.logger.Errorln(err)
+
+			return fmt.Errorf(myerrors.ErrTemplate, err)
+		}
+
+		if m.config.hideThreshold == 0 || !slices.Contains(hideableProductStatuses(), status) {
+			return nil
+		}
+
+		return m.autoHideProduct(ctx, tx, productID)
+	})
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return report, nil
+}
+
+// autoHideProduct hides product pending review if it has reached hide threshold, reports stay open
+// for moderator.
+func (m *ModerationStorage) autoHideProduct(ctx context.Context, tx pgx.Tx, productID uint64) error {
+	count, err := m.countOpenReports(ctx, tx, productID)
+	if err != nil {
+		return err
+	}
+
+	if count < m.config.hideThreshold {
+		return nil
+	}
+
+	if err := m.updateProductStatus(ctx, tx, productID, models.ProductStatusHidden); err != nil {
+		return err
+	}
+
+	_, err = m.insertModerationAction(ctx, tx, 0, models.ModerationActionAutoHide, productID, 0,
+		fmt.Sprintf(MessageAutoHideComment, count))
+
+	return err
+}
+
+// GetQueue returns products with open reports and products sent to review by premoderation,
+// the most reported first, then the longest waiting.
+func (m *ModerationStorage) GetQueue(ctx context.Context, moderatorID uint64, limit uint64, offset uint64,
+) ([]*models.ModerationItem, error) {
+	SQLSelectQueue := `SELECT p.id, p.saler_id, p.title, p.status, COALESCE(r.reports_count, 0),
+		COALESCE(r.reasons, '{}'), CASE WHEN p.status = $3 THEN p.review_reason ELSE '' END,
+		r.first_reported_at, r.last_reported_at,
+		COALESCE(LEAST(r.first_reported_at, CASE WHEN p.status = $3 THEN p.review_requested_at END),
+			p.created_at) AS waiting_since
+		FROM (SELECT product_id FROM public."report" WHERE NOT is_resolved
+			UNION SELECT id FROM public."product" WHERE status = $3) q
+		INNER JOIN public."product" p ON p.id = q.product_id
+		LEFT JOIN (SELECT product_id, COUNT(*) AS reports_count, ARRAY_AGG(DISTINCT reason) AS reasons,
+			MIN(created_at) AS first_reported_at, MAX(created_at) AS last_reported_at
+			FROM public."report" WHERE NOT is_resolved GROUP BY product_id) r ON r.product_id = p.id
+		ORDER BY COALESCE(r.reports_count, 0) DESC, waiting_since, p.id LIMIT $1 OFFSET $2`
+
+	var queue []*models.ModerationItem
+
+	err := pgx.BeginFunc(ctx, m.pool, func(tx pgx.Tx) error {
+		if err := m.checkModerator(ctx, tx, moderatorID); err != nil {
+			return err
+		}
+
+		rows, err := tx.Query(ctx, SQLSelectQueue, limit, offset, models.ProductStatusOnReview)
+		if err != nil {
+			m.logger.Errorln(err)
+
+			return fmt.Errorf(myerrors.ErrTemplate, err)
+		}
+
+		queue, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (*models.ModerationItem, error) {
+			item := &models.ModerationItem{} //nolint:exhaustruct
+
+			err := row.Scan(&item.ProductID, &item.SalerID, &item.Title, &item.Status, &item.ReportsCount,
+				&item.Reasons, &item.ReviewReason, &item.FirstReportedAt, &item.LastReportedAt, &item.WaitingSince)
+
+			return item, err //nolint:wrapcheck
+		})
+		if err != nil {
+			m.logger.Errorln(err)
+
+			return fmt.Errorf(myerrors.ErrTemplate, err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return queue, nil
+}
+
+// HideProduct hides product and closes its reports. Product hidden automatically can be hidden again,
+// that confirms automatic decision. Hiding product on review rejects it.
+func (m *ModerationStorage) HideProduct(ctx context.Context, productID uint64, moderatorID uint64,
+	preAction *models.PreModerationAction,
+) (*models.ModerationAction, error) {
+	var moderationAction *models.ModerationAction
+
+	err := pgx.BeginFunc(ctx, m.pool, func(tx pgx.Tx) error {
+		if err := m.checkModerator(ctx, tx, moderatorID); err != nil {
+			return err
+		}
+
+		_, status, err := m.selectProductForUpdate(ctx, tx, productID)
+		if err != nil {
+			return err
+		}
+
+		if status != models.ProductStatusHidden && status != models.ProductStatusOnReview &&
+			!slices.Contains(hideableProductStatuses(), status) {
+			return fmt.Errorf(myerrors.ErrTemplate, ErrProductNotHideable)
+		}
+
+		if err := m.updateProductStatus(ctx, tx, productID, models.ProductStatusHidden); err != nil {
+			return err
+		}
+
+		if err := m.resolveReports(ctx, tx, productID); err != nil {
+			return err
+		}
+
+		moderationAction, err = m.insertModerationAction(ctx, tx, moderatorID, models.ModerationActionHide,
+			productID, 0, preAction.Comment)
+
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return moderationAction, nil
+}
+
+// RestoreProduct returns hidden product or product on review to feed and dismisses its reports. Product held by
+// live order or accepted offer is restored as reserved. For product in other status it only dismisses reports.
+func (m *ModerationStorage) RestoreProduct(ctx context.Context, productID uint64, moderatorID uint64,
+	preAction *models.PreModerationAction,
+) (*models.ModerationAction, error) {
+	var moderationAction *models.ModerationAction
+
+	err := pgx.BeginFunc(ctx, m.pool, func(tx pgx.Tx) error {
+		if err := m.checkModerator(ctx, tx, moderatorID); err != nil {
+			return err
+		}
+
+		_, status, err := m.selectProductForUpdate(ctx, tx, productID)
+		if err != nil {
+			return err
+		}
+
+		if status == models.ProductStatusHidden || status == models.ProductStatusOnReview {
+			isHeld, err := m.isProductHeld(ctx, tx, productID)
+			if err != nil {
+				return err
+			}
+
+			restoredStatus := models.ProductStatusActive
+			if isHeld {
+				restoredStatus = models.ProductStatusReserved
+			}
+
+			if err := m.updateProductStatus(ctx, tx, productID, restoredStatus); err != nil {
+				return err
+			}
+
+			if err := repository.PublishProduct(ctx, tx, productID); err != nil {
+				return err
+			}
+		}
+
+		if err := m.resolveReports(ctx, tx, productID); err != nil {
+			return err
+		}
+
+		moderationAction, err = m.insertModerationAction(ctx, tx, moderatorID, models.ModerationActionRestore,
+			productID, 0, preAction.Comment)
+
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return moderationAction, nil
+}
+
+// BanUser blocks user and hides all his products which are in feed, their reports are closed.
+func (m *ModerationStorage) BanUser(ctx context.Context, userID uint64, moderatorID uint64,
+	preAction *models.PreModerationAction,
+) (*models.ModerationAction, error) {
+	SQLSelectUserForUpdate := `SELECT is_moderator, banned_at IS NOT NULL FROM public."user" WHERE id=$1 FOR UPDATE`
+	SQLBanUser := `UPDATE public."user" SET banned_at = NOW() WHERE id=$1`
+	SQLHideProducts := `UPDATE public."product" SET status=$2 WHERE saler_id=$1 AND status = ANY($3)`
+	SQLResolveReports := `UPDATE public."report" SET is_resolved = TRUE
+		WHERE NOT is_resolved AND product_id IN (SELECT id FROM public."product" WHERE saler_id=$1)`
+
+	var moderationAction *models.ModerationAction
+
+	err := pgx.BeginFunc(ctx, m.pool, func(tx pgx.Tx) error {
+		if err := m.checkModerator(ctx, tx, moderatorID); err != nil {
+			return err
+		}
+
+		var isModerator, isBanned bool
+
+		err := tx.QueryRow(ctx, SQLSelectUserForUpdate, userID).Scan(&isModerator, &isBanned)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return fmt.Errorf(myerrors.ErrTemplate, ErrUserNotFound)
+			}
+
+			m.logger.Errorf("error with userID=%d: %+v", userID, err)
+
+			return fmt.Errorf(myerrors.ErrTemplate, err)
+		}
+
+		if isModerator {
+			return fmt.Errorf(myerrors.ErrTemplate, ErrBanModerator)
+		}
+
+		if isBanned {
+			return fmt.Errorf(myerrors.ErrTemplate, ErrUserAlreadyBanned)
+		}
+
+		_, err = tx.Exec(ctx, SQLBanUser, userID)
+		if err != nil {
+			m.logger.Errorln(err)
+
+			return fmt.Errorf(myerrors.ErrTemplate, err)
+		}
+
+		_, err = tx.Exec(ctx, SQLHideProducts, userID, models.ProductStatusHidden, hideableProductStatuses())
+		if err != nil {
+			m.logger.Errorln(err)
+
+			return fmt.Errorf(myerrors.ErrTemplate, err)
+		}
+
+		_, err = tx.Exec(ctx, SQLResolveReports, userID)
+		if err != nil {
+			m.logger.Errorln(err)
+
+			return fmt.Errorf(myerrors.ErrTemplate, err)
+		}
+
+		moderationAction, err = m.insertModerationAction(ctx, tx, moderatorID, models.ModerationActionBan,
+			0, userID, preAction.Comment)
+
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return moderationAction, nil
+}
+
+// GetAuditTrail returns moderation actions, newest first. Zero productID or userID means no filter by it.
+func (m *ModerationStorage) GetAuditTrail(ctx context.Context, moderatorID uint64, productID uint64,
+	userID uint64, limit uint64, offset uint64,
+) ([]*models.ModerationAction, error) {
+	SQLSelectAuditTrail := SQLSelectModerationAction + `
+		WHERE ($1::bigint = 0 OR product_id = $1::bigint) AND ($2::bigint = 0 OR user_id = $2::bigint)
+		ORDER BY id DESC LIMIT $3 OFFSET $4`
+
+	var auditTrail []*models.ModerationAction
+
+	err := pgx.BeginFunc(ctx, m.pool, func(tx pgx.Tx) error {
+		if err := m.checkModerator(ctx, tx, moderatorID); err != nil {
+			return err
+		}
+
+		rows, err := tx.Query(ctx, SQLSelectAuditTrail, productID, userID, limit, offset)
+		if err != nil {
+			m.logger.Errorln(err)
+
+			return fmt.Errorf(myerrors.ErrTemplate, err)
+		}
+
+		auditTrail, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (*models.ModerationAction, error) {
+			return scanModerationAction(row)
+		})
+		if err != nil {
+			m.logger.Errorln(err)
+
+			return fmt.Errorf(myerrors.ErrTemplate, err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return auditTrail, nil
+}
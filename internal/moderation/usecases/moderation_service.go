@@ -0,0 +1,150 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+	moderationrepo "github.com/SanExpett/marketplace-backend/internal/moderation/repository"
+	"github.com/SanExpett/marketplace-backend/pkg/models"
+	myerrors "github.com/SanExpett/marketplace-backend/pkg/my_errors"
+	"github.com/SanExpett/marketplace-backend/pkg/my_logger"
+	"github.com/SanExpett/marketplace-backend/pkg/utils"
+	"go.uber.org/zap"
+	"io"
+)
+
+var _ IModerationStorage = (*moderationrepo.ModerationStorage)(nil)
+
+type IModerationStorage interface {
+	AddReport(ctx context.Context, productID uint64, reporterID uint64, preReport *models.PreReport) (*models.Report, error)
+	GetQueue(ctx context.Context, moderatorID uint64, limit uint64, offset uint64) ([]*models.ModerationItem, error)
+	HideProduct(ctx context.Context, productID uint64, moderatorID uint64,
+		preAction *models.PreModerationAction) (*models.ModerationAction, error)
+	RestoreProduct(ctx context.Context, productID uint64, moderatorID uint64,
+		preAction *models.PreModerationAction) (*models.ModerationAction, error)
+	BanUser(ctx context.Context, userID uint64, moderatorID uint64,
+		preAction *models.PreModerationAction) (*models.ModerationAction, error)
+	GetAuditTrail(ctx context.Context, moderatorID uint64, productID uint64, userID uint64, limit uint64,
+		offset uint64) ([]*models.ModerationAction, error)
+}
+
+type ModerationService struct {
+	storage      IModerationStorage
+	maxPageLimit uint64
+	logger       *zap.SugaredLogger
+}
+
+func NewModerationService(moderationStorage IModerationStorage, maxPageLimit uint64) (*ModerationService, error) {
+	logger, err := my_logger.Get()
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return &ModerationService{storage: moderationStorage, maxPageLimit: maxPageLimit, logger: logger}, nil
+}
+
+func (m *ModerationService) AddReport(ctx context.Context, r io.Reader, productID uint64, userID uint64,
+) (*models.Report, error) {
+	preReport, err := ValidatePreReport(r)
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	report, err := m.storage.AddReport(ctx, productID, userID, preReport)
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	report.Sanitize()
+
+	return report, nil
+}
+
+func (m *ModerationService) GetQueue(ctx context.Context, userID uint64, limit uint64, offset uint64,
+) ([]*models.ModerationItem, error) {
+	limit, err := utils.ValidateLimit(limit, defaultPageLimit, m.maxPageLimit)
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	queue, err := m.storage.GetQueue(ctx, userID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	for _, item := range queue {
+		item.Sanitize()
+	}
+
+	return queue, nil
+}
+
+func (m *ModerationService) HideProduct(ctx context.Context, r io.Reader, productID uint64, userID uint64,
+) (*models.ModerationAction, error) {
+	preAction, err := ValidatePreModerationAction(r)
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	moderationAction, err := m.storage.HideProduct(ctx, productID, userID, preAction)
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	moderationAction.Sanitize()
+
+	return moderationAction, nil
+}
+
+func (m *ModerationService) RestoreProduct(ctx context.Context, r io.Reader, productID uint64, userID uint64,
+) (*models.ModerationAction, error) {
+	preAction, err := ValidatePreModerationAction(r)
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	moderationAction, err := m.storage.RestoreProduct(ctx, productID, userID, preAction)
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	moderationAction.Sanitize()
+
+	return moderationAction, nil
+}
+
+func (m *ModerationService) BanUser(ctx context.Context, r io.Reader, bannedUserID uint64, userID uint64,
+) (*models.ModerationAction, error) {
+	preAction, err := ValidatePreModerationAction(r)
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	moderationAction, err := m.storage.BanUser(ctx, bannedUserID, userID, preAction)
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	moderationAction.Sanitize()
+
+	return moderationAction, nil
+}
+
+func (m *ModerationService) GetAuditTrail(ctx context.Context, userID uint64, productID uint64,
+	targetUserID uint64, limit uint64, offset uint64,
+) ([]*models.ModerationAction, error) {
+	limit, err := utils.ValidateLimit(limit, defaultPageLimit, m.maxPageLimit)
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	auditTrail, err := m.storage.GetAuditTrail(ctx, userID, productID, targetUserID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	for _, moderationAction := range auditTrail {
+		moderationAction.Sanitize()
+	}
+
+	return auditTrail, nil
+}
@@ -0,0 +1,95 @@
+package usecases
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/SanExpett/marketplace-backend/pkg/models"
+	myerrors "github.com/SanExpett/marketplace-backend/pkg/my_errors"
+	"github.com/SanExpett/marketplace-backend/pkg/my_logger"
+	"github.com/asaskevich/govalidator"
+	"io"
+)
+
+const defaultPageLimit = 20
+
+var (
+	ErrDecodePreReport           = myerrors.NewError("Некорректный json жалобы")
+	ErrUnknownReportReason       = myerrors.NewError("Неизвестная причина жалобы")
+	ErrDecodePreModerationAction = myerrors.NewError("Некорректный json действия модератора")
+)
+
+func validatePreReport(r io.Reader) (*models.PreReport, error) {
+	logger, err := my_logger.Get()
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(r)
+	preReport := &models.PreReport{} //nolint:exhaustruct
+	if err := decoder.Decode(preReport); err != nil {
+		logger.Errorln(err)
+
+		return nil, fmt.Errorf(myerrors.ErrTemplate, ErrDecodePreReport)
+	}
+
+	preReport.Trim()
+
+	_, err = govalidator.ValidateStruct(preReport)
+	if err != nil {
+		logger.Errorln(err)
+
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	if !models.IsReportReason(preReport.Reason) {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, ErrUnknownReportReason)
+	}
+
+	return preReport, nil
+}
+
+func ValidatePreReport(r io.Reader) (*models.PreReport, error) {
+	preReport, err := validatePreReport(r)
+	if err != nil {
+		return nil, myerrors.NewError(err.Error())
+	}
+
+	return preReport, nil
+}
+
+// validatePreModerationAction allows empty body: comment of moderator is optional.
+func validatePreModerationAction(r io.Reader) (*models.PreModerationAction, error) {
+	logger, err := my_logger.Get()
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(r)
+	preAction := &models.PreModerationAction{} //nolint:exhaustruct
+	if err := decoder.Decode(preAction); err != nil && !errors.Is(err, io.EOF) {
+		logger.Errorln(err)
+
+		return nil, fmt.Errorf(myerrors.ErrTemplate, ErrDecodePreModerationAction)
+	}
+
+	preAction.Trim()
+
+	_, err = govalidator.ValidateStruct(preAction)
+	if err != nil {
+		logger.Errorln(err)
+
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return preAction, nil
+}
+
+func ValidatePreModerationAction(r io.Reader) (*models.PreModerationAction, error) {
+	preAction, err := validatePreModerationAction(r)
+	if err != nil {
+		return nil, myerrors.NewError(err.Error())
+	}
+
+	return preAction, nil
+}
@@ -0,0 +1,229 @@
+package delivery
+
+import (
+	"context"
+	"fmt"
+	"github.com/SanExpett/marketplace-backend/internal/order/usecases"
+	"github.com/SanExpett/marketplace-backend/internal/server/delivery"
+	"github.com/SanExpett/marketplace-backend/pkg/models"
+	myerrors "github.com/SanExpett/marketplace-backend/pkg/my_errors"
+	"github.com/SanExpett/marketplace-backend/pkg/my_logger"
+	"github.com/SanExpett/marketplace-backend/pkg/utils"
+	"go.uber.org/zap"
+	"net/http"
+)
+
+var _ IOrderService = (*usecases.OrderService)(nil)
+
+type IOrderService interface {
+	CreateOrder(ctx context.Context, productID uint64, userID uint64) (*models.Order, error)
+	PayOrder(ctx context.Context, orderID uint64, userID uint64) (*models.Order, error)
+	ShipOrder(ctx context.Context, orderID uint64, userID uint64) (*models.Order, error)
+	CompleteOrder(ctx context.Context, orderID uint64, userID uint64) (*models.Order, error)
+	CancelOrder(ctx context.Context, orderID uint64, userID uint64) (*models.Order, error)
+	GetOrder(ctx context.Context, orderID uint64, userID uint64) (*models.Order, error)
+	GetOrders(ctx context.Context, userID uint64, limit uint64, offset uint64) ([]*models.Order, error)
+}
+
+type OrderHandler struct {
+	service IOrderService
+	logger  *zap.SugaredLogger
+}
+
+func NewOrderHandler(orderService IOrderService) (*OrderHandler, error) {
+	logger, err := my_logger.Get()
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return &OrderHandler{
+		service: orderService,
+		logger:  logger,
+	}, nil
+}
+
+// handleOrder is common part of handlers which get id from query and return one order.
+func (o *OrderHandler) handleOrder(w http.ResponseWriter, r *http.Request, method string, paramName string,
+	action func(ctx context.Context, id uint64, userID uint64) (*models.Order, error),
+) {
+	if r.Method != method {
+		http.Error(w, `Method not allowed`, http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	ctx := r.Context()
+
+	userID, err := delivery.GetUserIDFromCookie(r)
+	if err != nil {
+		delivery.HandleErr(w, o.logger, err)
+
+		return
+	}
+
+	id, err := utils.ParseUint64FromRequest(r, paramName)
+	if err != nil {
+		delivery.HandleErr(w, o.logger, err)
+
+		return
+	}
+
+	order, err := action(ctx, id, userID)
+	if err != nil {
+		delivery.HandleErr(w, o.logger, err)
+
+		return
+	}
+
+	delivery.SendOkResponse(w, o.logger, NewOrderResponse(delivery.StatusResponseSuccessful, order))
+	o.logger.Infof("in %s: user %d got order: %+v", r.URL.Path, userID, order)
+}
+
+// CreateOrderHandler godoc
+//
+//	@Summary    create order
+//	@Description  create order of product and hold product for buyer. Product reserved by accepted offer of buyer
+//	@Description  is bought by price of offer. Repeated creation returns the same order
+//	@Tags order
+//	@Produce    json
+//	@Param      product_id  query uint64 true  "product id"
+//	@Success    200  {object} OrderResponse
+//	@Failure    405  {string} string
+//	@Failure    500  {string} string
+//	@Failure    222  {object} delivery.ErrorResponse "Error"
+//	@Router      /order/add [post]
+func (o *OrderHandler) CreateOrderHandler(w http.ResponseWriter, r *http.Request) {
+	o.handleOrder(w, r, http.MethodPost, "product_id", o.service.CreateOrder)
+}
+
+// PayOrderHandler godoc
+//
+//	@Summary    pay order
+//	@Description  charge buyer for created order. Payment can be pending, then order becomes paid after webhook
+//	@Description  of provider. Declined payment is shown in payment_status and payment_error, it can be retried
+//	@Tags order
+//	@Produce    json
+//	@Param      id  query uint64 true  "order id"
+//	@Success    200  {object} OrderResponse
+//	@Failure    405  {string} string
+//	@Failure    500  {string} string
+//	@Failure    222  {object} delivery.ErrorResponse "Error"
+//	@Router      /order/pay [post]
+func (o *OrderHandler) PayOrderHandler(w http.ResponseWriter, r *http.Request) {
+	o.handleOrder(w, r, http.MethodPost, "id", o.service.PayOrder)
+}
+
+// ShipOrderHandler godoc
+//
+//	@Summary    ship order
+//	@Description  seller marks paid order as shipped
+//	@Tags order
+//	@Produce    json
+//	@Param      id  query uint64 true  "order id"
+//	@Success    200  {object} OrderResponse
+//	@Failure    405  {string} string
+//	@Failure    500  {string} string
+//	@Failure    222  {object} delivery.ErrorResponse "Error"
+//	@Router      /order/ship [post]
+func (o *OrderHandler) ShipOrderHandler(w http.ResponseWriter, r *http.Request) {
+	o.handleOrder(w, r, http.MethodPost, "id", o.service.ShipOrder)
+}
+
+// CompleteOrderHandler godoc
+//
+//	@Summary    complete order
+//	@Description  buyer confirms receiving of shipped order, product becomes sold
+//	@Tags order
+//	@Produce    json
+//	@Param      id  query uint64 true  "order id"
+//	@Success    200  {object} OrderResponse
+//	@Failure    405  {string} string
+//	@Failure    500  {string} string
+//	@Failure    222  {object} delivery.ErrorResponse "Error"
+//	@Router      /order/complete [post]
+func (o *OrderHandler) CompleteOrderHandler(w http.ResponseWriter, r *http.Request) {
+	o.handleOrder(w, r, http.MethodPost, "id", o.service.CompleteOrder)
+}
+
+// CancelOrderHandler godoc
+//
+//	@Summary    cancel order
+//	@Description  buyer or seller cancels unpaid order or refunds paid one, product becomes active again.
+//	@Description  Paid order is refunding until payment provider refunds it, repeated cancel retries refund
+//	@Tags order
+//	@Produce    json
+//	@Param      id  query uint64 true  "order id"
+//	@Success    200  {object} OrderResponse
+//	@Failure    405  {string} string
+//	@Failure    500  {string} string
+//	@Failure    222  {object} delivery.ErrorResponse "Error"
+//	@Router      /order/cancel [post]
+func (o *OrderHandler) CancelOrderHandler(w http.ResponseWriter, r *http.Request) {
+	o.handleOrder(w, r, http.MethodPost, "id", o.service.CancelOrder)
+}
+
+// GetOrderHandler godoc
+//
+//	@Summary    get order
+//	@Description  get order, it is available to buyer and seller
+//	@Tags order
+//	@Produce    json
+//	@Param      id  query uint64 true  "order id"
+//	@Success    200  {object} OrderResponse
+//	@Failure    405  {string} string
+//	@Failure    500  {string} string
+//	@Failure    222  {object} delivery.ErrorResponse "Error"
+//	@Router      /order/get [get]
+func (o *OrderHandler) GetOrderHandler(w http.ResponseWriter, r *http.Request) {
+	o.handleOrder(w, r, http.MethodGet, "id", o.service.GetOrder)
+}
+
+// GetOrdersHandler godoc
+//
+//	@Summary    get orders
+//	@Description  get purchases and sales of current user, newest first
+//	@Tags order
+//	@Produce    json
+//	@Param      limit  query uint64 false  "limit of orders, 20 by default, can't be bigger than configured maximum"
+//	@Param      offset  query uint64 false  "offset of orders"
+//	@Success    200  {object} OrderListResponse
+//	@Failure    405  {string} string
+//	@Failure    500  {string} string
+//	@Failure    222  {object} delivery.ErrorResponse "Error"
+//	@Router      /order/get_list [get]
+func (o *OrderHandler) GetOrdersHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, `Method not allowed`, http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	ctx := r.Context()
+
+	userID, err := delivery.GetUserIDFromCookie(r)
+	if err != nil {
+		delivery.HandleErr(w, o.logger, err)
+
+		return
+	}
+
+	limit, err := utils.ParseUint64FromRequest(r, "limit")
+	if err != nil {
+		limit = 0
+	}
+
+	offset, err := utils.ParseUint64FromRequest(r, "offset")
+	if err != nil {
+		offset = 0
+	}
+
+	orders, err := o.service.GetOrders(ctx, userID, limit, offset)
+	if err != nil {
+		delivery.HandleErr(w, o.logger, err)
+
+		return
+	}
+
+	delivery.SendOkResponse(w, o.logger, NewOrderListResponse(delivery.StatusResponseSuccessful, orders))
+	o.logger.Infof("in GetOrdersHandler: get %d orders of user %d", len(orders), userID)
+}
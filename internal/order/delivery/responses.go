@@ -0,0 +1,27 @@
+package delivery
+
+import "github.com/SanExpett/marketplace-backend/pkg/models"
+
+type OrderResponse struct {
+	Status int           `json:"status"`
+	Body   *models.Order `json:"body"`
+}
+
+func NewOrderResponse(status int, body *models.Order) *OrderResponse {
+	return &OrderResponse{
+		Status: status,
+		Body:   body,
+	}
+}
+
+type OrderListResponse struct {
+	Status int             `json:"status"`
+	Body   []*models.Order `json:"body"`
+}
+
+func NewOrderListResponse(status int, body []*models.Order) *OrderListResponse {
+	return &OrderListResponse{
+		Status: status,
+		Body:   body,
+	}
+}
@@ -0,0 +1,544 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/SanExpett/marketplace-backend/internal/server/repository"
+	"github.com/SanExpett/marketplace-backend/pkg/models"
+	myerrors "github.com/SanExpett/marketplace-backend/pkg/my_errors"
+	"github.com/SanExpett/marketplace-backend/pkg/my_logger"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+const MessageErrWrongOrderAction = "Нельзя выполнить действие %s с заказом в статусе %s"
+
+var (
+	ErrProductNotFound     = myerrors.NewError("Этот товар не найден")
+	ErrProductNotAvailable = myerrors.NewError("Объявление уже зарезервировано или продано")
+	ErrOrderOnMyProduct    = myerrors.NewError("Нельзя заказать свое объявление")
+	ErrOrderNotFound       = myerrors.NewError("Этот заказ не найден")
+	ErrOrderNotPayable     = myerrors.NewError("Оплатить можно только созданный заказ")
+	ErrOnlyBuyerPays       = myerrors.NewError("Оплатить заказ может только покупатель")
+	ErrPaymentInProgress   = myerrors.NewError("Заказ нельзя отменить, пока платеж обрабатывается")
+
+	NameSeqOrder = pgx.Identifier{"public", "order_id_seq"} //nolint:gochecknoglobals
+)
+
+type OrderStorage struct {
+	pool   *pgxpool.Pool
+	logger *zap.SugaredLogger
+}
+
+func NewOrderStorage(pool *pgxpool.Pool) (*OrderStorage, error) {
+	logger, err := my_logger.Get()
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return &OrderStorage{
+		pool:   pool,
+		logger: logger,
+	}, nil
+}
+
+const SQLSelectOrder = `SELECT id, product_id, buyer_id, saler_id, price, currency, status, payment_id,
+	payment_status, payment_error, payment_attempt, COALESCE(offer_id, 0), created_at, updated_at
+	FROM public."order"`
+
+func scanOrder(row pgx.Row) (*models.Order, error) {
+	order := &models.Order{} //nolint:exhaustruct
+
+	err := row.Scan(&order.ID, &order.ProductID, &order.BuyerID, &order.SalerID, &order.Price.Amount,
+		&order.Price.Currency, &order.Status, &order.PaymentID, &order.PaymentStatus, &order.PaymentError,
+		&order.PaymentAttempt, &order.OfferID, &order.CreatedAt, &order.UpdatedAt)
+
+	return order, err //nolint:wrapcheck
+}
+
+// selectOrderByID locks order until end of transaction if forUpdate is set.
+func (o *OrderStorage) selectOrderByID(ctx context.Context, tx pgx.Tx, orderID uint64, forUpdate bool,
+) (*models.Order, error) {
+	SQLSelectOrderByID := SQLSelectOrder + ` WHERE id=$1`
+	if forUpdate {
+		SQLSelectOrderByID += ` FOR UPDATE`
+	}
+
+	order, err := scanOrder(tx.QueryRow(ctx, SQLSelectOrderByID, orderID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf(myerrors.ErrTemplate, ErrOrderNotFound)
+		}
+
+		o.logger.Errorf("error with orderID=%d: %+v", orderID, err)
+
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return order, nil
+}
+
+// selectLiveOrderID returns id of order which holds product, 0 if product isn't held.
+func (o *OrderStorage) selectLiveOrderID(ctx context.Context, tx pgx.Tx, productID uint64) (uint64, error) {
+	SQLSelectLiveOrderID := `SELECT COALESCE((SELECT id FROM public."order"
+		WHERE product_id=$1 AND status = ANY($2)), 0)`
+
+	var orderID uint64
+
+	err := tx.QueryRow(ctx, SQLSelectLiveOrderID, productID, models.LiveOrderStatuses()).Scan(&orderID)
+	if err != nil {
+		o.logger.Errorln(err)
+
+		return 0, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return orderID, nil
+}
+
+// selectReservingOffer returns id and agreed price of accepted offer of buyer which reserves product,
+// 0 if there is no such offer. Offer which expired or was already ordered doesn't reserve product.
+func (o *OrderStorage) selectReservingOffer(ctx context.Context, tx pgx.Tx, productID uint64,
+	buyerID uint64,
+) (uint64, models.Money, error) {
+	SQLSelectReservingOffer := `SELECT id, COALESCE(counter_price, price), currency FROM public."offer" offer
+		WHERE product_id=$1 AND buyer_id=$2 AND status = 'accepted' AND expires_at > NOW()
+			AND NOT EXISTS(SELECT 1 FROM public."order" o WHERE o.offer_id = offer.id)`
+
+	var offerID uint64
+
+	price := models.Money{} //nolint:exhaustruct
+
+	err := tx.QueryRow(ctx, SQLSelectReservingOffer, productID, buyerID).Scan(&offerID, &price.Amount,
+		&price.Currency)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, price, nil
+		}
+
+		o.logger.Errorln(err)
+
+		return 0, price, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return offerID, price, nil
+}
+
+// releaseProduct makes product of cancelled or refunded order active again and closes offer of order,
+// so it can't be ordered again by its price.
+func (o *OrderStorage) releaseProduct(ctx context.Context, tx pgx.Tx, order *models.Order) error {
+	if err := o.updateProductStatus(ctx, tx, order.ProductID, models.ProductStatusActive); err != nil {
+		return err
+	}
+
+	if order.OfferID == 0 {
+		return nil
+	}
+
+	return o.closeOffer(ctx, tx, order.OfferID)
+}
+
+// closeOffer closes accepted offer of cancelled order.
+func (o *OrderStorage) closeOffer(ctx context.Context, tx pgx.Tx, offerID uint64) error {
+	SQLCloseOffer := `UPDATE public."offer" SET status = 'closed', updated_at = NOW()
+		WHERE id=$1 AND status = 'accepted'`
+
+	if _, err := tx.Exec(ctx, SQLCloseOffer, offerID); err != nil {
+		o.logger.Errorf("error with offerID=%d: %+v", offerID, err)
+
+		return fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return nil
+}
+
+// updateProductStatus doesn't touch product hidden by moderation, order can't bring it back to feed.
+func (o *OrderStorage) updateProductStatus(ctx context.Context, tx pgx.Tx, productID uint64, status string) error {
+	SQLUpdateProductStatus := `UPDATE public."product" SET status=$1 WHERE id=$2 AND status <> $3`
+
+	_, err := tx.Exec(ctx, SQLUpdateProductStatus, status, productID, models.ProductStatusHidden)
+	if err != nil {
+		o.logger.Errorln(err)
+
+		return fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return nil
+}
+
+// CreateOrder holds product for buyer. Active product is bought by its price, product reserved by accepted
+// offer of buyer is bought by price of offer and order is tied to this offer. Repeated creation returns
+// the same order.
+func (o *OrderStorage) CreateOrder(ctx context.Context, productID uint64, buyerID uint64) (*models.Order, error) {
+	SQLSelectProductForUpdate := `SELECT saler_id, price, currency, status FROM public."product"
+		WHERE id=$1 FOR UPDATE`
+	SQLInsertOrder := `INSERT INTO public."order"(product_id, buyer_id, saler_id, price, currency, offer_id)
+		VALUES($1, $2, $3, $4, $5, NULLIF($6, 0))`
+
+	var order *models.Order
+
+	err := pgx.BeginFunc(ctx, o.pool, func(tx pgx.Tx) error {
+		var (
+			salerID uint64
+			status  string
+			offerID uint64
+		)
+
+		price := models.Money{} //nolint:exhaustruct
+
+		err := tx.QueryRow(ctx, SQLSelectProductForUpdate, productID).Scan(&salerID, &price.Amount,
+			&price.Currency, &status)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return fmt.Errorf(myerrors.ErrTemplate, ErrProductNotFound)
+			}
+
+			o.logger.Errorln(err)
+
+			return fmt.Errorf(myerrors.ErrTemplate, err)
+		}
+
+		if salerID == buyerID {
+			return fmt.Errorf(myerrors.ErrTemplate, ErrOrderOnMyProduct)
+		}
+
+		liveOrderID, err := o.selectLiveOrderID(ctx, tx, productID)
+		if err != nil {
+			return err
+		}
+
+		if liveOrderID != 0 {
+			order, err = o.selectOrderByID(ctx, tx, liveOrderID, false)
+			if err != nil {
+				return err
+			}
+
+			if order.BuyerID != buyerID {
+				return fmt.Errorf(myerrors.ErrTemplate, ErrProductNotAvailable)
+			}
+
+			return nil
+		}
+
+		switch status {
+		case models.ProductStatusActive:
+		case models.ProductStatusReserved:
+			offerID, price, err = o.selectReservingOffer(ctx, tx, productID, buyerID)
+			if err != nil {
+				return err
+			}
+
+			if offerID == 0 {
+				return fmt.Errorf(myerrors.ErrTemplate, ErrProductNotAvailable)
+			}
+		case models.ProductStatusDraft, models.ProductStatusHidden, models.ProductStatusOnReview:
+			return fmt.Errorf(myerrors.ErrTemplate, ErrProductNotFound)
+		default:
+			return fmt.Errorf(myerrors.ErrTemplate, ErrProductNotAvailable)
+		}
+
+		_, err = tx.Exec(ctx, SQLInsertOrder, productID, buyerID, salerID, price.Amount, price.Currency, offerID)
+		if err != nil {
+			o.logger.Errorln(err)
+
+			return fmt.Errorf(myerrors.ErrTemplate, err)
+		}
+
+		if err := o.updateProductStatus(ctx, tx, productID, models.ProductStatusReserved); err != nil {
+			return err
+		}
+
+		orderID, err := repository.GetLastValSeq(ctx, tx, NameSeqOrder)
+		if err != nil {
+			return fmt.Errorf(myerrors.ErrTemplate, err)
+		}
+
+		order, err = o.selectOrderByID(ctx, tx, orderID, false)
+
+		return err
+	})
+	if err != nil {
+		o.logger.Errorln(err)
+
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return order, nil
+}
+
+// StartPayment marks payment of order as pending and returns true if provider should be charged. Paid order
+// is returned as is. Order with pending payment is returned with true and the same payment attempt: result
+// of its charge may be lost, so charge is repeated with the same idempotency key and isn't doubled.
+func (o *OrderStorage) StartPayment(ctx context.Context, orderID uint64, buyerID uint64,
+) (*models.Order, bool, error) {
+	SQLStartPayment := `UPDATE public."order" SET payment_status = 'pending', payment_error = '',
+		payment_attempt = payment_attempt + 1, updated_at = NOW() WHERE id=$1`
+
+	var (
+		order *models.Order
+		start bool
+	)
+
+	err := pgx.BeginFunc(ctx, o.pool, func(tx pgx.Tx) error {
+		var err error
+
+		order, err = o.selectOrderByID(ctx, tx, orderID, true)
+		if err != nil {
+			return err
+		}
+
+		if order.BuyerID != buyerID {
+			if order.SalerID == buyerID {
+				return fmt.Errorf(myerrors.ErrTemplate, ErrOnlyBuyerPays)
+			}
+
+			return fmt.Errorf(myerrors.ErrTemplate, ErrOrderNotFound)
+		}
+
+		switch order.PaymentStatus {
+		case models.PaymentStatusSucceeded:
+			return nil
+		case models.PaymentStatusPending:
+			start = true
+
+			return nil
+		}
+
+		if order.Status != models.OrderStatusCreated {
+			return fmt.Errorf(myerrors.ErrTemplate, ErrOrderNotPayable)
+		}
+
+		_, err = tx.Exec(ctx, SQLStartPayment, orderID)
+		if err != nil {
+			o.logger.Errorln(err)
+
+			return fmt.Errorf(myerrors.ErrTemplate, err)
+		}
+
+		start = true
+		order, err = o.selectOrderByID(ctx, tx, orderID, false)
+
+		return err
+	})
+	if err != nil {
+		o.logger.Errorln(err)
+
+		return nil, false, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return order, start, nil
+}
+
+// ApplyPayment saves result of charge. It is idempotent: result of payment which is already applied
+// or doesn't belong to pending payment of order changes nothing.
+func (o *OrderStorage) ApplyPayment(ctx context.Context, payment *models.Payment) (*models.Order, error) {
+	SQLApplyPayment := `UPDATE public."order" SET status=$1, payment_id=$2, payment_status=$3, payment_error=$4,
+		updated_at = NOW() WHERE id=$5`
+
+	var order *models.Order
+
+	err := pgx.BeginFunc(ctx, o.pool, func(tx pgx.Tx) error {
+		var err error
+
+		order, err = o.selectOrderByID(ctx, tx, payment.OrderID, true)
+		if err != nil {
+			return err
+		}
+
+		if order.Status != models.OrderStatusCreated || order.PaymentStatus != models.PaymentStatusPending ||
+			(order.PaymentID != "" && order.PaymentID != payment.ID) {
+			o.logger.Infof("payment %+v is ignored for order %+v", payment, order)
+
+			return nil
+		}
+
+		status := order.Status
+		if payment.Status == models.PaymentStatusSucceeded {
+			status = models.OrderStatusPaid
+		}
+
+		_, err = tx.Exec(ctx, SQLApplyPayment, status, payment.ID, payment.Status, payment.Error, payment.OrderID)
+		if err != nil {
+			o.logger.Errorln(err)
+
+			return fmt.Errorf(myerrors.ErrTemplate, err)
+		}
+
+		order, err = o.selectOrderByID(ctx, tx, payment.OrderID, false)
+
+		return err
+	})
+	if err != nil {
+		o.logger.Errorln(err)
+
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return order, nil
+}
+
+// TransitOrder applies action of seller or buyer. Repeated action returns order as is. Cancelled order
+// releases product, paid one becomes refunding and holds product until FinishRefund. Completed order sells it.
+func (o *OrderStorage) TransitOrder(ctx context.Context, orderID uint64, userID uint64, action string,
+) (*models.Order, error) {
+	SQLUpdateOrderStatus := `UPDATE public."order" SET status=$1, updated_at = NOW() WHERE id=$2`
+
+	var order *models.Order
+
+	err := pgx.BeginFunc(ctx, o.pool, func(tx pgx.Tx) error {
+		var err error
+
+		order, err = o.selectOrderByID(ctx, tx, orderID, true)
+		if err != nil {
+			return err
+		}
+
+		if userID != order.BuyerID && userID != order.SalerID {
+			return fmt.Errorf(myerrors.ErrTemplate, ErrOrderNotFound)
+		}
+
+		if models.IsOrderActionDone(order.Status, action) {
+			return nil
+		}
+
+		nextStatus, ok := models.NextOrderStatus(order.Status, action, userID == order.SalerID)
+		if !ok {
+			return myerrors.NewError(MessageErrWrongOrderAction, action, order.Status)
+		}
+
+		if nextStatus == models.OrderStatusCancelled && order.PaymentStatus == models.PaymentStatusPending {
+			return fmt.Errorf(myerrors.ErrTemplate, ErrPaymentInProgress)
+		}
+
+		_, err = tx.Exec(ctx, SQLUpdateOrderStatus, nextStatus, orderID)
+		if err != nil {
+			o.logger.Errorln(err)
+
+			return fmt.Errorf(myerrors.ErrTemplate, err)
+		}
+
+		switch nextStatus {
+		case models.OrderStatusCancelled:
+			err = o.releaseProduct(ctx, tx, order)
+		case models.OrderStatusCompleted:
+			err = o.updateProductStatus(ctx, tx, order.ProductID, models.ProductStatusSold)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		order, err = o.selectOrderByID(ctx, tx, orderID, false)
+
+		return err
+	})
+	if err != nil {
+		o.logger.Errorln(err)
+
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return order, nil
+}
+
+// FinishRefund marks refunding order as refunded after payment provider confirmed refund and releases
+// its product. Order in other status is returned as is.
+func (o *OrderStorage) FinishRefund(ctx context.Context, orderID uint64) (*models.Order, error) {
+	SQLFinishRefund := `UPDATE public."order" SET status=$1, updated_at = NOW() WHERE id=$2`
+
+	var order *models.Order
+
+	err := pgx.BeginFunc(ctx, o.pool, func(tx pgx.Tx) error {
+		var err error
+
+		order, err = o.selectOrderByID(ctx, tx, orderID, true)
+		if err != nil {
+			return err
+		}
+
+		if order.Status != models.OrderStatusRefunding {
+			return nil
+		}
+
+		_, err = tx.Exec(ctx, SQLFinishRefund, models.OrderStatusRefunded, orderID)
+		if err != nil {
+			o.logger.Errorln(err)
+
+			return fmt.Errorf(myerrors.ErrTemplate, err)
+		}
+
+		if err := o.releaseProduct(ctx, tx, order); err != nil {
+			return err
+		}
+
+		order, err = o.selectOrderByID(ctx, tx, orderID, false)
+
+		return err
+	})
+	if err != nil {
+		o.logger.Errorln(err)
+
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return order, nil
+}
+
+// GetOrder returns order only to its buyer and seller.
+func (o *OrderStorage) GetOrder(ctx context.Context, orderID uint64, userID uint64) (*models.Order, error) {
+	var order *models.Order
+
+	err := pgx.BeginFunc(ctx, o.pool, func(tx pgx.Tx) error {
+		var err error
+
+		order, err = o.selectOrderByID(ctx, tx, orderID, false)
+		if err != nil {
+			return err
+		}
+
+		if userID != order.BuyerID && userID != order.SalerID {
+			return fmt.Errorf(myerrors.ErrTemplate, ErrOrderNotFound)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return order, nil
+}
+
+// GetOrders returns purchases and sales of user, newest first.
+func (o *OrderStorage) GetOrders(ctx context.Context, userID uint64, limit uint64, offset uint64,
+) ([]*models.Order, error) {
+	SQLSelectOrders := SQLSelectOrder + ` WHERE buyer_id=$1 OR saler_id=$1 ORDER BY id DESC LIMIT $2 OFFSET $3`
+
+	var orders []*models.Order
+
+	err := pgx.BeginFunc(ctx, o.pool, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, SQLSelectOrders, userID, limit, offset)
+		if err != nil {
+			o.logger.Errorln(err)
+
+			return fmt.Errorf(myerrors.ErrTemplate, err)
+		}
+
+		orders, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (*models.Order, error) {
+			return scanOrder(row)
+		})
+		if err != nil {
+			o.logger.Errorln(err)
+
+			return fmt.Errorf(myerrors.ErrTemplate, err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return orders, nil
+}
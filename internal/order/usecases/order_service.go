@@ -0,0 +1,182 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+	orderrepo "github.com/SanExpett/marketplace-backend/internal/order/repository"
+	"github.com/SanExpett/marketplace-backend/pkg/models"
+	myerrors "github.com/SanExpett/marketplace-backend/pkg/my_errors"
+	"github.com/SanExpett/marketplace-backend/pkg/my_logger"
+	"github.com/SanExpett/marketplace-backend/pkg/payment"
+	"github.com/SanExpett/marketplace-backend/pkg/utils"
+	"go.uber.org/zap"
+)
+
+const defaultPageLimit = 20
+
+var _ IOrderStorage = (*orderrepo.OrderStorage)(nil)
+
+type IOrderStorage interface {
+	CreateOrder(ctx context.Context, productID uint64, buyerID uint64) (*models.Order, error)
+	StartPayment(ctx context.Context, orderID uint64, buyerID uint64) (*models.Order, bool, error)
+	ApplyPayment(ctx context.Context, payment *models.Payment) (*models.Order, error)
+	TransitOrder(ctx context.Context, orderID uint64, userID uint64, action string) (*models.Order, error)
+	FinishRefund(ctx context.Context, orderID uint64) (*models.Order, error)
+	GetOrder(ctx context.Context, orderID uint64, userID uint64) (*models.Order, error)
+	GetOrders(ctx context.Context, userID uint64, limit uint64, offset uint64) ([]*models.Order, error)
+}
+
+var _ IPaymentProvider = (*payment.FakeProvider)(nil)
+
+// IPaymentProvider charges buyers. Charges with the same idempotency key must return the same payment,
+// refunds with the same idempotency key must be done once. Result of pending payment is passed
+// to HandlePaymentWebhook.
+type IPaymentProvider interface {
+	Charge(ctx context.Context, target models.PaymentTarget, amount models.Money,
+		idempotencyKey string) (*models.Payment, error)
+	Refund(ctx context.Context, paymentID string, amount models.Money, idempotencyKey string) error
+}
+
+type OrderService struct {
+	storage         IOrderStorage
+	paymentProvider IPaymentProvider
+	maxPageLimit    uint64
+	logger          *zap.SugaredLogger
+}
+
+func NewOrderService(orderStorage IOrderStorage, paymentProvider IPaymentProvider, maxPageLimit uint64,
+) (*OrderService, error) {
+	logger, err := my_logger.Get()
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return &OrderService{
+		storage:         orderStorage,
+		paymentProvider: paymentProvider,
+		maxPageLimit:    maxPageLimit,
+		logger:          logger,
+	}, nil
+}
+
+func (o *OrderService) CreateOrder(ctx context.Context, productID uint64, userID uint64) (*models.Order, error) {
+	order, err := o.storage.CreateOrder(ctx, productID, userID)
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return order, nil
+}
+
+// PayOrder charges buyer. Declined payment is returned in order, so buyer can pay again. If charge or saving
+// of its result fails, payment stays pending and next call repeats charge with the same idempotency key.
+func (o *OrderService) PayOrder(ctx context.Context, orderID uint64, userID uint64) (*models.Order, error) {
+	order, start, err := o.storage.StartPayment(ctx, orderID, userID)
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	if !start {
+		return order, nil
+	}
+
+	idempotencyKey := fmt.Sprintf("order-%d-%d", order.ID, order.PaymentAttempt)
+
+	result, err := o.paymentProvider.Charge(ctx, models.PaymentTarget{OrderID: order.ID}, //nolint:exhaustruct
+		order.Price, idempotencyKey)
+	if err != nil {
+		o.logger.Errorf("charge of order %d failed: %+v", order.ID, err)
+
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	order, err = o.storage.ApplyPayment(ctx, result)
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return order, nil
+}
+
+// HandlePaymentWebhook applies result of asynchronous payment, repeated webhooks change nothing.
+func (o *OrderService) HandlePaymentWebhook(ctx context.Context, payment *models.Payment) {
+	order, err := o.storage.ApplyPayment(ctx, payment)
+	if err != nil {
+		o.logger.Errorf("webhook of payment %+v is not applied: %+v", payment, err)
+
+		return
+	}
+
+	o.logger.Infof("webhook of payment %s is applied to order: %+v", payment.ID, order)
+}
+
+func (o *OrderService) ShipOrder(ctx context.Context, orderID uint64, userID uint64) (*models.Order, error) {
+	order, err := o.storage.TransitOrder(ctx, orderID, userID, models.OrderActionShip)
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return order, nil
+}
+
+func (o *OrderService) CompleteOrder(ctx context.Context, orderID uint64, userID uint64) (*models.Order, error) {
+	order, err := o.storage.TransitOrder(ctx, orderID, userID, models.OrderActionComplete)
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return order, nil
+}
+
+// CancelOrder cancels unpaid order and refunds paid one. Paid order becomes refunding before provider
+// is called, so row of order isn't locked during refund. Order stays refunding if refund fails,
+// repeated cancel repeats refund with the same idempotency key.
+func (o *OrderService) CancelOrder(ctx context.Context, orderID uint64, userID uint64) (*models.Order, error) {
+	order, err := o.storage.TransitOrder(ctx, orderID, userID, models.OrderActionCancel)
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	if order.Status != models.OrderStatusRefunding {
+		return order, nil
+	}
+
+	idempotencyKey := fmt.Sprintf("refund-order-%d", order.ID)
+
+	if err := o.paymentProvider.Refund(ctx, order.PaymentID, order.Price, idempotencyKey); err != nil {
+		o.logger.Errorf("refund of order %d failed: %+v", order.ID, err)
+
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	order, err = o.storage.FinishRefund(ctx, order.ID)
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return order, nil
+}
+
+func (o *OrderService) GetOrder(ctx context.Context, orderID uint64, userID uint64) (*models.Order, error) {
+	order, err := o.storage.GetOrder(ctx, orderID, userID)
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return order, nil
+}
+
+func (o *OrderService) GetOrders(ctx context.Context, userID uint64, limit uint64, offset uint64,
+) ([]*models.Order, error) {
+	limit, err := utils.ValidateLimit(limit, defaultPageLimit, o.maxPageLimit)
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	orders, err := o.storage.GetOrders(ctx, userID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return orders, nil
+}
@@ -0,0 +1,231 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/SanExpett/marketplace-backend/pkg/models"
+	"github.com/SanExpett/marketplace-backend/pkg/my_logger"
+)
+
+func TestMain(m *testing.M) {
+	if _, err := my_logger.New([]string{os.DevNull}, []string{os.DevNull}); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	os.Exit(m.Run())
+}
+
+var errApplyPayment = errors.New("apply payment failed")
+
+// fakeOrderStorage keeps one order and applies payments like OrderStorage. failApply first calls
+// of ApplyPayment fail after payment is charged.
+type fakeOrderStorage struct {
+	order     models.Order
+	failApply int
+}
+
+func (f *fakeOrderStorage) CreateOrder(context.Context, uint64, uint64) (*models.Order, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (f *fakeOrderStorage) StartPayment(_ context.Context, _ uint64, _ uint64) (*models.Order, bool, error) {
+	switch f.order.PaymentStatus {
+	case models.PaymentStatusSucceeded:
+		return &f.order, false, nil
+	case models.PaymentStatusPending:
+		order := f.order
+
+		return &order, true, nil
+	}
+
+	f.order.PaymentStatus = models.PaymentStatusPending
+	f.order.PaymentAttempt++
+	order := f.order
+
+	return &order, true, nil
+}
+
+func (f *fakeOrderStorage) ApplyPayment(_ context.Context, payment *models.Payment) (*models.Order, error) {
+	if f.failApply > 0 {
+		f.failApply--
+
+		return nil, errApplyPayment
+	}
+
+	if f.order.PaymentStatus == models.PaymentStatusPending {
+		f.order.PaymentID = payment.ID
+		f.order.PaymentStatus = payment.Status
+
+		if payment.Status == models.PaymentStatusSucceeded {
+			f.order.Status = models.OrderStatusPaid
+		}
+	}
+
+	order := f.order
+
+	return &order, nil
+}
+
+// TransitOrder supports only cancel of paid order.
+func (f *fakeOrderStorage) TransitOrder(_ context.Context, _ uint64, _ uint64, action string,
+) (*models.Order, error) {
+	if action != models.OrderActionCancel {
+		return nil, errors.New("not implemented")
+	}
+
+	if f.order.Status == models.OrderStatusPaid {
+		f.order.Status = models.OrderStatusRefunding
+	}
+
+	order := f.order
+
+	return &order, nil
+}
+
+func (f *fakeOrderStorage) FinishRefund(context.Context, uint64) (*models.Order, error) {
+	if f.order.Status == models.OrderStatusRefunding {
+		f.order.Status = models.OrderStatusRefunded
+	}
+
+	order := f.order
+
+	return &order, nil
+}
+
+func (f *fakeOrderStorage) GetOrder(context.Context, uint64, uint64) (*models.Order, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (f *fakeOrderStorage) GetOrders(context.Context, uint64, uint64, uint64) ([]*models.Order, error) {
+	return nil, errors.New("not implemented")
+}
+
+var errRefund = errors.New("refund failed")
+
+// countingProvider succeeds every charge and returns the same payment for the same idempotency key.
+// failRefund first refunds fail.
+type countingProvider struct {
+	payments   map[string]*models.Payment
+	charges    int
+	refunds    map[string]int
+	failRefund int
+}
+
+func (c *countingProvider) Charge(_ context.Context, target models.PaymentTarget, _ models.Money,
+	idempotencyKey string,
+) (*models.Payment, error) {
+	c.charges++
+
+	if payment, ok := c.payments[idempotencyKey]; ok {
+		return payment, nil
+	}
+
+	payment := &models.Payment{ //nolint:exhaustruct
+		ID:      fmt.Sprintf("payment-%d", len(c.payments)+1),
+		OrderID: target.OrderID,
+		Status:  models.PaymentStatusSucceeded,
+	}
+	c.payments[idempotencyKey] = payment
+
+	return payment, nil
+}
+
+func (c *countingProvider) Refund(_ context.Context, _ string, _ models.Money, idempotencyKey string) error {
+	if c.failRefund > 0 {
+		c.failRefund--
+
+		return errRefund
+	}
+
+	c.refunds[idempotencyKey]++
+
+	return nil
+}
+
+func TestPayOrderRetriesPendingPayment(t *testing.T) {
+	storage := &fakeOrderStorage{ //nolint:exhaustruct
+		order: models.Order{ //nolint:exhaustruct
+			ID:            1,
+			BuyerID:       2,
+			Status:        models.OrderStatusCreated,
+			PaymentStatus: models.PaymentStatusNone,
+		},
+		failApply: 1,
+	}
+	provider := &countingProvider{payments: make(map[string]*models.Payment)} //nolint:exhaustruct
+
+	service, err := NewOrderService(storage, provider, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := service.PayOrder(context.Background(), 1, 2); !errors.Is(err, errApplyPayment) {
+		t.Fatalf("expected error of ApplyPayment, got %v", err)
+	}
+
+	if storage.order.PaymentStatus != models.PaymentStatusPending {
+		t.Fatalf("payment must stay pending, got %s", storage.order.PaymentStatus)
+	}
+
+	order, err := service.PayOrder(context.Background(), 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if order.Status != models.OrderStatusPaid || order.PaymentID != "payment-1" {
+		t.Fatalf("order must be paid by the first payment, got %+v", order)
+	}
+
+	if provider.charges != 2 || len(provider.payments) != 1 {
+		t.Fatalf("charge must be repeated with the same key, got %d charges with %d keys", provider.charges,
+			len(provider.payments))
+	}
+
+	if _, err := service.PayOrder(context.Background(), 1, 2); err != nil || provider.charges != 2 {
+		t.Fatalf("paid order must not be charged again: %v, %d charges", err, provider.charges)
+	}
+}
+
+func TestCancelOrderRetriesRefund(t *testing.T) {
+	storage := &fakeOrderStorage{ //nolint:exhaustruct
+		order: models.Order{ //nolint:exhaustruct
+			ID:            1,
+			BuyerID:       2,
+			Status:        models.OrderStatusPaid,
+			PaymentID:     "payment-1",
+			PaymentStatus: models.PaymentStatusSucceeded,
+		},
+	}
+	provider := &countingProvider{refunds: make(map[string]int), failRefund: 1} //nolint:exhaustruct
+
+	service, err := NewOrderService(storage, provider, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := service.CancelOrder(context.Background(), 1, 2); !errors.Is(err, errRefund) {
+		t.Fatalf("expected error of refund, got %v", err)
+	}
+
+	if storage.order.Status != models.OrderStatusRefunding {
+		t.Fatalf("order must stay refunding, got %s", storage.order.Status)
+	}
+
+	order, err := service.CancelOrder(context.Background(), 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if order.Status != models.OrderStatusRefunded {
+		t.Fatalf("order must be refunded, got %s", order.Status)
+	}
+
+	if len(provider.refunds) != 1 || provider.refunds["refund-order-1"] != 1 {
+		t.Fatalf("refund must be done once with key of order, got %v", provider.refunds)
+	}
+}
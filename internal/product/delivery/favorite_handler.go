@@ -0,0 +1,182 @@
+package delivery
+
+import (
+	"github.com/SanExpett/marketplace-backend/internal/server/delivery"
+	"github.com/SanExpett/marketplace-backend/pkg/models"
+	"github.com/SanExpett/marketplace-backend/pkg/utils"
+	"net/http"
+)
+
+const (
+	ResponseSuccessfulAddFavorite    = "Successful add to favorites"
+	ResponseSuccessfulDeleteFavorite = "Successful delete from favorites"
+)
+
+// AddFavoriteHandler godoc
+//
+//	@Summary    add product to favorites
+//	@Description  add product to favorites of current user, adding twice is not an error
+//	@Tags favorite
+//	@Accept      json
+//	@Produce    json
+//	@Param      product_id  query uint64 true  "product id"
+//	@Success    200  {object} delivery.Response
+//	@Failure    405  {string} string
+//	@Failure    500  {string} string
+//	@Failure    222  {object} delivery.ErrorResponse "Error"
+//	@Router      /favorite/add [post]
+func (p *ProductHandler) AddFavoriteHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, `Method not allowed`, http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	ctx := r.Context()
+
+	userID, err := delivery.GetUserIDFromCookie(r)
+	if err != nil {
+		delivery.HandleErr(w, p.logger, err)
+
+		return
+	}
+
+	productID, err := utils.ParseUint64FromRequest(r, "product_id")
+	if err != nil {
+		delivery.HandleErr(w, p.logger, err)
+
+		return
+	}
+
+	err = p.service.AddFavorite(ctx, userID, productID)
+	if err != nil {
+		delivery.HandleErr(w, p.logger, err)
+
+		return
+	}
+
+	delivery.SendOkResponse(w, p.logger,
+		delivery.NewResponse(delivery.StatusResponseSuccessful, ResponseSuccessfulAddFavorite))
+	p.logger.Infof("in AddFavoriteHandler: add product %d to favorites of user %d", productID, userID)
+}
+
+// DeleteFavoriteHandler godoc
+//
+//	@Summary    delete product from favorites
+//	@Description  delete product from favorites of current user
+//	@Tags favorite
+//	@Accept      json
+//	@Produce    json
+//	@Param      product_id  query uint64 true  "product id"
+//	@Success    200  {object} delivery.Response
+//	@Failure    405  {string} string
+//	@Failure    500  {string} string
+//	@Failure    222  {object} delivery.ErrorResponse "Error"
+//	@Router      /favorite/delete [delete]
+func (p *ProductHandler) DeleteFavoriteHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, `Method not allowed`, http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	ctx := r.Context()
+
+	userID, err := delivery.GetUserIDFromCookie(r)
+	if err != nil {
+		delivery.HandleErr(w, p.logger, err)
+
+		return
+	}
+
+	productID, err := utils.ParseUint64FromRequest(r, "product_id")
+	if err != nil {
+		delivery.HandleErr(w, p.logger, err)
+
+		return
+	}
+
+	err = p.service.DeleteFavorite(ctx, userID, productID)
+	if err != nil {
+		delivery.HandleErr(w, p.logger, err)
+
+		return
+	}
+
+	delivery.SendOkResponse(w, p.logger,
+		delivery.NewResponse(delivery.StatusResponseSuccessful, ResponseSuccessfulDeleteFavorite))
+	p.logger.Infof("in DeleteFavoriteHandler: delete product %d from favorites of user %d", productID, userID)
+}
+
+// GetFavoritesListHandler godoc
+//
+//	@Summary    get favorites list
+//	@Description  get favorite products of current user, accepts the same filters, sort and pagination as product/get_list
+//	@Tags favorite
+//	@Accept      json
+//	@Produce    json
+//	@Param      limit  query uint64 false  "limit Products, 10 by default"
+//	@Param      cursor  query string false  "next_cursor from previous page"
+//	@Param      status  query string false  "comma separated statuses: active, reserved, sold. All of them by default"
+//	@Param      sort query string false  "comma separated sort keys, -created_at by default"
+//	@Success    200  {object} ProductListResponse
+//	@Failure    405  {string} string
+//	@Failure    500  {string} string
+//	@Failure    222  {object} delivery.ErrorResponse "Error"
+//	@Router      /favorite/get_list [get]
+func (p *ProductHandler) GetFavoritesListHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, `Method not allowed`, http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	ctx := r.Context()
+
+	userID, err := delivery.GetUserIDFromCookie(r)
+	if err != nil {
+		delivery.HandleErr(w, p.logger, err)
+
+		return
+	}
+
+	limit, err := utils.ParseUint64FromRequest(r, "limit")
+	if err != nil {
+		limit = 0
+	}
+
+	offset, err := utils.ParseUint64FromRequest(r, "offset")
+	if err != nil {
+		offset = 0
+	}
+
+	sortSpec, err := models.ParseSortSpec(utils.ParseStringFromRequest(r, "sort"))
+	if err != nil {
+		delivery.HandleErr(w, p.logger, err)
+
+		return
+	}
+
+	filter, err := parseProductFilter(r)
+	if err != nil {
+		delivery.HandleErr(w, p.logger, err)
+
+		return
+	}
+
+	filter.Favorites = true
+
+	rawCursor := utils.ParseStringFromRequest(r, "cursor")
+
+	products, pageInfo, err := p.service.GetProductsList(ctx, filter, sortSpec, limit, offset, rawCursor, userID)
+	if err != nil {
+		delivery.HandleErr(w, p.logger, err)
+
+		return
+	}
+
+	delivery.SetLinkHeader(w, paginationLinks(r, pageInfo))
+	delivery.SendOkResponse(w, p.logger,
+		NewProductListResponse(delivery.StatusResponseSuccessful, products, pageInfo))
+	p.logger.Infof("in GetFavoritesListHandler: get favorites of user %d: %+v", userID, products)
+}
@@ -0,0 +1,219 @@
+package delivery
+
+import (
+	"context"
+	"github.com/SanExpett/marketplace-backend/internal/server/delivery"
+	"github.com/SanExpett/marketplace-backend/pkg/models"
+	"github.com/SanExpett/marketplace-backend/pkg/utils"
+	"net/http"
+)
+
+// AddOfferHandler godoc
+//
+//	@Summary    make offer
+//	@Description  make offer of price lower than price of active product, it expires if seller doesn't answer in time.
+//	@Description  Empty currency means currency of product
+//	@Tags offer
+//	@Accept      json
+//	@Produce    json
+//	@Param      product_id  query uint64 true  "product id"
+//	@Param      preOffer  body models.PreOffer true  "offered price"
+//	@Success    200  {object} OfferResponse
+//	@Failure    405  {string} string
+//	@Failure    500  {string} string
+//	@Failure    222  {object} delivery.ErrorResponse "Error"
+//	@Router      /offer/add [post]
+func (p *ProductHandler) AddOfferHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, `Method not allowed`, http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	ctx := r.Context()
+
+	userID, err := delivery.GetUserIDFromCookie(r)
+	if err != nil {
+		delivery.HandleErr(w, p.logger, err)
+
+		return
+	}
+
+	productID, err := utils.ParseUint64FromRequest(r, "product_id")
+	if err != nil {
+		delivery.HandleErr(w, p.logger, err)
+
+		return
+	}
+
+	offer, err := p.service.AddOffer(ctx, r.Body, productID, userID)
+	if err != nil {
+		delivery.HandleErr(w, p.logger, err)
+
+		return
+	}
+
+	delivery.SendOkResponse(w, p.logger, NewOfferResponse(delivery.StatusResponseSuccessful, offer))
+	p.logger.Infof("in AddOfferHandler: add offer: %+v", offer)
+}
+
+// handleOfferAction is common part of handlers of actions with offer without body.
+func (p *ProductHandler) handleOfferAction(w http.ResponseWriter, r *http.Request,
+	action func(ctx context.Context, offerID uint64, userID uint64) (*models.Offer, error),
+) {
+	if r.Method != http.MethodPost {
+		http.Error(w, `Method not allowed`, http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	ctx := r.Context()
+
+	userID, err := delivery.GetUserIDFromCookie(r)
+	if err != nil {
+		delivery.HandleErr(w, p.logger, err)
+
+		return
+	}
+
+	offerID, err := utils.ParseUint64FromRequest(r, "id")
+	if err != nil {
+		delivery.HandleErr(w, p.logger, err)
+
+		return
+	}
+
+	offer, err := action(ctx, offerID, userID)
+	if err != nil {
+		delivery.HandleErr(w, p.logger, err)
+
+		return
+	}
+
+	delivery.SendOkResponse(w, p.logger, NewOfferResponse(delivery.StatusResponseSuccessful, offer))
+	p.logger.Infof("in %s: user %d changed offer: %+v", r.URL.Path, userID, offer)
+}
+
+// AcceptOfferHandler godoc
+//
+//	@Summary    accept offer
+//	@Description  seller accepts pending offer or buyer accepts counter offer, product becomes reserved
+//	@Tags offer
+//	@Produce    json
+//	@Param      id  query uint64 true  "offer id"
+//	@Success    200  {object} OfferResponse
+//	@Failure    405  {string} string
+//	@Failure    500  {string} string
+//	@Failure    222  {object} delivery.ErrorResponse "Error"
+//	@Router      /offer/accept [post]
+func (p *ProductHandler) AcceptOfferHandler(w http.ResponseWriter, r *http.Request) {
+	p.handleOfferAction(w, r, p.service.AcceptOffer)
+}
+
+// DeclineOfferHandler godoc
+//
+//	@Summary    decline offer
+//	@Description  seller declines pending offer or buyer declines counter offer
+//	@Tags offer
+//	@Produce    json
+//	@Param      id  query uint64 true  "offer id"
+//	@Success    200  {object} OfferResponse
+//	@Failure    405  {string} string
+//	@Failure    500  {string} string
+//	@Failure    222  {object} delivery.ErrorResponse "Error"
+//	@Router      /offer/decline [post]
+func (p *ProductHandler) DeclineOfferHandler(w http.ResponseWriter, r *http.Request) {
+	p.handleOfferAction(w, r, p.service.DeclineOffer)
+}
+
+// WithdrawOfferHandler godoc
+//
+//	@Summary    withdraw offer
+//	@Description  buyer withdraws pending or countered offer
+//	@Tags offer
+//	@Produce    json
+//	@Param      id  query uint64 true  "offer id"
+//	@Success    200  {object} OfferResponse
+//	@Failure    405  {string} string
+//	@Failure    500  {string} string
+//	@Failure    222  {object} delivery.ErrorResponse "Error"
+//	@Router      /offer/withdraw [post]
+func (p *ProductHandler) WithdrawOfferHandler(w http.ResponseWriter, r *http.Request) {
+	p.handleOfferAction(w, r, p.service.WithdrawOffer)
+}
+
+// CounterOfferHandler godoc
+//
+//	@Summary    counter offer
+//	@Description  seller answers pending offer with bigger price, buyer can accept or decline it
+//	@Tags offer
+//	@Accept      json
+//	@Produce    json
+//	@Param      id  query uint64 true  "offer id"
+//	@Param      preOffer  body models.PreOffer true  "counter price"
+//	@Success    200  {object} OfferResponse
+//	@Failure    405  {string} string
+//	@Failure    500  {string} string
+//	@Failure    222  {object} delivery.ErrorResponse "Error"
+//	@Router      /offer/counter [post]
+func (p *ProductHandler) CounterOfferHandler(w http.ResponseWriter, r *http.Request) {
+	p.handleOfferAction(w, r, func(ctx context.Context, offerID uint64, userID uint64) (*models.Offer, error) {
+		return p.service.CounterOffer(ctx, r.Body, offerID, userID)
+	})
+}
+
+// GetOffersHandler godoc
+//
+//	@Summary    get offers
+//	@Description  get offers made or received by current user, newest first
+//	@Tags offer
+//	@Produce    json
+//	@Param      product_id  query uint64 false  "only offers on this product"
+//	@Param      limit  query uint64 false  "limit of offers, 10 by default, can't be bigger than configured maximum"
+//	@Param      offset  query uint64 false  "offset of offers"
+//	@Success    200  {object} OfferListResponse
+//	@Failure    405  {string} string
+//	@Failure    500  {string} string
+//	@Failure    222  {object} delivery.ErrorResponse "Error"
+//	@Router      /offer/get_list [get]
+func (p *ProductHandler) GetOffersHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, `Method not allowed`, http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	ctx := r.Context()
+
+	userID, err := delivery.GetUserIDFromCookie(r)
+	if err != nil {
+		delivery.HandleErr(w, p.logger, err)
+
+		return
+	}
+
+	productID, err := utils.ParseUint64FromRequest(r, "product_id")
+	if err != nil {
+		productID = 0
+	}
+
+	limit, err := utils.ParseUint64FromRequest(r, "limit")
+	if err != nil {
+		limit = 0
+	}
+
+	offset, err := utils.ParseUint64FromRequest(r, "offset")
+	if err != nil {
+		offset = 0
+	}
+
+	offers, err := p.service.GetOffers(ctx, userID, productID, limit, offset)
+	if err != nil {
+		delivery.HandleErr(w, p.logger, err)
+
+		return
+	}
+
+	delivery.SendOkResponse(w, p.logger, NewOfferListResponse(delivery.StatusResponseSuccessful, offers))
+	p.logger.Infof("in GetOffersHandler: get %d offers of user %d", len(offers), userID)
+}
@@ -12,17 +12,45 @@ import (
 	"github.com/SanExpett/marketplace-backend/pkg/utils"
 	"go.uber.org/zap"
 	"io"
-	"math"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 )
 
+const attributeParamPrefix = "attr."
+
 var _ IProductService = (*usecases.ProductService)(nil)
 
 type IProductService interface {
 	AddProduct(ctx context.Context, r io.Reader, userID uint64) (*models.Product, error)
 	GetProduct(ctx context.Context, productID uint64, userID uint64) (*models.ProductWithIsMy, error)
-	GetProductsList(ctx context.Context, limit uint64, offset uint64, sortType uint64, minPrice uint64,
-		maxPrice uint64, userID uint64) ([]*models.ProductWithIsMy, error)
+	GetProductsList(ctx context.Context, filter *models.ProductFilter, sortSpec models.SortSpec, limit uint64,
+		offset uint64, rawCursor string, userID uint64) ([]*models.ProductWithIsMy, *models.PageInfo, error)
+	GetFacets(ctx context.Context, filter *models.ProductFilter, userID uint64) ([]*models.Facet, error)
+	GetSuggestions(ctx context.Context, searchQuery string, limit uint64) (*models.Suggestions, error)
+	AddFavorite(ctx context.Context, userID uint64, productID uint64) error
+	DeleteFavorite(ctx context.Context, userID uint64, productID uint64) error
+	RecordView(product *models.ProductWithIsMy, viewerKey string, userAgent string)
+	GetDailyViews(ctx context.Context, productID uint64, days uint64, userID uint64) ([]*models.DailyViews, error)
+	UpdateProduct(ctx context.Context, r io.Reader, productID uint64,
+		userID uint64) (*models.ProductWithIsMy, error)
+	GetPriceHistory(ctx context.Context, productID uint64, userID uint64) ([]*models.PriceChange, error)
+	AddOffer(ctx context.Context, r io.Reader, productID uint64, userID uint64) (*models.Offer, error)
+	AcceptOffer(ctx context.Context, offerID uint64, userID uint64) (*models.Offer, error)
+	DeclineOffer(ctx context.Context, offerID uint64, userID uint64) (*models.Offer, error)
+	WithdrawOffer(ctx context.Context, offerID uint64, userID uint64) (*models.Offer, error)
+	CounterOffer(ctx context.Context, r io.Reader, offerID uint64, userID uint64) (*models.Offer, error)
+	GetOffers(ctx context.Context, userID uint64, productID uint64, limit uint64,
+		offset uint64) ([]*models.Offer, error)
+	AddPromotion(ctx context.Context, r io.Reader, productID uint64, userID uint64) (*models.Promotion, error)
+	GrantPromotion(ctx context.Context, r io.Reader, productID uint64, moderatorID uint64) (*models.Promotion, error)
+	GetPromotions(ctx context.Context, userID uint64, productID uint64, limit uint64,
+		offset uint64) ([]*models.Promotion, error)
+	AddSavedSearch(ctx context.Context, r io.Reader, filter *models.ProductFilter, query string,
+		userID uint64) (*models.SavedSearch, error)
+	GetSavedSearches(ctx context.Context, userID uint64, limit uint64, offset uint64) ([]*models.SavedSearch, error)
+	DeleteSavedSearch(ctx context.Context, savedSearchID uint64, userID uint64) error
 }
 
 type ProductHandler struct {
@@ -45,7 +73,8 @@ func NewProductHandler(productService IProductService) (*ProductHandler, error)
 // AddProductHandler godoc
 //
 //	@Summary    add product
-//	@Description  add product by data
+//	@Description  add product by data, premoderation rejects it with reason or sends it to review with status on_review
+//	@Description  Duplicate of existing product is rejected, sent to review or added with warnings depending on config
 //	@Description Error.status can be:
 //	@Description StatusErrBadRequest      = 400
 //	@Description  StatusErrInternalServer  = 500
@@ -133,22 +162,140 @@ func (p *ProductHandler) GetProductHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	p.service.RecordView(product, viewerKey(r, userID), r.UserAgent())
+
 	delivery.SendOkResponse(w, p.logger, NewProductWithIsMyResponse(delivery.StatusResponseSuccessful, product))
 	p.logger.Infof("in GetProductHandler: get product: %+v", product)
 }
 
+// UpdateProductHandler godoc
+//
+//	@Summary    update product
+//	@Description  partial update of own product, only passed fields are changed. Change of price is kept in price history.
+//	@Description  Edited product is checked by premoderation like new one, active product may be sent to review
+//	@Tags product
+//	@Accept      json
+//	@Produce    json
+//	@Param      id  query uint64 true  "product id"
+//	@Param      product  body models.PartialProduct true  "changed fields of product"
+//	@Success    200  {object} ProductWithIsMyResponse
+//	@Failure    405  {string} string
+//	@Failure    500  {string} string
+//	@Failure    222  {object} delivery.ErrorResponse "Error"
+//	@Router      /product/update [patch]
+func (p *ProductHandler) UpdateProductHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPatch {
+		http.Error(w, `Method not allowed`, http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	ctx := r.Context()
+
+	userID, err := delivery.GetUserIDFromCookie(r)
+	if err != nil {
+		delivery.HandleErr(w, p.logger, err)
+
+		return
+	}
+
+	productID, err := utils.ParseUint64FromRequest(r, "id")
+	if err != nil {
+		delivery.HandleErr(w, p.logger, err)
+
+		return
+	}
+
+	product, err := p.service.UpdateProduct(ctx, r.Body, productID, userID)
+	if err != nil {
+		delivery.HandleErr(w, p.logger, err)
+
+		return
+	}
+
+	delivery.SendOkResponse(w, p.logger, NewProductWithIsMyResponse(delivery.StatusResponseSuccessful, product))
+	p.logger.Infof("in UpdateProductHandler: update product: %+v", product)
+}
+
+// GetPriceHistoryHandler godoc
+//
+//	@Summary    get price history
+//	@Description  get price changes of product from newest to oldest
+//	@Tags product
+//	@Accept      json
+//	@Produce    json
+//	@Param      id  query uint64 true  "product id"
+//	@Success    200  {object} PriceHistoryResponse
+//	@Failure    405  {string} string
+//	@Failure    500  {string} string
+//	@Failure    222  {object} delivery.ErrorResponse "Error"
+//	@Router      /product/price_history [get]
+func (p *ProductHandler) GetPriceHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, `Method not allowed`, http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	ctx := r.Context()
+
+	userID, err := delivery.GetUserIDFromCookie(r)
+	if err != nil {
+		if errors.Is(err, delivery.ErrCookieNotPresented) {
+			userID = 0
+		} else {
+			delivery.HandleErr(w, p.logger, err)
+
+			return
+		}
+	}
+
+	productID, err := utils.ParseUint64FromRequest(r, "id")
+	if err != nil {
+		delivery.HandleErr(w, p.logger, err)
+
+		return
+	}
+
+	priceHistory, err := p.service.GetPriceHistory(ctx, productID, userID)
+	if err != nil {
+		delivery.HandleErr(w, p.logger, err)
+
+		return
+	}
+
+	delivery.SendOkResponse(w, p.logger, NewPriceHistoryResponse(delivery.StatusResponseSuccessful, priceHistory))
+	p.logger.Infof("in GetPriceHistoryHandler: get price history of product %d: %+v", productID, priceHistory)
+}
+
 // GetProductsListHandler godoc
 //
 //	@Summary    get Products list
 //	@Description  get Products by count and last_id return old Products
+//	@Description  Without sort common feed has promoted products with flag promoted in configured slots of page
 //	@Tags product
 //	@Accept      json
 //	@Produce    json
-//	@Param      limit  query uint64 true  "limit Products"
-//	@Param      offset  query uint64 true  "offset of Products"
-//	@Param      min_price  query uint64 true  "min price of product"
-//	@Param      max_price  query uint64 true  "max price of product"
-//	@Param      sort_type query uint64 true  "type of sort(nil - by date desc, 1 - by price asc, 2 - by price desc, 3 - by date asc, 4 - by date desc)"
+//	@Param      limit  query uint64 false  "limit Products, 10 by default, can't be bigger than configured maximum"
+//	@Param      offset  query uint64 false  "offset of Products, legacy: ignored when cursor is passed"
+//	@Param      cursor  query string false  "next_cursor from previous page"
+//	@Param      currency  query string false  "ISO 4217 currency of products, base currency by default with min_price or max_price"
+//	@Param      min_price  query uint64 false  "min price of product in minor units of currency"
+//	@Param      max_price  query uint64 false  "max price of product in minor units of currency"
+//	@Param      category  query uint64 false  "category id, products of subcategories are included"
+//	@Param      seller_id  query uint64 false  "id of seller"
+//	@Param      created_after  query string false  "RFC 3339 time, products created at or after it"
+//	@Param      created_before  query string false  "RFC 3339 time, products created before it"
+//	@Param      status  query string false  "comma separated statuses: active, reserved, sold (and draft, hidden, on_review with mine). active by default, all with mine"
+//	@Param      mine  query bool false  "only products of current user in any status, requires auth"
+//	@Param      favorites  query bool false  "only favorite products of current user, requires auth"
+//	@Param      has_image  query bool false  "only products with (true) or without (false) image"
+//	@Param      attr.{name}  query string false  "attribute filter: attr.name=value, attr.name_gte=number, attr.name_lte=number"
+//	@Param      near  query string false  "point 'lat,lon', only products not further than radius_km from it"
+//	@Param      radius_km  query number false  "radius of near search in km, 25 by default, not bigger than 500"
+//	@Param      sort query string false  "comma separated sort keys: price, created_at, relevance, distance, '-' prefix for desc (e.g. -price,created_at). By default -relevance with q, distance with near, -created_at otherwise"
+//	@Param      sort_type query uint64 false  "deprecated, used without sort: 1 - price, 2 - -price, 3 - created_at, 4 - -created_at, 5 - -relevance"
+//	@Param      q  query string false  "full-text search query over title and description"
 //	@Success    200  {object} ProductListResponse
 //	@Failure    405  {string} string
 //	@Failure    500  {string} string
@@ -176,7 +323,7 @@ func (p *ProductHandler) GetProductListHandler(w http.ResponseWriter, r *http.Re
 
 	limit, err := utils.ParseUint64FromRequest(r, "limit")
 	if err != nil {
-		limit = 10
+		limit = 0
 	}
 
 	offset, err := utils.ParseUint64FromRequest(r, "offset")
@@ -184,28 +331,296 @@ func (p *ProductHandler) GetProductListHandler(w http.ResponseWriter, r *http.Re
 		offset = 0
 	}
 
-	sortType, err := utils.ParseUint64FromRequest(r, "sort_type")
+	sortSpec, err := models.ParseSortSpec(utils.ParseStringFromRequest(r, "sort"))
 	if err != nil {
-		sortType = 0
+		delivery.HandleErr(w, p.logger, err)
+
+		return
 	}
 
-	minPrice, err := utils.ParseUint64FromRequest(r, "min_price")
-	if err != nil {
-		minPrice = 0
+	if len(sortSpec) == 0 {
+		sortType, err := utils.ParseUint64FromRequest(r, "sort_type")
+		if err == nil {
+			sortSpec = models.LegacySortSpec(sortType)
+		}
 	}
 
-	maxPrice, err := utils.ParseUint64FromRequest(r, "max_price")
+	filter, err := parseProductFilter(r)
 	if err != nil {
-		maxPrice = math.MaxUint64
+		delivery.HandleErr(w, p.logger, err)
+
+		return
 	}
 
-	products, err := p.service.GetProductsList(ctx, limit, offset, sortType, minPrice, maxPrice, userID)
+	rawCursor := utils.ParseStringFromRequest(r, "cursor")
+
+	products, pageInfo, err := p.service.GetProductsList(ctx, filter, sortSpec, limit, offset, rawCursor, userID)
 	if err != nil {
 		delivery.HandleErr(w, p.logger, err)
 
 		return
 	}
 
-	delivery.SendOkResponse(w, p.logger, NewProductListResponse(delivery.StatusResponseSuccessful, products))
+	delivery.SetLinkHeader(w, paginationLinks(r, pageInfo))
+	delivery.SendOkResponse(w, p.logger,
+		NewProductListResponse(delivery.StatusResponseSuccessful, products, pageInfo))
 	p.logger.Infof("in GetProductListHandler: get Product list: %+v", products)
 }
+
+// paginationLinks makes RFC 8288 links relative to current request. Next page is always
+// addressed by cursor, prev and first pages are given only in legacy offset mode.
+func paginationLinks(r *http.Request, pageInfo *models.PageInfo) map[string]string {
+	links := make(map[string]string)
+
+	linkWithQuery := func(change func(query url.Values)) string {
+		query := r.URL.Query()
+		change(query)
+
+		return (&url.URL{Path: r.URL.Path, RawQuery: query.Encode()}).String() //nolint:exhaustruct
+	}
+
+	if pageInfo.NextCursor != "" {
+		links["next"] = linkWithQuery(func(query url.Values) {
+			query.Del("offset")
+			query.Set("cursor", pageInfo.NextCursor)
+		})
+	}
+
+	if pageInfo.Cursor == "" && pageInfo.Offset > 0 {
+		links["first"] = linkWithQuery(func(query url.Values) {
+			query.Set("offset", "0")
+		})
+		links["prev"] = linkWithQuery(func(query url.Values) {
+			query.Set("offset", strconv.FormatUint(pageInfo.Offset-min(pageInfo.Offset, pageInfo.Limit), 10))
+		})
+	}
+
+	return links
+}
+
+// parseProductFilter reads feed filters from query. Absent or malformed numbers are ignored
+// as before, malformed dates and booleans are reported.
+func parseProductFilter(r *http.Request) (*models.ProductFilter, error) {
+	query := r.URL.Query()
+	filter := &models.ProductFilter{ //nolint:exhaustruct
+		SearchQuery: utils.ParseStringFromRequest(r, "q"),
+		Attributes:  parseAttributeFilters(r),
+	}
+
+	filter.Currency = utils.ParseStringFromRequest(r, "currency")
+
+	if minPrice, err := utils.ParseUint64FromRequest(r, "min_price"); err == nil {
+		filter.MinPrice = &minPrice
+	}
+
+	if maxPrice, err := utils.ParseUint64FromRequest(r, "max_price"); err == nil {
+		filter.MaxPrice = &maxPrice
+	}
+
+	if categoryID, err := utils.ParseUint64FromRequest(r, "category"); err == nil {
+		filter.CategoryID = categoryID
+	}
+
+	if query.Has("seller_id") {
+		if salerID, err := utils.ParseUint64FromRequest(r, "seller_id"); err == nil {
+			filter.SalerID = salerID
+		}
+	}
+
+	if query.Has("created_after") {
+		createdAfter, err := utils.ParseTimeFromRequest(r, "created_after")
+		if err != nil {
+			return nil, err
+		}
+
+		filter.CreatedAfter = &createdAfter
+	}
+
+	if query.Has("created_before") {
+		createdBefore, err := utils.ParseTimeFromRequest(r, "created_before")
+		if err != nil {
+			return nil, err
+		}
+
+		filter.CreatedBefore = &createdBefore
+	}
+
+	if query.Has("has_image") {
+		hasImage, err := utils.ParseBoolFromRequest(r, "has_image")
+		if err != nil {
+			return nil, err
+		}
+
+		filter.HasImage = &hasImage
+	}
+
+	if query.Has("mine") {
+		mine, err := utils.ParseBoolFromRequest(r, "mine")
+		if err != nil {
+			return nil, err
+		}
+
+		filter.Mine = mine
+	}
+
+	if query.Has("favorites") {
+		favorites, err := utils.ParseBoolFromRequest(r, "favorites")
+		if err != nil {
+			return nil, err
+		}
+
+		filter.Favorites = favorites
+	}
+
+	if query.Has("near") {
+		near, err := models.ParseGeoPoint(utils.ParseStringFromRequest(r, "near"))
+		if err != nil {
+			return nil, err
+		}
+
+		filter.Near = near
+	}
+
+	if query.Has("radius_km") {
+		radiusKm, err := utils.ParseFloat64FromRequest(r, "radius_km")
+		if err != nil {
+			return nil, err
+		}
+
+		filter.RadiusKm = radiusKm
+	}
+
+	if statuses := utils.ParseStringFromRequest(r, "status"); statuses != "" {
+		filter.Statuses = strings.Split(statuses, ",")
+	}
+
+	return filter, nil
+}
+
+// parseAttributeFilters collects query params like attr.condition=used or attr.year_gte=2015.
+func parseAttributeFilters(r *http.Request) []*models.AttributeFilter {
+	attrFilters := make([]*models.AttributeFilter, 0)
+
+	for param, values := range r.URL.Query() {
+		name, ok := strings.CutPrefix(param, attributeParamPrefix)
+		if !ok || len(values) == 0 {
+			continue
+		}
+
+		op := models.AttributeFilterEq
+
+		if nameWithoutOp, ok := strings.CutSuffix(name, "_"+models.AttributeFilterGte); ok {
+			name, op = nameWithoutOp, models.AttributeFilterGte
+		} else if nameWithoutOp, ok := strings.CutSuffix(name, "_"+models.AttributeFilterLte); ok {
+			name, op = nameWithoutOp, models.AttributeFilterLte
+		}
+
+		attrFilters = append(attrFilters, &models.AttributeFilter{Name: name, Op: op, Value: values[0]})
+	}
+
+	return attrFilters
+}
+
+// GetFacetsHandler godoc
+//
+//	@Summary    get facets
+//	@Description  get counts of attribute values for products matching filters
+//	@Tags product
+//	@Produce    json
+//	@Param      currency  query string false  "ISO 4217 currency of products, base currency by default with min_price or max_price"
+//	@Param      min_price  query uint64 false  "min price of product in minor units of currency"
+//	@Param      max_price  query uint64 false  "max price of product in minor units of currency"
+//	@Param      category  query uint64 false  "category id, products of subcategories are included"
+//	@Param      seller_id  query uint64 false  "id of seller"
+//	@Param      created_after  query string false  "RFC 3339 time, products created at or after it"
+//	@Param      created_before  query string false  "RFC 3339 time, products created before it"
+//	@Param      status  query string false  "comma separated statuses: active, reserved, sold (and draft, hidden, on_review with mine). active by default, all with mine"
+//	@Param      mine  query bool false  "only products of current user in any status, requires auth"
+//	@Param      favorites  query bool false  "only favorite products of current user, requires auth"
+//	@Param      has_image  query bool false  "only products with (true) or without (false) image"
+//	@Param      q  query string false  "full-text search query over title and description"
+//	@Param      near  query string false  "point 'lat,lon', only products not further than radius_km from it"
+//	@Param      radius_km  query number false  "radius of near search in km, 25 by default, not bigger than 500"
+//	@Param      attr.{name}  query string false  "attribute filter: attr.name=value, attr.name_gte=number, attr.name_lte=number"
+//	@Success    200  {object} FacetListResponse
+//	@Failure    405  {string} string
+//	@Failure    500  {string} string
+//	@Failure    222  {object} delivery.ErrorResponse "Error"
+//	@Router      /product/facets [get]
+func (p *ProductHandler) GetFacetsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, `Method not allowed`, http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	ctx := r.Context()
+
+	userID, err := delivery.GetUserIDFromCookie(r)
+	if err != nil {
+		if errors.Is(err, delivery.ErrCookieNotPresented) {
+			userID = 0
+		} else {
+			delivery.HandleErr(w, p.logger, err)
+
+			return
+		}
+	}
+
+	filter, err := parseProductFilter(r)
+	if err != nil {
+		delivery.HandleErr(w, p.logger, err)
+
+		return
+	}
+
+	facets, err := p.service.GetFacets(ctx, filter, userID)
+	if err != nil {
+		delivery.HandleErr(w, p.logger, err)
+
+		return
+	}
+
+	delivery.SendOkResponse(w, p.logger, NewFacetListResponse(delivery.StatusResponseSuccessful, facets))
+	p.logger.Infof("in GetFacetsHandler: get facets: %+v", facets)
+}
+
+// GetSuggestionsHandler godoc
+//
+//	@Summary    get search suggestions
+//	@Description  get title completions of active products and matching categories
+//	@Tags product
+//	@Produce    json
+//	@Param      q  query string true  "beginning of search query"
+//	@Param      limit  query uint64 false  "max count of suggestions of each kind, at most 10"
+//	@Success    200  {object} SuggestionsResponse
+//	@Failure    405  {string} string
+//	@Failure    500  {string} string
+//	@Failure    222  {object} delivery.ErrorResponse "Error"
+//	@Router      /product/suggest [get]
+func (p *ProductHandler) GetSuggestionsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, `Method not allowed`, http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	ctx := r.Context()
+
+	limit, err := utils.ParseUint64FromRequest(r, "limit")
+	if err != nil {
+		limit = 0
+	}
+
+	searchQuery := utils.ParseStringFromRequest(r, "q")
+
+	suggestions, err := p.service.GetSuggestions(ctx, searchQuery, limit)
+	if err != nil {
+		delivery.HandleErr(w, p.logger, err)
+
+		return
+	}
+
+	delivery.SendOkResponse(w, p.logger, NewSuggestionsResponse(delivery.StatusResponseSuccessful, suggestions))
+	p.logger.Infof("in GetSuggestionsHandler: get suggestions: %+v", suggestions)
+}
@@ -0,0 +1,86 @@
+package delivery
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/SanExpett/marketplace-backend/pkg/models"
+	"github.com/SanExpett/marketplace-backend/pkg/my_logger"
+)
+
+func TestMain(m *testing.M) {
+	if _, err := my_logger.New([]string{os.DevNull}, []string{os.DevNull}); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	os.Exit(m.Run())
+}
+
+func TestParseProductFilter(t *testing.T) {
+	r := httptest.NewRequest("GET", "/api/v1/product/get_list?q=bike&currency=usd&min_price=100&max_price=500"+
+		"&category=3&seller_id=7&created_after=2024-01-02T03:04:05Z&has_image=false&mine=true"+
+		"&near=55.75,37.62&radius_km=2.5&status=active,sold&attr.condition=used", nil)
+
+	filter, err := parseProductFilter(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	minPrice, maxPrice, hasImage := uint64(100), uint64(500), false
+	createdAfter := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	want := &models.ProductFilter{ //nolint:exhaustruct
+		SearchQuery:  "bike",
+		Currency:     "usd",
+		MinPrice:     &minPrice,
+		MaxPrice:     &maxPrice,
+		CategoryID:   3,
+		SalerID:      7,
+		CreatedAfter: &createdAfter,
+		HasImage:     &hasImage,
+		Mine:         true,
+		Near:         &models.GeoPoint{Latitude: 55.75, Longitude: 37.62},
+		RadiusKm:     2.5,
+		Statuses:     []string{models.ProductStatusActive, models.ProductStatusSold},
+		Attributes:   []*models.AttributeFilter{{Name: "condition", Op: models.AttributeFilterEq, Value: "used"}},
+	}
+
+	if !reflect.DeepEqual(filter, want) {
+		t.Fatalf("parseProductFilter() = %+v, want %+v", filter, want)
+	}
+}
+
+func TestParseProductFilterErrors(t *testing.T) {
+	for _, rawQuery := range []string{
+		"created_after=yesterday", "created_before=2024-01-02", "has_image=maybe",
+		"mine=1x", "favorites=yes", "near=91", "radius_km=far",
+	} {
+		if _, err := parseProductFilter(httptest.NewRequest("GET", "/?"+rawQuery, nil)); err == nil {
+			t.Errorf("parseProductFilter(%q): expected error", rawQuery)
+		}
+	}
+}
+
+func TestParseAttributeFilters(t *testing.T) {
+	r := httptest.NewRequest("GET", "/?attr.year_gte=2015&attr.year_lte=2020&attr.size=1&q=golf", nil)
+
+	attrFilters := parseAttributeFilters(r)
+	sort.Slice(attrFilters, func(i, j int) bool {
+		return attrFilters[i].Name+attrFilters[i].Op < attrFilters[j].Name+attrFilters[j].Op
+	})
+
+	want := []*models.AttributeFilter{
+		{Name: "size", Op: models.AttributeFilterEq, Value: "1"},
+		{Name: "year", Op: models.AttributeFilterGte, Value: "2015"},
+		{Name: "year", Op: models.AttributeFilterLte, Value: "2020"},
+	}
+
+	if !reflect.DeepEqual(attrFilters, want) {
+		t.Fatalf("parseAttributeFilters() = %v, want %v", attrFilters, want)
+	}
+}
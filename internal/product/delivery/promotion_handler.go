@@ -0,0 +1,144 @@
+package delivery
+
+import (
+	"context"
+	"github.com/SanExpett/marketplace-backend/internal/server/delivery"
+	"github.com/SanExpett/marketplace-backend/pkg/models"
+	"github.com/SanExpett/marketplace-backend/pkg/utils"
+	"io"
+	"net/http"
+)
+
+// AddPromotionHandler godoc
+//
+//	@Summary    buy promotion
+//	@Description  seller buys promotion of own active product, price is configured price of day for every
+//	@Description  started day. Promotion runs after payment succeeds, declined payment is returned in promotion.
+//	@Description  If payment stays pending, repeated request for the same type and period repeats it.
+//	@Description  Promoted product is shown in promoted slots of default feed
+//	@Description  from started_at until ended_at, empty started_at means now. Type is bump, highlight or
+//	@Description  top_of_category, the last one works only in feed of category
+//	@Tags promotion
+//	@Accept      json
+//	@Produce    json
+//	@Param      product_id  query uint64 true  "product id"
+//	@Param      prePromotion  body models.PrePromotion true  "type and period of promotion"
+//	@Success    200  {object} PromotionResponse
+//	@Failure    405  {string} string
+//	@Failure    500  {string} string
+//	@Failure    222  {object} delivery.ErrorResponse "Error"
+//	@Router      /promotion/add [post]
+func (p *ProductHandler) AddPromotionHandler(w http.ResponseWriter, r *http.Request) {
+	p.handleAddPromotion(w, r, p.service.AddPromotion)
+}
+
+// GrantPromotionHandler godoc
+//
+//	@Summary    grant promotion
+//	@Description  moderator adds free promotion of active product, it belongs to seller of product and runs at once
+//	@Tags promotion
+//	@Accept      json
+//	@Produce    json
+//	@Param      product_id  query uint64 true  "product id"
+//	@Param      prePromotion  body models.PrePromotion true  "type and period of promotion"
+//	@Success    200  {object} PromotionResponse
+//	@Failure    405  {string} string
+//	@Failure    500  {string} string
+//	@Failure    222  {object} delivery.ErrorResponse "Error"
+//	@Router      /promotion/grant [post]
+func (p *ProductHandler) GrantPromotionHandler(w http.ResponseWriter, r *http.Request) {
+	p.handleAddPromotion(w, r, p.service.GrantPromotion)
+}
+
+// handleAddPromotion adds promotion of product from query by current user with addPromotion.
+func (p *ProductHandler) handleAddPromotion(w http.ResponseWriter, r *http.Request,
+	addPromotion func(ctx context.Context, r io.Reader, productID uint64, userID uint64) (*models.Promotion, error),
+) {
+	if r.Method != http.MethodPost {
+		http.Error(w, `Method not allowed`, http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	ctx := r.Context()
+
+	userID, err := delivery.GetUserIDFromCookie(r)
+	if err != nil {
+		delivery.HandleErr(w, p.logger, err)
+
+		return
+	}
+
+	productID, err := utils.ParseUint64FromRequest(r, "product_id")
+	if err != nil {
+		delivery.HandleErr(w, p.logger, err)
+
+		return
+	}
+
+	promotion, err := addPromotion(ctx, r.Body, productID, userID)
+	if err != nil {
+		delivery.HandleErr(w, p.logger, err)
+
+		return
+	}
+
+	delivery.SendOkResponse(w, p.logger, NewPromotionResponse(delivery.StatusResponseSuccessful, promotion))
+	p.logger.Infof("in handleAddPromotion: add promotion: %+v", promotion)
+}
+
+// GetPromotionsHandler godoc
+//
+//	@Summary    get promotions
+//	@Description  get promotions of products of current user, newest first
+//	@Tags promotion
+//	@Produce    json
+//	@Param      product_id  query uint64 false  "only promotions of this product"
+//	@Param      limit  query uint64 false  "limit of promotions, 10 by default, can't be bigger than configured maximum"
+//	@Param      offset  query uint64 false  "offset of promotions"
+//	@Success    200  {object} PromotionListResponse
+//	@Failure    405  {string} string
+//	@Failure    500  {string} string
+//	@Failure    222  {object} delivery.ErrorResponse "Error"
+//	@Router      /promotion/get_list [get]
+func (p *ProductHandler) GetPromotionsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, `Method not allowed`, http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	ctx := r.Context()
+
+	userID, err := delivery.GetUserIDFromCookie(r)
+	if err != nil {
+		delivery.HandleErr(w, p.logger, err)
+
+		return
+	}
+
+	productID, err := utils.ParseUint64FromRequest(r, "product_id")
+	if err != nil {
+		productID = 0
+	}
+
+	limit, err := utils.ParseUint64FromRequest(r, "limit")
+	if err != nil {
+		limit = 0
+	}
+
+	offset, err := utils.ParseUint64FromRequest(r, "offset")
+	if err != nil {
+		offset = 0
+	}
+
+	promotions, err := p.service.GetPromotions(ctx, userID, productID, limit, offset)
+	if err != nil {
+		delivery.HandleErr(w, p.logger, err)
+
+		return
+	}
+
+	delivery.SendOkResponse(w, p.logger, NewPromotionListResponse(delivery.StatusResponseSuccessful, promotions))
+	p.logger.Infof("in GetPromotionsHandler: get %d promotions of user %d", len(promotions), userID)
+}
@@ -31,11 +31,134 @@ func NewProductWithIsMyResponse(status int, body *models.ProductWithIsMy) *Produ
 type ProductListResponse struct {
 	Status int                       `json:"status"`
 	Body   []*models.ProductWithIsMy `json:"body"`
+	Meta   *models.PageInfo          `json:"meta"`
 }
 
-func NewProductListResponse(status int, body []*models.ProductWithIsMy) *ProductListResponse {
+func NewProductListResponse(status int, body []*models.ProductWithIsMy, meta *models.PageInfo,
+) *ProductListResponse {
 	return &ProductListResponse{
 		Status: status,
 		Body:   body,
+		Meta:   meta,
+	}
+}
+
+type FacetListResponse struct {
+	Status int             `json:"status"`
+	Body   []*models.Facet `json:"body"`
+}
+
+func NewFacetListResponse(status int, body []*models.Facet) *FacetListResponse {
+	return &FacetListResponse{
+		Status: status,
+		Body:   body,
+	}
+}
+
+type SuggestionsResponse struct {
+	Status int                 `json:"status"`
+	Body   *models.Suggestions `json:"body"`
+}
+
+func NewSuggestionsResponse(status int, body *models.Suggestions) *SuggestionsResponse {
+	return &SuggestionsResponse{
+		Status: status,
+		Body:   body,
+	}
+}
+
+type DailyViewsResponse struct {
+	Status int                  `json:"status"`
+	Body   []*models.DailyViews `json:"body"`
+}
+
+func NewDailyViewsResponse(status int, body []*models.DailyViews) *DailyViewsResponse {
+	return &DailyViewsResponse{
+		Status: status,
+		Body:   body,
+	}
+}
+
+type PriceHistoryResponse struct {
+	Status int                   `json:"status"`
+	Body   []*models.PriceChange `json:"body"`
+}
+
+func NewPriceHistoryResponse(status int, body []*models.PriceChange) *PriceHistoryResponse {
+	return &PriceHistoryResponse{
+		Status: status,
+		Body:   body,
+	}
+}
+
+type OfferResponse struct {
+	Status int           `json:"status"`
+	Body   *models.Offer `json:"body"`
+}
+
+func NewOfferResponse(status int, body *models.Offer) *OfferResponse {
+	return &OfferResponse{
+		Status: status,
+		Body:   body,
+	}
+}
+
+type OfferListResponse struct {
+	Status int             `json:"status"`
+	Body   []*models.Offer `json:"body"`
+}
+
+func NewOfferListResponse(status int, body []*models.Offer) *OfferListResponse {
+	return &OfferListResponse{
+		Status: status,
+		Body:   body,
+	}
+}
+
+type PromotionResponse struct {
+	Status int               `json:"status"`
+	Body   *models.Promotion `json:"body"`
+}
+
+func NewPromotionResponse(status int, body *models.Promotion) *PromotionResponse {
+	return &PromotionResponse{
+		Status: status,
+		Body:   body,
+	}
+}
+
+type PromotionListResponse struct {
+	Status int                 `json:"status"`
+	Body   []*models.Promotion `json:"body"`
+}
+
+func NewPromotionListResponse(status int, body []*models.Promotion) *PromotionListResponse {
+	return &PromotionListResponse{
+		Status: status,
+		Body:   body,
+	}
+}
+
+type SavedSearchResponse struct {
+	Status int                 `json:"status"`
+	Body   *models.SavedSearch `json:"body"`
+}
+
+func NewSavedSearchResponse(status int, body *models.SavedSearch) *SavedSearchResponse {
+	return &SavedSearchResponse{
+		Status: status,
+		Body:   body,
+	}
+}
+
+type SavedSearchListResponse struct {
+	Status int                   `json:"status"`
+	Body   []*models.SavedSearch `json:"body"`
+}
+
+func NewSavedSearchListResponse(status int, body []*models.SavedSearch) *SavedSearchListResponse {
+	return &SavedSearchListResponse{
+		Status: status,
+		Body:   body,
 	}
 }
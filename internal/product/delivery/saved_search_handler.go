@@ -0,0 +1,176 @@
+package delivery
+
+import (
+	"github.com/SanExpett/marketplace-backend/internal/server/delivery"
+	"github.com/SanExpett/marketplace-backend/pkg/utils"
+	"net/http"
+)
+
+const ResponseSuccessfulDeleteSavedSearch = "Successful delete saved search"
+
+// savedSearchQuery is query string of feed without pagination, client opens feed of saved search with it.
+func savedSearchQuery(r *http.Request) string {
+	query := r.URL.Query()
+
+	for _, param := range []string{"limit", "offset", "cursor"} {
+		query.Del(param)
+	}
+
+	return query.Encode()
+}
+
+// AddSavedSearchHandler godoc
+//
+//	@Summary    save search
+//	@Description  save filter of feed passed in query params like in product/get_list. New active products which match it
+//	@Description  are pushed to stream as saved_search events: at once with notify instant, at most once an hour or
+//	@Description  a day with hourly and daily. Filters mine, favorites, status and created dates can't be saved
+//	@Tags saved_search
+//	@Accept      json
+//	@Produce    json
+//	@Param      preSavedSearch  body models.PreSavedSearch true  "name and notify mode: instant (default), hourly or daily"
+//	@Param      q  query string false  "full-text search query over title and description"
+//	@Param      category  query uint64 false  "category id, products of subcategories are included"
+//	@Param      currency  query string false  "ISO 4217 currency of products, base currency by default with min_price or max_price"
+//	@Param      min_price  query uint64 false  "min price of product in minor units of currency"
+//	@Param      max_price  query uint64 false  "max price of product in minor units of currency"
+//	@Param      seller_id  query uint64 false  "id of seller"
+//	@Param      has_image  query bool false  "only products with (true) or without (false) image"
+//	@Param      attr.{name}  query string false  "attribute filter: attr.name=value, attr.name_gte=number, attr.name_lte=number"
+//	@Param      near  query string false  "point 'lat,lon', only products not further than radius_km from it"
+//	@Param      radius_km  query number false  "radius of near search in km, 25 by default, not bigger than 500"
+//	@Success    200  {object} SavedSearchResponse
+//	@Failure    405  {string} string
+//	@Failure    500  {string} string
+//	@Failure    222  {object} delivery.ErrorResponse "Error"
+//	@Router      /saved_search/add [post]
+func (p *ProductHandler) AddSavedSearchHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, `Method not allowed`, http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	ctx := r.Context()
+
+	userID, err := delivery.GetUserIDFromCookie(r)
+	if err != nil {
+		delivery.HandleErr(w, p.logger, err)
+
+		return
+	}
+
+	filter, err := parseProductFilter(r)
+	if err != nil {
+		delivery.HandleErr(w, p.logger, err)
+
+		return
+	}
+
+	savedSearch, err := p.service.AddSavedSearch(ctx, r.Body, filter, savedSearchQuery(r), userID)
+	if err != nil {
+		delivery.HandleErr(w, p.logger, err)
+
+		return
+	}
+
+	delivery.SendOkResponse(w, p.logger, NewSavedSearchResponse(delivery.StatusResponseSuccessful, savedSearch))
+	p.logger.Infof("in AddSavedSearchHandler: add saved search: %+v", savedSearch)
+}
+
+// GetSavedSearchesHandler godoc
+//
+//	@Summary    get saved searches
+//	@Description  get saved searches of current user, newest first
+//	@Tags saved_search
+//	@Produce    json
+//	@Param      limit  query uint64 false  "limit of saved searches, 10 by default, can't be bigger than configured maximum"
+//	@Param      offset  query uint64 false  "offset of saved searches"
+//	@Success    200  {object} SavedSearchListResponse
+//	@Failure    405  {string} string
+//	@Failure    500  {string} string
+//	@Failure    222  {object} delivery.ErrorResponse "Error"
+//	@Router      /saved_search/get_list [get]
+func (p *ProductHandler) GetSavedSearchesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, `Method not allowed`, http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	ctx := r.Context()
+
+	userID, err := delivery.GetUserIDFromCookie(r)
+	if err != nil {
+		delivery.HandleErr(w, p.logger, err)
+
+		return
+	}
+
+	limit, err := utils.ParseUint64FromRequest(r, "limit")
+	if err != nil {
+		limit = 0
+	}
+
+	offset, err := utils.ParseUint64FromRequest(r, "offset")
+	if err != nil {
+		offset = 0
+	}
+
+	savedSearches, err := p.service.GetSavedSearches(ctx, userID, limit, offset)
+	if err != nil {
+		delivery.HandleErr(w, p.logger, err)
+
+		return
+	}
+
+	delivery.SendOkResponse(w, p.logger, NewSavedSearchListResponse(delivery.StatusResponseSuccessful, savedSearches))
+	p.logger.Infof("in GetSavedSearchesHandler: get %d saved searches of user %d", len(savedSearches), userID)
+}
+
+// DeleteSavedSearchHandler godoc
+//
+//	@Summary    delete saved search
+//	@Description  delete saved search of current user, its products which are not sent yet are dropped
+//	@Tags saved_search
+//	@Produce    json
+//	@Param      id  query uint64 true  "saved search id"
+//	@Success    200  {object} delivery.Response
+//	@Failure    405  {string} string
+//	@Failure    500  {string} string
+//	@Failure    222  {object} delivery.ErrorResponse "Error"
+//	@Router      /saved_search/delete [delete]
+func (p *ProductHandler) DeleteSavedSearchHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, `Method not allowed`, http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	ctx := r.Context()
+
+	userID, err := delivery.GetUserIDFromCookie(r)
+	if err != nil {
+		delivery.HandleErr(w, p.logger, err)
+
+		return
+	}
+
+	savedSearchID, err := utils.ParseUint64FromRequest(r, "id")
+	if err != nil {
+		delivery.HandleErr(w, p.logger, err)
+
+		return
+	}
+
+	err = p.service.DeleteSavedSearch(ctx, savedSearchID, userID)
+	if err != nil {
+		delivery.HandleErr(w, p.logger, err)
+
+		return
+	}
+
+	delivery.SendOkResponse(w, p.logger,
+		delivery.NewResponse(delivery.StatusResponseSuccessful, ResponseSuccessfulDeleteSavedSearch))
+	p.logger.Infof("in DeleteSavedSearchHandler: delete saved search %d of user %d", savedSearchID, userID)
+}
@@ -0,0 +1,77 @@
+package delivery
+
+import (
+	"fmt"
+	"github.com/SanExpett/marketplace-backend/internal/server/delivery"
+	"github.com/SanExpett/marketplace-backend/pkg/utils"
+	"net"
+	"net/http"
+)
+
+// viewerKey identifies viewer for deduplication of views: by user id or by address and user agent for guests.
+// Behind reverse proxy the address is taken from its headers by middleware.RealIP.
+func viewerKey(r *http.Request, userID uint64) string {
+	if userID != 0 {
+		return fmt.Sprintf("user:%d", userID)
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	return "guest:" + host + ":" + r.UserAgent()
+}
+
+// GetDailyViewsHandler godoc
+//
+//	@Summary    get views of product
+//	@Description  get unique views of own product by days, days without views have zero
+//	@Tags product
+//	@Accept      json
+//	@Produce    json
+//	@Param      id  query uint64 true  "product id"
+//	@Param      days  query uint64 false  "number of last days including today, 30 by default, not bigger than 365"
+//	@Success    200  {object} DailyViewsResponse
+//	@Failure    405  {string} string
+//	@Failure    500  {string} string
+//	@Failure    222  {object} delivery.ErrorResponse "Error"
+//	@Router      /product/views [get]
+func (p *ProductHandler) GetDailyViewsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, `Method not allowed`, http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	ctx := r.Context()
+
+	userID, err := delivery.GetUserIDFromCookie(r)
+	if err != nil {
+		delivery.HandleErr(w, p.logger, err)
+
+		return
+	}
+
+	productID, err := utils.ParseUint64FromRequest(r, "id")
+	if err != nil {
+		delivery.HandleErr(w, p.logger, err)
+
+		return
+	}
+
+	days, err := utils.ParseUint64FromRequest(r, "days")
+	if err != nil {
+		days = 0
+	}
+
+	dailyViews, err := p.service.GetDailyViews(ctx, productID, days, userID)
+	if err != nil {
+		delivery.HandleErr(w, p.logger, err)
+
+		return
+	}
+
+	delivery.SendOkResponse(w, p.logger, NewDailyViewsResponse(delivery.StatusResponseSuccessful, dailyViews))
+	p.logger.Infof("in GetDailyViewsHandler: get views of product %d: %+v", productID, dailyViews)
+}
@@ -0,0 +1,135 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"github.com/SanExpett/marketplace-backend/pkg/models"
+	myerrors "github.com/SanExpett/marketplace-backend/pkg/my_errors"
+	"github.com/jackc/pgx/v5"
+)
+
+// isProductVisible reports whether product exists and user can see it: drafts and hidden products
+// are visible only to owner.
+func (p *ProductStorage) isProductVisible(ctx context.Context, tx pgx.Tx, productID uint64, userID uint64,
+) (bool, error) {
+	SQLIsProductVisible := `SELECT EXISTS(SELECT 1 FROM public."product"
+		WHERE id=$1 AND (status <> ALL($2) OR saler_id = $3))`
+
+	var visible bool
+
+	err := tx.QueryRow(ctx, SQLIsProductVisible, productID, models.PrivateProductStatuses(), userID).Scan(&visible)
+	if err != nil {
+		p.logger.Errorf("error with productID=%d: %+v", productID, err)
+
+		return false, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return visible, nil
+}
+
+func (p *ProductStorage) updateFavoritesCount(ctx context.Context, tx pgx.Tx, productID uint64, delta int64) error {
+	SQLUpdateFavoritesCount := `UPDATE public."product" SET favorites_count = favorites_count + $1 WHERE id=$2`
+
+	_, err := tx.Exec(ctx, SQLUpdateFavoritesCount, delta, productID)
+	if err != nil {
+		p.logger.Errorln(err)
+
+		return fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return nil
+}
+
+// AddFavorite is idempotent: adding product twice doesn't change favorites count.
+func (p *ProductStorage) AddFavorite(ctx context.Context, userID uint64, productID uint64) error {
+	SQLInsertFavorite := `INSERT INTO public."favorite"(user_id, product_id) VALUES($1, $2)
+		ON CONFLICT (user_id, product_id) DO NOTHING`
+
+	err := pgx.BeginFunc(ctx, p.pool, func(tx pgx.Tx) error {
+		visible, err := p.isProductVisible(ctx, tx, productID, userID)
+		if err != nil {
+			return err
+		}
+
+		if !visible {
+			return fmt.Errorf(myerrors.ErrTemplate, ErrProductNotFound)
+		}
+
+		result, err := tx.Exec(ctx, SQLInsertFavorite, userID, productID)
+		if err != nil {
+			p.logger.Errorln(err)
+
+			return fmt.Errorf(myerrors.ErrTemplate, err)
+		}
+
+		if result.RowsAffected() == 0 {
+			return nil
+		}
+
+		return p.updateFavoritesCount(ctx, tx, productID, 1)
+	})
+	if err != nil {
+		p.logger.Errorln(err)
+
+		return fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return nil
+}
+
+// DeleteFavorite is idempotent: deleting product which is not in favorites does nothing.
+func (p *ProductStorage) DeleteFavorite(ctx context.Context, userID uint64, productID uint64) error {
+	SQLDeleteFavorite := `DELETE FROM public."favorite" WHERE user_id=$1 AND product_id=$2`
+
+	err := pgx.BeginFunc(ctx, p.pool, func(tx pgx.Tx) error {
+		result, err := tx.Exec(ctx, SQLDeleteFavorite, userID, productID)
+		if err != nil {
+			p.logger.Errorln(err)
+
+			return fmt.Errorf(myerrors.ErrTemplate, err)
+		}
+
+		if result.RowsAffected() == 0 {
+			return nil
+		}
+
+		return p.updateFavoritesCount(ctx, tx, productID, -1)
+	})
+	if err != nil {
+		p.logger.Errorln(err)
+
+		return fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return nil
+}
+
+// GetFavoritedUserIDs returns users who have product in favorites.
+func (p *ProductStorage) GetFavoritedUserIDs(ctx context.Context, productID uint64) ([]uint64, error) {
+	SQLSelectFavoritedUserIDs := `SELECT user_id FROM public."favorite" WHERE product_id=$1`
+
+	var userIDs []uint64
+
+	err := pgx.BeginFunc(ctx, p.pool, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, SQLSelectFavoritedUserIDs, productID)
+		if err != nil {
+			p.logger.Errorln(err)
+
+			return fmt.Errorf(myerrors.ErrTemplate, err)
+		}
+
+		userIDs, err = pgx.CollectRows(rows, pgx.RowTo[uint64])
+		if err != nil {
+			p.logger.Errorln(err)
+
+			return fmt.Errorf(myerrors.ErrTemplate, err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return userIDs, nil
+}
@@ -0,0 +1,381 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/SanExpett/marketplace-backend/internal/server/repository"
+	"github.com/SanExpett/marketplace-backend/pkg/models"
+	myerrors "github.com/SanExpett/marketplace-backend/pkg/my_errors"
+	"github.com/jackc/pgx/v5"
+	"slices"
+)
+
+const MessageErrWrongOfferAction = "Нельзя выполнить действие %s с предложением в статусе %s"
+
+var (
+	ErrOfferNotFound       = myerrors.NewError("Это предложение не найдено")
+	ErrOfferExpired        = myerrors.NewError("Срок действия предложения истек")
+	ErrOfferAlreadyExists  = myerrors.NewError("У вас уже есть открытое предложение на это объявление")
+	ErrOfferOnMyProduct    = myerrors.NewError("Нельзя сделать предложение на свое объявление")
+	ErrOfferPriceTooHigh   = myerrors.NewError("Предложение должно быть ниже цены объявления")
+	ErrOfferCurrency       = myerrors.NewError("Предложение должно быть в валюте объявления")
+	ErrCounterPriceTooLow  = myerrors.NewError("Встречная цена должна быть выше предложенной")
+	ErrProductNotAvailable = myerrors.NewError("Объявление уже зарезервировано или продано")
+
+	NameSeqOffer = pgx.Identifier{"public", "offer_id_seq"} //nolint:gochecknoglobals
+)
+
+// SQLNotOrderedOffer matches offers without order, accepted offer which is ordered doesn't expire.
+const SQLNotOrderedOffer = `NOT EXISTS(SELECT 1 FROM public."order" o WHERE o.offer_id = offer.id)`
+
+// SQLOfferStatus shows open and accepted offers with passed expires_at as expired before they are expired
+// by background job.
+const SQLOfferStatus = `CASE WHEN status IN ('pending', 'countered', 'accepted') AND expires_at <= NOW()
+	AND ` + SQLNotOrderedOffer + ` THEN 'expired' ELSE status END`
+
+const SQLSelectOffer = `SELECT id, product_id, buyer_id, saler_id, price, counter_price, currency, ` +
+	SQLOfferStatus + `, created_at, updated_at, expires_at FROM public."offer" offer`
+
+func scanOffer(row pgx.Row) (*models.Offer, error) {
+	offer := &models.Offer{} //nolint:exhaustruct
+
+	var counterPrice *uint64
+
+	err := row.Scan(&offer.ID, &offer.ProductID, &offer.BuyerID, &offer.SalerID, &offer.Price.Amount,
+		&counterPrice, &offer.Price.Currency, &offer.Status, &offer.CreatedAt, &offer.UpdatedAt, &offer.ExpiresAt)
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	if counterPrice != nil {
+		offer.CounterPrice = &models.Money{Amount: *counterPrice, Currency: offer.Price.Currency}
+	}
+
+	return offer, nil
+}
+
+// selectOfferByID locks offer until end of transaction if forUpdate is set.
+func (p *ProductStorage) selectOfferByID(ctx context.Context, tx pgx.Tx, offerID uint64, forUpdate bool,
+) (*models.Offer, error) {
+	SQLSelectOfferByID := SQLSelectOffer + ` WHERE id=$1`
+	if forUpdate {
+		SQLSelectOfferByID += ` FOR UPDATE`
+	}
+
+	offer, err := scanOffer(tx.QueryRow(ctx, SQLSelectOfferByID, offerID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf(myerrors.ErrTemplate, ErrOfferNotFound)
+		}
+
+		p.logger.Errorf("error with offerID=%d: %+v", offerID, err)
+
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return offer, nil
+}
+
+// hasOpenOffer expires outdated offer of buyer first, so buyer can make new offer right after expiry.
+func (p *ProductStorage) hasOpenOffer(ctx context.Context, tx pgx.Tx, productID uint64, buyerID uint64,
+) (bool, error) {
+	SQLExpireOutdatedOffer := `UPDATE public."offer" SET status = 'expired', updated_at = NOW()
+		WHERE product_id=$1 AND buyer_id=$2 AND status IN ('pending', 'countered') AND expires_at <= NOW()`
+	SQLHasOpenOffer := `SELECT EXISTS(SELECT 1 FROM public."offer"
+		WHERE product_id=$1 AND buyer_id=$2 AND status IN ('pending', 'countered'))`
+
+	_, err := tx.Exec(ctx, SQLExpireOutdatedOffer, productID, buyerID)
+	if err != nil {
+		p.logger.Errorln(err)
+
+		return false, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	var hasOpenOffer bool
+
+	err = tx.QueryRow(ctx, SQLHasOpenOffer, productID, buyerID).Scan(&hasOpenOffer)
+	if err != nil {
+		p.logger.Errorln(err)
+
+		return false, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return hasOpenOffer, nil
+}
+
+// AddOffer creates pending offer of buyer. Price must be lower than price of active product and be
+// in its currency, empty currency means currency of product.
+func (p *ProductStorage) AddOffer(ctx context.Context, productID uint64, buyerID uint64,
+	preOffer *models.PreOffer,
+) (*models.Offer, error) {
+	SQLInsertOffer := `INSERT INTO public."offer"(product_id, buyer_id, saler_id, price, currency, expires_at)
+		VALUES($1, $2, $3, $4, $5, NOW() + make_interval(secs => $6))`
+
+	var offer *models.Offer
+
+	err := pgx.BeginFunc(ctx, p.pool, func(tx pgx.Tx) error {
+		product, err := p.selectProductForUpdate(ctx, tx, productID)
+		if err != nil {
+			return err
+		}
+
+		if product.SalerID == buyerID {
+			return fmt.Errorf(myerrors.ErrTemplate, ErrOfferOnMyProduct)
+		}
+
+		if slices.Contains(models.PrivateProductStatuses(), product.Status) {
+			return fmt.Errorf(myerrors.ErrTemplate, ErrProductNotFound)
+		}
+
+		if product.Status != models.ProductStatusActive {
+			return fmt.Errorf(myerrors.ErrTemplate, ErrProductNotAvailable)
+		}
+
+		if preOffer.Price.Currency == "" {
+			preOffer.Price.Currency = product.Price.Currency
+		}
+
+		if preOffer.Price.Currency != product.Price.Currency {
+			return fmt.Errorf(myerrors.ErrTemplate, ErrOfferCurrency)
+		}
+
+		if preOffer.Price.Amount >= product.Price.Amount {
+			return fmt.Errorf(myerrors.ErrTemplate, ErrOfferPriceTooHigh)
+		}
+
+		hasOpenOffer, err := p.hasOpenOffer(ctx, tx, productID, buyerID)
+		if err != nil {
+			return err
+		}
+
+		if hasOpenOffer {
+			return fmt.Errorf(myerrors.ErrTemplate, ErrOfferAlreadyExists)
+		}
+
+		_, err = tx.Exec(ctx, SQLInsertOffer, productID, buyerID, product.SalerID, preOffer.Price.Amount,
+			preOffer.Price.Currency, p.config.offerTTL.Seconds())
+		if err != nil {
+			p.logger.Errorln(err)
+
+			return fmt.Errorf(myerrors.ErrTemplate, err)
+		}
+
+		offerID, err := repository.GetLastValSeq(ctx, tx, NameSeqOffer)
+		if err != nil {
+			return fmt.Errorf(myerrors.ErrTemplate, err)
+		}
+
+		offer, err = p.selectOfferByID(ctx, tx, offerID, false)
+
+		return err
+	})
+	if err != nil {
+		p.logger.Errorln(err)
+
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return offer, nil
+}
+
+// reserveProduct is called with locked offer, so two offers on product can't be accepted concurrently.
+func (p *ProductStorage) reserveProduct(ctx context.Context, tx pgx.Tx, productID uint64) error {
+	product, err := p.selectProductForUpdate(ctx, tx, productID)
+	if err != nil {
+		return err
+	}
+
+	if product.Status != models.ProductStatusActive {
+		return fmt.Errorf(myerrors.ErrTemplate, ErrProductNotAvailable)
+	}
+
+	reserved := models.ProductStatusReserved
+
+	return p.updateProduct(ctx, tx, productID, &models.PartialProduct{Status: &reserved}) //nolint:exhaustruct
+}
+
+// TransitOffer applies action of seller or buyer to offer. Accepted offer reserves product in the same
+// transaction until it expires. counterPrice is used only by counter action, empty currency means
+// currency of offer.
+func (p *ProductStorage) TransitOffer(ctx context.Context, offerID uint64, userID uint64, action string,
+	counterPrice *models.Money,
+) (*models.Offer, error) {
+	SQLUpdateOffer := `UPDATE public."offer" SET status=$1, updated_at = NOW() WHERE id=$2`
+	SQLAcceptOffer := `UPDATE public."offer" SET status=$1, updated_at = NOW(),
+		expires_at = NOW() + make_interval(secs => $2) WHERE id=$3`
+	SQLCounterOffer := `UPDATE public."offer" SET status=$1, counter_price=$2, updated_at = NOW(),
+		expires_at = NOW() + make_interval(secs => $3) WHERE id=$4`
+
+	var offer *models.Offer
+
+	err := pgx.BeginFunc(ctx, p.pool, func(tx pgx.Tx) error {
+		var err error
+
+		offer, err = p.selectOfferByID(ctx, tx, offerID, true)
+		if err != nil {
+			return err
+		}
+
+		if userID != offer.BuyerID && userID != offer.SalerID {
+			return fmt.Errorf(myerrors.ErrTemplate, ErrOfferNotFound)
+		}
+
+		nextStatus, ok := models.NextOfferStatus(offer.Status, action, userID == offer.SalerID)
+		if !ok {
+			if offer.Status == models.OfferStatusExpired {
+				return fmt.Errorf(myerrors.ErrTemplate, ErrOfferExpired)
+			}
+
+			return myerrors.NewError(MessageErrWrongOfferAction, action, offer.Status)
+		}
+
+		switch nextStatus {
+		case models.OfferStatusAccepted:
+			err = p.reserveProduct(ctx, tx, offer.ProductID)
+			if err == nil {
+				_, err = tx.Exec(ctx, SQLAcceptOffer, nextStatus, p.config.acceptedOfferTTL.Seconds(), offerID)
+			}
+		case models.OfferStatusCountered:
+			if counterPrice.Currency == "" {
+				counterPrice.Currency = offer.Price.Currency
+			}
+
+			if counterPrice.Currency != offer.Price.Currency {
+				return fmt.Errorf(myerrors.ErrTemplate, ErrOfferCurrency)
+			}
+
+			if counterPrice.Amount <= offer.Price.Amount {
+				return fmt.Errorf(myerrors.ErrTemplate, ErrCounterPriceTooLow)
+			}
+
+			_, err = tx.Exec(ctx, SQLCounterOffer, nextStatus, counterPrice.Amount, p.config.offerTTL.Seconds(),
+				offerID)
+		default:
+			_, err = tx.Exec(ctx, SQLUpdateOffer, nextStatus, offerID)
+		}
+
+		if err != nil {
+			p.logger.Errorln(err)
+
+			return fmt.Errorf(myerrors.ErrTemplate, err)
+		}
+
+		offer, err = p.selectOfferByID(ctx, tx, offerID, false)
+
+		return err
+	})
+	if err != nil {
+		p.logger.Errorln(err)
+
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return offer, nil
+}
+
+// ExpireOffers expires at most limit outdated open and accepted offers and returns them with ids of
+// products which were reserved by expired accepted offers and became active again. Offers locked by
+// concurrent actions are skipped until next call.
+func (p *ProductStorage) ExpireOffers(ctx context.Context, limit uint64) ([]*models.Offer, []uint64, error) {
+	SQLSelectOutdatedOffers := SQLSelectOffer + ` WHERE status IN ('pending', 'countered', 'accepted')
+		AND expires_at <= NOW() AND ` + SQLNotOrderedOffer + ` ORDER BY expires_at LIMIT $1 FOR UPDATE SKIP LOCKED`
+	SQLReleaseProducts := `UPDATE public."product" SET status = 'active' WHERE status = 'reserved'
+		AND id IN (SELECT product_id FROM public."offer" WHERE id = ANY($1) AND status = 'accepted') RETURNING id`
+	SQLExpireOffers := `UPDATE public."offer" SET status = 'expired', updated_at = NOW() WHERE id = ANY($1)`
+
+	var (
+		offers     []*models.Offer
+		productIDs []uint64
+	)
+
+	err := pgx.BeginFunc(ctx, p.pool, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, SQLSelectOutdatedOffers, limit)
+		if err != nil {
+			p.logger.Errorln(err)
+
+			return fmt.Errorf(myerrors.ErrTemplate, err)
+		}
+
+		offers, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (*models.Offer, error) {
+			return scanOffer(row)
+		})
+		if err != nil {
+			p.logger.Errorln(err)
+
+			return fmt.Errorf(myerrors.ErrTemplate, err)
+		}
+
+		if len(offers) == 0 {
+			return nil
+		}
+
+		offerIDs := make([]uint64, 0, len(offers))
+		for _, offer := range offers {
+			offerIDs = append(offerIDs, offer.ID)
+		}
+
+		rows, err = tx.Query(ctx, SQLReleaseProducts, offerIDs)
+		if err != nil {
+			p.logger.Errorln(err)
+
+			return fmt.Errorf(myerrors.ErrTemplate, err)
+		}
+
+		productIDs, err = pgx.CollectRows(rows, pgx.RowTo[uint64])
+		if err != nil {
+			p.logger.Errorln(err)
+
+			return fmt.Errorf(myerrors.ErrTemplate, err)
+		}
+
+		_, err = tx.Exec(ctx, SQLExpireOffers, offerIDs)
+		if err != nil {
+			p.logger.Errorln(err)
+
+			return fmt.Errorf(myerrors.ErrTemplate, err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return offers, productIDs, nil
+}
+
+// GetOffers returns offers made or received by user, newest first. productID = 0 means all products.
+func (p *ProductStorage) GetOffers(ctx context.Context, userID uint64, productID uint64, limit uint64,
+	offset uint64,
+) ([]*models.Offer, error) {
+	SQLSelectOffers := SQLSelectOffer + ` WHERE (buyer_id=$1 OR saler_id=$1)
+		AND ($2::bigint = 0 OR product_id = $2::bigint) ORDER BY id DESC LIMIT $3 OFFSET $4`
+
+	var offers []*models.Offer
+
+	err := pgx.BeginFunc(ctx, p.pool, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, SQLSelectOffers, userID, productID, limit, offset)
+		if err != nil {
+			p.logger.Errorln(err)
+
+			return fmt.Errorf(myerrors.ErrTemplate, err)
+		}
+
+		offers, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (*models.Offer, error) {
+			return scanOffer(row)
+		})
+		if err != nil {
+			p.logger.Errorln(err)
+
+			return fmt.Errorf(myerrors.ErrTemplate, err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return offers, nil
+}
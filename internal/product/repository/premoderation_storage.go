@@ -0,0 +1,132 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/SanExpett/marketplace-backend/pkg/models"
+	myerrors "github.com/SanExpett/marketplace-backend/pkg/my_errors"
+	"github.com/jackc/pgx/v5"
+	"strconv"
+	"time"
+)
+
+// GetPriceMedian returns median price of active products of category in currency and number of them.
+func (p *ProductStorage) GetPriceMedian(ctx context.Context, categoryID uint64, currency string,
+) (uint64, uint64, error) {
+	SQLSelectPriceMedian := `SELECT COALESCE(PERCENTILE_DISC(0.5) WITHIN GROUP (ORDER BY price), 0), COUNT(*)
+		FROM public."product" WHERE category_id=$1 AND currency=$2 AND status=$3`
+
+	var median, count uint64
+
+	err := pgx.BeginFunc(ctx, p.pool, func(tx pgx.Tx) error {
+		err := tx.QueryRow(ctx, SQLSelectPriceMedian, categoryID, currency, models.ProductStatusActive).
+			Scan(&median, &count)
+		if err != nil {
+			p.logger.Errorf("error with categoryID=%d: %+v", categoryID, err)
+
+			return fmt.Errorf(myerrors.ErrTemplate, err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return 0, 0, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return median, count, nil
+}
+
+// CountProductsSince returns number of products added by saler since time.
+func (p *ProductStorage) CountProductsSince(ctx context.Context, salerID uint64, since time.Time) (uint64, error) {
+	SQLCountProductsSince := `SELECT COUNT(*) FROM public."product" WHERE saler_id=$1 AND created_at >= $2`
+
+	var count uint64
+
+	err := pgx.BeginFunc(ctx, p.pool, func(tx pgx.Tx) error {
+		err := tx.QueryRow(ctx, SQLCountProductsSince, salerID, since).Scan(&count)
+		if err != nil {
+			p.logger.Errorf("error with salerID=%d: %+v", salerID, err)
+
+			return fmt.Errorf(myerrors.ErrTemplate, err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return count, nil
+}
+
+// duplicateCandidateStatuses are statuses of products which new product must not repeat. Sold products
+// and drafts are not counted: seller may sell similar thing again.
+func duplicateCandidateStatuses() []string {
+	return []string{models.ProductStatusActive, models.ProductStatusReserved, models.ProductStatusHidden,
+		models.ProductStatusOnReview}
+}
+
+// nullableImageHash converts hash for BIGINT column, zero hash of not ingested image becomes NULL.
+func nullableImageHash(imageHash uint64) *int64 {
+	if imageHash == 0 {
+		return nil
+	}
+
+	hash := int64(imageHash)
+
+	return &hash
+}
+
+// FindDuplicate returns the most similar product of saler or of any saler when acrossSalers is set.
+// Product with the same image hash goes first, then products by trigram similarity of normalized text.
+func (p *ProductStorage) FindDuplicate(ctx context.Context, preProduct *models.PreProduct, similarity float64,
+	acrossSalers bool,
+) (*models.Duplicate, error) {
+	// threshold of operator % is set only for transaction, so index can be used for it
+	SQLSetSimilarityThreshold := `SELECT set_config('pg_trgm.similarity_threshold', $1, true)`
+	// input is normalized the same way as generated column fingerprint
+	SQLInputFingerprint := `TRIM(REGEXP_REPLACE(LOWER($1::text), '[^[:alnum:]]+', ' ', 'g'))`
+	SQLSelectDuplicate := `SELECT id, saler_id, similarity(fingerprint, ` + SQLInputFingerprint + `) AS sim,
+		COALESCE(image_hash = $2, false) AS same_image
+		FROM public."product"
+		WHERE status = ANY($3) AND ($4::boolean OR saler_id = $5) AND id <> $6
+		  AND (fingerprint % ` + SQLInputFingerprint + ` OR image_hash = $2)
+		ORDER BY same_image DESC, sim DESC, id DESC LIMIT 1`
+
+	var duplicate *models.Duplicate
+
+	err := pgx.BeginFunc(ctx, p.pool, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, SQLSetSimilarityThreshold, strconv.FormatFloat(similarity, 'f', -1, 64))
+		if err != nil {
+			p.logger.Errorln(err)
+
+			return fmt.Errorf(myerrors.ErrTemplate, err)
+		}
+
+		found := &models.Duplicate{} //nolint:exhaustruct
+
+		err = tx.QueryRow(ctx, SQLSelectDuplicate, preProduct.Title+" "+preProduct.Description,
+			nullableImageHash(preProduct.ImageHash), duplicateCandidateStatuses(), acrossSalers, preProduct.SalerID,
+			preProduct.ID).
+			Scan(&found.ProductID, &found.SalerID, &found.Similarity, &found.SameImage)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return nil
+			}
+
+			p.logger.Errorf("error with salerID=%d: %+v", preProduct.SalerID, err)
+
+			return fmt.Errorf(myerrors.ErrTemplate, err)
+		}
+
+		duplicate = found
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return duplicate, nil
+}
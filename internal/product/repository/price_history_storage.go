@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"github.com/SanExpett/marketplace-backend/pkg/models"
+	myerrors "github.com/SanExpett/marketplace-backend/pkg/my_errors"
+	"github.com/jackc/pgx/v5"
+)
+
+func (p *ProductStorage) insertPriceChange(ctx context.Context, tx pgx.Tx, productID uint64,
+	oldPrice models.Money, newPrice models.Money,
+) error {
+	SQLInsertPriceChange := `INSERT INTO public."product_price_history"(product_id, old_price, old_currency,
+		new_price, new_currency) VALUES($1, $2, $3, $4, $5)`
+
+	_, err := tx.Exec(ctx, SQLInsertPriceChange, productID, oldPrice.Amount, oldPrice.Currency,
+		newPrice.Amount, newPrice.Currency)
+	if err != nil {
+		p.logger.Errorln(err)
+
+		return fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return nil
+}
+
+func (p *ProductStorage) selectPriceHistory(ctx context.Context, tx pgx.Tx, productID uint64,
+) ([]*models.PriceChange, error) {
+	SQLSelectPriceHistory := `SELECT old_price, old_currency, new_price, new_currency, changed_at
+		FROM public."product_price_history" WHERE product_id=$1 ORDER BY changed_at DESC, id DESC`
+
+	rowsPriceChanges, err := tx.Query(ctx, SQLSelectPriceHistory, productID)
+	if err != nil {
+		p.logger.Errorln(err)
+
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	priceHistory, err := pgx.CollectRows(rowsPriceChanges, func(row pgx.CollectableRow) (*models.PriceChange, error) {
+		priceChange := &models.PriceChange{} //nolint:exhaustruct
+
+		err := row.Scan(&priceChange.OldPrice.Amount, &priceChange.OldPrice.Currency, &priceChange.NewPrice.Amount,
+			&priceChange.NewPrice.Currency, &priceChange.ChangedAt)
+
+		return priceChange, err //nolint:wrapcheck
+	})
+	if err != nil {
+		p.logger.Errorln(err)
+
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return priceHistory, nil
+}
+
+// GetPriceHistory returns price changes from newest to oldest. Drafts are visible only to seller.
+func (p *ProductStorage) GetPriceHistory(ctx context.Context, productID uint64, userID uint64,
+) ([]*models.PriceChange, error) {
+	var priceHistory []*models.PriceChange
+
+	err := pgx.BeginFunc(ctx, p.pool, func(tx pgx.Tx) error {
+		visible, err := p.isProductVisible(ctx, tx, productID, userID)
+		if err != nil {
+			return err
+		}
+
+		if !visible {
+			return fmt.Errorf(myerrors.ErrTemplate, ErrProductNotFound)
+		}
+
+		priceHistory, err = p.selectPriceHistory(ctx, tx, productID)
+
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return priceHistory, nil
+}
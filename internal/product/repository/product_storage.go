@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/Masterminds/squirrel"
@@ -12,29 +13,116 @@ import (
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"go.uber.org/zap"
-	"math"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
+	"unicode"
 )
 
 var (
-	ErrProductNotFound = myerrors.NewError("Этот товар не найден")
+	ErrProductNotFound  = myerrors.NewError("Этот товар не найден")
+	ErrCategoryNotFound = myerrors.NewError("Такой категории не существует")
+	ErrNotMyProduct     = myerrors.NewError("Изменять объявление может только продавец")
+	ErrProductHidden    = myerrors.NewError("Объявление скрыто модератором, его нельзя изменить")
+	ErrProductOnReview  = myerrors.NewError("Объявление на проверке модератора, его статус нельзя изменить")
+	ErrProductHeld      = myerrors.NewError("Объявление зарезервировано заказом или принятым предложением, " +
+		"его статус нельзя изменить")
 
 	NameSeqProduct = pgx.Identifier{"public", "product_id_seq"} //nolint:gochecknoglobals
 )
 
+type ConfigProductStorage struct {
+	// priceDropPeriod is period during which fallen price is shown as price drop
+	priceDropPeriod time.Duration
+	// priceSortFactors convert prices to minor units of base currency for sorting, empty means no conversion
+	priceSortFactors map[string]float64
+	// offerTTL is time given to other side to answer offer or counter offer
+	offerTTL time.Duration
+	// acceptedOfferTTL is time during which accepted offer reserves product for buyer
+	acceptedOfferTTL time.Duration
+}
+
+func NewConfigProductStorage(priceDropPeriod time.Duration, priceSortFactors map[string]float64,
+	offerTTL time.Duration, acceptedOfferTTL time.Duration,
+) *ConfigProductStorage {
+	return &ConfigProductStorage{
+		priceDropPeriod:  priceDropPeriod,
+		priceSortFactors: priceSortFactors,
+		offerTTL:         offerTTL,
+		acceptedOfferTTL: acceptedOfferTTL,
+	}
+}
+
 type ProductStorage struct {
 	pool   *pgxpool.Pool
+	config *ConfigProductStorage
 	logger *zap.SugaredLogger
 }
 
-const (
-	byPriceASC  = 1
-	byPriceDESC = 2
-	byDateASC   = 3
-	byDateDESC  = 4
-)
+// SQLCategoryWithDescendants matches products of category and all its subcategories.
+const SQLCategoryWithDescendants = `category_id IN (WITH RECURSIVE subcategory AS (
+		SELECT id FROM public."category" WHERE id = ?
+		UNION ALL
+		SELECT c.id FROM public."category" c JOIN subcategory s ON c.parent_id = s.id)
+	SELECT id FROM subcategory)`
+
+// SQLSearchTsQuery takes search query twice: for russian and english configurations.
+const SQLSearchTsQuery = `(websearch_to_tsquery('russian', ?) || websearch_to_tsquery('english', ?))`
+
+// SQLHeadline highlights words of query in description, it takes configuration twice, query and options.
+const SQLHeadline = `ts_headline(?::regconfig, description, websearch_to_tsquery(?::regconfig, ?), ?)`
+
+const headlineOptions = "StartSel=<b>, StopSel=</b>, MaxWords=35, MinWords=15, MaxFragments=2"
+
+// SQLIsFavorite is computed in the same select as products, it takes id of current user.
+const SQLIsFavorite = `EXISTS(SELECT 1 FROM public."favorite" f WHERE f.product_id = product.id AND f.user_id = ?)`
+
+// SQLPreviousPrice is the oldest price in the current currency which was changed after given time,
+// 0 if price wasn't changed.
+const SQLPreviousPrice = `COALESCE((SELECT h.old_price FROM public."product_price_history" h
+	WHERE h.product_id = product.id AND h.old_currency = product.currency AND h.changed_at >= ?
+	ORDER BY h.changed_at LIMIT 1), 0)`
+
+// SQLJoinSaler adds login and rating aggregates of seller, names of its columns don't clash with product ones.
+const SQLJoinSaler = `INNER JOIN LATERAL (SELECT u.login AS saler_login, u.rating_sum AS saler_rating_sum,
+	u.reviews_count AS saler_reviews_count FROM public."user" u WHERE u.id = product.saler_id) saler ON TRUE`
+
+// SQLJoinPromotion adds type and start of the best running promotion of product, promotion_type is NULL
+// without it. Bought promotion runs only after its payment succeeds. It takes flag whether top of category counts,
+// type of top of category and types ordered by priority.
+const SQLJoinPromotion = `LEFT JOIN LATERAL (SELECT pr.type AS promotion_type, pr.started_at AS promotion_started_at
+	FROM public."promotion" pr WHERE pr.product_id = product.id AND pr.started_at <= NOW() AND pr.ended_at > NOW()
+	  AND pr.payment_status IN ('none', 'succeeded') AND (? OR pr.type <> ?)
+	ORDER BY ARRAY_POSITION(?::text[], pr.type), pr.started_at DESC LIMIT 1) promotion ON TRUE`
+
+// SQLNotPromoted matches products without running promotion which counts in SQLJoinPromotion.
+// It takes the same flag and type of top of category.
+const SQLNotPromoted = `NOT EXISTS (SELECT 1 FROM public."promotion" pr WHERE pr.product_id = product.id
+	AND pr.started_at <= NOW() AND pr.ended_at > NOW() AND pr.payment_status IN ('none', 'succeeded')
+	AND (? OR pr.type <> ?))`
+
+// promotionJoin makes SQLJoinPromotion for filter: top of category is promoted only in feed of category.
+func promotionJoin(filter *models.ProductFilter) squirrel.Sqlizer {
+	return squirrel.Expr(SQLJoinPromotion, filter.CategoryID != 0, models.PromotionTypeTopOfCategory,
+		models.PromotionTypes())
+}
 
-func NewProductStorage(pool *pgxpool.Pool) (*ProductStorage, error) {
+// newSaler makes seller of product from columns of SQLJoinSaler.
+func newSaler(salerID uint64, login string, ratingSum uint64, reviewsCount uint64) *models.Saler {
+	return &models.Saler{
+		ID:           salerID,
+		Login:        login,
+		Rating:       models.Rating(ratingSum, reviewsCount),
+		ReviewsCount: reviewsCount,
+	}
+}
+
+// SQLFavoritesOf matches products which are added to favorites by user.
+const SQLFavoritesOf = `id IN (SELECT product_id FROM public."favorite" WHERE user_id = ?)`
+
+func NewProductStorage(pool *pgxpool.Pool, config *ConfigProductStorage) (*ProductStorage, error) {
 	logger, err := my_logger.Get()
 	if err != nil {
 		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
@@ -42,16 +130,29 @@ func NewProductStorage(pool *pgxpool.Pool) (*ProductStorage, error) {
 
 	return &ProductStorage{
 		pool:   pool,
+		config: config,
 		logger: logger,
 	}, nil
 }
 
+// setPriceDrop sets previous price only if it is bigger than current one.
+func setPriceDrop(product *models.ProductWithIsMy, previousAmount uint64) {
+	if previousAmount > product.Price.Amount {
+		product.PriceDropped = true
+		product.PreviousPrice = &models.Money{Amount: previousAmount, Currency: product.Price.Currency}
+	}
+}
+
 func (p *ProductStorage) insertProduct(ctx context.Context, tx pgx.Tx, preProduct *models.PreProduct) error {
-	SQLInsertProduct := `INSERT INTO public."product"(saler_id,
-		title, description, price, image_url) VALUES(
-		$1, $2, $3, $4, $5)`
-	_, err := tx.Exec(ctx, SQLInsertProduct, preProduct.SalerID,
-		preProduct.Title, preProduct.Description, preProduct.Price, preProduct.ImageUrl)
+	SQLInsertProduct := `INSERT INTO public."product"(saler_id, category_id,
+		title, description, price, currency, image_url, attributes, latitude, longitude, city, status,
+		review_reason, review_requested_at, image_hash) VALUES(
+		$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, CASE WHEN $12::text = $14::text THEN NOW() END, $15)`
+	_, err := tx.Exec(ctx, SQLInsertProduct, preProduct.SalerID, preProduct.CategoryID,
+		preProduct.Title, preProduct.Description, preProduct.Price.Amount, preProduct.Price.Currency,
+		preProduct.ImageUrl, preProduct.Attributes, preProduct.Latitude, preProduct.Longitude, preProduct.City,
+		preProduct.Status, preProduct.ReviewReason, models.ProductStatusOnReview,
+		nullableImageHash(preProduct.ImageHash))
 
 	if err != nil {
 		p.logger.Errorln(err)
@@ -62,12 +163,28 @@ func (p *ProductStorage) insertProduct(ctx context.Context, tx pgx.Tx, preProduc
 	return nil
 }
 
+// AddProduct adds active product or product with status given by premoderation.
 func (p *ProductStorage) AddProduct(ctx context.Context, preProduct *models.PreProduct) (*models.Product, error) {
+	if preProduct.Status == "" {
+		preProduct.Status = models.ProductStatusActive
+	}
+
 	product := &models.Product{Title: preProduct.Title, Description: preProduct.Description,
-		Price: preProduct.Price, SalerID: preProduct.SalerID, ImageUrl: preProduct.ImageUrl}
+		Price: preProduct.Price, SalerID: preProduct.SalerID, CategoryID: preProduct.CategoryID,
+		ImageUrl: preProduct.ImageUrl, Attributes: preProduct.Attributes, Latitude: preProduct.Latitude,
+		Longitude: preProduct.Longitude, City: preProduct.City, Status: preProduct.Status}
 
 	err := pgx.BeginFunc(ctx, p.pool, func(tx pgx.Tx) error {
-		err := p.insertProduct(ctx, tx, preProduct)
+		categoryExists, err := p.isCategoryExists(ctx, tx, preProduct.CategoryID)
+		if err != nil {
+			return err
+		}
+
+		if !categoryExists {
+			return fmt.Errorf(myerrors.ErrTemplate, ErrCategoryNotFound)
+		}
+
+		err = p.insertProduct(ctx, tx, preProduct)
 		if err != nil {
 			return err
 		}
@@ -77,6 +194,12 @@ func (p *ProductStorage) AddProduct(ctx context.Context, preProduct *models.PreP
 			return err
 		}
 
+		product.ID = lastProductID
+
+		if err := repository.PublishProduct(ctx, tx, lastProductID); err != nil {
+			return err
+		}
+
 		createdAt, err := p.selectCreatedAtByProductID(ctx, tx, lastProductID)
 		if err != nil {
 			return err
@@ -95,15 +218,223 @@ func (p *ProductStorage) AddProduct(ctx context.Context, preProduct *models.PreP
 	return product, nil
 }
 
+// selectProductForUpdate returns saler, price and status of product and locks it until end of transaction.
+func (p *ProductStorage) selectProductForUpdate(ctx context.Context, tx pgx.Tx, productID uint64,
+) (*models.Product, error) {
+	SQLSelectProductForUpdate := `SELECT saler_id, price, currency, status FROM public."product" WHERE id=$1 FOR UPDATE`
+
+	product := &models.Product{ID: productID} //nolint:exhaustruct
+
+	err := tx.QueryRow(ctx, SQLSelectProductForUpdate, productID).Scan(&product.SalerID, &product.Price.Amount,
+		&product.Price.Currency, &product.Status)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf(myerrors.ErrTemplate, ErrProductNotFound)
+		}
+
+		p.logger.Errorf("error with productID=%d: %+v", productID, err)
+
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return product, nil
+}
+
+func (p *ProductStorage) updateProduct(ctx context.Context, tx pgx.Tx, productID uint64,
+	partialProduct *models.PartialProduct,
+) error {
+	updateFields := make(map[string]any)
+
+	if partialProduct.Title != nil {
+		updateFields["title"] = *partialProduct.Title
+	}
+
+	if partialProduct.Description != nil {
+		updateFields["description"] = *partialProduct.Description
+	}
+
+	if partialProduct.ImageUrl != nil {
+		updateFields["image_url"] = *partialProduct.ImageUrl
+		updateFields["image_hash"] = nullableImageHash(partialProduct.ImageHash)
+	}
+
+	if partialProduct.Price != nil {
+		updateFields["price"] = partialProduct.Price.Amount
+		updateFields["currency"] = partialProduct.Price.Currency
+	}
+
+	if partialProduct.Status != nil {
+		updateFields["status"] = *partialProduct.Status
+
+		if *partialProduct.Status == models.ProductStatusOnReview {
+			updateFields["review_reason"] = partialProduct.ReviewReason
+			updateFields["review_requested_at"] = squirrel.Expr("NOW()")
+		}
+	}
+
+	if len(updateFields) == 0 {
+		return nil
+	}
+
+	SQLUpdateProduct, args, err := squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar).
+		Update(`public."product"`).SetMap(updateFields).Where(squirrel.Eq{"id": productID}).ToSql()
+	if err != nil {
+		p.logger.Errorln(err)
+
+		return fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	_, err = tx.Exec(ctx, SQLUpdateProduct, args...)
+	if err != nil {
+		p.logger.Errorln(err)
+
+		return fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return nil
+}
+
+// UpdateProduct changes product of seller with userID. Change of price is written to price history
+// in the same transaction. Status equal to current one is reset to nil, so caller can tell whether it changed.
+func (p *ProductStorage) UpdateProduct(ctx context.Context, productID uint64, userID uint64,
+	partialProduct *models.PartialProduct,
+) (*models.ProductWithIsMy, error) {
+	var product *models.ProductWithIsMy
+
+	err := pgx.BeginFunc(ctx, p.pool, func(tx pgx.Tx) error {
+		oldProduct, err := p.selectProductForUpdate(ctx, tx, productID)
+		if err != nil {
+			return err
+		}
+
+		if oldProduct.SalerID != userID {
+			return fmt.Errorf(myerrors.ErrTemplate, ErrNotMyProduct)
+		}
+
+		if oldProduct.Status == models.ProductStatusHidden {
+			return fmt.Errorf(myerrors.ErrTemplate, ErrProductHidden)
+		}
+
+		oldPrice := oldProduct.Price
+
+		if partialProduct.Status != nil && *partialProduct.Status == oldProduct.Status {
+			partialProduct.Status = nil
+		}
+
+		if partialProduct.Status != nil && oldProduct.Status == models.ProductStatusOnReview {
+			return fmt.Errorf(myerrors.ErrTemplate, ErrProductOnReview)
+		}
+
+		if partialProduct.Status != nil {
+			isHeld, err := p.isProductHeld(ctx, tx, productID)
+			if err != nil {
+				return err
+			}
+
+			if isHeld {
+				return fmt.Errorf(myerrors.ErrTemplate, ErrProductHeld)
+			}
+		}
+
+		if partialProduct.Price != nil && partialProduct.Price.Currency == "" {
+			partialProduct.Price.Currency = oldPrice.Currency
+		}
+
+		err = p.updateProduct(ctx, tx, productID, partialProduct)
+		if err != nil {
+			return err
+		}
+
+		if partialProduct.Price != nil && *partialProduct.Price != oldPrice {
+			err = p.insertPriceChange(ctx, tx, productID, oldPrice, *partialProduct.Price)
+			if err != nil {
+				return err
+			}
+		}
+
+		if partialProduct.Status != nil && *partialProduct.Status == models.ProductStatusActive {
+			if err := repository.PublishProduct(ctx, tx, productID); err != nil {
+				return err
+			}
+		}
+
+		product, err = p.selectProductByID(ctx, tx, productID, userID)
+
+		return err
+	})
+	if err != nil {
+		p.logger.Errorln(err)
+
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return product, nil
+}
+
+// isProductHeld reports whether product is held by live order or reserved by accepted offer: its status is
+// changed only by them. Accepted offer is closed when its order is cancelled.
+func (p *ProductStorage) isProductHeld(ctx context.Context, tx pgx.Tx, productID uint64) (bool, error) {
+	SQLIsProductHeld := `SELECT EXISTS(SELECT 1 FROM public."order" WHERE product_id=$1 AND status = ANY($2))
+		OR EXISTS(SELECT 1 FROM public."offer" o JOIN public."product" p ON p.id = o.product_id
+			WHERE o.product_id=$1 AND o.status=$3 AND o.expires_at > NOW() AND p.status=$4)`
+
+	var isHeld bool
+
+	err := tx.QueryRow(ctx, SQLIsProductHeld, productID, models.LiveOrderStatuses(),
+		models.OfferStatusAccepted, models.ProductStatusReserved).Scan(&isHeld)
+	if err != nil {
+		p.logger.Errorf("error with productID=%d: %+v", productID, err)
+
+		return false, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return isHeld, nil
+}
+
+func (p *ProductStorage) isCategoryExists(ctx context.Context, tx pgx.Tx, categoryID uint64) (bool, error) {
+	SQLIsCategoryExists := `SELECT EXISTS(SELECT 1 FROM public."category" WHERE id=$1)`
+
+	var exists bool
+
+	if err := tx.QueryRow(ctx, SQLIsCategoryExists, categoryID).Scan(&exists); err != nil {
+		p.logger.Errorf("error with categoryID=%d: %+v", categoryID, err)
+
+		return false, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return exists, nil
+}
+
 func (p *ProductStorage) selectProductByID(ctx context.Context, tx pgx.Tx, productID uint64, userID uint64,
 ) (*models.ProductWithIsMy, error) {
-	SQLSelectProduct := `SELECT saler_id, image_url, title,
-       description, price, created_at FROM public."product" WHERE id=$1`
+	query := squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar).Select("saler_id, category_id, " +
+		"image_url, title, description, price, currency, attributes, latitude, longitude, city, status, created_at, " +
+		"favorites_count").Column(squirrel.Expr(SQLIsFavorite, userID)).
+		Column(squirrel.Expr(SQLPreviousPrice, time.Now().Add(-p.config.priceDropPeriod))).
+		Column("saler_login, saler_rating_sum, saler_reviews_count").
+		From(`public."product"`).JoinClause(SQLJoinSaler).Where(squirrel.Eq{"id": productID})
+
+	SQLSelectProduct, args, err := query.ToSql()
+	if err != nil {
+		p.logger.Errorln(err)
+
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
 	product := &models.ProductWithIsMy{ID: productID} //nolint:exhaustruct
 
-	productRow := tx.QueryRow(ctx, SQLSelectProduct, productID)
-	if err := productRow.Scan(&product.SalerID, &product.ImageUrl,
-		&product.Title, &product.Description, &product.Price, &product.CreatedAt); err != nil {
+	var (
+		previousAmount    uint64
+		salerLogin        string
+		salerRatingSum    uint64
+		salerReviewsCount uint64
+	)
+
+	productRow := tx.QueryRow(ctx, SQLSelectProduct, args...)
+	if err := productRow.Scan(&product.SalerID, &product.CategoryID, &product.ImageUrl,
+		&product.Title, &product.Description, &product.Price.Amount, &product.Price.Currency, &product.Attributes,
+		&product.Latitude, &product.Longitude, &product.City, &product.Status, &product.CreatedAt, &product.FavoritesCount, &product.IsFavorite,
+		&previousAmount, &salerLogin, &salerRatingSum, &salerReviewsCount); err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, fmt.Errorf(myerrors.ErrTemplate, ErrProductNotFound)
 		}
@@ -117,8 +448,12 @@ func (p *ProductStorage) selectProductByID(ctx context.Context, tx pgx.Tx, produ
 		product.IsMy = true
 	} else {
 		product.IsMy = false
+		product.FavoritesCount = 0
 	}
 
+	setPriceDrop(product, previousAmount)
+	product.Saler = newSaler(product.SalerID, salerLogin, salerRatingSum, salerReviewsCount)
+
 	return product, nil
 }
 
@@ -142,6 +477,7 @@ func (p *ProductStorage) selectCreatedAtByProductID(ctx context.Context, tx pgx.
 	return createdAt, nil
 }
 
+// GetProduct returns product, drafts and hidden products are visible only to saler.
 func (p *ProductStorage) GetProduct(ctx context.Context, productID uint64, userID uint64) (*models.ProductWithIsMy, error) {
 	var product *models.ProductWithIsMy
 
@@ -151,6 +487,10 @@ func (p *ProductStorage) GetProduct(ctx context.Context, productID uint64, userI
 			return err
 		}
 
+		if productInner.SalerID != userID && slices.Contains(models.PrivateProductStatuses(), productInner.Status) {
+			return fmt.Errorf(myerrors.ErrTemplate, ErrProductNotFound)
+		}
+
 		product = productInner
 
 		return nil
@@ -162,12 +502,37 @@ func (p *ProductStorage) GetProduct(ctx context.Context, productID uint64, userI
 	return product, nil
 }
 
+// feedColumns are computed columns of products list which depend on filter.
+type feedColumns struct {
+	snippet   squirrel.Sqlizer
+	rank      squirrel.Sqlizer
+	sortPrice squirrel.Sqlizer
+	distance  squirrel.Sqlizer
+	// promotion is join with columns of running promotion
+	promotion squirrel.Sqlizer
+}
+
 func (p *ProductStorage) selectProductsWithWhereOrderLimitOffset(ctx context.Context, tx pgx.Tx,
-	limit uint64, offset uint64, whereClause any, orderByClause []string, userID uint64,
+	limit uint64, offset uint64, whereClause any, orderByClause []squirrel.Sqlizer, columns *feedColumns,
+	userID uint64,
 ) ([]*models.ProductWithIsMy, error) {
-	query := squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar).Select("id, saler_id, title," +
-		"description, price, created_at, image_url").From(`public."product"`).
-		Where(whereClause).OrderBy(orderByClause...).Limit(limit).Offset(offset)
+	isFavoriteColumn := squirrel.Expr("false")
+	if userID != 0 {
+		isFavoriteColumn = squirrel.Expr(SQLIsFavorite, userID)
+	}
+
+	query := squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar).Select("id, saler_id, category_id, title," +
+		"description, price, currency, attributes, latitude, longitude, city, status, created_at, image_url, " +
+		"favorites_count").Column(columns.snippet).Column(columns.rank).Column(isFavoriteColumn).
+		Column(squirrel.Expr(SQLPreviousPrice, time.Now().Add(-p.config.priceDropPeriod))).
+		Column(columns.sortPrice).Column(columns.distance).
+		Column("saler_login, saler_rating_sum, saler_reviews_count, COALESCE(promotion_type, '')").
+		From(`public."product"`).JoinClause(SQLJoinSaler).JoinClause(columns.promotion).Where(whereClause).
+		Limit(limit).Offset(offset)
+
+	for _, orderBy := range orderByClause {
+		query = query.OrderByClause(orderBy)
+	}
 
 	SQLQuery, args, err := query.ToSql()
 	if err != nil {
@@ -187,19 +552,49 @@ func (p *ProductStorage) selectProductsWithWhereOrderLimitOffset(ctx context.Con
 
 	var slProduct []*models.ProductWithIsMy
 
+	var (
+		previousAmount    uint64
+		salerLogin        string
+		salerRatingSum    uint64
+		salerReviewsCount uint64
+	)
+
 	_, err = pgx.ForEachRow(rowsProducts, []any{
-		&curProduct.ID, &curProduct.SalerID, &curProduct.Title, &curProduct.Description,
-		&curProduct.Price, &curProduct.CreatedAt, &curProduct.ImageUrl,
+		&curProduct.ID, &curProduct.SalerID, &curProduct.CategoryID, &curProduct.Title, &curProduct.Description,
+		&curProduct.Price.Amount, &curProduct.Price.Currency, &curProduct.Attributes, &curProduct.Latitude,
+		&curProduct.Longitude, &curProduct.City, &curProduct.Status, &curProduct.CreatedAt, &curProduct.ImageUrl,
+		&curProduct.FavoritesCount, &curProduct.Snippet, &curProduct.Rank, &curProduct.IsFavorite, &previousAmount,
+		&curProduct.SortPrice, &curProduct.Distance, &salerLogin, &salerRatingSum, &salerReviewsCount,
+		&curProduct.PromotionType,
 	}, func() error {
-		slProduct = append(slProduct, &models.ProductWithIsMy{ //nolint:exhaustruct
+		product := &models.ProductWithIsMy{ //nolint:exhaustruct
 			ID:          curProduct.ID,
 			SalerID:     curProduct.SalerID,
+			CategoryID:  curProduct.CategoryID,
 			Title:       curProduct.Title,
 			Description: curProduct.Description,
 			Price:       curProduct.Price,
+			Attributes:  curProduct.Attributes,
+			Latitude:    curProduct.Latitude,
+			Longitude:   curProduct.Longitude,
+			City:        curProduct.City,
+			Status:      curProduct.Status,
 			CreatedAt:   curProduct.CreatedAt,
 			ImageUrl:    curProduct.ImageUrl,
-		})
+			Snippet:     curProduct.Snippet,
+			Rank:        curProduct.Rank,
+			IsFavorite:  curProduct.IsFavorite,
+			Distance:    curProduct.Distance,
+
+			FavoritesCount: curProduct.FavoritesCount,
+			SortPrice:      curProduct.SortPrice,
+			Saler:          newSaler(curProduct.SalerID, salerLogin, salerRatingSum, salerReviewsCount),
+			PromotionType:  curProduct.PromotionType,
+		}
+
+		setPriceDrop(product, previousAmount)
+
+		slProduct = append(slProduct, product)
 
 		return nil
 	})
@@ -214,41 +609,368 @@ func (p *ProductStorage) selectProductsWithWhereOrderLimitOffset(ctx context.Con
 			product.IsMy = true
 		} else {
 			product.IsMy = false
+			product.FavoritesCount = 0
 		}
 	}
 
 	return slProduct, nil
 }
 
+// buildWhereClause makes predicates shared by products list, facets and counts.
+// Filter must be validated in usecases before.
+func buildWhereClause(filter *models.ProductFilter) (squirrel.And, error) {
+	whereClause := squirrel.And{squirrel.Eq{"status": filter.Statuses}}
+
+	if filter.Currency != "" {
+		whereClause = append(whereClause, squirrel.Eq{"currency": filter.Currency})
+	}
+
+	if filter.MinPrice != nil {
+		whereClause = append(whereClause, squirrel.GtOrEq{"price": *filter.MinPrice})
+	}
+
+	if filter.MaxPrice != nil {
+		whereClause = append(whereClause, squirrel.LtOrEq{"price": *filter.MaxPrice})
+	}
+
+	if filter.SalerID != 0 {
+		whereClause = append(whereClause, squirrel.Eq{"saler_id": filter.SalerID})
+	}
+
+	if filter.FavoritesOf != 0 {
+		whereClause = append(whereClause, squirrel.Expr(SQLFavoritesOf, filter.FavoritesOf))
+	}
+
+	if filter.CategoryID != 0 {
+		whereClause = append(whereClause, squirrel.Expr(SQLCategoryWithDescendants, filter.CategoryID))
+	}
+
+	if filter.CreatedAfter != nil {
+		whereClause = append(whereClause, squirrel.GtOrEq{"created_at": *filter.CreatedAfter})
+	}
+
+	if filter.CreatedBefore != nil {
+		whereClause = append(whereClause, squirrel.Lt{"created_at": *filter.CreatedBefore})
+	}
+
+	if filter.HasImage != nil {
+		if *filter.HasImage {
+			whereClause = append(whereClause, squirrel.NotEq{"image_url": ""})
+		} else {
+			whereClause = append(whereClause, squirrel.Eq{"image_url": ""})
+		}
+	}
+
+	if filter.SearchQuery != "" {
+		whereClause = append(whereClause,
+			squirrel.Expr("search_vector @@ "+SQLSearchTsQuery, filter.SearchQuery, filter.SearchQuery))
+	}
+
+	if filter.Near != nil {
+		whereClause = append(whereClause, nearToSql(filter.Near, filter.RadiusKm))
+	}
+
+	for _, attrFilter := range filter.Attributes {
+		predicate, err := attributeFilterToSql(attrFilter)
+		if err != nil {
+			return nil, err
+		}
+
+		whereClause = append(whereClause, predicate)
+	}
+
+	return whereClause, nil
+}
+
+// haversineExpr is great-circle distance in kilometers from point to product.
+func haversineExpr(point *models.GeoPoint) (string, []any) {
+	return `(2 * ?::float8 * asin(LEAST(1, sqrt(power(sin(radians(latitude - ?) / 2), 2) +
+		cos(radians(?)) * cos(radians(latitude)) * power(sin(radians(longitude - ?) / 2), 2)))))`,
+		[]any{models.EarthRadiusKm, point.Latitude, point.Latitude, point.Longitude}
+}
+
+// nearToSql prefilters products by bounding box, which uses index on coordinates,
+// and then checks exact distance.
+func nearToSql(point *models.GeoPoint, radiusKm float64) squirrel.Sqlizer {
+	box := point.BoundingBox(radiusKm)
+
+	var longitudeClause squirrel.Sqlizer = squirrel.And{
+		squirrel.GtOrEq{"longitude": box.MinLongitude}, squirrel.LtOrEq{"longitude": box.MaxLongitude},
+	}
+
+	if box.MinLongitude > box.MaxLongitude {
+		longitudeClause = squirrel.Or{
+			squirrel.GtOrEq{"longitude": box.MinLongitude}, squirrel.LtOrEq{"longitude": box.MaxLongitude},
+		}
+	}
+
+	distanceExpr, distanceArgs := haversineExpr(point)
+
+	return squirrel.And{
+		squirrel.GtOrEq{"latitude": box.MinLatitude},
+		squirrel.LtOrEq{"latitude": box.MaxLatitude},
+		longitudeClause,
+		squirrel.Expr(distanceExpr+" <= ?", append(distanceArgs, radiusKm)...),
+	}
+}
+
+// attributeFilterNumber formats bound of range filter as jsonpath numeric literal. Filter is validated
+// in usecases, so value is finite number, but it can be written in notation unknown to jsonpath like 0x1p-2.
+func attributeFilterNumber(value string) (string, error) {
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return "", fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return strconv.FormatFloat(number, 'g', -1, 64), nil
+}
+
+// attributeFilterValues returns values which equality filter matches. Attributes are stored with type of
+// schema: string and enum attributes as strings, int and bool ones as json numbers and booleans, so value
+// which looks like number or boolean is matched in both forms.
+func attributeFilterValues(value string) []any {
+	values := []any{value}
+
+	if number, err := strconv.ParseFloat(value, 64); err == nil {
+		values = append(values, number)
+	} else if boolean, err := strconv.ParseBool(value); err == nil {
+		values = append(values, boolean)
+	}
+
+	return values
+}
+
+// attributeFilterToSql matches equality filters by @>, which is supported by GIN jsonb_path_ops index.
+// Range filters can't use this index, they are checked on rows selected by other predicates.
+func attributeFilterToSql(filter *models.AttributeFilter) (squirrel.Sqlizer, error) {
+	switch filter.Op {
+	case models.AttributeFilterGte, models.AttributeFilterLte:
+		operator := ">="
+		if filter.Op == models.AttributeFilterLte {
+			operator = "<="
+		}
+
+		number, err := attributeFilterNumber(filter.Value)
+		if err != nil {
+			return nil, err
+		}
+
+		return squirrel.Expr("attributes @@ ?::jsonpath",
+			fmt.Sprintf(`$.%q %s %s`, filter.Name, operator, number)), nil
+	default:
+		predicates := squirrel.Or{}
+
+		for _, value := range attributeFilterValues(filter.Value) {
+			containedJSON, err := json.Marshal(map[string]any{filter.Name: value})
+			if err != nil {
+				return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+			}
+
+			predicates = append(predicates, squirrel.Expr("attributes @> ?::jsonb", string(containedJSON)))
+		}
+
+		return predicates, nil
+	}
+}
+
+// sortFieldExpr returns sql expression of sort field and value of this field in cursor.
+// sortPriceExpr converts prices to base currency by configured rates, when feed isn't limited by one currency.
+// Prices in currencies without rate are compared as is.
+func (p *ProductStorage) sortPriceExpr(filter *models.ProductFilter) (string, []any) {
+	if filter.Currency != "" || len(p.config.priceSortFactors) == 0 {
+		return "price", nil
+	}
+
+	currencies := make([]string, 0, len(p.config.priceSortFactors))
+	for currency := range p.config.priceSortFactors {
+		currencies = append(currencies, currency)
+	}
+
+	sort.Strings(currencies)
+
+	var caseBuilder strings.Builder
+
+	args := make([]any, 0, 2*len(currencies)) //nolint:gomnd
+
+	caseBuilder.WriteString("ROUND(price * CASE currency")
+
+	for _, currency := range currencies {
+		caseBuilder.WriteString(" WHEN ? THEN ?::numeric")
+
+		args = append(args, currency, strconv.FormatFloat(p.config.priceSortFactors[currency], 'f', -1, 64))
+	}
+
+	caseBuilder.WriteString(" ELSE 1 END)::bigint")
+
+	return caseBuilder.String(), args
+}
+
+func sortFieldExpr(field models.SortField, filter *models.ProductFilter, priceExpr string, priceArgs []any,
+	cursor *models.ProductCursor,
+) (string, []any, any) {
+	var cursorValue any
+
+	switch field {
+	case models.SortFieldPrice:
+		if cursor != nil {
+			cursorValue = cursor.Price
+		}
+
+		return priceExpr, priceArgs, cursorValue
+	case models.SortFieldRelevance:
+		if cursor != nil {
+			cursorValue = cursor.Rank
+		}
+
+		return "ts_rank(search_vector, " + SQLSearchTsQuery + ")",
+			[]any{filter.SearchQuery, filter.SearchQuery}, cursorValue
+	case models.SortFieldDistance:
+		if cursor != nil {
+			cursorValue = cursor.Distance
+		}
+
+		distanceExpr, distanceArgs := haversineExpr(filter.Near)
+
+		return distanceExpr, distanceArgs, cursorValue
+	default:
+		if cursor != nil {
+			cursorValue = cursor.CreatedAt
+		}
+
+		return "created_at", nil, cursorValue
+	}
+}
+
+func direction(desc bool) string {
+	if desc {
+		return "DESC"
+	}
+
+	return "ASC"
+}
+
+// buildOrderAndKeyset makes ORDER BY of sort spec with id as tiebreaker and, when cursor is not nil,
+// predicate selecting rows after cursor: (k1 after v1) OR (k1 = v1 AND k2 after v2) OR ... OR (all equal AND id after).
+func buildOrderAndKeyset(sortSpec models.SortSpec, filter *models.ProductFilter, priceExpr string,
+	priceArgs []any, cursor *models.ProductCursor,
+) ([]squirrel.Sqlizer, squirrel.Sqlizer) {
+	orderByClause := make([]squirrel.Sqlizer, 0, len(sortSpec)+1)
+	keysetClause := squirrel.Or{}
+	equalPrefix := squirrel.And{}
+
+	after := func(desc bool) string {
+		if desc {
+			return "<"
+		}
+
+		return ">"
+	}
+
+	for _, key := range sortSpec {
+		expr, args, cursorValue := sortFieldExpr(key.Field, filter, priceExpr, priceArgs, cursor)
+		orderByClause = append(orderByClause, squirrel.Expr(expr+" "+direction(key.Desc), args...))
+
+		if cursor == nil {
+			continue
+		}
+
+		placeholder := "?"
+
+		switch key.Field { //nolint:exhaustive
+		case models.SortFieldRelevance:
+			placeholder = "?::real"
+		case models.SortFieldDistance:
+			placeholder = "?::float8"
+		}
+
+		keysetClause = append(keysetClause, append(append(squirrel.And{}, equalPrefix...),
+			squirrel.Expr(expr+" "+after(key.Desc)+" "+placeholder, append(args, cursorValue)...)))
+		equalPrefix = append(equalPrefix, squirrel.Expr(expr+" = "+placeholder, append(args, cursorValue)...))
+	}
+
+	idDesc := len(sortSpec) > 0 && sortSpec[0].Desc
+	orderByClause = append(orderByClause, squirrel.Expr("id "+direction(idDesc)))
+
+	if cursor == nil {
+		return orderByClause, nil
+	}
+
+	keysetClause = append(keysetClause, append(append(squirrel.And{}, equalPrefix...),
+		squirrel.Expr("id "+after(idDesc)+" ?", cursor.ID)))
+
+	return orderByClause, keysetClause
+}
+
+// headlineConfig chooses configuration of search vector for highlighting: russian for query with cyrillic
+// letters and english otherwise. Headline is built with the same configuration as its query, so words are
+// normalized equally in both.
+func headlineConfig(searchQuery string) string {
+	for _, r := range searchQuery {
+		if unicode.Is(unicode.Cyrillic, r) {
+			return "russian"
+		}
+	}
+
+	return "english"
+}
+
+func newFeedColumns(filter *models.ProductFilter, priceExpr string, priceArgs []any) *feedColumns {
+	columns := &feedColumns{
+		snippet:   squirrel.Expr("''"),
+		rank:      squirrel.Expr("0::real"),
+		sortPrice: squirrel.Expr(priceExpr, priceArgs...),
+		distance:  squirrel.Expr("NULL::float8"),
+		promotion: promotionJoin(filter),
+	}
+
+	if searchQuery := filter.SearchQuery; searchQuery != "" {
+		config := headlineConfig(searchQuery)
+		columns.snippet = squirrel.Expr(SQLHeadline, config, config, searchQuery, headlineOptions)
+		columns.rank = squirrel.Expr("ts_rank(search_vector, "+SQLSearchTsQuery+")", searchQuery, searchQuery)
+	}
+
+	if filter.Near != nil {
+		distanceExpr, distanceArgs := haversineExpr(filter.Near)
+		columns.distance = squirrel.Expr(distanceExpr, distanceArgs...)
+	}
+
+	return columns
+}
+
+// GetProductsList works in keyset mode when cursor is not nil, then offset is ignored.
+// Sort spec must be validated in usecases: relevance is allowed only with search query and distance only with near.
 func (p *ProductStorage) GetProductsList(ctx context.Context,
-	limit uint64, offset uint64, sortType uint64, minPrice uint64, maxPrice uint64, userID uint64,
+	filter *models.ProductFilter, sortSpec models.SortSpec, limit uint64, offset uint64, cursor *models.ProductCursor,
+	userID uint64,
 ) ([]*models.ProductWithIsMy, error) {
 	var slProduct []*models.ProductWithIsMy
 
-	var orderByClause []string
-
-	switch sortType {
-	case byPriceASC:
-		orderByClause = []string{"price ASC"}
-	case byPriceDESC:
-		orderByClause = []string{"price DESC"}
-	case byDateASC:
-		orderByClause = []string{"created_at ASC"}
-	case byDateDESC:
-		orderByClause = []string{"created_at DESC"}
-	default:
-		orderByClause = []string{"created_at DESC"}
+	priceExpr, priceArgs := p.sortPriceExpr(filter)
+
+	orderByClause, keysetClause := buildOrderAndKeyset(sortSpec, filter, priceExpr, priceArgs, cursor)
+
+	columns := newFeedColumns(filter, priceExpr, priceArgs)
+
+	whereClause, err := buildWhereClause(filter)
+	if err != nil {
+		p.logger.Errorln(err)
+
+		return nil, err
 	}
 
-	whereClause := ""
-	if !(minPrice == 0 && maxPrice == math.MaxUint64) && (minPrice <= maxPrice) {
-		whereClause = fmt.Sprintf("price >= %d AND price <= %d", minPrice, maxPrice)
+	if filter.ExcludePromoted {
+		whereClause = append(whereClause, squirrel.Expr("promotion_type IS NULL"))
 	}
 
-	err := pgx.BeginFunc(ctx, p.pool, func(tx pgx.Tx) error {
+	if keysetClause != nil {
+		whereClause = append(whereClause, keysetClause)
+		offset = 0
+	}
+
+	err = pgx.BeginFunc(ctx, p.pool, func(tx pgx.Tx) error {
 		var err error
 		slProduct, err = p.selectProductsWithWhereOrderLimitOffset(ctx,
-			tx, limit, offset, whereClause, orderByClause, userID)
+			tx, limit, offset, whereClause, orderByClause, columns, userID)
 		if err != nil {
 			return err
 		}
@@ -263,3 +985,345 @@ func (p *ProductStorage) GetProductsList(ctx context.Context,
 
 	return slProduct, nil
 }
+
+// GetPromotedProducts returns products of filter with running promotion ordered by priority of promotion type,
+// then the latest promotion goes first.
+func (p *ProductStorage) GetPromotedProducts(ctx context.Context, filter *models.ProductFilter, limit uint64,
+	offset uint64, userID uint64,
+) ([]*models.ProductWithIsMy, error) {
+	var slProduct []*models.ProductWithIsMy
+
+	priceExpr, priceArgs := p.sortPriceExpr(filter)
+
+	columns := newFeedColumns(filter, priceExpr, priceArgs)
+
+	whereClause, err := buildWhereClause(filter)
+	if err != nil {
+		p.logger.Errorln(err)
+
+		return nil, err
+	}
+
+	whereClause = append(whereClause, squirrel.Expr("promotion_type IS NOT NULL"))
+
+	orderByClause := []squirrel.Sqlizer{
+		squirrel.Expr("ARRAY_POSITION(?::text[], promotion_type)", models.PromotionTypes()),
+		squirrel.Expr("promotion_started_at DESC"),
+		squirrel.Expr("id DESC"),
+	}
+
+	err = pgx.BeginFunc(ctx, p.pool, func(tx pgx.Tx) error {
+		var err error
+		slProduct, err = p.selectProductsWithWhereOrderLimitOffset(ctx,
+			tx, limit, offset, whereClause, orderByClause, columns, userID)
+
+		return err
+	})
+	if err != nil {
+		p.logger.Errorln(err)
+
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return slProduct, nil
+}
+
+func (p *ProductStorage) selectEstimatedCount(ctx context.Context, tx pgx.Tx, whereClause any) (uint64, error) {
+	query := squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar).Select("1").
+		From(`public."product"`).Where(whereClause)
+
+	SQLQuery, args, err := query.ToSql()
+	if err != nil {
+		p.logger.Errorln(err)
+
+		return 0, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	var plans []struct {
+		Plan struct {
+			PlanRows float64 `json:"Plan Rows"`
+		} `json:"Plan"`
+	}
+
+	if err := tx.QueryRow(ctx, "EXPLAIN (FORMAT JSON) "+SQLQuery, args...).Scan(&plans); err != nil {
+		p.logger.Errorln(err)
+
+		return 0, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	if len(plans) == 0 {
+		return 0, nil
+	}
+
+	return uint64(plans[0].Plan.PlanRows), nil
+}
+
+func (p *ProductStorage) selectExactCount(ctx context.Context, tx pgx.Tx, whereClause any) (uint64, error) {
+	query := squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar).Select("COUNT(*)").
+		From(`public."product"`).Where(whereClause)
+
+	SQLQuery, args, err := query.ToSql()
+	if err != nil {
+		p.logger.Errorln(err)
+
+		return 0, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	var count uint64
+
+	if err := tx.QueryRow(ctx, SQLQuery, args...).Scan(&count); err != nil {
+		p.logger.Errorln(err)
+
+		return 0, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return count, nil
+}
+
+// CountProducts counts products matching filters. If planner estimates more rows than
+// estimateThreshold, the estimate is returned instead of slow exact count.
+func (p *ProductStorage) CountProducts(ctx context.Context, filter *models.ProductFilter, estimateThreshold uint64,
+) (uint64, bool, error) {
+	var (
+		count     uint64
+		estimated bool
+	)
+
+	whereClause, err := buildWhereClause(filter)
+	if err != nil {
+		p.logger.Errorln(err)
+
+		return 0, false, err
+	}
+
+	if filter.ExcludePromoted {
+		whereClause = append(whereClause, squirrel.Expr(SQLNotPromoted, filter.CategoryID != 0,
+			models.PromotionTypeTopOfCategory))
+	}
+
+	err = pgx.BeginFunc(ctx, p.pool, func(tx pgx.Tx) error {
+		estimatedCount, err := p.selectEstimatedCount(ctx, tx, whereClause)
+		if err != nil {
+			return err
+		}
+
+		if estimatedCount > estimateThreshold {
+			count, estimated = estimatedCount, true
+
+			return nil
+		}
+
+		count, err = p.selectExactCount(ctx, tx, whereClause)
+
+		return err
+	})
+	if err != nil {
+		return 0, false, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return count, estimated, nil
+}
+
+func (p *ProductStorage) selectFacetsWithWhere(ctx context.Context, tx pgx.Tx, whereClause any,
+) ([]*models.Facet, error) {
+	query := squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar).Select("kv.key, kv.value, COUNT(*)").
+		From(`public."product", jsonb_each_text(attributes) kv`).Where(whereClause).
+		GroupBy("kv.key", "kv.value").OrderBy("kv.key", "COUNT(*) DESC")
+
+	SQLQuery, args, err := query.ToSql()
+	if err != nil {
+		p.logger.Errorln(err)
+
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	rowsFacets, err := tx.Query(ctx, SQLQuery, args...)
+	if err != nil {
+		p.logger.Errorln(err)
+
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	var (
+		name       string
+		facetValue models.FacetValue
+		slFacet    []*models.Facet
+	)
+
+	_, err = pgx.ForEachRow(rowsFacets, []any{&name, &facetValue.Value, &facetValue.Count}, func() error {
+		if len(slFacet) == 0 || slFacet[len(slFacet)-1].Name != name {
+			slFacet = append(slFacet, &models.Facet{Name: name, Values: []*models.FacetValue{}})
+		}
+
+		lastFacet := slFacet[len(slFacet)-1]
+		lastFacet.Values = append(lastFacet.Values, &models.FacetValue{
+			Value: facetValue.Value,
+			Count: facetValue.Count,
+		})
+
+		return nil
+	})
+	if err != nil {
+		p.logger.Errorln(err)
+
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return slFacet, nil
+}
+
+func (p *ProductStorage) GetFacets(ctx context.Context, filter *models.ProductFilter) ([]*models.Facet, error) {
+	var slFacet []*models.Facet
+
+	whereClause, err := buildWhereClause(filter)
+	if err != nil {
+		p.logger.Errorln(err)
+
+		return nil, err
+	}
+
+	err = pgx.BeginFunc(ctx, p.pool, func(tx pgx.Tx) error {
+		var err error
+
+		slFacet, err = p.selectFacetsWithWhere(ctx, tx, whereClause)
+
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return slFacet, nil
+}
+
+func (p *ProductStorage) selectCategoryAttributes(ctx context.Context, tx pgx.Tx, categoryID uint64,
+) ([]*models.CategoryAttribute, error) {
+	SQLSelectCategoryAttributes := `SELECT id, category_id, name, type, enum_values, min_value, max_value, required
+		FROM public."category_attribute" WHERE category_id=$1 ORDER BY id`
+
+	rowsAttributes, err := tx.Query(ctx, SQLSelectCategoryAttributes, categoryID)
+	if err != nil {
+		p.logger.Errorln(err)
+
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	slAttribute, err := pgx.CollectRows(rowsAttributes, func(row pgx.CollectableRow) (*models.CategoryAttribute, error) {
+		attribute := &models.CategoryAttribute{} //nolint:exhaustruct
+
+		err := row.Scan(&attribute.ID, &attribute.CategoryID, &attribute.Name, &attribute.Type,
+			&attribute.EnumValues, &attribute.MinValue, &attribute.MaxValue, &attribute.Required)
+
+		return attribute, err //nolint:wrapcheck
+	})
+	if err != nil {
+		p.logger.Errorln(err)
+
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return slAttribute, nil
+}
+
+func (p *ProductStorage) GetCategoryAttributes(ctx context.Context, categoryID uint64,
+) ([]*models.CategoryAttribute, error) {
+	var slAttribute []*models.CategoryAttribute
+
+	err := pgx.BeginFunc(ctx, p.pool, func(tx pgx.Tx) error {
+		var err error
+
+		slAttribute, err = p.selectCategoryAttributes(ctx, tx, categoryID)
+
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return slAttribute, nil
+}
+
+// escapeLike escapes LIKE wildcards so user input is matched literally as prefix.
+func escapeLike(str string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(str)
+}
+
+// selectTitleSuggestions keeps status literal, so planner of prepared statement can use partial
+// index product_title_trgm_idx.
+func (p *ProductStorage) selectTitleSuggestions(ctx context.Context, tx pgx.Tx, searchQuery string, limit uint64,
+) ([]string, error) {
+	SQLSelectTitleSuggestions := `SELECT title FROM (
+			SELECT DISTINCT ON (LOWER(title)) title, similarity(LOWER(title), LOWER($1)) AS sim
+			FROM public."product"
+			WHERE status = 'active' AND (LOWER(title) LIKE LOWER($2) OR LOWER(title) % LOWER($1))
+			ORDER BY LOWER(title), sim DESC
+		) AS suggestion ORDER BY sim DESC LIMIT $3`
+
+	rowsTitles, err := tx.Query(ctx, SQLSelectTitleSuggestions, searchQuery, escapeLike(searchQuery)+"%", limit)
+	if err != nil {
+		p.logger.Errorln(err)
+
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	titles, err := pgx.CollectRows(rowsTitles, pgx.RowTo[string])
+	if err != nil {
+		p.logger.Errorln(err)
+
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return titles, nil
+}
+
+func (p *ProductStorage) selectCategorySuggestions(ctx context.Context, tx pgx.Tx, searchQuery string, limit uint64,
+) ([]*models.Category, error) {
+	SQLSelectCategorySuggestions := `SELECT id, COALESCE(parent_id, 0), slug, name FROM public."category"
+		WHERE LOWER(name) LIKE LOWER($2) OR LOWER(name) % LOWER($1)
+		ORDER BY similarity(LOWER(name), LOWER($1)) DESC LIMIT $3`
+
+	rowsCategories, err := tx.Query(ctx, SQLSelectCategorySuggestions, searchQuery, escapeLike(searchQuery)+"%", limit)
+	if err != nil {
+		p.logger.Errorln(err)
+
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	categories, err := pgx.CollectRows(rowsCategories, func(row pgx.CollectableRow) (*models.Category, error) {
+		category := &models.Category{Children: []*models.Category{}} //nolint:exhaustruct
+
+		err := row.Scan(&category.ID, &category.ParentID, &category.Slug, &category.Name)
+
+		return category, err //nolint:wrapcheck
+	})
+	if err != nil {
+		p.logger.Errorln(err)
+
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return categories, nil
+}
+
+func (p *ProductStorage) GetSuggestions(ctx context.Context, searchQuery string, limit uint64,
+) (*models.Suggestions, error) {
+	suggestions := &models.Suggestions{} //nolint:exhaustruct
+
+	err := pgx.BeginFunc(ctx, p.pool, func(tx pgx.Tx) error {
+		var err error
+
+		suggestions.Titles, err = p.selectTitleSuggestions(ctx, tx, searchQuery, limit)
+		if err != nil {
+			return err
+		}
+
+		suggestions.Categories, err = p.selectCategorySuggestions(ctx, tx, searchQuery, limit)
+
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return suggestions, nil
+}
@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/SanExpett/marketplace-backend/pkg/models"
+)
+
+func TestAttributeFilterToSql(t *testing.T) {
+	tests := []struct {
+		name     string
+		filter   *models.AttributeFilter
+		wantSQL  string
+		wantArgs []any
+	}{
+		{
+			name:     "range bound in hex notation",
+			filter:   &models.AttributeFilter{Name: "year", Op: models.AttributeFilterGte, Value: "0x1p-2"},
+			wantSQL:  "attributes @@ ?::jsonpath",
+			wantArgs: []any{`$."year" >= 0.25`},
+		},
+		{
+			name:     "range bound with exponent",
+			filter:   &models.AttributeFilter{Name: "mileage", Op: models.AttributeFilterLte, Value: "1e5"},
+			wantSQL:  "attributes @@ ?::jsonpath",
+			wantArgs: []any{`$."mileage" <= 100000`},
+		},
+		{
+			name:     "numeric looking value matches string and number",
+			filter:   &models.AttributeFilter{Name: "size", Op: models.AttributeFilterEq, Value: "1"},
+			wantSQL:  "(attributes @> ?::jsonb OR attributes @> ?::jsonb)",
+			wantArgs: []any{`{"size":"1"}`, `{"size":1}`},
+		},
+		{
+			name:     "boolean looking value matches string and boolean",
+			filter:   &models.AttributeFilter{Name: "new", Op: models.AttributeFilterEq, Value: "true"},
+			wantSQL:  "(attributes @> ?::jsonb OR attributes @> ?::jsonb)",
+			wantArgs: []any{`{"new":"true"}`, `{"new":true}`},
+		},
+		{
+			name:     "string value",
+			filter:   &models.AttributeFilter{Name: "color", Op: models.AttributeFilterEq, Value: "red"},
+			wantSQL:  "(attributes @> ?::jsonb)",
+			wantArgs: []any{`{"color":"red"}`},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			predicate, err := attributeFilterToSql(test.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			sql, args, err := predicate.ToSql()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if sql != test.wantSQL || !reflect.DeepEqual(args, test.wantArgs) {
+				t.Fatalf("got %q %v, want %q %v", sql, args, test.wantSQL, test.wantArgs)
+			}
+		})
+	}
+}
+
+func TestHeadlineConfig(t *testing.T) {
+	for query, want := range map[string]string{
+		"велосипед":         "russian",
+		"bike":              "english",
+		"горный bike":       "russian",
+		"iphone 13 pro":     "english",
+		"\"Ёлка\" -искусст": "russian",
+	} {
+		if got := headlineConfig(query); got != want {
+			t.Errorf("headlineConfig(%q) = %s, want %s", query, got, want)
+		}
+	}
+}
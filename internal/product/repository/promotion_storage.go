@@ -0,0 +1,299 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/SanExpett/marketplace-backend/internal/server/repository"
+	"github.com/SanExpett/marketplace-backend/pkg/models"
+	myerrors "github.com/SanExpett/marketplace-backend/pkg/my_errors"
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrPromotionNotFound     = myerrors.NewError("Это продвижение не найдено")
+	ErrPromotionNotAllowed   = myerrors.NewError("Бесплатное продвижение добавляет только модератор")
+	ErrNotMyPromotedProduct  = myerrors.NewError("Купить продвижение может только продавец объявления")
+	ErrProductNotPromotable  = myerrors.NewError("Продвигать можно только активное объявление")
+	ErrPromotionAlreadyExist = myerrors.NewError("У объявления уже есть продвижение этого типа на это время")
+
+	NameSeqPromotion = pgx.Identifier{"public", "promotion_id_seq"} //nolint:gochecknoglobals
+)
+
+const SQLSelectPromotion = `SELECT id, product_id, saler_id, type, started_at, ended_at, price, currency, payment_id,
+	payment_status, payment_error, created_at
+	FROM public."promotion"`
+
+func scanPromotion(row pgx.Row) (*models.Promotion, error) {
+	promotion := &models.Promotion{} //nolint:exhaustruct
+
+	err := row.Scan(&promotion.ID, &promotion.ProductID, &promotion.SalerID, &promotion.Type,
+		&promotion.StartedAt, &promotion.EndedAt, &promotion.Price.Amount, &promotion.Price.Currency,
+		&promotion.PaymentID, &promotion.PaymentStatus, &promotion.PaymentError, &promotion.CreatedAt)
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	return promotion, nil
+}
+
+func (p *ProductStorage) selectPromotionByID(ctx context.Context, tx pgx.Tx, promotionID uint64, forUpdate bool,
+) (*models.Promotion, error) {
+	SQLSelectPromotionByID := SQLSelectPromotion + ` WHERE id=$1`
+	if forUpdate {
+		SQLSelectPromotionByID += ` FOR UPDATE`
+	}
+
+	promotion, err := scanPromotion(tx.QueryRow(ctx, SQLSelectPromotionByID, promotionID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf(myerrors.ErrTemplate, ErrPromotionNotFound)
+		}
+
+		p.logger.Errorf("error with promotionID=%d: %+v", promotionID, err)
+
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return promotion, nil
+}
+
+// selectOverlappingPromotion returns promotion of product of the same type which intersects new one, nil without it.
+// Failed promotions don't count, pending ones go last, so pending promotion is returned only if nothing else
+// intersects new one.
+func (p *ProductStorage) selectOverlappingPromotion(ctx context.Context, tx pgx.Tx, productID uint64,
+	prePromotion *models.PrePromotion,
+) (*models.Promotion, error) {
+	SQLSelectOverlappingPromotion := SQLSelectPromotion + ` WHERE product_id=$1 AND type=$2 AND started_at < $4
+		AND ended_at > $3 AND payment_status <> 'failed' ORDER BY payment_status = 'pending', id LIMIT 1`
+
+	promotion, err := scanPromotion(tx.QueryRow(ctx, SQLSelectOverlappingPromotion, productID, prePromotion.Type,
+		prePromotion.StartedAt, prePromotion.EndedAt))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil //nolint:nilnil
+		}
+
+		p.logger.Errorf("error with productID=%d: %+v", productID, err)
+
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return promotion, nil
+}
+
+// checkCanPromote returns ErrPromotionNotAllowed if user is not moderator. Only moderators grant free promotions.
+func (p *ProductStorage) checkCanPromote(ctx context.Context, tx pgx.Tx, userID uint64) error {
+	SQLIsModerator := `SELECT EXISTS(SELECT 1 FROM public."user" WHERE id=$1 AND is_moderator AND banned_at IS NULL)`
+
+	var isModerator bool
+
+	err := tx.QueryRow(ctx, SQLIsModerator, userID).Scan(&isModerator)
+	if err != nil {
+		p.logger.Errorf("error with userID=%d: %+v", userID, err)
+
+		return fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	if !isModerator {
+		return fmt.Errorf(myerrors.ErrTemplate, ErrPromotionNotAllowed)
+	}
+
+	return nil
+}
+
+// insertPromotion adds promotion of product which belongs to its seller.
+func (p *ProductStorage) insertPromotion(ctx context.Context, tx pgx.Tx, product *models.Product,
+	prePromotion *models.PrePromotion, price models.Money, paymentStatus string,
+) (*models.Promotion, error) {
+	SQLInsertPromotion := `INSERT INTO public."promotion"(product_id, saler_id, type, started_at, ended_at, price,
+		currency, payment_status) VALUES($1, $2, $3, $4, $5, $6, $7, $8)`
+
+	_, err := tx.Exec(ctx, SQLInsertPromotion, product.ID, product.SalerID, prePromotion.Type,
+		prePromotion.StartedAt, prePromotion.EndedAt, price.Amount, price.Currency, paymentStatus)
+	if err != nil {
+		p.logger.Errorln(err)
+
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	promotionID, err := repository.GetLastValSeq(ctx, tx, NameSeqPromotion)
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return p.selectPromotionByID(ctx, tx, promotionID, false)
+}
+
+// GrantPromotion adds free promotion of active product on behalf of moderator with moderatorID, promotion belongs
+// to seller of product. StartedAt must be set by usecases.
+// Product is locked, so promotions of the same type can't overlap.
+func (p *ProductStorage) GrantPromotion(ctx context.Context, productID uint64, moderatorID uint64,
+	prePromotion *models.PrePromotion,
+) (*models.Promotion, error) {
+	var promotion *models.Promotion
+
+	err := pgx.BeginFunc(ctx, p.pool, func(tx pgx.Tx) error {
+		if err := p.checkCanPromote(ctx, tx, moderatorID); err != nil {
+			return err
+		}
+
+		product, err := p.selectProductForUpdate(ctx, tx, productID)
+		if err != nil {
+			return err
+		}
+
+		if product.Status != models.ProductStatusActive {
+			return fmt.Errorf(myerrors.ErrTemplate, ErrProductNotPromotable)
+		}
+
+		overlapping, err := p.selectOverlappingPromotion(ctx, tx, productID, prePromotion)
+		if err != nil {
+			return err
+		}
+
+		if overlapping != nil {
+			return fmt.Errorf(myerrors.ErrTemplate, ErrPromotionAlreadyExist)
+		}
+
+		promotion, err = p.insertPromotion(ctx, tx, product, prePromotion, models.Money{}, //nolint:exhaustruct
+			models.PaymentStatusNone)
+
+		return err
+	})
+	if err != nil {
+		p.logger.Errorln(err)
+
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return promotion, nil
+}
+
+// StartPromotionPayment adds pending promotion of own active product of seller with userID, it runs after
+// ApplyPromotionPayment saves succeeded payment. Pending promotion of the same type which overlaps new one
+// is returned instead, so its charge is repeated. StartedAt must be set by usecases.
+func (p *ProductStorage) StartPromotionPayment(ctx context.Context, productID uint64, userID uint64,
+	prePromotion *models.PrePromotion, price models.Money,
+) (*models.Promotion, error) {
+	var promotion *models.Promotion
+
+	err := pgx.BeginFunc(ctx, p.pool, func(tx pgx.Tx) error {
+		product, err := p.selectProductForUpdate(ctx, tx, productID)
+		if err != nil {
+			return err
+		}
+
+		if product.SalerID != userID {
+			return fmt.Errorf(myerrors.ErrTemplate, ErrNotMyPromotedProduct)
+		}
+
+		if product.Status != models.ProductStatusActive {
+			return fmt.Errorf(myerrors.ErrTemplate, ErrProductNotPromotable)
+		}
+
+		promotion, err = p.selectOverlappingPromotion(ctx, tx, productID, prePromotion)
+		if err != nil {
+			return err
+		}
+
+		if promotion != nil {
+			if promotion.PaymentStatus != models.PaymentStatusPending {
+				return fmt.Errorf(myerrors.ErrTemplate, ErrPromotionAlreadyExist)
+			}
+
+			return nil
+		}
+
+		promotion, err = p.insertPromotion(ctx, tx, product, prePromotion, price, models.PaymentStatusPending)
+
+		return err
+	})
+	if err != nil {
+		p.logger.Errorln(err)
+
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return promotion, nil
+}
+
+// ApplyPromotionPayment saves result of charge for promotion. It is idempotent: result of payment which is
+// already applied or doesn't belong to pending payment of promotion changes nothing.
+func (p *ProductStorage) ApplyPromotionPayment(ctx context.Context, payment *models.Payment,
+) (*models.Promotion, error) {
+	SQLApplyPromotionPayment := `UPDATE public."promotion" SET payment_id=$1, payment_status=$2, payment_error=$3
+		WHERE id=$4`
+
+	var promotion *models.Promotion
+
+	err := pgx.BeginFunc(ctx, p.pool, func(tx pgx.Tx) error {
+		var err error
+
+		promotion, err = p.selectPromotionByID(ctx, tx, payment.PromotionID, true)
+		if err != nil {
+			return err
+		}
+
+		if promotion.PaymentStatus != models.PaymentStatusPending ||
+			(promotion.PaymentID != "" && promotion.PaymentID != payment.ID) {
+			p.logger.Infof("payment %+v is ignored for promotion %+v", payment, promotion)
+
+			return nil
+		}
+
+		_, err = tx.Exec(ctx, SQLApplyPromotionPayment, payment.ID, payment.Status, payment.Error,
+			payment.PromotionID)
+		if err != nil {
+			p.logger.Errorln(err)
+
+			return fmt.Errorf(myerrors.ErrTemplate, err)
+		}
+
+		promotion, err = p.selectPromotionByID(ctx, tx, payment.PromotionID, false)
+
+		return err
+	})
+	if err != nil {
+		p.logger.Errorln(err)
+
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return promotion, nil
+}
+
+// GetPromotions returns promotions of seller, of one product if productID is not zero. The latest go first.
+func (p *ProductStorage) GetPromotions(ctx context.Context, userID uint64, productID uint64, limit uint64,
+	offset uint64,
+) ([]*models.Promotion, error) {
+	SQLSelectPromotions := SQLSelectPromotion + ` WHERE saler_id=$1
+		AND ($2::bigint = 0 OR product_id = $2::bigint) ORDER BY id DESC LIMIT $3 OFFSET $4`
+
+	var promotions []*models.Promotion
+
+	err := pgx.BeginFunc(ctx, p.pool, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, SQLSelectPromotions, userID, productID, limit, offset)
+		if err != nil {
+			p.logger.Errorln(err)
+
+			return fmt.Errorf(myerrors.ErrTemplate, err)
+		}
+
+		promotions, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (*models.Promotion, error) {
+			return scanPromotion(row)
+		})
+		if err != nil {
+			p.logger.Errorln(err)
+
+			return fmt.Errorf(myerrors.ErrTemplate, err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return promotions, nil
+}
@@ -0,0 +1,426 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/SanExpett/marketplace-backend/internal/server/repository"
+	"github.com/SanExpett/marketplace-backend/pkg/models"
+	myerrors "github.com/SanExpett/marketplace-backend/pkg/my_errors"
+	"github.com/jackc/pgx/v5"
+	"strings"
+)
+
+const MessageErrTooManySavedSearches = "Нельзя сохранить больше %d поисков"
+
+var (
+	ErrSavedSearchNotFound = myerrors.NewError("Этот сохранённый поиск не найден")
+
+	NameSeqSavedSearch = pgx.Identifier{"public", "saved_search_id_seq"} //nolint:gochecknoglobals
+)
+
+const SQLSelectSavedSearch = `SELECT id, user_id, name, query, notify, last_notified_at, created_at
+	FROM public."saved_search"`
+
+// SQLMatchSavedSearches matches new active products with saved searches. Search is candidate only if
+// its category is category of product, one of categories above it or 0, so each product is checked
+// by index only with searches which can select it, the rest of filter is checked for candidates.
+// Products of user are not matched with his own searches.
+const SQLMatchSavedSearches = `WITH RECURSIVE new_product AS (
+		SELECT id, saler_id, category_id, price, currency, image_url, search_vector, attributes, latitude, longitude
+		FROM public."product" WHERE id = ANY($1) AND status = 'active'
+	), category_path AS (
+		SELECT id AS product_id, category_id FROM new_product
+		UNION
+		SELECT category_path.product_id, category.parent_id FROM category_path
+		INNER JOIN public."category" category ON category.id = category_path.category_id
+		WHERE category.parent_id IS NOT NULL
+	), candidate AS (
+		SELECT product_id, category_id FROM category_path
+		UNION ALL
+		SELECT id, 0 FROM new_product
+	)
+	INSERT INTO public."saved_search_match"(saved_search_id, product_id)
+	SELECT ss.id, np.id FROM new_product np
+	INNER JOIN candidate ON candidate.product_id = np.id
+	INNER JOIN public."saved_search" ss ON ss.category_id = candidate.category_id
+	WHERE ss.user_id <> np.saler_id
+		AND (ss.currency = '' OR ss.currency = np.currency)
+		AND (ss.min_price IS NULL OR np.price >= ss.min_price)
+		AND (ss.max_price IS NULL OR np.price <= ss.max_price)
+		AND (ss.saler_id = 0 OR ss.saler_id = np.saler_id)
+		AND (ss.has_image IS NULL OR ss.has_image = (np.image_url <> ''))
+		AND (ss.search_query = '' OR np.search_vector @@
+			(websearch_to_tsquery('russian', ss.search_query) || websearch_to_tsquery('english', ss.search_query)))
+		AND (ss.latitude IS NULL OR np.latitude IS NOT NULL AND
+			2 * $2::float8 * asin(LEAST(1, sqrt(power(sin(radians(np.latitude - ss.latitude) / 2), 2) +
+			cos(radians(ss.latitude)) * cos(radians(np.latitude)) *
+			power(sin(radians(np.longitude - ss.longitude) / 2), 2)))) <= ss.radius_km)
+		AND (ss.attributes_path IS NULL OR np.attributes @@ ss.attributes_path)
+	ON CONFLICT DO NOTHING`
+
+func scanSavedSearch(row pgx.Row) (*models.SavedSearch, error) {
+	savedSearch := &models.SavedSearch{} //nolint:exhaustruct
+
+	err := row.Scan(&savedSearch.ID, &savedSearch.UserID, &savedSearch.Name, &savedSearch.Query,
+		&savedSearch.Notify, &savedSearch.LastNotifiedAt, &savedSearch.CreatedAt)
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	return savedSearch, nil
+}
+
+// attributeFiltersToJsonPath joins attribute filters into one jsonpath predicate with the same meaning
+// as predicates of feed. It returns nil when there are no filters.
+func attributeFiltersToJsonPath(filters []*models.AttributeFilter) (*string, error) {
+	if len(filters) == 0 {
+		return nil, nil //nolint:nilnil
+	}
+
+	predicates := make([]string, 0, len(filters))
+
+	for _, filter := range filters {
+		switch filter.Op {
+		case models.AttributeFilterGte, models.AttributeFilterLte:
+			operator := ">="
+			if filter.Op == models.AttributeFilterLte {
+				operator = "<="
+			}
+
+			number, err := attributeFilterNumber(filter.Value)
+			if err != nil {
+				return nil, err
+			}
+
+			predicates = append(predicates, fmt.Sprintf(`$.%q %s %s`, filter.Name, operator, number))
+		default:
+			values := attributeFilterValues(filter.Value)
+			alternatives := make([]string, 0, len(values))
+
+			for _, value := range values {
+				rawValue, err := json.Marshal(value)
+				if err != nil {
+					return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+				}
+
+				alternatives = append(alternatives, fmt.Sprintf(`$.%q == %s`, filter.Name, rawValue))
+			}
+
+			predicates = append(predicates, "("+strings.Join(alternatives, " || ")+")")
+		}
+	}
+
+	jsonPath := strings.Join(predicates, " && ")
+
+	return &jsonPath, nil
+}
+
+func (p *ProductStorage) countSavedSearches(ctx context.Context, tx pgx.Tx, userID uint64) (uint64, error) {
+	SQLCountSavedSearches := `SELECT COUNT(*) FROM public."saved_search" WHERE user_id=$1`
+
+	var count uint64
+
+	if err := tx.QueryRow(ctx, SQLCountSavedSearches, userID).Scan(&count); err != nil {
+		p.logger.Errorf("error with userID=%d: %+v", userID, err)
+
+		return 0, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return count, nil
+}
+
+func (p *ProductStorage) insertSavedSearch(ctx context.Context, tx pgx.Tx, userID uint64,
+	preSavedSearch *models.PreSavedSearch,
+) error {
+	SQLInsertSavedSearch := `INSERT INTO public."saved_search"(user_id, name, query, notify, category_id,
+		currency, min_price, max_price, saler_id, has_image, search_query, latitude, longitude, radius_km,
+		attributes_path) VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)`
+
+	filter := preSavedSearch.Filter
+
+	attributesPath, err := attributeFiltersToJsonPath(filter.Attributes)
+	if err != nil {
+		return err
+	}
+
+	var latitude, longitude, radiusKm *float64
+
+	if filter.Near != nil {
+		latitude, longitude, radiusKm = &filter.Near.Latitude, &filter.Near.Longitude, &filter.RadiusKm
+	}
+
+	_, err = tx.Exec(ctx, SQLInsertSavedSearch, userID, preSavedSearch.Name, preSavedSearch.Query,
+		preSavedSearch.Notify, filter.CategoryID, filter.Currency, filter.MinPrice, filter.MaxPrice, filter.SalerID,
+		filter.HasImage, filter.SearchQuery, latitude, longitude, radiusKm, attributesPath)
+	if err != nil {
+		p.logger.Errorln(err)
+
+		return fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return nil
+}
+
+// AddSavedSearch saves search of user, who can have at most maxSavedSearches of them.
+// Filter must be validated in usecases before.
+func (p *ProductStorage) AddSavedSearch(ctx context.Context, userID uint64, preSavedSearch *models.PreSavedSearch,
+	maxSavedSearches uint64,
+) (*models.SavedSearch, error) {
+	var savedSearch *models.SavedSearch
+
+	err := pgx.BeginFunc(ctx, p.pool, func(tx pgx.Tx) error {
+		if categoryID := preSavedSearch.Filter.CategoryID; categoryID != 0 {
+			categoryExists, err := p.isCategoryExists(ctx, tx, categoryID)
+			if err != nil {
+				return err
+			}
+
+			if !categoryExists {
+				return fmt.Errorf(myerrors.ErrTemplate, ErrCategoryNotFound)
+			}
+		}
+
+		count, err := p.countSavedSearches(ctx, tx, userID)
+		if err != nil {
+			return err
+		}
+
+		if count >= maxSavedSearches {
+			return myerrors.NewError(MessageErrTooManySavedSearches, maxSavedSearches)
+		}
+
+		if err := p.insertSavedSearch(ctx, tx, userID, preSavedSearch); err != nil {
+			return err
+		}
+
+		savedSearchID, err := repository.GetLastValSeq(ctx, tx, NameSeqSavedSearch)
+		if err != nil {
+			return fmt.Errorf(myerrors.ErrTemplate, err)
+		}
+
+		savedSearch, err = scanSavedSearch(tx.QueryRow(ctx, SQLSelectSavedSearch+` WHERE id=$1`, savedSearchID))
+		if err != nil {
+			p.logger.Errorf("error with savedSearchID=%d: %+v", savedSearchID, err)
+
+			return fmt.Errorf(myerrors.ErrTemplate, err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return savedSearch, nil
+}
+
+// GetSavedSearches returns saved searches of user, the latest go first.
+func (p *ProductStorage) GetSavedSearches(ctx context.Context, userID uint64, limit uint64, offset uint64,
+) ([]*models.SavedSearch, error) {
+	SQLSelectSavedSearches := SQLSelectSavedSearch + ` WHERE user_id=$1 ORDER BY id DESC LIMIT $2 OFFSET $3`
+
+	var savedSearches []*models.SavedSearch
+
+	err := pgx.BeginFunc(ctx, p.pool, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, SQLSelectSavedSearches, userID, limit, offset)
+		if err != nil {
+			p.logger.Errorln(err)
+
+			return fmt.Errorf(myerrors.ErrTemplate, err)
+		}
+
+		savedSearches, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (*models.SavedSearch, error) {
+			return scanSavedSearch(row)
+		})
+		if err != nil {
+			p.logger.Errorln(err)
+
+			return fmt.Errorf(myerrors.ErrTemplate, err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return savedSearches, nil
+}
+
+// DeleteSavedSearch deletes saved search of user with its not sent matches.
+func (p *ProductStorage) DeleteSavedSearch(ctx context.Context, savedSearchID uint64, userID uint64) error {
+	SQLDeleteSavedSearch := `DELETE FROM public."saved_search" WHERE id=$1 AND user_id=$2`
+
+	err := pgx.BeginFunc(ctx, p.pool, func(tx pgx.Tx) error {
+		result, err := tx.Exec(ctx, SQLDeleteSavedSearch, savedSearchID, userID)
+		if err != nil {
+			p.logger.Errorf("error with savedSearchID=%d: %+v", savedSearchID, err)
+
+			return fmt.Errorf(myerrors.ErrTemplate, err)
+		}
+
+		if result.RowsAffected() == 0 {
+			return fmt.Errorf(myerrors.ErrTemplate, ErrSavedSearchNotFound)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return nil
+}
+
+// MatchSavedSearches takes at most limit published products from queue and saves their matches with
+// saved searches. It returns number of taken products. Products taken by concurrent matcher are skipped.
+func (p *ProductStorage) MatchSavedSearches(ctx context.Context, limit uint64) (uint64, error) {
+	SQLTakeQueuedProducts := `DELETE FROM public."saved_search_queue" WHERE product_id IN (
+		SELECT product_id FROM public."saved_search_queue" ORDER BY queued_at LIMIT $1 FOR UPDATE SKIP LOCKED
+	) RETURNING product_id`
+
+	var productIDs []uint64
+
+	err := pgx.BeginFunc(ctx, p.pool, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, SQLTakeQueuedProducts, limit)
+		if err != nil {
+			p.logger.Errorln(err)
+
+			return fmt.Errorf(myerrors.ErrTemplate, err)
+		}
+
+		productIDs, err = pgx.CollectRows(rows, pgx.RowTo[uint64])
+		if err != nil {
+			p.logger.Errorln(err)
+
+			return fmt.Errorf(myerrors.ErrTemplate, err)
+		}
+
+		if len(productIDs) == 0 {
+			return nil
+		}
+
+		_, err = tx.Exec(ctx, SQLMatchSavedSearches, productIDs, models.EarthRadiusKm)
+		if err != nil {
+			p.logger.Errorln(err)
+
+			return fmt.Errorf(myerrors.ErrTemplate, err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return uint64(len(productIDs)), nil
+}
+
+// TakeSavedSearchDigests marks as notified matches of at most limit saved searches whose notify period
+// passed and returns digests of their products which are still active, with at most maxProducts newest ids.
+// Searches locked by concurrent call are skipped until next call.
+func (p *ProductStorage) TakeSavedSearchDigests(ctx context.Context, limit uint64, maxProducts uint64,
+) ([]*models.SavedSearchDigest, error) {
+	SQLSelectDueSavedSearches := `SELECT id, user_id, name, query FROM public."saved_search"
+		WHERE id IN (SELECT saved_search_id FROM public."saved_search_match" WHERE notified_at IS NULL)
+			AND (last_notified_at IS NULL OR last_notified_at <= NOW() - CASE notify
+				WHEN 'hourly' THEN INTERVAL '1 hour' WHEN 'daily' THEN INTERVAL '1 day' ELSE INTERVAL '0' END)
+		ORDER BY id LIMIT $1 FOR UPDATE SKIP LOCKED`
+	SQLNotifyMatches := `WITH notified AS (
+			UPDATE public."saved_search_match" SET notified_at = NOW()
+			WHERE saved_search_id = ANY($1) AND notified_at IS NULL RETURNING saved_search_id, product_id
+		)
+		SELECT notified.saved_search_id, COUNT(*),
+			(ARRAY_AGG(notified.product_id ORDER BY notified.product_id DESC))[1:$2::int]
+		FROM notified INNER JOIN public."product" product ON product.id = notified.product_id
+		WHERE product.status = 'active' GROUP BY notified.saved_search_id`
+	SQLUpdateLastNotifiedAt := `UPDATE public."saved_search" SET last_notified_at = NOW() WHERE id = ANY($1)`
+
+	var digests []*models.SavedSearchDigest
+
+	err := pgx.BeginFunc(ctx, p.pool, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, SQLSelectDueSavedSearches, limit)
+		if err != nil {
+			p.logger.Errorln(err)
+
+			return fmt.Errorf(myerrors.ErrTemplate, err)
+		}
+
+		dueSearches, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (*models.SavedSearchDigest, error) {
+			digest := &models.SavedSearchDigest{} //nolint:exhaustruct
+
+			err := row.Scan(&digest.SavedSearchID, &digest.UserID, &digest.Name, &digest.Query)
+
+			return digest, err //nolint:wrapcheck
+		})
+		if err != nil {
+			p.logger.Errorln(err)
+
+			return fmt.Errorf(myerrors.ErrTemplate, err)
+		}
+
+		if len(dueSearches) == 0 {
+			return nil
+		}
+
+		digestsByID := make(map[uint64]*models.SavedSearchDigest, len(dueSearches))
+		savedSearchIDs := make([]uint64, 0, len(dueSearches))
+
+		for _, digest := range dueSearches {
+			digestsByID[digest.SavedSearchID] = digest
+			savedSearchIDs = append(savedSearchIDs, digest.SavedSearchID)
+		}
+
+		rows, err = tx.Query(ctx, SQLNotifyMatches, savedSearchIDs, maxProducts)
+		if err != nil {
+			p.logger.Errorln(err)
+
+			return fmt.Errorf(myerrors.ErrTemplate, err)
+		}
+
+		digests, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (*models.SavedSearchDigest, error) {
+			var savedSearchID, count uint64
+
+			var productIDs []uint64
+
+			if err := row.Scan(&savedSearchID, &count, &productIDs); err != nil {
+				return nil, err //nolint:wrapcheck
+			}
+
+			digest := digestsByID[savedSearchID]
+			digest.Count, digest.ProductIDs = count, productIDs
+
+			return digest, nil
+		})
+		if err != nil {
+			p.logger.Errorln(err)
+
+			return fmt.Errorf(myerrors.ErrTemplate, err)
+		}
+
+		if len(digests) == 0 {
+			return nil
+		}
+
+		notifiedIDs := make([]uint64, 0, len(digests))
+		for _, digest := range digests {
+			notifiedIDs = append(notifiedIDs, digest.SavedSearchID)
+		}
+
+		// searches whose products are all sold or hidden wait for next products without delay
+		_, err = tx.Exec(ctx, SQLUpdateLastNotifiedAt, notifiedIDs)
+		if err != nil {
+			p.logger.Errorln(err)
+
+			return fmt.Errorf(myerrors.ErrTemplate, err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return digests, nil
+}
@@ -0,0 +1,34 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/SanExpett/marketplace-backend/pkg/models"
+)
+
+func TestAttributeFiltersToJsonPath(t *testing.T) {
+	jsonPath, err := attributeFiltersToJsonPath(nil)
+	if err != nil || jsonPath != nil {
+		t.Fatalf("expected nil path without filters, got %v, %v", jsonPath, err)
+	}
+
+	filters := []*models.AttributeFilter{
+		{Name: "year", Op: models.AttributeFilterGte, Value: "0x1p-2"},
+		{Name: "mileage", Op: models.AttributeFilterLte, Value: "1e5"},
+		{Name: "size", Op: models.AttributeFilterEq, Value: "1"},
+		{Name: "used", Op: models.AttributeFilterEq, Value: "true"},
+		{Name: "color", Op: models.AttributeFilterEq, Value: `red "dark"`},
+	}
+
+	want := `$."year" >= 0.25 && $."mileage" <= 100000 && ($."size" == "1" || $."size" == 1) && ` +
+		`($."used" == "true" || $."used" == true) && ($."color" == "red \"dark\"")`
+
+	jsonPath, err = attributeFiltersToJsonPath(filters)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if jsonPath == nil || *jsonPath != want {
+		t.Fatalf("got %v, want %s", jsonPath, want)
+	}
+}
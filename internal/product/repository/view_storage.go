@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"github.com/SanExpett/marketplace-backend/pkg/models"
+	myerrors "github.com/SanExpett/marketplace-backend/pkg/my_errors"
+	"github.com/jackc/pgx/v5"
+	"time"
+)
+
+// AddViews adds views of products for one UTC day in a single statement.
+func (p *ProductStorage) AddViews(ctx context.Context, day time.Time, views map[uint64]uint64) error {
+	SQLAddViews := `INSERT INTO public."product_view_daily"(product_id, day, views)
+		SELECT product_id, $1::date, views FROM unnest($2::bigint[], $3::bigint[]) AS v(product_id, views)
+		ON CONFLICT (product_id, day) DO UPDATE SET views = product_view_daily.views + EXCLUDED.views`
+
+	productIDs := make([]uint64, 0, len(views))
+	counts := make([]uint64, 0, len(views))
+
+	for productID, count := range views {
+		productIDs = append(productIDs, productID)
+		counts = append(counts, count)
+	}
+
+	_, err := p.pool.Exec(ctx, SQLAddViews, day.Format(time.DateOnly), productIDs, counts)
+	if err != nil {
+		p.logger.Errorln(err)
+
+		return fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return nil
+}
+
+func (p *ProductStorage) selectDailyViews(ctx context.Context, tx pgx.Tx, productID uint64, days uint64,
+) ([]*models.DailyViews, error) {
+	SQLSelectDailyViews := `SELECT to_char(d.day, 'YYYY-MM-DD'), COALESCE(v.views, 0)
+		FROM generate_series((now() AT TIME ZONE 'UTC')::date - ($2::int - 1), (now() AT TIME ZONE 'UTC')::date,
+			interval '1 day') AS d(day)
+		LEFT JOIN public."product_view_daily" v ON v.product_id = $1 AND v.day = d.day::date
+		ORDER BY d.day`
+
+	rowsViews, err := tx.Query(ctx, SQLSelectDailyViews, productID, days)
+	if err != nil {
+		p.logger.Errorln(err)
+
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	dailyViews, err := pgx.CollectRows(rowsViews, func(row pgx.CollectableRow) (*models.DailyViews, error) {
+		curViews := &models.DailyViews{} //nolint:exhaustruct
+
+		err := row.Scan(&curViews.Day, &curViews.Views)
+
+		return curViews, err //nolint:wrapcheck
+	})
+	if err != nil {
+		p.logger.Errorln(err)
+
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return dailyViews, nil
+}
+
+// GetDailyViews returns views for last days including today, days without views have zero.
+func (p *ProductStorage) GetDailyViews(ctx context.Context, productID uint64, days uint64,
+) ([]*models.DailyViews, error) {
+	var dailyViews []*models.DailyViews
+
+	err := pgx.BeginFunc(ctx, p.pool, func(tx pgx.Tx) error {
+		var err error
+
+		dailyViews, err = p.selectDailyViews(ctx, tx, productID, days)
+
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return dailyViews, nil
+}
@@ -0,0 +1,23 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+	myerrors "github.com/SanExpett/marketplace-backend/pkg/my_errors"
+)
+
+func (p *ProductService) AddFavorite(ctx context.Context, userID uint64, productID uint64) error {
+	if err := p.storage.AddFavorite(ctx, userID, productID); err != nil {
+		return fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return nil
+}
+
+func (p *ProductService) DeleteFavorite(ctx context.Context, userID uint64, productID uint64) error {
+	if err := p.storage.DeleteFavorite(ctx, userID, productID); err != nil {
+		return fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return nil
+}
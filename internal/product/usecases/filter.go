@@ -0,0 +1,247 @@
+package usecases
+
+import (
+	"fmt"
+	"github.com/SanExpett/marketplace-backend/pkg/models"
+	myerrors "github.com/SanExpett/marketplace-backend/pkg/my_errors"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	MessageErrWrongAttributeFilter = "Некорректный фильтр по характеристике %s"
+	MessageErrWrongRadius          = "Некорректный радиус: он должен быть больше 0 и не больше %d км"
+
+	maxLenSearchQuery = 256
+	defaultRadiusKm   = 25
+	maxRadiusKm       = 500
+)
+
+var (
+	ErrMinPriceGreaterMax    = myerrors.NewError("Минимальная цена не может быть больше максимальной")
+	ErrCreatedAfterNotBefore = myerrors.NewError("Начало периода создания должно быть раньше его конца")
+	ErrForbiddenStatusFilter = myerrors.NewError("Фильтровать можно только по статусам active, reserved и sold")
+	ErrMineWithoutAuth       = myerrors.NewError("Чтобы увидеть свои объявления, нужно авторизоваться")
+	ErrMineWithOtherSeller   = myerrors.NewError("Фильтр mine нельзя сочетать с чужим seller_id")
+	ErrUnknownStatusFilter   = myerrors.NewError("Неизвестный статус объявления")
+	ErrFavoritesWithoutAuth  = myerrors.NewError("Чтобы увидеть избранное, нужно авторизоваться")
+	ErrRadiusWithoutNear     = myerrors.NewError("Радиус поиска задаётся только вместе с параметром near")
+	ErrRelevanceWithoutQuery = myerrors.NewError("Сортировка по релевантности возможна только с поисковым запросом")
+	ErrDistanceWithoutNear   = myerrors.NewError("Сортировка по расстоянию возможна только с параметром near")
+	ErrSearchQueryTooLong    = myerrors.NewError("Поисковый запрос должен быть не длиннее %d символов",
+		maxLenSearchQuery)
+)
+
+var regexpAttributeName = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`) //nolint:gochecknoglobals
+
+func ValidateAttributeFilters(filters []*models.AttributeFilter) error {
+	for _, filter := range filters {
+		if !regexpAttributeName.MatchString(filter.Name) {
+			return myerrors.NewError(MessageErrWrongAttributeFilter, filter.Name)
+		}
+
+		if filter.Op == models.AttributeFilterGte || filter.Op == models.AttributeFilterLte {
+			number, err := strconv.ParseFloat(filter.Value, 64)
+			if err != nil || math.IsInf(number, 0) || math.IsNaN(number) {
+				return myerrors.NewError(MessageErrWrongAttributeFilter, filter.Name)
+			}
+		}
+	}
+
+	return nil
+}
+
+// ValidateSearchQuery trims query, empty result means search is not requested.
+func ValidateSearchQuery(searchQuery string) (string, error) {
+	searchQuery = strings.TrimFunc(searchQuery, unicode.IsSpace)
+	if utf8.RuneCountInString(searchQuery) > maxLenSearchQuery {
+		return "", fmt.Errorf(myerrors.ErrTemplate, ErrSearchQueryTooLong)
+	}
+
+	return searchQuery, nil
+}
+
+// ValidateSortSpec chooses default sort for empty spec: by relevance for search, by distance for near
+// and by date desc otherwise.
+func ValidateSortSpec(sortSpec models.SortSpec, filter *models.ProductFilter) (models.SortSpec, error) {
+	if len(sortSpec) == 0 {
+		if filter.SearchQuery != "" {
+			return models.SortSpec{{Field: models.SortFieldRelevance, Desc: true}}, nil
+		}
+
+		if filter.Near != nil {
+			return models.SortSpec{{Field: models.SortFieldDistance, Desc: false}}, nil
+		}
+
+		return models.SortSpec{{Field: models.SortFieldCreatedAt, Desc: true}}, nil
+	}
+
+	if sortSpec.Has(models.SortFieldRelevance) && filter.SearchQuery == "" {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, ErrRelevanceWithoutQuery)
+	}
+
+	if sortSpec.Has(models.SortFieldDistance) && filter.Near == nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, ErrDistanceWithoutNear)
+	}
+
+	return sortSpec, nil
+}
+
+//nolint:gochecknoglobals
+var (
+	publicProductStatuses = []string{
+		models.ProductStatusActive, models.ProductStatusReserved, models.ProductStatusSold,
+	}
+	allProductStatuses = []string{
+		models.ProductStatusDraft, models.ProductStatusActive, models.ProductStatusReserved, models.ProductStatusSold,
+	}
+	// ownProductStatuses are visible to saler, hidden and on_review statuses are set only by moderation
+	ownProductStatuses = append(slices.Clone(allProductStatuses), models.ProductStatusHidden,
+		models.ProductStatusOnReview)
+)
+
+// validateStatuses sets default statuses: all for own products, public for favorites and active for others.
+// Drafts, hidden products and products on review are visible only to owner.
+func validateStatuses(filter *models.ProductFilter) error {
+	if filter.Mine {
+		if len(filter.Statuses) == 0 {
+			filter.Statuses = ownProductStatuses
+		}
+
+		for _, status := range filter.Statuses {
+			if !slices.Contains(ownProductStatuses, status) {
+				return fmt.Errorf(myerrors.ErrTemplate, ErrUnknownStatusFilter)
+			}
+		}
+
+		return nil
+	}
+
+	if len(filter.Statuses) == 0 {
+		filter.Statuses = []string{models.ProductStatusActive}
+
+		// reserved and sold products stay in favorites
+		if filter.Favorites {
+			filter.Statuses = publicProductStatuses
+		}
+	}
+
+	for _, status := range filter.Statuses {
+		if !slices.Contains(publicProductStatuses, status) {
+			return fmt.Errorf(myerrors.ErrTemplate, ErrForbiddenStatusFilter)
+		}
+	}
+
+	return nil
+}
+
+// validatePriceFilter sets defaultCurrency for price range without currency: prices are compared
+// only within one currency.
+func validatePriceFilter(filter *models.ProductFilter, defaultCurrency string) error {
+	filter.Currency = models.NormalizeCurrency(filter.Currency)
+
+	if filter.Currency == "" && (filter.MinPrice != nil || filter.MaxPrice != nil) {
+		filter.Currency = defaultCurrency
+	}
+
+	if filter.Currency != "" {
+		if err := models.ValidateCurrency(filter.Currency); err != nil {
+			return err
+		}
+	}
+
+	for _, bound := range []*uint64{filter.MinPrice, filter.MaxPrice} {
+		if bound == nil {
+			continue
+		}
+
+		if err := models.ValidateAmount(*bound); err != nil {
+			return err
+		}
+	}
+
+	if filter.MinPrice != nil && filter.MaxPrice != nil && *filter.MinPrice > *filter.MaxPrice {
+		return fmt.Errorf(myerrors.ErrTemplate, ErrMinPriceGreaterMax)
+	}
+
+	return nil
+}
+
+// validateNearFilter sets default radius for near filter.
+func validateNearFilter(filter *models.ProductFilter) error {
+	if filter.Near == nil {
+		if filter.RadiusKm != 0 {
+			return fmt.Errorf(myerrors.ErrTemplate, ErrRadiusWithoutNear)
+		}
+
+		return nil
+	}
+
+	if err := filter.Near.Validate(); err != nil {
+		return err
+	}
+
+	if filter.RadiusKm == 0 {
+		filter.RadiusKm = defaultRadiusKm
+	}
+
+	if filter.RadiusKm < 0 || filter.RadiusKm > maxRadiusKm {
+		return myerrors.NewError(MessageErrWrongRadius, maxRadiusKm)
+	}
+
+	return nil
+}
+
+// ValidateProductFilter normalizes filter in place: trims search query, sets default statuses, currency,
+// seller for mine filter and owner of favorites of user with userID.
+func ValidateProductFilter(filter *models.ProductFilter, userID uint64, defaultCurrency string) error {
+	if err := validatePriceFilter(filter, defaultCurrency); err != nil {
+		return err
+	}
+
+	if err := validateNearFilter(filter); err != nil {
+		return err
+	}
+
+	if filter.CreatedAfter != nil && filter.CreatedBefore != nil && !filter.CreatedAfter.Before(*filter.CreatedBefore) {
+		return fmt.Errorf(myerrors.ErrTemplate, ErrCreatedAfterNotBefore)
+	}
+
+	if filter.Mine {
+		if userID == 0 {
+			return fmt.Errorf(myerrors.ErrTemplate, ErrMineWithoutAuth)
+		}
+
+		if filter.SalerID != 0 && filter.SalerID != userID {
+			return fmt.Errorf(myerrors.ErrTemplate, ErrMineWithOtherSeller)
+		}
+
+		filter.SalerID = userID
+	}
+
+	if filter.Favorites {
+		if userID == 0 {
+			return fmt.Errorf(myerrors.ErrTemplate, ErrFavoritesWithoutAuth)
+		}
+
+		filter.FavoritesOf = userID
+	}
+
+	if err := validateStatuses(filter); err != nil {
+		return err
+	}
+
+	searchQuery, err := ValidateSearchQuery(filter.SearchQuery)
+	if err != nil {
+		return err
+	}
+
+	filter.SearchQuery = searchQuery
+
+	return ValidateAttributeFilters(filter.Attributes)
+}
@@ -0,0 +1,142 @@
+package usecases
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/SanExpett/marketplace-backend/pkg/models"
+)
+
+func TestValidateAttributeFilters(t *testing.T) {
+	valid := []*models.AttributeFilter{
+		{Name: "year", Op: models.AttributeFilterGte, Value: "0x1p-2"},
+		{Name: "size", Op: models.AttributeFilterEq, Value: "1"},
+	}
+	if err := ValidateAttributeFilters(valid); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, filter := range []*models.AttributeFilter{
+		{Name: "Year", Op: models.AttributeFilterEq, Value: "1"},
+		{Name: "year", Op: models.AttributeFilterGte, Value: "abc"},
+		{Name: "year", Op: models.AttributeFilterLte, Value: "NaN"},
+		{Name: "year", Op: models.AttributeFilterLte, Value: "Inf"},
+	} {
+		if err := ValidateAttributeFilters([]*models.AttributeFilter{filter}); err == nil {
+			t.Errorf("expected error for %+v", filter)
+		}
+	}
+}
+
+func TestValidateSortSpec(t *testing.T) {
+	near := &models.GeoPoint{Latitude: 55.75, Longitude: 37.62}
+
+	tests := []struct {
+		name     string
+		sortSpec models.SortSpec
+		filter   *models.ProductFilter
+		want     models.SortSpec
+	}{
+		{"newest by default", models.SortSpec{}, &models.ProductFilter{},
+			models.SortSpec{{Field: models.SortFieldCreatedAt, Desc: true}}},
+		{"relevance with query", models.SortSpec{}, &models.ProductFilter{SearchQuery: "bike"},
+			models.SortSpec{{Field: models.SortFieldRelevance, Desc: true}}},
+		{"distance with near", models.SortSpec{}, &models.ProductFilter{Near: near},
+			models.SortSpec{{Field: models.SortFieldDistance, Desc: false}}},
+		{"explicit sort is kept", models.SortSpec{{Field: models.SortFieldPrice, Desc: true}},
+			&models.ProductFilter{SearchQuery: "bike"}, models.SortSpec{{Field: models.SortFieldPrice, Desc: true}}},
+	}
+
+	for _, test := range tests {
+		got, err := ValidateSortSpec(test.sortSpec, test.filter)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", test.name, err)
+
+			continue
+		}
+
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got %v, want %v", test.name, got, test.want)
+		}
+	}
+
+	if _, err := ValidateSortSpec(models.SortSpec{{Field: models.SortFieldRelevance, Desc: true}},
+		&models.ProductFilter{}); !errors.Is(err, ErrRelevanceWithoutQuery) {
+		t.Errorf("expected ErrRelevanceWithoutQuery, got %v", err)
+	}
+
+	if _, err := ValidateSortSpec(models.SortSpec{{Field: models.SortFieldDistance, Desc: false}},
+		&models.ProductFilter{}); !errors.Is(err, ErrDistanceWithoutNear) {
+		t.Errorf("expected ErrDistanceWithoutNear, got %v", err)
+	}
+}
+
+func TestValidateProductFilter(t *testing.T) {
+	minPrice := uint64(100)
+
+	filter := &models.ProductFilter{SearchQuery: "  bike  ", MinPrice: &minPrice, Mine: true} //nolint:exhaustruct
+	if err := ValidateProductFilter(filter, 7, "RUB"); err != nil {
+		t.Fatal(err)
+	}
+
+	if filter.SearchQuery != "bike" || filter.Currency != "RUB" || filter.SalerID != 7 ||
+		!reflect.DeepEqual(filter.Statuses, ownProductStatuses) {
+		t.Fatalf("unexpected normalized filter %+v", filter)
+	}
+
+	filter = &models.ProductFilter{Favorites: true} //nolint:exhaustruct
+	if err := ValidateProductFilter(filter, 7, "RUB"); err != nil {
+		t.Fatal(err)
+	}
+
+	if filter.FavoritesOf != 7 || !reflect.DeepEqual(filter.Statuses, publicProductStatuses) {
+		t.Fatalf("unexpected normalized filter %+v", filter)
+	}
+
+	filter = &models.ProductFilter{Near: &models.GeoPoint{Latitude: 55.75, Longitude: 37.62}} //nolint:exhaustruct
+	if err := ValidateProductFilter(filter, 0, "RUB"); err != nil {
+		t.Fatal(err)
+	}
+
+	if filter.RadiusKm != defaultRadiusKm || !reflect.DeepEqual(filter.Statuses, []string{models.ProductStatusActive}) {
+		t.Fatalf("unexpected normalized filter %+v", filter)
+	}
+}
+
+func TestValidateProductFilterErrors(t *testing.T) {
+	minPrice, maxPrice := uint64(500), uint64(100)
+	createdAfter := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	near := &models.GeoPoint{Latitude: 55.75, Longitude: 37.62}
+
+	tests := []struct {
+		name   string
+		filter *models.ProductFilter
+		userID uint64
+		want   error
+	}{
+		{"min greater max", &models.ProductFilter{MinPrice: &minPrice, MaxPrice: &maxPrice}, 0, ErrMinPriceGreaterMax},
+		{"empty created period", &models.ProductFilter{CreatedAfter: &createdAfter, CreatedBefore: &createdAfter}, 0,
+			ErrCreatedAfterNotBefore},
+		{"radius without near", &models.ProductFilter{RadiusKm: 5}, 0, ErrRadiusWithoutNear},
+		{"mine without auth", &models.ProductFilter{Mine: true}, 0, ErrMineWithoutAuth},
+		{"mine with other seller", &models.ProductFilter{Mine: true, SalerID: 8}, 7, ErrMineWithOtherSeller},
+		{"favorites without auth", &models.ProductFilter{Favorites: true}, 0, ErrFavoritesWithoutAuth},
+		{"draft in feed", &models.ProductFilter{Statuses: []string{models.ProductStatusDraft}}, 7,
+			ErrForbiddenStatusFilter},
+		{"unknown own status", &models.ProductFilter{Mine: true, Statuses: []string{"lost"}}, 7,
+			ErrUnknownStatusFilter},
+	}
+
+	for _, test := range tests {
+		if err := ValidateProductFilter(test.filter, test.userID, "RUB"); !errors.Is(err, test.want) {
+			t.Errorf("%s: expected %v, got %v", test.name, test.want, err)
+		}
+	}
+
+	filter := &models.ProductFilter{Near: near, RadiusKm: maxRadiusKm + 1} //nolint:exhaustruct
+	if err := ValidateProductFilter(filter, 0, "RUB"); err == nil {
+		t.Error("expected error for too large radius")
+	}
+}
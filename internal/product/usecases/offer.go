@@ -0,0 +1,166 @@
+package usecases
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/SanExpett/marketplace-backend/pkg/models"
+	myerrors "github.com/SanExpett/marketplace-backend/pkg/my_errors"
+	"github.com/SanExpett/marketplace-backend/pkg/utils"
+	"io"
+	"time"
+)
+
+const expireOffersBatch = 1000
+
+var ErrDecodePreOffer = myerrors.NewError("Некорректный json предложения цены")
+
+// ValidatePreOffer allows empty currency, storage sets currency of product or offer.
+func ValidatePreOffer(r io.Reader) (*models.PreOffer, error) {
+	preOffer := &models.PreOffer{} //nolint:exhaustruct
+	if err := json.NewDecoder(r).Decode(preOffer); err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, ErrDecodePreOffer)
+	}
+
+	if err := validatePrice(&preOffer.Price); err != nil {
+		return nil, err
+	}
+
+	return preOffer, nil
+}
+
+func (p *ProductService) AddOffer(ctx context.Context, r io.Reader, productID uint64, userID uint64,
+) (*models.Offer, error) {
+	preOffer, err := ValidatePreOffer(r)
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	offer, err := p.storage.AddOffer(ctx, productID, userID, preOffer)
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	p.publishOffer(ctx, offer, offer.SalerID)
+
+	return offer, nil
+}
+
+func (p *ProductService) transitOffer(ctx context.Context, offerID uint64, userID uint64, action string,
+	counterPrice *models.Money,
+) (*models.Offer, error) {
+	offer, err := p.storage.TransitOffer(ctx, offerID, userID, action, counterPrice)
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	counterpartyID := offer.SalerID
+	if userID == offer.SalerID {
+		counterpartyID = offer.BuyerID
+	}
+
+	p.publishOffer(ctx, offer, counterpartyID)
+
+	if offer.Status == models.OfferStatusAccepted {
+		p.publishStatusChange(ctx, offer.ProductID, models.ProductStatusReserved)
+	}
+
+	return offer, nil
+}
+
+// AcceptOffer is done by seller for pending offer and by buyer for counter offer, product becomes reserved.
+func (p *ProductService) AcceptOffer(ctx context.Context, offerID uint64, userID uint64) (*models.Offer, error) {
+	return p.transitOffer(ctx, offerID, userID, models.OfferActionAccept, nil)
+}
+
+// DeclineOffer is done by seller for pending offer and by buyer for counter offer.
+func (p *ProductService) DeclineOffer(ctx context.Context, offerID uint64, userID uint64) (*models.Offer, error) {
+	return p.transitOffer(ctx, offerID, userID, models.OfferActionDecline, nil)
+}
+
+// WithdrawOffer is done by buyer for open offer.
+func (p *ProductService) WithdrawOffer(ctx context.Context, offerID uint64, userID uint64) (*models.Offer, error) {
+	return p.transitOffer(ctx, offerID, userID, models.OfferActionWithdraw, nil)
+}
+
+// CounterOffer is done by seller for pending offer, counter price must be bigger than price of offer.
+func (p *ProductService) CounterOffer(ctx context.Context, r io.Reader, offerID uint64, userID uint64,
+) (*models.Offer, error) {
+	preOffer, err := ValidatePreOffer(r)
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return p.transitOffer(ctx, offerID, userID, models.OfferActionCounter, &preOffer.Price)
+}
+
+func (p *ProductService) GetOffers(ctx context.Context, userID uint64, productID uint64, limit uint64,
+	offset uint64,
+) ([]*models.Offer, error) {
+	limit, err := utils.ValidateLimit(limit, defaultPageLimit, p.config.maxPageLimit)
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	offers, err := p.storage.GetOffers(ctx, userID, productID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return offers, nil
+}
+
+// publishOffer doesn't fail action with offer: it is saved and users see it on next fetch anyway.
+func (p *ProductService) publishOffer(ctx context.Context, offer *models.Offer, recipients ...uint64) {
+	if p.publisher == nil {
+		return
+	}
+
+	event, err := models.NewEvent(models.EventTypeOffer, offer)
+	if err == nil {
+		err = p.publisher.Publish(ctx, event, recipients...)
+	}
+
+	if err != nil {
+		p.logger.Errorf("offer %d is not published: %+v", offer.ID, err)
+	}
+}
+
+func (p *ProductService) expireOffers(ctx context.Context) {
+	for {
+		offers, productIDs, err := p.storage.ExpireOffers(ctx, expireOffersBatch)
+		if err != nil {
+			p.logger.Errorf("error in expiring of offers: %+v", err)
+
+			return
+		}
+
+		for _, offer := range offers {
+			p.publishOffer(ctx, offer, offer.BuyerID, offer.SalerID)
+		}
+
+		for _, productID := range productIDs {
+			p.publishStatusChange(ctx, productID, models.ProductStatusActive)
+		}
+
+		if len(offers) < expireOffersBatch {
+			return
+		}
+	}
+}
+
+// RunOfferExpiry periodically expires offers which weren't answered in time and accepted offers which
+// weren't bought in time, until ctx is done.
+func (p *ProductService) RunOfferExpiry(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			p.expireOffers(ctx)
+		}
+	}
+}
@@ -4,72 +4,538 @@ import (
 	"context"
 	"fmt"
 	productrepo "github.com/SanExpett/marketplace-backend/internal/product/repository"
+	"github.com/SanExpett/marketplace-backend/pkg/cursor"
+	"github.com/SanExpett/marketplace-backend/pkg/event_hub"
+	"github.com/SanExpett/marketplace-backend/pkg/image_loader"
 	"github.com/SanExpett/marketplace-backend/pkg/models"
 	myerrors "github.com/SanExpett/marketplace-backend/pkg/my_errors"
 	"github.com/SanExpett/marketplace-backend/pkg/my_logger"
+	"github.com/SanExpett/marketplace-backend/pkg/payment"
+	"github.com/SanExpett/marketplace-backend/pkg/premoderation"
+	"github.com/SanExpett/marketplace-backend/pkg/utils"
+	"github.com/SanExpett/marketplace-backend/pkg/view_counter"
 	"go.uber.org/zap"
 	"io"
 )
 
+const maxSuggestionsLimit = 10
+
+const MessageErrProductRejected = "Объявление отклонено: %s"
+
 var _ IProductStorage = (*productrepo.ProductStorage)(nil)
 
 type IProductStorage interface {
 	AddProduct(ctx context.Context, preProduct *models.PreProduct) (*models.Product, error)
 	GetProduct(ctx context.Context, productID uint64, userID uint64) (*models.ProductWithIsMy, error)
-	GetProductsList(ctx context.Context, limit uint64, offset uint64, sortType uint64, minPrice uint64,
-		maxPrice uint64, userID uint64) ([]*models.ProductWithIsMy, error)
+	GetProductsList(ctx context.Context, filter *models.ProductFilter, sortSpec models.SortSpec, limit uint64,
+		offset uint64, cursor *models.ProductCursor, userID uint64) ([]*models.ProductWithIsMy, error)
+	GetFacets(ctx context.Context, filter *models.ProductFilter) ([]*models.Facet, error)
+	GetCategoryAttributes(ctx context.Context, categoryID uint64) ([]*models.CategoryAttribute, error)
+	GetSuggestions(ctx context.Context, searchQuery string, limit uint64) (*models.Suggestions, error)
+	CountProducts(ctx context.Context, filter *models.ProductFilter, estimateThreshold uint64) (uint64, bool, error)
+	AddFavorite(ctx context.Context, userID uint64, productID uint64) error
+	DeleteFavorite(ctx context.Context, userID uint64, productID uint64) error
+	GetDailyViews(ctx context.Context, productID uint64, days uint64) ([]*models.DailyViews, error)
+	UpdateProduct(ctx context.Context, productID uint64, userID uint64,
+		partialProduct *models.PartialProduct) (*models.ProductWithIsMy, error)
+	GetPriceHistory(ctx context.Context, productID uint64, userID uint64) ([]*models.PriceChange, error)
+	GetFavoritedUserIDs(ctx context.Context, productID uint64) ([]uint64, error)
+	AddOffer(ctx context.Context, productID uint64, buyerID uint64, preOffer *models.PreOffer) (*models.Offer, error)
+	TransitOffer(ctx context.Context, offerID uint64, userID uint64, action string,
+		counterPrice *models.Money) (*models.Offer, error)
+	ExpireOffers(ctx context.Context, limit uint64) ([]*models.Offer, []uint64, error)
+	GetOffers(ctx context.Context, userID uint64, productID uint64, limit uint64,
+		offset uint64) ([]*models.Offer, error)
+	GetPromotedProducts(ctx context.Context, filter *models.ProductFilter, limit uint64, offset uint64,
+		userID uint64) ([]*models.ProductWithIsMy, error)
+	GrantPromotion(ctx context.Context, productID uint64, moderatorID uint64,
+		prePromotion *models.PrePromotion) (*models.Promotion, error)
+	StartPromotionPayment(ctx context.Context, productID uint64, userID uint64, prePromotion *models.PrePromotion,
+		price models.Money) (*models.Promotion, error)
+	ApplyPromotionPayment(ctx context.Context, payment *models.Payment) (*models.Promotion, error)
+	GetPromotions(ctx context.Context, userID uint64, productID uint64, limit uint64,
+		offset uint64) ([]*models.Promotion, error)
+	AddSavedSearch(ctx context.Context, userID uint64, preSavedSearch *models.PreSavedSearch,
+		maxSavedSearches uint64) (*models.SavedSearch, error)
+	GetSavedSearches(ctx context.Context, userID uint64, limit uint64, offset uint64) ([]*models.SavedSearch, error)
+	DeleteSavedSearch(ctx context.Context, savedSearchID uint64, userID uint64) error
+	MatchSavedSearches(ctx context.Context, limit uint64) (uint64, error)
+	TakeSavedSearchDigests(ctx context.Context, limit uint64,
+		maxProducts uint64) ([]*models.SavedSearchDigest, error)
+}
+
+var _ IImageLoader = (*image_loader.ImageLoader)(nil)
+
+type IImageLoader interface {
+	Load(ctx context.Context, rawURL string) (string, uint64, error)
+}
+
+var _ IViewCounter = (*view_counter.ViewCounter)(nil)
+
+type IViewCounter interface {
+	Record(productID uint64, viewerKey string)
+}
+
+var _ IEventPublisher = (*event_hub.EventHub)(nil)
+
+type IEventPublisher interface {
+	Publish(ctx context.Context, event *models.Event, recipients ...uint64) error
+}
+
+var _ IPremoderator = (*premoderation.Pipeline)(nil)
+
+type IPremoderator interface {
+	Check(ctx context.Context, preProduct *models.PreProduct, isNew bool) (*models.Verdict, error)
+}
+
+var _ IPaymentProvider = (*payment.FakeProvider)(nil)
+
+// IPaymentProvider charges sellers for promotions. Charges with the same idempotency key must return the same
+// payment. Result of pending payment is passed to HandlePromotionPaymentWebhook.
+type IPaymentProvider interface {
+	Charge(ctx context.Context, target models.PaymentTarget, amount models.Money,
+		idempotencyKey string) (*models.Payment, error)
+}
+
+type ConfigProductService struct {
+	cursorSecret           []byte
+	maxPageLimit           uint64
+	estimateCountThreshold uint64
+	// defaultCurrency is used for prices and price filters without currency
+	defaultCurrency string
+	// promotedSlots are positions on page of default feed from 0 which are taken by promoted products
+	promotedSlots    []uint64
+	maxPromotionDays uint64
+	// promotionDayPrice is price of one started day of promotion in minor units of defaultCurrency
+	promotionDayPrice uint64
+	maxSavedSearches  uint64
+}
+
+func NewConfigProductService(cursorSecret []byte, maxPageLimit uint64, estimateCountThreshold uint64,
+	defaultCurrency string, promotedSlots []uint64, maxPromotionDays uint64, promotionDayPrice uint64,
+	maxSavedSearches uint64,
+) *ConfigProductService {
+	return &ConfigProductService{
+		cursorSecret:           cursorSecret,
+		maxPageLimit:           maxPageLimit,
+		estimateCountThreshold: estimateCountThreshold,
+		defaultCurrency:        defaultCurrency,
+		promotedSlots:          promotedSlots,
+		maxPromotionDays:       maxPromotionDays,
+		promotionDayPrice:      promotionDayPrice,
+		maxSavedSearches:       maxSavedSearches,
+	}
 }
 
 type ProductService struct {
-	storage IProductStorage
-	logger  *zap.SugaredLogger
+	storage         IProductStorage
+	imageLoader     IImageLoader
+	viewCounter     IViewCounter
+	publisher       IEventPublisher
+	premoderator    IPremoderator
+	paymentProvider IPaymentProvider
+	config          *ConfigProductService
+	logger          *zap.SugaredLogger
 }
 
-func NewProductService(productStorage IProductStorage) (*ProductService, error) {
+// NewProductService creates service. imageLoader can be nil, then image urls are stored as is.
+// viewCounter can be nil, then views are not recorded. publisher can be nil, then changes of status
+// are not pushed to users who have product in favorites. premoderator can be nil, then products are not checked.
+func NewProductService(productStorage IProductStorage, imageLoader IImageLoader, viewCounter IViewCounter,
+	publisher IEventPublisher, premoderator IPremoderator, paymentProvider IPaymentProvider,
+	config *ConfigProductService,
+) (*ProductService, error) {
 	logger, err := my_logger.Get()
 	if err != nil {
 		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
 	}
 
-	return &ProductService{storage: productStorage, logger: logger}, nil
+	return &ProductService{
+		storage:         productStorage,
+		imageLoader:     imageLoader,
+		viewCounter:     viewCounter,
+		publisher:       publisher,
+		premoderator:    premoderator,
+		paymentProvider: paymentProvider,
+		config:          config,
+		logger:          logger,
+	}, nil
 }
 
 func (p *ProductService) AddProduct(ctx context.Context, r io.Reader, userID uint64) (*models.Product, error) {
-	preProduct, err := ValidatePreProduct(r, userID)
+	preProduct, err := ValidatePreProduct(r, userID, p.config.defaultCurrency)
 	if err != nil {
 		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
 	}
 
+	schema, err := p.storage.GetCategoryAttributes(ctx, preProduct.CategoryID)
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	preProduct.Attributes, err = ValidateAttributes(preProduct.Attributes, schema)
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	// image is ingested before premoderation: its hash is needed to find duplicates
+	if p.imageLoader != nil && preProduct.ImageUrl != "" {
+		preProduct.ImageUrl, preProduct.ImageHash, err = p.imageLoader.Load(ctx, preProduct.ImageUrl)
+		if err != nil {
+			return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+		}
+	}
+
+	verdict, err := p.premoderate(ctx, preProduct, true)
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	if verdict.Outcome == models.VerdictReview {
+		preProduct.Status = models.ProductStatusOnReview
+		preProduct.ReviewReason = verdict.Reason()
+	}
+
 	product, err := p.storage.AddProduct(ctx, preProduct)
 	if err != nil {
 		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
 	}
 
+	product.Warnings = verdict.Warnings
+
 	return product, nil
 }
 
-func (p *ProductService) GetProduct(ctx context.Context, productID uint64, userID uint64) (*models.ProductWithIsMy, error) {
+// premoderate returns error with reason for rejected product. Without premoderator everything is accepted.
+func (p *ProductService) premoderate(ctx context.Context, preProduct *models.PreProduct, isNew bool,
+) (*models.Verdict, error) {
+	if p.premoderator == nil {
+		return models.NewVerdict(models.VerdictAccept), nil
+	}
+
+	verdict, err := p.premoderator.Check(ctx, preProduct, isNew)
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	if verdict.Outcome == models.VerdictReject {
+		return nil, myerrors.NewError(MessageErrProductRejected, verdict.Reason())
+	}
+
+	return verdict, nil
+}
+
+// premoderateEdit checks product as it will be after edit. Product which would get to feed is sent to review
+// instead, other statuses are kept: drafts are checked again when they are published.
+func (p *ProductService) premoderateEdit(ctx context.Context, productID uint64, userID uint64,
+	partialProduct *models.PartialProduct,
+) error {
+	if p.premoderator == nil {
+		return nil
+	}
+
 	product, err := p.storage.GetProduct(ctx, productID, userID)
+	if err != nil {
+		return fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	if product.SalerID != userID {
+		return fmt.Errorf(myerrors.ErrTemplate, productrepo.ErrNotMyProduct)
+	}
+
+	preProduct := &models.PreProduct{ //nolint:exhaustruct
+		ID:          productID,
+		SalerID:     product.SalerID,
+		CategoryID:  product.CategoryID,
+		Title:       product.Title,
+		Description: product.Description,
+		Price:       product.Price,
+		ImageHash:   partialProduct.ImageHash,
+	}
+
+	status := product.Status
+
+	if partialProduct.Title != nil {
+		preProduct.Title = *partialProduct.Title
+	}
+
+	if partialProduct.Description != nil {
+		preProduct.Description = *partialProduct.Description
+	}
+
+	if partialProduct.Price != nil {
+		preProduct.Price = *partialProduct.Price
+		if preProduct.Price.Currency == "" {
+			preProduct.Price.Currency = product.Price.Currency
+		}
+	}
+
+	if partialProduct.Status != nil {
+		status = *partialProduct.Status
+	}
+
+	verdict, err := p.premoderate(ctx, preProduct, false)
+	if err != nil {
+		return err
+	}
+
+	if verdict.Outcome == models.VerdictReview && status == models.ProductStatusActive {
+		onReview := models.ProductStatusOnReview
+		partialProduct.Status = &onReview
+		partialProduct.ReviewReason = verdict.Reason()
+	}
+
+	return nil
+}
+
+// UpdateProduct applies partial edit of seller. New image url is ingested like in AddProduct.
+func (p *ProductService) UpdateProduct(ctx context.Context, r io.Reader, productID uint64, userID uint64,
+) (*models.ProductWithIsMy, error) {
+	partialProduct, err := ValidatePartialProduct(r)
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	// image is ingested before premoderation: its hash is needed to find duplicates
+	if p.imageLoader != nil && partialProduct.ImageUrl != nil && *partialProduct.ImageUrl != "" {
+		imageURL, imageHash, err := p.imageLoader.Load(ctx, *partialProduct.ImageUrl)
+		if err != nil {
+			return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+		}
+
+		partialProduct.ImageUrl = &imageURL
+		partialProduct.ImageHash = imageHash
+	}
+
+	if err := p.premoderateEdit(ctx, productID, userID, partialProduct); err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	product, err := p.storage.UpdateProduct(ctx, productID, userID, partialProduct)
 	if err != nil {
 		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
 	}
 
+	if partialProduct.Status != nil {
+		p.publishStatusChange(ctx, productID, *partialProduct.Status)
+	}
+
 	product.Sanitize()
 
 	return product, nil
 }
 
-func (p *ProductService) GetProductsList(ctx context.Context, limit uint64, offset uint64, sortType uint64,
-	minPrice uint64, maxPrice uint64, userID uint64,
-) ([]*models.ProductWithIsMy, error) {
-	products, err := p.storage.GetProductsList(ctx, limit, offset, sortType, minPrice, maxPrice, userID)
+// publishStatusChange doesn't fail update: status is saved and users see it on next fetch anyway.
+func (p *ProductService) publishStatusChange(ctx context.Context, productID uint64, status string) {
+	if p.publisher == nil {
+		return
+	}
+
+	userIDs, err := p.storage.GetFavoritedUserIDs(ctx, productID)
+	if err != nil {
+		p.logger.Errorf("status change of product %d is not published: %+v", productID, err)
+
+		return
+	}
+
+	event, err := models.NewEvent(models.EventTypeProductStatus,
+		&models.ProductStatusChange{ProductID: productID, Status: status})
+	if err == nil {
+		err = p.publisher.Publish(ctx, event, userIDs...)
+	}
+
+	if err != nil {
+		p.logger.Errorf("status change of product %d is not published: %+v", productID, err)
+	}
+}
+
+func (p *ProductService) GetPriceHistory(ctx context.Context, productID uint64, userID uint64,
+) ([]*models.PriceChange, error) {
+	priceHistory, err := p.storage.GetPriceHistory(ctx, productID, userID)
 	if err != nil {
 		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
 	}
 
+	return priceHistory, nil
+}
+
+func (p *ProductService) GetProduct(ctx context.Context, productID uint64, userID uint64) (*models.ProductWithIsMy, error) {
+	product, err := p.storage.GetProduct(ctx, productID, userID)
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	product.Sanitize()
+
+	return product, nil
+}
+
+func (p *ProductService) decodeCursor(rawCursor string, sortSpec models.SortSpec) (*models.ProductCursor, error) {
+	if rawCursor == "" {
+		return nil, nil //nolint:nilnil
+	}
+
+	productCursor := &models.ProductCursor{} //nolint:exhaustruct
+
+	if err := cursor.Decode(rawCursor, p.config.cursorSecret, productCursor); err != nil {
+		p.logger.Errorln(err)
+
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	if productCursor.Sort != sortSpec.String() {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, cursor.ErrInvalidCursor)
+	}
+
+	return productCursor, nil
+}
+
+func newProductCursor(product *models.ProductWithIsMy, sortSpec models.SortSpec) *models.ProductCursor {
+	productCursor := &models.ProductCursor{ //nolint:exhaustruct
+		Sort:      sortSpec.String(),
+		Price:     product.SortPrice,
+		CreatedAt: product.CreatedAt,
+		Rank:      product.Rank,
+		ID:        product.ID,
+	}
+
+	if product.Distance != nil {
+		productCursor.Distance = *product.Distance
+	}
+
+	return productCursor
+}
+
+func (p *ProductService) encodeCursor(productCursor *models.ProductCursor) (string, error) {
+	rawCursor, err := cursor.Encode(productCursor, p.config.cursorSecret)
+	if err != nil {
+		p.logger.Errorln(err)
+
+		return "", fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return rawCursor, nil
+}
+
+// GetProductsList returns page of products and its metadata with cursor of next page.
+// Offset is legacy and used only without cursor. Default feed has promoted products in configured slots.
+func (p *ProductService) GetProductsList(ctx context.Context, filter *models.ProductFilter,
+	sortSpec models.SortSpec, limit uint64, offset uint64, rawCursor string, userID uint64,
+) ([]*models.ProductWithIsMy, *models.PageInfo, error) {
+	limit, err := utils.ValidateLimit(limit, defaultPageLimit, p.config.maxPageLimit)
+	if err != nil {
+		return nil, nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	if err := ValidateProductFilter(filter, userID, p.config.defaultCurrency); err != nil {
+		return nil, nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	isDefaultSort := len(sortSpec) == 0
+
+	sortSpec, err = ValidateSortSpec(sortSpec, filter)
+	if err != nil {
+		return nil, nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	productCursor, err := p.decodeCursor(rawCursor, sortSpec)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pageInfo := &models.PageInfo{Limit: limit, Cursor: rawCursor} //nolint:exhaustruct
+	if productCursor == nil {
+		pageInfo.Offset = offset
+	}
+
+	var products []*models.ProductWithIsMy
+
+	if slots := p.promotedSlots(limit); isDefaultSort && len(slots) != 0 && isPromotedFeed(filter) {
+		products, err = p.getProductsWithPromoted(ctx, filter, sortSpec, limit, offset, productCursor, slots,
+			userID, pageInfo)
+	} else {
+		products, err = p.getProducts(ctx, filter, sortSpec, limit, offset, productCursor, userID, pageInfo)
+	}
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pageInfo.Total, pageInfo.TotalIsEstimated, err = p.storage.CountProducts(ctx, filter,
+		p.config.estimateCountThreshold)
+	if err != nil {
+		return nil, nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
 	for _, product := range products {
 		product.Sanitize()
 	}
 
+	return products, pageInfo, nil
+}
+
+// getProducts returns page of feed in order of sort spec and sets next page of pageInfo.
+func (p *ProductService) getProducts(ctx context.Context, filter *models.ProductFilter, sortSpec models.SortSpec,
+	limit uint64, offset uint64, productCursor *models.ProductCursor, userID uint64, pageInfo *models.PageInfo,
+) ([]*models.ProductWithIsMy, error) {
+	// one extra product shows whether there is next page
+	products, err := p.storage.GetProductsList(ctx, filter, sortSpec, limit+1, offset, productCursor, userID)
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	if uint64(len(products)) > limit {
+		products = products[:limit]
+		pageInfo.HasMore = true
+
+		pageInfo.NextCursor, err = p.encodeCursor(newProductCursor(products[len(products)-1], sortSpec))
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return products, nil
 }
+
+func (p *ProductService) GetFacets(ctx context.Context, filter *models.ProductFilter, userID uint64,
+) ([]*models.Facet, error) {
+	if err := ValidateProductFilter(filter, userID, p.config.defaultCurrency); err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	facets, err := p.storage.GetFacets(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	for _, facet := range facets {
+		facet.Sanitize()
+	}
+
+	return facets, nil
+}
+
+func (p *ProductService) GetSuggestions(ctx context.Context, searchQuery string, limit uint64,
+) (*models.Suggestions, error) {
+	searchQuery, err := ValidateSearchQuery(searchQuery)
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	if searchQuery == "" {
+		return &models.Suggestions{Titles: []string{}, Categories: []*models.Category{}}, nil
+	}
+
+	if limit == 0 || limit > maxSuggestionsLimit {
+		limit = maxSuggestionsLimit
+	}
+
+	suggestions, err := p.storage.GetSuggestions(ctx, searchQuery, limit)
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	suggestions.Sanitize()
+
+	return suggestions, nil
+}
@@ -0,0 +1,364 @@
+package usecases
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/SanExpett/marketplace-backend/pkg/models"
+	myerrors "github.com/SanExpett/marketplace-backend/pkg/my_errors"
+	"github.com/SanExpett/marketplace-backend/pkg/utils"
+	"io"
+	"math"
+	"slices"
+	"time"
+)
+
+const (
+	MessageErrPromotionTooLong = "Продвижение не может длиться дольше %d дней"
+
+	day = 24 * time.Hour
+)
+
+var (
+	ErrDecodePrePromotion   = myerrors.NewError("Некорректный json продвижения")
+	ErrUnknownPromotionType = myerrors.NewError("Тип продвижения должен быть bump, highlight или top_of_category")
+	ErrWrongPromotionPeriod = myerrors.NewError("Конец продвижения должен быть позже его начала")
+)
+
+// ValidatePrePromotion sets start of promotion to now when it is empty or passed.
+func ValidatePrePromotion(r io.Reader, maxDays uint64) (*models.PrePromotion, error) {
+	prePromotion := &models.PrePromotion{} //nolint:exhaustruct
+	if err := json.NewDecoder(r).Decode(prePromotion); err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, ErrDecodePrePromotion)
+	}
+
+	if !models.IsPromotionType(prePromotion.Type) {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, ErrUnknownPromotionType)
+	}
+
+	now := time.Now()
+	if prePromotion.StartedAt == nil || prePromotion.StartedAt.Before(now) {
+		prePromotion.StartedAt = &now
+	}
+
+	if !prePromotion.EndedAt.After(*prePromotion.StartedAt) {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, ErrWrongPromotionPeriod)
+	}
+
+	if prePromotion.EndedAt.Sub(*prePromotion.StartedAt) > time.Duration(maxDays)*day {
+		return nil, myerrors.NewError(MessageErrPromotionTooLong, maxDays)
+	}
+
+	return prePromotion, nil
+}
+
+// promotionPrice charges every started day of promotion.
+func (p *ProductService) promotionPrice(prePromotion *models.PrePromotion) models.Money {
+	days := uint64((prePromotion.EndedAt.Sub(*prePromotion.StartedAt) + day - 1) / day)
+
+	return models.Money{Amount: days * p.config.promotionDayPrice, Currency: p.config.defaultCurrency}
+}
+
+// AddPromotion buys promotion of own product for seller, it runs after payment succeeds. Declined payment
+// is returned in promotion, so seller can buy it again. If charge or saving of its result fails, promotion
+// stays pending and next call for overlapping period repeats charge with the same idempotency key.
+func (p *ProductService) AddPromotion(ctx context.Context, r io.Reader, productID uint64, userID uint64,
+) (*models.Promotion, error) {
+	prePromotion, err := ValidatePrePromotion(r, p.config.maxPromotionDays)
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	promotion, err := p.storage.StartPromotionPayment(ctx, productID, userID, prePromotion,
+		p.promotionPrice(prePromotion))
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	idempotencyKey := fmt.Sprintf("promotion-%d", promotion.ID)
+
+	result, err := p.paymentProvider.Charge(ctx, models.PaymentTarget{PromotionID: promotion.ID}, //nolint:exhaustruct
+		promotion.Price, idempotencyKey)
+	if err != nil {
+		p.logger.Errorf("charge of promotion %d failed: %+v", promotion.ID, err)
+
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	promotion, err = p.storage.ApplyPromotionPayment(ctx, result)
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return promotion, nil
+}
+
+// HandlePromotionPaymentWebhook applies result of asynchronous payment of promotion, repeated webhooks
+// change nothing.
+func (p *ProductService) HandlePromotionPaymentWebhook(ctx context.Context, payment *models.Payment) {
+	promotion, err := p.storage.ApplyPromotionPayment(ctx, payment)
+	if err != nil {
+		p.logger.Errorf("webhook of payment %+v is not applied: %+v", payment, err)
+
+		return
+	}
+
+	p.logger.Infof("webhook of payment %s is applied to promotion: %+v", payment.ID, promotion)
+}
+
+// GrantPromotion is available only to moderators, they add free promotions.
+func (p *ProductService) GrantPromotion(ctx context.Context, r io.Reader, productID uint64, moderatorID uint64,
+) (*models.Promotion, error) {
+	prePromotion, err := ValidatePrePromotion(r, p.config.maxPromotionDays)
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	promotion, err := p.storage.GrantPromotion(ctx, productID, moderatorID, prePromotion)
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return promotion, nil
+}
+
+func (p *ProductService) GetPromotions(ctx context.Context, userID uint64, productID uint64, limit uint64,
+	offset uint64,
+) ([]*models.Promotion, error) {
+	limit, err := utils.ValidateLimit(limit, defaultPageLimit, p.config.maxPageLimit)
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	promotions, err := p.storage.GetPromotions(ctx, userID, productID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return promotions, nil
+}
+
+// isPromotedFeed reports whether filter selects common feed. Own products, favorites and products
+// of one seller are shown without promoted slots.
+func isPromotedFeed(filter *models.ProductFilter) bool {
+	return !filter.Mine && !filter.Favorites && filter.SalerID == 0
+}
+
+// promotedSlots returns configured slots which fit into page of limit.
+func (p *ProductService) promotedSlots(limit uint64) []uint64 {
+	var slots []uint64
+
+	for _, slot := range p.config.promotedSlots {
+		if slot < limit {
+			slots = append(slots, slot)
+		}
+	}
+
+	return slots
+}
+
+// interleavePromoted puts promoted products into slots of page, organic products fill the rest.
+// Promoted products also fill page when organic ones run out. It returns page and numbers of used
+// organic and promoted products.
+func interleavePromoted(organic []*models.ProductWithIsMy, promoted []*models.ProductWithIsMy, slots []uint64,
+	limit uint64,
+) ([]*models.ProductWithIsMy, int, int) {
+	page := make([]*models.ProductWithIsMy, 0, limit)
+
+	var usedOrganic, usedPromoted int
+
+	for position := uint64(0); position < limit; position++ {
+		hasOrganic := usedOrganic < len(organic)
+		hasPromoted := usedPromoted < len(promoted)
+
+		switch {
+		case hasPromoted && (!hasOrganic || slices.Contains(slots, position)):
+			promoted[usedPromoted].Promoted = true
+			page = append(page, promoted[usedPromoted])
+			usedPromoted++
+		case hasOrganic:
+			page = append(page, organic[usedOrganic])
+			usedOrganic++
+		default:
+			return page, usedOrganic, usedPromoted
+		}
+	}
+
+	return page, usedOrganic, usedPromoted
+}
+
+// slotsBefore returns number of promoted slots among first offset positions of feed split into pages of limit.
+func slotsBefore(offset uint64, limit uint64, slots []uint64) uint64 {
+	count := offset / limit * uint64(len(slots))
+
+	for _, slot := range slots {
+		if slot < offset%limit {
+			count++
+		}
+	}
+
+	return count
+}
+
+// pageSlots returns slots of page which starts at offset, positions are counted from its start.
+func pageSlots(offset uint64, limit uint64, slots []uint64) []uint64 {
+	shift := offset % limit
+	if shift == 0 {
+		return slots
+	}
+
+	shifted := make([]uint64, 0, len(slots))
+	for _, slot := range slots {
+		shifted = append(shifted, (slot+limit-shift)%limit)
+	}
+
+	return shifted
+}
+
+// getOrganicAfter returns up to limit+1 organic products after first organicBefore ones and keyset cursor
+// of the last skipped product. Both are empty when organic products run out before organicBefore.
+func (p *ProductService) getOrganicAfter(ctx context.Context, organicFilter *models.ProductFilter,
+	sortSpec models.SortSpec, limit uint64, organicBefore uint64, userID uint64,
+) ([]*models.ProductWithIsMy, *models.ProductCursor, error) {
+	if organicBefore == 0 {
+		organic, err := p.storage.GetProductsList(ctx, organicFilter, sortSpec, limit+1, 0, nil, userID)
+		if err != nil {
+			return nil, nil, fmt.Errorf(myerrors.ErrTemplate, err)
+		}
+
+		return organic, nil, nil
+	}
+
+	// the last skipped product is selected too, next cursor is built from it when page has no organic products
+	organic, err := p.storage.GetProductsList(ctx, organicFilter, sortSpec, limit+2, organicBefore-1, nil, userID)
+	if err != nil {
+		return nil, nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	if len(organic) == 0 {
+		return nil, nil, nil
+	}
+
+	return organic[1:], newProductCursor(organic[0], sortSpec), nil
+}
+
+// getOrganicAfterOffset returns number of promoted products shown before offset, organic products after offset
+// and keyset cursor of the last organic product before offset. Promoted products take slots of previous pages
+// while they last and every position after the last organic product.
+func (p *ProductService) getOrganicAfterOffset(ctx context.Context, filter *models.ProductFilter,
+	organicFilter *models.ProductFilter, sortSpec models.SortSpec, limit uint64, offset uint64, slots []uint64,
+	userID uint64,
+) (uint64, []*models.ProductWithIsMy, *models.ProductCursor, error) {
+	promotedBefore := slotsBefore(offset, limit, slots)
+
+	if promotedBefore != 0 {
+		shown, err := p.storage.GetPromotedProducts(ctx, filter, promotedBefore, 0, userID)
+		if err != nil {
+			return 0, nil, nil, fmt.Errorf(myerrors.ErrTemplate, err)
+		}
+
+		promotedBefore = uint64(len(shown))
+	}
+
+	organicBefore := offset - promotedBefore
+
+	organic, lastOrganic, err := p.getOrganicAfter(ctx, organicFilter, sortSpec, limit, organicBefore, userID)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+
+	if organicBefore == 0 || lastOrganic != nil {
+		return promotedBefore, organic, lastOrganic, nil
+	}
+
+	// organic products ran out before offset, the rest of positions were taken by promoted ones
+	organicTotal, _, err := p.storage.CountProducts(ctx, organicFilter, math.MaxUint64)
+	if err != nil {
+		return 0, nil, nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	organicTotal = min(organicTotal, organicBefore)
+
+	organic, lastOrganic, err = p.getOrganicAfter(ctx, organicFilter, sortSpec, limit, organicTotal, userID)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+
+	return offset - organicTotal, organic, lastOrganic, nil
+}
+
+// getProductsWithPromoted returns page of default feed with promoted products in slots, organic products
+// don't repeat them. Number of shown promoted products is kept in cursor, in offset mode it is counted
+// by slots before offset. Keyset of cursor is built only from organic products.
+// Expired promotions are not selected, so their products go back to organic feed.
+func (p *ProductService) getProductsWithPromoted(ctx context.Context, filter *models.ProductFilter,
+	sortSpec models.SortSpec, limit uint64, offset uint64, productCursor *models.ProductCursor, slots []uint64,
+	userID uint64, pageInfo *models.PageInfo,
+) ([]*models.ProductWithIsMy, error) {
+	organicFilter := *filter
+	organicFilter.ExcludePromoted = true
+
+	var (
+		promotedBefore uint64
+		organic        []*models.ProductWithIsMy
+		lastOrganic    *models.ProductCursor
+		err            error
+	)
+
+	if productCursor != nil {
+		promotedBefore = productCursor.Promoted
+		lastOrganic = productCursor
+
+		keyset := productCursor
+		if productCursor.OrganicStart {
+			keyset = nil
+		}
+
+		// one extra product shows whether there are more
+		organic, err = p.storage.GetProductsList(ctx, &organicFilter, sortSpec, limit+1, 0, keyset, userID)
+		if err != nil {
+			return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+		}
+	} else {
+		promotedBefore, organic, lastOrganic, err = p.getOrganicAfterOffset(ctx, filter, &organicFilter, sortSpec,
+			limit, offset, slots, userID)
+		if err != nil {
+			return nil, err
+		}
+
+		slots = pageSlots(offset, limit, slots)
+	}
+
+	promoted, err := p.storage.GetPromotedProducts(ctx, filter, limit+1, promotedBefore, userID)
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	products, usedOrganic, usedPromoted := interleavePromoted(organic, promoted, slots, limit)
+
+	pageInfo.HasMore = len(organic) > usedOrganic || len(promoted) > usedPromoted
+	if !pageInfo.HasMore {
+		return products, nil
+	}
+
+	// keyset goes on after the last organic product, promoted ones are counted separately
+	var nextCursor models.ProductCursor
+
+	switch {
+	case usedOrganic != 0:
+		nextCursor = *newProductCursor(organic[usedOrganic-1], sortSpec)
+	case lastOrganic != nil:
+		nextCursor = *lastOrganic
+	default:
+		nextCursor = models.ProductCursor{Sort: sortSpec.String(), OrganicStart: true} //nolint:exhaustruct
+	}
+
+	nextCursor.Promoted = promotedBefore + uint64(usedPromoted)
+
+	pageInfo.NextCursor, err = p.encodeCursor(&nextCursor)
+	if err != nil {
+		return nil, err
+	}
+
+	return products, nil
+}
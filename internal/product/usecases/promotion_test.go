@@ -0,0 +1,299 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SanExpett/marketplace-backend/pkg/models"
+	"github.com/SanExpett/marketplace-backend/pkg/my_logger"
+)
+
+func TestMain(m *testing.M) {
+	if _, err := my_logger.New([]string{os.DevNull}, []string{os.DevNull}); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	os.Exit(m.Run())
+}
+
+func newTestProducts(firstID uint64, count uint64) []*models.ProductWithIsMy {
+	products := make([]*models.ProductWithIsMy, 0, count)
+	for id := firstID; id < firstID+count; id++ {
+		products = append(products, &models.ProductWithIsMy{ID: id}) //nolint:exhaustruct
+	}
+
+	return products
+}
+
+func productIDs(products []*models.ProductWithIsMy) []uint64 {
+	ids := make([]uint64, 0, len(products))
+	for _, product := range products {
+		ids = append(ids, product.ID)
+	}
+
+	return ids
+}
+
+func TestInterleavePromoted(t *testing.T) {
+	tests := []struct {
+		name         string
+		organic      uint64
+		promoted     uint64
+		slots        []uint64
+		limit        uint64
+		want         []uint64
+		usedOrganic  int
+		usedPromoted int
+	}{
+		{
+			name: "slots", organic: 10, promoted: 10, slots: []uint64{0, 3}, limit: 5,
+			want: []uint64{101, 1, 2, 102, 3}, usedOrganic: 3, usedPromoted: 2,
+		},
+		{
+			name: "promoted run out", organic: 10, promoted: 1, slots: []uint64{0, 3}, limit: 5,
+			want: []uint64{101, 1, 2, 3, 4}, usedOrganic: 4, usedPromoted: 1,
+		},
+		{
+			name: "organic run out", organic: 1, promoted: 10, slots: []uint64{3}, limit: 5,
+			want: []uint64{1, 101, 102, 103, 104}, usedOrganic: 1, usedPromoted: 4,
+		},
+		{
+			name: "both run out", organic: 1, promoted: 1, slots: []uint64{0}, limit: 5,
+			want: []uint64{101, 1}, usedOrganic: 1, usedPromoted: 1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			page, usedOrganic, usedPromoted := interleavePromoted(newTestProducts(1, test.organic),
+				newTestProducts(101, test.promoted), test.slots, test.limit)
+
+			if got := productIDs(page); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("page = %v, want %v", got, test.want)
+			}
+
+			if usedOrganic != test.usedOrganic || usedPromoted != test.usedPromoted {
+				t.Errorf("used = %d, %d, want %d, %d", usedOrganic, usedPromoted, test.usedOrganic,
+					test.usedPromoted)
+			}
+
+			for _, product := range page {
+				if product.Promoted != (product.ID > 100) {
+					t.Errorf("product %d has promoted = %v", product.ID, product.Promoted)
+				}
+			}
+		})
+	}
+}
+
+func TestSlotsBefore(t *testing.T) {
+	slots := []uint64{0, 3}
+
+	tests := []struct {
+		offset uint64
+		want   uint64
+	}{
+		{0, 0},
+		{1, 1},
+		{3, 1},
+		{4, 2},
+		{5, 2},
+		{6, 3},
+		{12, 5},
+	}
+
+	for _, test := range tests {
+		if got := slotsBefore(test.offset, 5, slots); got != test.want {
+			t.Errorf("slotsBefore(%d) = %d, want %d", test.offset, got, test.want)
+		}
+	}
+}
+
+func TestPageSlots(t *testing.T) {
+	tests := []struct {
+		offset uint64
+		want   []uint64
+	}{
+		{0, []uint64{0, 3}},
+		{10, []uint64{0, 3}},
+		{1, []uint64{4, 2}},
+		{3, []uint64{2, 0}},
+		{9, []uint64{1, 4}},
+	}
+
+	for _, test := range tests {
+		if got := pageSlots(test.offset, 5, []uint64{0, 3}); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("pageSlots(%d) = %v, want %v", test.offset, got, test.want)
+		}
+	}
+}
+
+// TestOffsetPagesMatchFeed checks that page at any offset repeats feed built page by page from the start,
+// offsets are counted the same way as in getOrganicAfterOffset.
+func TestOffsetPagesMatchFeed(t *testing.T) {
+	const limit = 4
+
+	slots := []uint64{0, 2}
+
+	for _, sizes := range [][2]uint64{{20, 3}, {3, 20}, {0, 5}, {5, 0}, {7, 7}} {
+		organic := newTestProducts(1, sizes[0])
+		promoted := newTestProducts(101, sizes[1])
+
+		var feed []*models.ProductWithIsMy
+
+		for usedOrganic, usedPromoted := 0, 0; ; {
+			page, pageOrganic, pagePromoted := interleavePromoted(organic[usedOrganic:],
+				promoted[usedPromoted:], slots, limit)
+			if len(page) == 0 {
+				break
+			}
+
+			feed = append(feed, page...)
+			usedOrganic += pageOrganic
+			usedPromoted += pagePromoted
+		}
+
+		for offset := uint64(0); offset <= uint64(len(feed)); offset++ {
+			promotedBefore := min(slotsBefore(offset, limit, slots), sizes[1])
+
+			organicBefore := offset - promotedBefore
+			if organicBefore > sizes[0] {
+				organicBefore = sizes[0]
+				promotedBefore = min(offset-organicBefore, sizes[1])
+			}
+
+			page, _, _ := interleavePromoted(organic[organicBefore:], promoted[promotedBefore:],
+				pageSlots(offset, limit, slots), limit)
+
+			want := productIDs(feed[offset:min(offset+limit, uint64(len(feed)))])
+			if got := productIDs(page); !reflect.DeepEqual(got, want) {
+				t.Errorf("sizes %v, offset %d: page = %v, want %v", sizes, offset, got, want)
+			}
+		}
+	}
+}
+
+var errApplyPromotionPayment = errors.New("apply payment of promotion failed")
+
+// fakePromotionStorage keeps one promotion bought by seller, other methods of IProductStorage are not
+// implemented. failApply first calls of ApplyPromotionPayment fail after promotion is charged.
+type fakePromotionStorage struct {
+	IProductStorage
+	promotion *models.Promotion
+	failApply int
+}
+
+func (f *fakePromotionStorage) StartPromotionPayment(_ context.Context, productID uint64, userID uint64,
+	prePromotion *models.PrePromotion, price models.Money,
+) (*models.Promotion, error) {
+	if f.promotion == nil || f.promotion.PaymentStatus != models.PaymentStatusPending {
+		f.promotion = &models.Promotion{ //nolint:exhaustruct
+			ID:            1,
+			ProductID:     productID,
+			SalerID:       userID,
+			Type:          prePromotion.Type,
+			StartedAt:     *prePromotion.StartedAt,
+			EndedAt:       prePromotion.EndedAt,
+			Price:         price,
+			PaymentStatus: models.PaymentStatusPending,
+		}
+	}
+
+	promotion := *f.promotion
+
+	return &promotion, nil
+}
+
+func (f *fakePromotionStorage) ApplyPromotionPayment(_ context.Context, payment *models.Payment,
+) (*models.Promotion, error) {
+	if f.failApply > 0 {
+		f.failApply--
+
+		return nil, errApplyPromotionPayment
+	}
+
+	if f.promotion.PaymentStatus == models.PaymentStatusPending {
+		f.promotion.PaymentID = payment.ID
+		f.promotion.PaymentStatus = payment.Status
+	}
+
+	promotion := *f.promotion
+
+	return &promotion, nil
+}
+
+// countingProvider succeeds every charge and returns the same payment for the same idempotency key.
+type countingProvider struct {
+	payments map[string]*models.Payment
+	charges  int
+	amounts  []models.Money
+}
+
+func (c *countingProvider) Charge(_ context.Context, target models.PaymentTarget, amount models.Money,
+	idempotencyKey string,
+) (*models.Payment, error) {
+	c.charges++
+	c.amounts = append(c.amounts, amount)
+
+	if payment, ok := c.payments[idempotencyKey]; ok {
+		return payment, nil
+	}
+
+	payment := &models.Payment{ //nolint:exhaustruct
+		ID:          fmt.Sprintf("payment-%d", len(c.payments)+1),
+		PromotionID: target.PromotionID,
+		Status:      models.PaymentStatusSucceeded,
+	}
+	c.payments[idempotencyKey] = payment
+
+	return payment, nil
+}
+
+func TestAddPromotionRetriesPendingPayment(t *testing.T) {
+	storage := &fakePromotionStorage{failApply: 1}                            //nolint:exhaustruct
+	provider := &countingProvider{payments: make(map[string]*models.Payment)} //nolint:exhaustruct
+
+	service, err := NewProductService(storage, nil, nil, nil, nil, provider,
+		NewConfigProductService(nil, 100, 0, models.CurrencyRUB, nil, 30, 10000, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body := fmt.Sprintf(`{"type": "bump", "ended_at": %q}`, time.Now().Add(25*time.Hour).Format(time.RFC3339))
+
+	if _, err := service.AddPromotion(context.Background(), strings.NewReader(body), 1, 2); !errors.Is(err,
+		errApplyPromotionPayment) {
+		t.Fatalf("expected error of ApplyPromotionPayment, got %v", err)
+	}
+
+	if storage.promotion.PaymentStatus != models.PaymentStatusPending {
+		t.Fatalf("payment must stay pending, got %s", storage.promotion.PaymentStatus)
+	}
+
+	promotion, err := service.AddPromotion(context.Background(), strings.NewReader(body), 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if promotion.PaymentStatus != models.PaymentStatusSucceeded || promotion.PaymentID != "payment-1" {
+		t.Fatalf("promotion must be paid by the first payment, got %+v", promotion)
+	}
+
+	if provider.charges != 2 || len(provider.payments) != 1 {
+		t.Fatalf("charge must be repeated with the same key, got %d charges with %d keys", provider.charges,
+			len(provider.payments))
+	}
+
+	// every started day is paid: 25 hours cost 2 days
+	want := models.Money{Amount: 20000, Currency: models.CurrencyRUB}
+	if provider.amounts[0] != want || provider.amounts[1] != want {
+		t.Fatalf("charged %v, want %v", provider.amounts, want)
+	}
+}
@@ -0,0 +1,191 @@
+package usecases
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/SanExpett/marketplace-backend/pkg/models"
+	myerrors "github.com/SanExpett/marketplace-backend/pkg/my_errors"
+	"github.com/SanExpett/marketplace-backend/pkg/utils"
+	"io"
+	"slices"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	matchSavedSearchesBatch = 1000
+	savedSearchDigestsBatch = 1000
+	// maxDigestProducts is number of the newest products which are sent in digest, client fetches the rest by query
+	maxDigestProducts = 20
+
+	maxLenSavedSearchName = 256
+)
+
+var (
+	ErrDecodePreSavedSearch     = myerrors.NewError("Некорректный json сохранённого поиска")
+	ErrWrongSavedSearchName     = myerrors.NewError("Название поиска должно быть длинной от 1 до 256 символов")
+	ErrUnknownSavedSearchNotify = myerrors.NewError("Режим уведомлений должен быть instant, hourly или daily")
+	ErrSavedSearchNotFeed       = myerrors.NewError("Сохранить можно только поиск по общей ленте")
+	ErrSavedSearchWithStatus    = myerrors.NewError("Сохранённый поиск находит только новые активные объявления, " +
+		"фильтры по статусу и дате создания в нём не работают")
+)
+
+// ValidatePreSavedSearch sets instant notify mode by default and validates filter like in feed.
+// Saved search can't select own or favorite products, products in other statuses or created before some time.
+func ValidatePreSavedSearch(r io.Reader, filter *models.ProductFilter, query string, userID uint64,
+	defaultCurrency string,
+) (*models.PreSavedSearch, error) {
+	preSavedSearch := &models.PreSavedSearch{} //nolint:exhaustruct
+	if err := json.NewDecoder(r).Decode(preSavedSearch); err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, ErrDecodePreSavedSearch)
+	}
+
+	preSavedSearch.Trim()
+
+	if preSavedSearch.Name == "" || utf8.RuneCountInString(preSavedSearch.Name) > maxLenSavedSearchName {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, ErrWrongSavedSearchName)
+	}
+
+	if preSavedSearch.Notify == "" {
+		preSavedSearch.Notify = models.SavedSearchNotifyInstant
+	}
+
+	if !models.IsSavedSearchNotifyMode(preSavedSearch.Notify) {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, ErrUnknownSavedSearchNotify)
+	}
+
+	if filter.Mine || filter.Favorites {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, ErrSavedSearchNotFeed)
+	}
+
+	if err := ValidateProductFilter(filter, userID, defaultCurrency); err != nil {
+		return nil, err
+	}
+
+	if filter.CreatedAfter != nil || filter.CreatedBefore != nil ||
+		!slices.Equal(filter.Statuses, []string{models.ProductStatusActive}) {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, ErrSavedSearchWithStatus)
+	}
+
+	preSavedSearch.Filter = filter
+	preSavedSearch.Query = query
+
+	return preSavedSearch, nil
+}
+
+// AddSavedSearch saves feed filter with query string of feed from which it was parsed.
+func (p *ProductService) AddSavedSearch(ctx context.Context, r io.Reader, filter *models.ProductFilter,
+	query string, userID uint64,
+) (*models.SavedSearch, error) {
+	preSavedSearch, err := ValidatePreSavedSearch(r, filter, query, userID, p.config.defaultCurrency)
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	savedSearch, err := p.storage.AddSavedSearch(ctx, userID, preSavedSearch, p.config.maxSavedSearches)
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	savedSearch.Sanitize()
+
+	return savedSearch, nil
+}
+
+func (p *ProductService) GetSavedSearches(ctx context.Context, userID uint64, limit uint64, offset uint64,
+) ([]*models.SavedSearch, error) {
+	limit, err := utils.ValidateLimit(limit, defaultPageLimit, p.config.maxPageLimit)
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	savedSearches, err := p.storage.GetSavedSearches(ctx, userID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	for _, savedSearch := range savedSearches {
+		savedSearch.Sanitize()
+	}
+
+	return savedSearches, nil
+}
+
+func (p *ProductService) DeleteSavedSearch(ctx context.Context, savedSearchID uint64, userID uint64) error {
+	if err := p.storage.DeleteSavedSearch(ctx, savedSearchID, userID); err != nil {
+		return fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return nil
+}
+
+// RunSavedSearchMatcher periodically matches published products with saved searches and sends digests
+// of found products to users whose notify period passed, until ctx is done.
+func (p *ProductService) RunSavedSearchMatcher(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			p.matchSavedSearches(ctx)
+			p.sendSavedSearchDigests(ctx)
+		}
+	}
+}
+
+func (p *ProductService) matchSavedSearches(ctx context.Context) {
+	for {
+		matched, err := p.storage.MatchSavedSearches(ctx, matchSavedSearchesBatch)
+		if err != nil {
+			p.logger.Errorf("error in matching of saved searches: %+v", err)
+
+			return
+		}
+
+		if matched < matchSavedSearchesBatch {
+			return
+		}
+	}
+}
+
+// sendSavedSearchDigests doesn't retry digests which are not published: their products are still in feed
+// of saved search.
+func (p *ProductService) sendSavedSearchDigests(ctx context.Context) {
+	for {
+		digests, err := p.storage.TakeSavedSearchDigests(ctx, savedSearchDigestsBatch, maxDigestProducts)
+		if err != nil {
+			p.logger.Errorf("error in taking of saved search digests: %+v", err)
+
+			return
+		}
+
+		for _, digest := range digests {
+			p.publishSavedSearchDigest(ctx, digest)
+		}
+
+		if len(digests) < savedSearchDigestsBatch {
+			return
+		}
+	}
+}
+
+func (p *ProductService) publishSavedSearchDigest(ctx context.Context, digest *models.SavedSearchDigest) {
+	if p.publisher == nil {
+		return
+	}
+
+	digest.Sanitize()
+
+	event, err := models.NewEvent(models.EventTypeSavedSearch, digest)
+	if err == nil {
+		err = p.publisher.Publish(ctx, event, digest.UserID)
+	}
+
+	if err != nil {
+		p.logger.Errorf("digest of saved search %d is not published: %+v", digest.SavedSearchID, err)
+	}
+}
@@ -8,13 +8,18 @@ import (
 	"github.com/SanExpett/marketplace-backend/pkg/my_logger"
 	"github.com/asaskevich/govalidator"
 	"io"
+	"math"
+	"slices"
+	"strings"
+	"unicode/utf8"
 )
 
 var (
-	ErrDecodePreProduct = myerrors.NewError("Некорректный json объявления")
+	ErrDecodePreProduct         = myerrors.NewError("Некорректный json объявления")
+	ErrLatitudeWithoutLongitude = myerrors.NewError("Широта и долгота объявления указываются только вместе")
 )
 
-func validatePreProduct(r io.Reader, userID uint64) (*models.PreProduct, error) {
+func validatePreProduct(r io.Reader, userID uint64, defaultCurrency string) (*models.PreProduct, error) {
 	logger, err := my_logger.Get()
 	if err != nil {
 		return nil, err
@@ -39,14 +44,200 @@ func validatePreProduct(r io.Reader, userID uint64) (*models.PreProduct, error)
 		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
 	}
 
+	if preProduct.Price.Currency == "" {
+		preProduct.Price.Currency = defaultCurrency
+	}
+
+	if err := validatePrice(&preProduct.Price); err != nil {
+		return nil, err
+	}
+
+	if (preProduct.Latitude == nil) != (preProduct.Longitude == nil) {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, ErrLatitudeWithoutLongitude)
+	}
+
+	if preProduct.Latitude != nil {
+		if err := models.ValidateCoordinates(*preProduct.Latitude, *preProduct.Longitude); err != nil {
+			return nil, err
+		}
+	}
+
 	return preProduct, nil
 }
 
-func ValidatePreProduct(r io.Reader, userID uint64) (*models.PreProduct, error) {
-	preProduct, err := validatePreProduct(r, userID)
+// ValidatePreProduct sets defaultCurrency for price without currency.
+func ValidatePreProduct(r io.Reader, userID uint64, defaultCurrency string) (*models.PreProduct, error) {
+	preProduct, err := validatePreProduct(r, userID, defaultCurrency)
 	if err != nil {
 		return nil, myerrors.NewError(err.Error())
 	}
 
 	return preProduct, nil
 }
+
+const (
+	maxLenTitle       = 256
+	maxLenDescription = 4000
+	maxLenImageURL    = 256
+)
+
+var (
+	ErrDecodePartialProduct = myerrors.NewError("Некорректный json изменений объявления")
+	ErrEmptyPartialProduct  = myerrors.NewError("Не указано ни одного изменения объявления")
+	ErrWrongTitle           = myerrors.NewError("Заголовок должен быть длинной от 1 до 256 символов")
+	ErrWrongDescription     = myerrors.NewError("Описание должно быть длинной от 1 до 4000 симвволов")
+	ErrWrongImageURL        = myerrors.NewError("Изображение должно быть png или jpeg и не длиннее 256 символов")
+	ErrWrongPrice           = myerrors.NewError("Цена должна быть больше нуля")
+	ErrReservedBySaler      = myerrors.NewError("Статус reserved ставится только заказом или принятым предложением")
+)
+
+// validatePrice allows empty currency, it must be set by caller.
+func validatePrice(price *models.Money) error {
+	if price.Amount == 0 {
+		return fmt.Errorf(myerrors.ErrTemplate, ErrWrongPrice)
+	}
+
+	if price.Currency == "" {
+		return models.ValidateAmount(price.Amount)
+	}
+
+	return price.Validate()
+}
+
+func isValidImageURL(imageURL string) bool {
+	if utf8.RuneCountInString(imageURL) > maxLenImageURL {
+		return false
+	}
+
+	return imageURL == "" || strings.HasSuffix(imageURL, ".png") || strings.HasSuffix(imageURL, ".jpeg") ||
+		strings.HasSuffix(imageURL, ".jpg")
+}
+
+// ValidatePartialProduct checks only fields which are changed. Price without currency keeps current currency.
+func ValidatePartialProduct(r io.Reader) (*models.PartialProduct, error) {
+	partialProduct := &models.PartialProduct{} //nolint:exhaustruct
+	if err := json.NewDecoder(r).Decode(partialProduct); err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, ErrDecodePartialProduct)
+	}
+
+	partialProduct.Trim()
+
+	if *partialProduct == (models.PartialProduct{}) { //nolint:exhaustruct
+		return nil, fmt.Errorf(myerrors.ErrTemplate, ErrEmptyPartialProduct)
+	}
+
+	if partialProduct.Title != nil &&
+		(*partialProduct.Title == "" || utf8.RuneCountInString(*partialProduct.Title) > maxLenTitle) {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, ErrWrongTitle)
+	}
+
+	if partialProduct.Description != nil && (*partialProduct.Description == "" ||
+		utf8.RuneCountInString(*partialProduct.Description) > maxLenDescription) {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, ErrWrongDescription)
+	}
+
+	if partialProduct.ImageUrl != nil && !isValidImageURL(*partialProduct.ImageUrl) {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, ErrWrongImageURL)
+	}
+
+	if partialProduct.Price != nil {
+		if err := validatePrice(partialProduct.Price); err != nil {
+			return nil, err
+		}
+	}
+
+	if partialProduct.Status != nil && !slices.Contains(allProductStatuses, *partialProduct.Status) {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, ErrUnknownStatusFilter)
+	}
+
+	if partialProduct.Status != nil && *partialProduct.Status == models.ProductStatusReserved {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, ErrReservedBySaler)
+	}
+
+	return partialProduct, nil
+}
+
+const (
+	MessageErrUnknownAttribute    = "Неизвестная характеристика %s"
+	MessageErrRequiredAttribute   = "Не указана обязательная характеристика %s"
+	MessageErrWrongAttributeValue = "Некорректное значение характеристики %s"
+
+	maxLenStringAttribute = 256
+)
+
+const defaultPageLimit = 10
+
+func validateAttributeValue(value any, attribute *models.CategoryAttribute) (any, bool) {
+	switch attribute.Type {
+	case models.AttributeTypeInt:
+		number, ok := value.(float64)
+		if !ok || number != math.Trunc(number) {
+			return nil, false
+		}
+
+		if (attribute.MinValue != nil && number < float64(*attribute.MinValue)) ||
+			(attribute.MaxValue != nil && number > float64(*attribute.MaxValue)) {
+			return nil, false
+		}
+
+		return int64(number), true
+	case models.AttributeTypeString:
+		str, ok := value.(string)
+		if !ok {
+			return nil, false
+		}
+
+		str = strings.TrimSpace(str)
+		if str == "" || utf8.RuneCountInString(str) > maxLenStringAttribute {
+			return nil, false
+		}
+
+		return str, true
+	case models.AttributeTypeEnum:
+		str, ok := value.(string)
+		if !ok || !slices.Contains(attribute.EnumValues, str) {
+			return nil, false
+		}
+
+		return str, true
+	case models.AttributeTypeBool:
+		boolean, ok := value.(bool)
+
+		return boolean, ok
+	default:
+		return nil, false
+	}
+}
+
+// ValidateAttributes checks attributes against schema of category and returns normalized attributes.
+func ValidateAttributes(attributes map[string]any, schema []*models.CategoryAttribute,
+) (map[string]any, error) {
+	attributeByName := make(map[string]*models.CategoryAttribute, len(schema))
+	for _, attribute := range schema {
+		attributeByName[attribute.Name] = attribute
+	}
+
+	result := make(map[string]any, len(attributes))
+
+	for name, value := range attributes {
+		attribute, ok := attributeByName[name]
+		if !ok {
+			return nil, myerrors.NewError(MessageErrUnknownAttribute, name)
+		}
+
+		normalized, ok := validateAttributeValue(value, attribute)
+		if !ok {
+			return nil, myerrors.NewError(MessageErrWrongAttributeValue, name)
+		}
+
+		result[name] = normalized
+	}
+
+	for _, attribute := range schema {
+		if _, ok := result[attribute.Name]; attribute.Required && !ok {
+			return nil, myerrors.NewError(MessageErrRequiredAttribute, attribute.Name)
+		}
+	}
+
+	return result, nil
+}
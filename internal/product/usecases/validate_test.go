@@ -0,0 +1,61 @@
+package usecases
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/SanExpett/marketplace-backend/pkg/models"
+)
+
+func TestValidateAttributes(t *testing.T) {
+	minYear, maxYear := int64(1900), int64(2100)
+	schema := []*models.CategoryAttribute{
+		{Name: "model", Type: models.AttributeTypeString, Required: true},
+		{Name: "year", Type: models.AttributeTypeInt, MinValue: &minYear, MaxValue: &maxYear},
+		{Name: "size", Type: models.AttributeTypeEnum, EnumValues: []string{"1", "2"}},
+	}
+
+	attributes, err := ValidateAttributes(map[string]any{"model": "  Golf  ", "year": 2015.0, "size": "1"}, schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if attributes["model"] != "Golf" || attributes["year"] != int64(2015) || attributes["size"] != "1" {
+		t.Fatalf("unexpected normalized attributes %v", attributes)
+	}
+
+	for name, invalid := range map[string]map[string]any{
+		"blank string":     {"model": "   "},
+		"fractional int":   {"model": "Golf", "year": 2015.5},
+		"int out of range": {"model": "Golf", "year": 1800.0},
+		"unknown enum":     {"model": "Golf", "size": "3"},
+		"unknown name":     {"model": "Golf", "color": "red"},
+		"missing required": {"year": 2015.0},
+	} {
+		if _, err := ValidateAttributes(invalid, schema); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestValidatePartialProductStatus(t *testing.T) {
+	partialProduct, err := ValidatePartialProduct(strings.NewReader(`{"status": "sold"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if *partialProduct.Status != models.ProductStatusSold {
+		t.Fatalf("unexpected status %q", *partialProduct.Status)
+	}
+
+	for body, want := range map[string]error{
+		`{"status": "reserved"}`:  ErrReservedBySaler,
+		`{"status": "hidden"}`:    ErrUnknownStatusFilter,
+		`{"status": "on_review"}`: ErrUnknownStatusFilter,
+	} {
+		if _, err := ValidatePartialProduct(strings.NewReader(body)); !errors.Is(err, want) {
+			t.Errorf("%s: expected %v, got %v", body, want, err)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+	"github.com/SanExpett/marketplace-backend/pkg/models"
+	myerrors "github.com/SanExpett/marketplace-backend/pkg/my_errors"
+	"github.com/SanExpett/marketplace-backend/pkg/view_counter"
+)
+
+const (
+	defaultViewsDays = 30
+	maxViewsDays     = 365
+)
+
+const MessageErrWrongViewsDays = "Некорректное число дней: оно должно быть не больше %d"
+
+var ErrViewsOfNotMyProduct = myerrors.NewError("Статистика просмотров доступна только продавцу")
+
+// ValidateViewsDays sets default period of views stats for zero days.
+func ValidateViewsDays(days uint64) (uint64, error) {
+	if days == 0 {
+		return defaultViewsDays, nil
+	}
+
+	if days > maxViewsDays {
+		return 0, myerrors.NewError(MessageErrWrongViewsDays, maxViewsDays)
+	}
+
+	return days, nil
+}
+
+// RecordView counts view of product asynchronously. Views of owner and bots are ignored.
+func (p *ProductService) RecordView(product *models.ProductWithIsMy, viewerKey string, userAgent string) {
+	if p.viewCounter == nil || product.IsMy || view_counter.IsBot(userAgent) {
+		return
+	}
+
+	p.viewCounter.Record(product.ID, viewerKey)
+}
+
+// GetDailyViews returns views of product by days, it is available only to owner of product.
+func (p *ProductService) GetDailyViews(ctx context.Context, productID uint64, days uint64, userID uint64,
+) ([]*models.DailyViews, error) {
+	days, err := ValidateViewsDays(days)
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	product, err := p.storage.GetProduct(ctx, productID, userID)
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	if !product.IsMy {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, ErrViewsOfNotMyProduct)
+	}
+
+	dailyViews, err := p.storage.GetDailyViews(ctx, productID, days)
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return dailyViews, nil
+}
@@ -0,0 +1,27 @@
+package delivery
+
+import "github.com/SanExpett/marketplace-backend/pkg/models"
+
+type ReviewResponse struct {
+	Status int            `json:"status"`
+	Body   *models.Review `json:"body"`
+}
+
+func NewReviewResponse(status int, body *models.Review) *ReviewResponse {
+	return &ReviewResponse{
+		Status: status,
+		Body:   body,
+	}
+}
+
+type ReviewListResponse struct {
+	Status int              `json:"status"`
+	Body   []*models.Review `json:"body"`
+}
+
+func NewReviewListResponse(status int, body []*models.Review) *ReviewListResponse {
+	return &ReviewListResponse{
+		Status: status,
+		Body:   body,
+	}
+}
@@ -0,0 +1,187 @@
+package delivery
+
+import (
+	"context"
+	"fmt"
+	"github.com/SanExpett/marketplace-backend/internal/review/usecases"
+	"github.com/SanExpett/marketplace-backend/internal/server/delivery"
+	"github.com/SanExpett/marketplace-backend/pkg/models"
+	myerrors "github.com/SanExpett/marketplace-backend/pkg/my_errors"
+	"github.com/SanExpett/marketplace-backend/pkg/my_logger"
+	"github.com/SanExpett/marketplace-backend/pkg/utils"
+	"go.uber.org/zap"
+	"io"
+	"net/http"
+)
+
+var _ IReviewService = (*usecases.ReviewService)(nil)
+
+type IReviewService interface {
+	AddReview(ctx context.Context, r io.Reader, orderID uint64, userID uint64) (*models.Review, error)
+	ReplyReview(ctx context.Context, r io.Reader, reviewID uint64, userID uint64) (*models.Review, error)
+	GetReviews(ctx context.Context, salerID uint64, limit uint64, offset uint64) ([]*models.Review, error)
+}
+
+type ReviewHandler struct {
+	service IReviewService
+	logger  *zap.SugaredLogger
+}
+
+func NewReviewHandler(reviewService IReviewService) (*ReviewHandler, error) {
+	logger, err := my_logger.Get()
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return &ReviewHandler{
+		service: reviewService,
+		logger:  logger,
+	}, nil
+}
+
+// AddReviewHandler godoc
+//
+//	@Summary    add review
+//	@Description  add review about saler, only buyer of completed order can add it, one review per order
+//	@Tags review
+//	@Accept      json
+//	@Produce    json
+//	@Param      order_id  query uint64 true  "order id"
+//	@Param      preReview  body models.PreReview true  "review data, rating is from 1 to 5"
+//	@Success    200  {object} ReviewResponse
+//	@Failure    405  {string} string
+//	@Failure    500  {string} string
+//	@Failure    222  {object} delivery.ErrorResponse "Error"
+//	@Router      /review/add [post]
+func (rh *ReviewHandler) AddReviewHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, `Method not allowed`, http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	ctx := r.Context()
+
+	userID, err := delivery.GetUserIDFromCookie(r)
+	if err != nil {
+		delivery.HandleErr(w, rh.logger, err)
+
+		return
+	}
+
+	orderID, err := utils.ParseUint64FromRequest(r, "order_id")
+	if err != nil {
+		delivery.HandleErr(w, rh.logger, err)
+
+		return
+	}
+
+	review, err := rh.service.AddReview(ctx, r.Body, orderID, userID)
+	if err != nil {
+		delivery.HandleErr(w, rh.logger, err)
+
+		return
+	}
+
+	delivery.SendOkResponse(w, rh.logger, NewReviewResponse(delivery.StatusResponseSuccessful, review))
+	rh.logger.Infof("in AddReviewHandler: user %d added review %d", userID, review.ID)
+}
+
+// ReplyReviewHandler godoc
+//
+//	@Summary    reply review
+//	@Description  reply to review, only saler can reply and only once
+//	@Tags review
+//	@Accept      json
+//	@Produce    json
+//	@Param      id  query uint64 true  "review id"
+//	@Param      preReply  body models.PreReply true  "reply data"
+//	@Success    200  {object} ReviewResponse
+//	@Failure    405  {string} string
+//	@Failure    500  {string} string
+//	@Failure    222  {object} delivery.ErrorResponse "Error"
+//	@Router      /review/reply [post]
+func (rh *ReviewHandler) ReplyReviewHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, `Method not allowed`, http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	ctx := r.Context()
+
+	userID, err := delivery.GetUserIDFromCookie(r)
+	if err != nil {
+		delivery.HandleErr(w, rh.logger, err)
+
+		return
+	}
+
+	reviewID, err := utils.ParseUint64FromRequest(r, "id")
+	if err != nil {
+		delivery.HandleErr(w, rh.logger, err)
+
+		return
+	}
+
+	review, err := rh.service.ReplyReview(ctx, r.Body, reviewID, userID)
+	if err != nil {
+		delivery.HandleErr(w, rh.logger, err)
+
+		return
+	}
+
+	delivery.SendOkResponse(w, rh.logger, NewReviewResponse(delivery.StatusResponseSuccessful, review))
+	rh.logger.Infof("in ReplyReviewHandler: user %d replied review %d", userID, review.ID)
+}
+
+// GetReviewsHandler godoc
+//
+//	@Summary    get reviews
+//	@Description  get reviews about saler, newest first
+//	@Tags review
+//	@Produce    json
+//	@Param      saler_id  query uint64 true  "saler id"
+//	@Param      limit  query uint64 false  "limit of reviews, 20 by default, can't be bigger than configured maximum"
+//	@Param      offset  query uint64 false  "offset of reviews"
+//	@Success    200  {object} ReviewListResponse
+//	@Failure    405  {string} string
+//	@Failure    500  {string} string
+//	@Failure    222  {object} delivery.ErrorResponse "Error"
+//	@Router      /review/get_list [get]
+func (rh *ReviewHandler) GetReviewsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, `Method not allowed`, http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	ctx := r.Context()
+
+	salerID, err := utils.ParseUint64FromRequest(r, "saler_id")
+	if err != nil {
+		delivery.HandleErr(w, rh.logger, err)
+
+		return
+	}
+
+	limit, err := utils.ParseUint64FromRequest(r, "limit")
+	if err != nil {
+		limit = 0
+	}
+
+	offset, err := utils.ParseUint64FromRequest(r, "offset")
+	if err != nil {
+		offset = 0
+	}
+
+	reviews, err := rh.service.GetReviews(ctx, salerID, limit, offset)
+	if err != nil {
+		delivery.HandleErr(w, rh.logger, err)
+
+		return
+	}
+
+	delivery.SendOkResponse(w, rh.logger, NewReviewListResponse(delivery.StatusResponseSuccessful, reviews))
+	rh.logger.Infof("in GetReviewsHandler: get %d reviews of saler %d", len(reviews), salerID)
+}
@@ -0,0 +1,236 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/SanExpett/marketplace-backend/pkg/models"
+	myerrors "github.com/SanExpett/marketplace-backend/pkg/my_errors"
+	"github.com/SanExpett/marketplace-backend/pkg/my_logger"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrOrderNotFound      = myerrors.NewError("Этот заказ не найден")
+	ErrOnlyBuyerReviews   = myerrors.NewError("Оставить отзыв может только покупатель")
+	ErrOrderNotCompleted  = myerrors.NewError("Отзыв можно оставить только после завершения сделки")
+	ErrReviewExists       = myerrors.NewError("Отзыв об этой сделке уже оставлен")
+	ErrReviewNotFound     = myerrors.NewError("Этот отзыв не найден")
+	ErrOnlySalerReplies   = myerrors.NewError("Ответить на отзыв может только продавец")
+	ErrReviewAlreadyReply = myerrors.NewError("На этот отзыв уже есть ответ")
+)
+
+type ReviewStorage struct {
+	pool   *pgxpool.Pool
+	logger *zap.SugaredLogger
+}
+
+func NewReviewStorage(pool *pgxpool.Pool) (*ReviewStorage, error) {
+	logger, err := my_logger.Get()
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return &ReviewStorage{
+		pool:   pool,
+		logger: logger,
+	}, nil
+}
+
+const SQLSelectReview = `SELECT r.id, r.order_id, r.product_id, r.author_id, u.login, r.saler_id, r.rating,
+	r.text, r.reply, r.replied_at, r.created_at
+	FROM public."review" r INNER JOIN public."user" u ON u.id = r.author_id`
+
+func scanReview(row pgx.Row) (*models.Review, error) {
+	review := &models.Review{} //nolint:exhaustruct
+
+	err := row.Scan(&review.ID, &review.OrderID, &review.ProductID, &review.AuthorID, &review.AuthorLogin,
+		&review.SalerID, &review.Rating, &review.Text, &review.Reply, &review.RepliedAt, &review.CreatedAt)
+
+	return review, err //nolint:wrapcheck
+}
+
+func (r *ReviewStorage) selectReview(ctx context.Context, tx pgx.Tx, where string, arg uint64,
+) (*models.Review, error) {
+	review, err := scanReview(tx.QueryRow(ctx, SQLSelectReview+` WHERE `+where, arg))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf(myerrors.ErrTemplate, ErrReviewNotFound)
+		}
+
+		r.logger.Errorf("error with %s %d: %+v", where, arg, err)
+
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return review, nil
+}
+
+// selectOrderForReview locks order, so review and aggregates of saler are changed once per order.
+func (r *ReviewStorage) selectOrderForReview(ctx context.Context, tx pgx.Tx, orderID uint64,
+) (*models.Order, error) {
+	SQLSelectOrder := `SELECT product_id, buyer_id, saler_id, status FROM public."order" WHERE id=$1 FOR UPDATE`
+
+	order := &models.Order{ID: orderID} //nolint:exhaustruct
+
+	err := tx.QueryRow(ctx, SQLSelectOrder, orderID).Scan(&order.ProductID, &order.BuyerID, &order.SalerID,
+		&order.Status)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf(myerrors.ErrTemplate, ErrOrderNotFound)
+		}
+
+		r.logger.Errorf("error with orderID=%d: %+v", orderID, err)
+
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return order, nil
+}
+
+// AddReview leaves review of buyer about saler of completed order and adds its rating
+// to aggregates of saler in the same transaction.
+func (r *ReviewStorage) AddReview(ctx context.Context, orderID uint64, authorID uint64,
+	preReview *models.PreReview,
+) (*models.Review, error) {
+	SQLInsertReview := `INSERT INTO public."review"(order_id, product_id, author_id, saler_id, rating, text)
+		VALUES($1, $2, $3, $4, $5, $6) ON CONFLICT (order_id) DO NOTHING`
+	SQLAddRating := `UPDATE public."user" SET rating_sum = rating_sum + $2, reviews_count = reviews_count + 1
+		WHERE id=$1`
+
+	var review *models.Review
+
+	err := pgx.BeginFunc(ctx, r.pool, func(tx pgx.Tx) error {
+		order, err := r.selectOrderForReview(ctx, tx, orderID)
+		if err != nil {
+			return err
+		}
+
+		if order.BuyerID != authorID {
+			if order.SalerID == authorID {
+				return fmt.Errorf(myerrors.ErrTemplate, ErrOnlyBuyerReviews)
+			}
+
+			return fmt.Errorf(myerrors.ErrTemplate, ErrOrderNotFound)
+		}
+
+		if order.Status != models.OrderStatusCompleted {
+			return fmt.Errorf(myerrors.ErrTemplate, ErrOrderNotCompleted)
+		}
+
+		tag, err := tx.Exec(ctx, SQLInsertReview, orderID, order.ProductID, authorID, order.SalerID,
+			preReview.Rating, preReview.Text)
+		if err != nil {
+			r.logger.Errorln(err)
+
+			return fmt.Errorf(myerrors.ErrTemplate, err)
+		}
+
+		if tag.RowsAffected() == 0 {
+			return fmt.Errorf(myerrors.ErrTemplate, ErrReviewExists)
+		}
+
+		_, err = tx.Exec(ctx, SQLAddRating, order.SalerID, preReview.Rating)
+		if err != nil {
+			r.logger.Errorln(err)
+
+			return fmt.Errorf(myerrors.ErrTemplate, err)
+		}
+
+		review, err = r.selectReview(ctx, tx, `r.order_id=$1`, orderID)
+
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return review, nil
+}
+
+// ReplyReview sets reply of saler, saler replies once.
+func (r *ReviewStorage) ReplyReview(ctx context.Context, reviewID uint64, salerID uint64,
+	preReply *models.PreReply,
+) (*models.Review, error) {
+	SQLSelectReviewForReply := `SELECT saler_id, replied_at IS NOT NULL FROM public."review" WHERE id=$1 FOR UPDATE`
+	SQLUpdateReply := `UPDATE public."review" SET reply=$2, replied_at=NOW() WHERE id=$1`
+
+	var review *models.Review
+
+	err := pgx.BeginFunc(ctx, r.pool, func(tx pgx.Tx) error {
+		var (
+			reviewSalerID uint64
+			isReplied     bool
+		)
+
+		err := tx.QueryRow(ctx, SQLSelectReviewForReply, reviewID).Scan(&reviewSalerID, &isReplied)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return fmt.Errorf(myerrors.ErrTemplate, ErrReviewNotFound)
+			}
+
+			r.logger.Errorf("error with reviewID=%d: %+v", reviewID, err)
+
+			return fmt.Errorf(myerrors.ErrTemplate, err)
+		}
+
+		if reviewSalerID != salerID {
+			return fmt.Errorf(myerrors.ErrTemplate, ErrOnlySalerReplies)
+		}
+
+		if isReplied {
+			return fmt.Errorf(myerrors.ErrTemplate, ErrReviewAlreadyReply)
+		}
+
+		_, err = tx.Exec(ctx, SQLUpdateReply, reviewID, preReply.Text)
+		if err != nil {
+			r.logger.Errorln(err)
+
+			return fmt.Errorf(myerrors.ErrTemplate, err)
+		}
+
+		review, err = r.selectReview(ctx, tx, `r.id=$1`, reviewID)
+
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return review, nil
+}
+
+// GetReviews returns reviews about saler, newest first.
+func (r *ReviewStorage) GetReviews(ctx context.Context, salerID uint64, limit uint64, offset uint64,
+) ([]*models.Review, error) {
+	SQLSelectReviews := SQLSelectReview + ` WHERE r.saler_id=$1 ORDER BY r.id DESC LIMIT $2 OFFSET $3`
+
+	var reviews []*models.Review
+
+	err := pgx.BeginFunc(ctx, r.pool, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, SQLSelectReviews, salerID, limit, offset)
+		if err != nil {
+			r.logger.Errorln(err)
+
+			return fmt.Errorf(myerrors.ErrTemplate, err)
+		}
+
+		reviews, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (*models.Review, error) {
+			return scanReview(row)
+		})
+		if err != nil {
+			r.logger.Errorln(err)
+
+			return fmt.Errorf(myerrors.ErrTemplate, err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return reviews, nil
+}
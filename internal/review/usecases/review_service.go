@@ -0,0 +1,89 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+	reviewrepo "github.com/SanExpett/marketplace-backend/internal/review/repository"
+	"github.com/SanExpett/marketplace-backend/pkg/models"
+	myerrors "github.com/SanExpett/marketplace-backend/pkg/my_errors"
+	"github.com/SanExpett/marketplace-backend/pkg/my_logger"
+	"github.com/SanExpett/marketplace-backend/pkg/utils"
+	"go.uber.org/zap"
+	"io"
+)
+
+var _ IReviewStorage = (*reviewrepo.ReviewStorage)(nil)
+
+type IReviewStorage interface {
+	AddReview(ctx context.Context, orderID uint64, authorID uint64, preReview *models.PreReview) (*models.Review, error)
+	ReplyReview(ctx context.Context, reviewID uint64, salerID uint64, preReply *models.PreReply) (*models.Review, error)
+	GetReviews(ctx context.Context, salerID uint64, limit uint64, offset uint64) ([]*models.Review, error)
+}
+
+type ReviewService struct {
+	storage      IReviewStorage
+	maxPageLimit uint64
+	logger       *zap.SugaredLogger
+}
+
+func NewReviewService(reviewStorage IReviewStorage, maxPageLimit uint64) (*ReviewService, error) {
+	logger, err := my_logger.Get()
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return &ReviewService{storage: reviewStorage, maxPageLimit: maxPageLimit, logger: logger}, nil
+}
+
+func (rs *ReviewService) AddReview(ctx context.Context, r io.Reader, orderID uint64, userID uint64,
+) (*models.Review, error) {
+	preReview, err := ValidatePreReview(r)
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	review, err := rs.storage.AddReview(ctx, orderID, userID, preReview)
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	review.Sanitize()
+
+	return review, nil
+}
+
+func (rs *ReviewService) ReplyReview(ctx context.Context, r io.Reader, reviewID uint64, userID uint64,
+) (*models.Review, error) {
+	preReply, err := ValidatePreReply(r)
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	review, err := rs.storage.ReplyReview(ctx, reviewID, userID, preReply)
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	review.Sanitize()
+
+	return review, nil
+}
+
+func (rs *ReviewService) GetReviews(ctx context.Context, salerID uint64, limit uint64, offset uint64,
+) ([]*models.Review, error) {
+	limit, err := utils.ValidateLimit(limit, defaultPageLimit, rs.maxPageLimit)
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	reviews, err := rs.storage.GetReviews(ctx, salerID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	for _, review := range reviews {
+		review.Sanitize()
+	}
+
+	return reviews, nil
+}
@@ -0,0 +1,96 @@
+package usecases
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/SanExpett/marketplace-backend/pkg/models"
+	myerrors "github.com/SanExpett/marketplace-backend/pkg/my_errors"
+	"github.com/SanExpett/marketplace-backend/pkg/my_logger"
+	"github.com/asaskevich/govalidator"
+	"io"
+)
+
+const (
+	defaultPageLimit = 20
+
+	MessageErrWrongRating = "Оценка должна быть от %d до %d"
+)
+
+var (
+	ErrDecodePreReview = myerrors.NewError("Некорректный json отзыва")
+	ErrDecodePreReply  = myerrors.NewError("Некорректный json ответа на отзыв")
+)
+
+func validatePreReview(r io.Reader) (*models.PreReview, error) {
+	logger, err := my_logger.Get()
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(r)
+	preReview := &models.PreReview{} //nolint:exhaustruct
+	if err := decoder.Decode(preReview); err != nil {
+		logger.Errorln(err)
+
+		return nil, fmt.Errorf(myerrors.ErrTemplate, ErrDecodePreReview)
+	}
+
+	preReview.Trim()
+
+	if preReview.Rating < models.MinRating || preReview.Rating > models.MaxRating {
+		return nil, myerrors.NewError(MessageErrWrongRating, models.MinRating, models.MaxRating)
+	}
+
+	_, err = govalidator.ValidateStruct(preReview)
+	if err != nil {
+		logger.Errorln(err)
+
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return preReview, nil
+}
+
+func ValidatePreReview(r io.Reader) (*models.PreReview, error) {
+	preReview, err := validatePreReview(r)
+	if err != nil {
+		return nil, myerrors.NewError(err.Error())
+	}
+
+	return preReview, nil
+}
+
+func validatePreReply(r io.Reader) (*models.PreReply, error) {
+	logger, err := my_logger.Get()
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(r)
+	preReply := &models.PreReply{} //nolint:exhaustruct
+	if err := decoder.Decode(preReply); err != nil {
+		logger.Errorln(err)
+
+		return nil, fmt.Errorf(myerrors.ErrTemplate, ErrDecodePreReply)
+	}
+
+	preReply.Trim()
+
+	_, err = govalidator.ValidateStruct(preReply)
+	if err != nil {
+		logger.Errorln(err)
+
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return preReply, nil
+}
+
+func ValidatePreReply(r io.Reader) (*models.PreReply, error) {
+	preReply, err := validatePreReply(r)
+	if err != nil {
+		return nil, myerrors.NewError(err.Error())
+	}
+
+	return preReply, nil
+}
@@ -2,9 +2,11 @@ package delivery
 
 import (
 	"encoding/json"
+	"fmt"
 	myerrors "github.com/SanExpett/marketplace-backend/pkg/my_errors"
 	"go.uber.org/zap"
 	"net/http"
+	"sort"
 )
 
 const (
@@ -84,6 +86,20 @@ func sendResponse(w http.ResponseWriter, logger *zap.SugaredLogger, response any
 	}
 }
 
+// SetLinkHeader sets RFC 8288 Link header, links maps relation type to target uri.
+func SetLinkHeader(w http.ResponseWriter, links map[string]string) {
+	relations := make([]string, 0, len(links))
+	for relation := range links {
+		relations = append(relations, relation)
+	}
+
+	sort.Strings(relations)
+
+	for _, relation := range relations {
+		w.Header().Add("Link", fmt.Sprintf(`<%s>; rel="%s"`, links[relation], relation))
+	}
+}
+
 func SendErrResponse(w http.ResponseWriter, logger *zap.SugaredLogger, response any) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(HTTPStatusError)
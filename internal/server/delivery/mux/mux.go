@@ -2,31 +2,50 @@ package mux
 
 import (
 	"context"
+	"github.com/SanExpett/marketplace-backend/pkg/image_loader"
 	"github.com/SanExpett/marketplace-backend/pkg/middleware"
 	"net/http"
+	"time"
 
+	categorydelivery "github.com/SanExpett/marketplace-backend/internal/category/delivery"
+	chatdelivery "github.com/SanExpett/marketplace-backend/internal/chat/delivery"
+	moderationdelivery "github.com/SanExpett/marketplace-backend/internal/moderation/delivery"
+	orderdelivery "github.com/SanExpett/marketplace-backend/internal/order/delivery"
 	productdelivery "github.com/SanExpett/marketplace-backend/internal/product/delivery"
+	reviewdelivery "github.com/SanExpett/marketplace-backend/internal/review/delivery"
+	streamdelivery "github.com/SanExpett/marketplace-backend/internal/stream/delivery"
 	userdelivery "github.com/SanExpett/marketplace-backend/internal/user/delivery"
 
 	"go.uber.org/zap"
 )
 
 type ConfigMux struct {
-	addrOrigin string
-	schema     string
-	portServer string
+	addrOrigin        string
+	schema            string
+	portServer        string
+	pathToImages      string
+	streamHeartbeat   time.Duration
+	trustProxyHeaders bool
 }
 
-func NewConfigMux(addrOrigin string, schema string, portServer string) *ConfigMux {
+func NewConfigMux(addrOrigin string, schema string, portServer string, pathToImages string,
+	streamHeartbeat time.Duration, trustProxyHeaders bool,
+) *ConfigMux {
 	return &ConfigMux{
-		addrOrigin: addrOrigin,
-		schema:     schema,
-		portServer: portServer,
+		addrOrigin:        addrOrigin,
+		schema:            schema,
+		portServer:        portServer,
+		pathToImages:      pathToImages,
+		streamHeartbeat:   streamHeartbeat,
+		trustProxyHeaders: trustProxyHeaders,
 	}
 }
 
 func NewMux(ctx context.Context, configMux *ConfigMux, userService userdelivery.IUserService,
-	productService productdelivery.IProductService, logger *zap.SugaredLogger,
+	productService productdelivery.IProductService, categoryService categorydelivery.ICategoryService,
+	chatService chatdelivery.IChatService, orderService orderdelivery.IOrderService,
+	reviewService reviewdelivery.IReviewService, moderationService moderationdelivery.IModerationService,
+	eventSubscriber streamdelivery.IEventSubscriber, logger *zap.SugaredLogger,
 ) (http.Handler, error) {
 	router := http.NewServeMux()
 
@@ -40,21 +59,157 @@ func NewMux(ctx context.Context, configMux *ConfigMux, userService userdelivery.
 		return nil, err
 	}
 
+	categoryHandler, err := categorydelivery.NewCategoryHandler(categoryService)
+	if err != nil {
+		return nil, err
+	}
+
+	chatHandler, err := chatdelivery.NewChatHandler(chatService)
+	if err != nil {
+		return nil, err
+	}
+
+	orderHandler, err := orderdelivery.NewOrderHandler(orderService)
+	if err != nil {
+		return nil, err
+	}
+
+	reviewHandler, err := reviewdelivery.NewReviewHandler(reviewService)
+	if err != nil {
+		return nil, err
+	}
+
+	moderationHandler, err := moderationdelivery.NewModerationHandler(moderationService)
+	if err != nil {
+		return nil, err
+	}
+
+	streamHandler, err := streamdelivery.NewStreamHandler(eventSubscriber, configMux.streamHeartbeat)
+	if err != nil {
+		return nil, err
+	}
+
 	router.Handle("/api/v1/signup", middleware.Context(ctx,
 		middleware.SetupCORS(userHandler.SignUpHandler, configMux.addrOrigin, configMux.schema)))
 	router.Handle("/api/v1/signin", middleware.Context(ctx,
 		middleware.SetupCORS(userHandler.SignInHandler, configMux.addrOrigin, configMux.schema)))
 	router.Handle("/api/v1/logout", middleware.Context(ctx, http.HandlerFunc(userHandler.LogOutHandler)))
+	router.Handle("/api/v1/profile/get", middleware.Context(ctx,
+		middleware.SetupCORS(userHandler.GetProfileHandler, configMux.addrOrigin, configMux.schema)))
 
 	router.Handle("/api/v1/product/add", middleware.Context(ctx,
 		middleware.SetupCORS(productHandler.AddProductHandler, configMux.addrOrigin, configMux.schema)))
 	router.Handle("/api/v1/product/get", middleware.Context(ctx,
 		middleware.SetupCORS(productHandler.GetProductHandler, configMux.addrOrigin, configMux.schema)))
+	router.Handle("/api/v1/product/update", middleware.Context(ctx,
+		middleware.SetupCORS(productHandler.UpdateProductHandler, configMux.addrOrigin, configMux.schema)))
+	router.Handle("/api/v1/product/price_history", middleware.Context(ctx,
+		middleware.SetupCORS(productHandler.GetPriceHistoryHandler, configMux.addrOrigin, configMux.schema)))
 	router.Handle("/api/v1/product/get_list", middleware.Context(ctx,
 		middleware.SetupCORS(productHandler.GetProductListHandler, configMux.addrOrigin, configMux.schema)))
+	router.Handle("/api/v1/product/facets", middleware.Context(ctx,
+		middleware.SetupCORS(productHandler.GetFacetsHandler, configMux.addrOrigin, configMux.schema)))
+	router.Handle("/api/v1/product/suggest", middleware.Context(ctx,
+		middleware.SetupCORS(productHandler.GetSuggestionsHandler, configMux.addrOrigin, configMux.schema)))
+	router.Handle("/api/v1/product/views", middleware.Context(ctx,
+		middleware.SetupCORS(productHandler.GetDailyViewsHandler, configMux.addrOrigin, configMux.schema)))
+	router.Handle("/api/v1/favorite/add", middleware.Context(ctx,
+		middleware.SetupCORS(productHandler.AddFavoriteHandler, configMux.addrOrigin, configMux.schema)))
+	router.Handle("/api/v1/favorite/delete", middleware.Context(ctx,
+		middleware.SetupCORS(productHandler.DeleteFavoriteHandler, configMux.addrOrigin, configMux.schema)))
+	router.Handle("/api/v1/favorite/get_list", middleware.Context(ctx,
+		middleware.SetupCORS(productHandler.GetFavoritesListHandler, configMux.addrOrigin, configMux.schema)))
+	router.Handle("/api/v1/offer/add", middleware.Context(ctx,
+		middleware.SetupCORS(productHandler.AddOfferHandler, configMux.addrOrigin, configMux.schema)))
+	router.Handle("/api/v1/offer/accept", middleware.Context(ctx,
+		middleware.SetupCORS(productHandler.AcceptOfferHandler, configMux.addrOrigin, configMux.schema)))
+	router.Handle("/api/v1/offer/decline", middleware.Context(ctx,
+		middleware.SetupCORS(productHandler.DeclineOfferHandler, configMux.addrOrigin, configMux.schema)))
+	router.Handle("/api/v1/offer/withdraw", middleware.Context(ctx,
+		middleware.SetupCORS(productHandler.WithdrawOfferHandler, configMux.addrOrigin, configMux.schema)))
+	router.Handle("/api/v1/offer/counter", middleware.Context(ctx,
+		middleware.SetupCORS(productHandler.CounterOfferHandler, configMux.addrOrigin, configMux.schema)))
+	router.Handle("/api/v1/offer/get_list", middleware.Context(ctx,
+		middleware.SetupCORS(productHandler.GetOffersHandler, configMux.addrOrigin, configMux.schema)))
+	router.Handle("/api/v1/promotion/add", middleware.Context(ctx,
+		middleware.SetupCORS(productHandler.AddPromotionHandler, configMux.addrOrigin, configMux.schema)))
+	router.Handle("/api/v1/promotion/grant", middleware.Context(ctx,
+		middleware.SetupCORS(productHandler.GrantPromotionHandler, configMux.addrOrigin, configMux.schema)))
+	router.Handle("/api/v1/promotion/get_list", middleware.Context(ctx,
+		middleware.SetupCORS(productHandler.GetPromotionsHandler, configMux.addrOrigin, configMux.schema)))
+	router.Handle("/api/v1/saved_search/add", middleware.Context(ctx,
+		middleware.SetupCORS(productHandler.AddSavedSearchHandler, configMux.addrOrigin, configMux.schema)))
+	router.Handle("/api/v1/saved_search/get_list", middleware.Context(ctx,
+		middleware.SetupCORS(productHandler.GetSavedSearchesHandler, configMux.addrOrigin, configMux.schema)))
+	router.Handle("/api/v1/saved_search/delete", middleware.Context(ctx,
+		middleware.SetupCORS(productHandler.DeleteSavedSearchHandler, configMux.addrOrigin, configMux.schema)))
+
+	router.Handle("/api/v1/categories", middleware.Context(ctx,
+		middleware.SetupCORS(categoryHandler.GetCategoriesHandler, configMux.addrOrigin, configMux.schema)))
+
+	router.Handle("/api/v1/chat/start", middleware.Context(ctx,
+		middleware.SetupCORS(chatHandler.StartConversationHandler, configMux.addrOrigin, configMux.schema)))
+	router.Handle("/api/v1/chat/send", middleware.Context(ctx,
+		middleware.SetupCORS(chatHandler.SendMessageHandler, configMux.addrOrigin, configMux.schema)))
+	router.Handle("/api/v1/chat/get_list", middleware.Context(ctx,
+		middleware.SetupCORS(chatHandler.GetConversationsHandler, configMux.addrOrigin, configMux.schema)))
+	router.Handle("/api/v1/chat/messages", middleware.Context(ctx,
+		middleware.SetupCORS(chatHandler.GetMessagesHandler, configMux.addrOrigin, configMux.schema)))
+	router.Handle("/api/v1/chat/read", middleware.Context(ctx,
+		middleware.SetupCORS(chatHandler.MarkReadHandler, configMux.addrOrigin, configMux.schema)))
+	router.Handle("/api/v1/chat/unread", middleware.Context(ctx,
+		middleware.SetupCORS(chatHandler.GetUnreadCountHandler, configMux.addrOrigin, configMux.schema)))
+
+	router.Handle("/api/v1/order/add", middleware.Context(ctx,
+		middleware.SetupCORS(orderHandler.CreateOrderHandler, configMux.addrOrigin, configMux.schema)))
+	router.Handle("/api/v1/order/pay", middleware.Context(ctx,
+		middleware.SetupCORS(orderHandler.PayOrderHandler, configMux.addrOrigin, configMux.schema)))
+	router.Handle("/api/v1/order/ship", middleware.Context(ctx,
+		middleware.SetupCORS(orderHandler.ShipOrderHandler, configMux.addrOrigin, configMux.schema)))
+	router.Handle("/api/v1/order/complete", middleware.Context(ctx,
+		middleware.SetupCORS(orderHandler.CompleteOrderHandler, configMux.addrOrigin, configMux.schema)))
+	router.Handle("/api/v1/order/cancel", middleware.Context(ctx,
+		middleware.SetupCORS(orderHandler.CancelOrderHandler, configMux.addrOrigin, configMux.schema)))
+	router.Handle("/api/v1/order/get", middleware.Context(ctx,
+		middleware.SetupCORS(orderHandler.GetOrderHandler, configMux.addrOrigin, configMux.schema)))
+	router.Handle("/api/v1/order/get_list", middleware.Context(ctx,
+		middleware.SetupCORS(orderHandler.GetOrdersHandler, configMux.addrOrigin, configMux.schema)))
+
+	router.Handle("/api/v1/review/add", middleware.Context(ctx,
+		middleware.SetupCORS(reviewHandler.AddReviewHandler, configMux.addrOrigin, configMux.schema)))
+	router.Handle("/api/v1/review/reply", middleware.Context(ctx,
+		middleware.SetupCORS(reviewHandler.ReplyReviewHandler, configMux.addrOrigin, configMux.schema)))
+	router.Handle("/api/v1/review/get_list", middleware.Context(ctx,
+		middleware.SetupCORS(reviewHandler.GetReviewsHandler, configMux.addrOrigin, configMux.schema)))
+
+	router.Handle("/api/v1/report/add", middleware.Context(ctx,
+		middleware.SetupCORS(moderationHandler.AddReportHandler, configMux.addrOrigin, configMux.schema)))
+	router.Handle("/api/v1/moderation/queue", middleware.Context(ctx,
+		middleware.SetupCORS(moderationHandler.GetQueueHandler, configMux.addrOrigin, configMux.schema)))
+	router.Handle("/api/v1/moderation/hide", middleware.Context(ctx,
+		middleware.SetupCORS(moderationHandler.HideProductHandler, configMux.addrOrigin, configMux.schema)))
+	router.Handle("/api/v1/moderation/restore", middleware.Context(ctx,
+		middleware.SetupCORS(moderationHandler.RestoreProductHandler, configMux.addrOrigin, configMux.schema)))
+	router.Handle("/api/v1/moderation/ban", middleware.Context(ctx,
+		middleware.SetupCORS(moderationHandler.BanUserHandler, configMux.addrOrigin, configMux.schema)))
+	router.Handle("/api/v1/moderation/audit", middleware.Context(ctx,
+		middleware.SetupCORS(moderationHandler.GetAuditTrailHandler, configMux.addrOrigin, configMux.schema)))
+
+	// stream is not wrapped in middleware.Context: it needs context of request to notice disconnect of client
+	router.Handle("/api/v1/stream",
+		middleware.SetupCORS(streamHandler.StreamHandler, configMux.addrOrigin, configMux.schema))
+
+	router.Handle(image_loader.URLPrefixImages, http.StripPrefix(image_loader.URLPrefixImages,
+		http.FileServer(http.Dir(configMux.pathToImages))))
+
+	// banned user can log out
+	var handler http.Handler = middleware.RejectBanned(router, userService, logger, "/api/v1/logout")
+	if configMux.trustProxyHeaders {
+		handler = middleware.RealIP(handler)
+	}
 
 	mux := http.NewServeMux()
-	mux.Handle("/", middleware.Panic(router, logger))
+	mux.Handle("/", middleware.Panic(handler, logger))
 
 	return mux, nil
 }
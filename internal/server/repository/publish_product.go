@@ -0,0 +1,32 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	myerrors "github.com/SanExpett/marketplace-backend/pkg/my_errors"
+	"github.com/SanExpett/marketplace-backend/pkg/my_logger"
+	"github.com/jackc/pgx/v5"
+)
+
+// PublishProduct marks the first activation of product and queues it for matching with saved searches.
+// It must be called in transaction which makes product active, later activations change nothing.
+func PublishProduct(ctx context.Context, tx pgx.Tx, productID uint64) error {
+	logger, err := my_logger.Get()
+	if err != nil {
+		return fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	SQLPublishProduct := `WITH published AS (
+			UPDATE public."product" SET published_at = NOW()
+			WHERE id = $1 AND status = 'active' AND published_at IS NULL RETURNING id
+		)
+		INSERT INTO public."saved_search_queue"(product_id) SELECT id FROM published ON CONFLICT DO NOTHING`
+
+	if _, err := tx.Exec(ctx, SQLPublishProduct, productID); err != nil {
+		logger.Errorf("error in PublishProduct with productID=%d: %+v", productID, err)
+
+		return fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return nil
+}
@@ -2,35 +2,71 @@ package server
 
 import (
 	"context"
+	categoryrepo "github.com/SanExpett/marketplace-backend/internal/category/repository"
+	categoryusecases "github.com/SanExpett/marketplace-backend/internal/category/usecases"
+	chatrepo "github.com/SanExpett/marketplace-backend/internal/chat/repository"
+	chatusecases "github.com/SanExpett/marketplace-backend/internal/chat/usecases"
+	moderationrepo "github.com/SanExpett/marketplace-backend/internal/moderation/repository"
+	moderationusecases "github.com/SanExpett/marketplace-backend/internal/moderation/usecases"
+	orderrepo "github.com/SanExpett/marketplace-backend/internal/order/repository"
+	orderusecases "github.com/SanExpett/marketplace-backend/internal/order/usecases"
 	productrepo "github.com/SanExpett/marketplace-backend/internal/product/repository"
 	productusecases "github.com/SanExpett/marketplace-backend/internal/product/usecases"
+	reviewrepo "github.com/SanExpett/marketplace-backend/internal/review/repository"
+	reviewusecases "github.com/SanExpett/marketplace-backend/internal/review/usecases"
 	"github.com/SanExpett/marketplace-backend/internal/server/delivery/mux"
 	"github.com/SanExpett/marketplace-backend/internal/server/repository"
 	userrepo "github.com/SanExpett/marketplace-backend/internal/user/repository"
 	userusecases "github.com/SanExpett/marketplace-backend/internal/user/usecases"
 	"github.com/SanExpett/marketplace-backend/pkg/config"
+	"github.com/SanExpett/marketplace-backend/pkg/event_hub"
+	"github.com/SanExpett/marketplace-backend/pkg/image_loader"
+	"github.com/SanExpett/marketplace-backend/pkg/models"
+	myerrors "github.com/SanExpett/marketplace-backend/pkg/my_errors"
 	"github.com/SanExpett/marketplace-backend/pkg/my_logger"
+	"github.com/SanExpett/marketplace-backend/pkg/payment"
+	"github.com/SanExpett/marketplace-backend/pkg/premoderation"
+	"github.com/SanExpett/marketplace-backend/pkg/view_counter"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 )
 
 const (
-	basicTimeout = 10 * time.Second
+	basicTimeout    = 10 * time.Second
+	shutdownTimeout = 30 * time.Second
+
+	paymentProviderFake = "fake"
 )
 
+const MessageErrUnknownPaymentProvider = "Неизвестный платежный провайдер %s"
+
 type Server struct {
 	httpServer *http.Server
 }
 
+// Run serves until SIGINT or SIGTERM, then shuts server down gracefully and waits for background jobs.
 func (s *Server) Run(config *config.Config) error {
-	baseCtx := context.Background()
+	signalCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// baseCtx outlives requests in progress during shutdown, it is cancelled after them
+	baseCtx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 
 	pool, err := repository.NewPgxPool(baseCtx, config.URLDataBase)
 	if err != nil {
 		return err //nolint:wrapcheck
 	}
 
+	defer pool.Close()
+
 	logger, err := my_logger.New(strings.Split(config.OutputLogPath, " "),
 		strings.Split(config.ErrorOutputLogPath, " "))
 	if err != nil {
@@ -39,6 +75,29 @@ func (s *Server) Run(config *config.Config) error {
 
 	defer logger.Sync()
 
+	// background jobs use pool, so they are stopped and awaited before it is closed
+	var background sync.WaitGroup
+
+	defer background.Wait()
+	defer cancel()
+
+	runInBackground := func(job func(ctx context.Context)) {
+		background.Add(1)
+
+		go func() {
+			defer background.Done()
+
+			job(baseCtx)
+		}()
+	}
+
+	eventHub, err := event_hub.NewEventHub(pool, uint64(config.StreamBufferSize))
+	if err != nil {
+		return err
+	}
+
+	runInBackground(eventHub.Run)
+
 	userStorage, err := userrepo.NewUserStorage(pool)
 	if err != nil {
 		return err
@@ -49,18 +108,150 @@ func (s *Server) Run(config *config.Config) error {
 		return err
 	}
 
-	productStorage, err := productrepo.NewProductStorage(pool)
+	baseCurrency := models.NormalizeCurrency(config.BaseCurrency)
+	if err := models.ValidateCurrency(baseCurrency); err != nil {
+		return err
+	}
+
+	currencyRates, err := models.ParseCurrencyRates(config.CurrencyRates)
+	if err != nil {
+		return err
+	}
+
+	var priceSortFactors map[string]float64
+	if len(currencyRates) != 0 {
+		priceSortFactors = currencyRates.MinorUnitFactors(baseCurrency)
+	}
+
+	productStorage, err := productrepo.NewProductStorage(pool, productrepo.NewConfigProductStorage(
+		time.Duration(config.PriceDropDays)*24*time.Hour, priceSortFactors,
+		time.Duration(config.OfferTTL)*time.Hour, time.Duration(config.AcceptedOfferTTL)*time.Hour))
 	if err != nil {
 		return err
 	}
 
-	productService, err := productusecases.NewProductService(productStorage)
+	pathToImages := filepath.Join(config.PathToRoot, config.PathToImages)
+
+	var imageLoader productusecases.IImageLoader
+
+	if config.IngestImages {
+		imageLoader, err = image_loader.NewImageLoader(pathToImages, config.ImageMaxSize,
+			time.Duration(config.ImageFetchTimeout)*time.Second)
+		if err != nil {
+			return err
+		}
+	}
+
+	viewCounter, err := view_counter.NewViewCounter(productStorage, uint64(config.ViewBufferSize),
+		time.Duration(config.ViewFlushInterval)*time.Second, time.Duration(config.ViewDedupeWindow)*time.Minute)
+	if err != nil {
+		return err
+	}
+
+	runInBackground(viewCounter.Run)
+
+	premoderator, err := newPremoderation(config, productStorage)
+	if err != nil {
+		return err
+	}
+
+	promotedSlots, err := models.ParsePromotedSlots(config.PromotedSlots)
+	if err != nil {
+		return err
+	}
+
+	if config.PaymentProvider != paymentProviderFake {
+		return myerrors.NewError(MessageErrUnknownPaymentProvider, config.PaymentProvider)
+	}
+
+	paymentProvider, err := payment.NewFakeProvider(config.FakePaymentMode,
+		time.Duration(config.FakeWebhookDelay)*time.Second)
+	if err != nil {
+		return err
+	}
+
+	productService, err := productusecases.NewProductService(productStorage, imageLoader, viewCounter, eventHub,
+		premoderator, paymentProvider, productusecases.NewConfigProductService([]byte(config.CursorSecret),
+			uint64(config.MaxPageLimit), uint64(config.EstimateCountThreshold), baseCurrency, promotedSlots,
+			uint64(config.PromotionMaxDays), uint64(config.PromotionDayPrice), uint64(config.MaxSavedSearches)))
+	if err != nil {
+		return err
+	}
+
+	runInBackground(func(ctx context.Context) {
+		productService.RunOfferExpiry(ctx, time.Duration(config.OfferExpiryInterval)*time.Second)
+	})
+	runInBackground(func(ctx context.Context) {
+		productService.RunSavedSearchMatcher(ctx, time.Duration(config.SavedSearchMatch)*time.Second)
+	})
+
+	categoryStorage, err := categoryrepo.NewCategoryStorage(pool)
+	if err != nil {
+		return err
+	}
+
+	categoryService, err := categoryusecases.NewCategoryService(categoryStorage)
+	if err != nil {
+		return err
+	}
+
+	chatStorage, err := chatrepo.NewChatStorage(pool)
+	if err != nil {
+		return err
+	}
+
+	chatService, err := chatusecases.NewChatService(chatStorage, eventHub, uint64(config.MaxPageLimit))
+	if err != nil {
+		return err
+	}
+
+	orderStorage, err := orderrepo.NewOrderStorage(pool)
+	if err != nil {
+		return err
+	}
+
+	orderService, err := orderusecases.NewOrderService(orderStorage, paymentProvider, uint64(config.MaxPageLimit))
+	if err != nil {
+		return err
+	}
+
+	// webhooks of promotions bought by sellers go to product service, the rest are payments of orders
+	paymentProvider.SetWebhookHandler(func(ctx context.Context, result *models.Payment) {
+		if result.PromotionID != 0 {
+			productService.HandlePromotionPaymentWebhook(ctx, result)
+
+			return
+		}
+
+		orderService.HandlePaymentWebhook(ctx, result)
+	})
+
+	reviewStorage, err := reviewrepo.NewReviewStorage(pool)
+	if err != nil {
+		return err
+	}
+
+	reviewService, err := reviewusecases.NewReviewService(reviewStorage, uint64(config.MaxPageLimit))
+	if err != nil {
+		return err
+	}
+
+	moderationStorage, err := moderationrepo.NewModerationStorage(pool,
+		moderationrepo.NewConfigModerationStorage(uint64(config.ReportThreshold)))
+	if err != nil {
+		return err
+	}
+
+	moderationService, err := moderationusecases.NewModerationService(moderationStorage, uint64(config.MaxPageLimit))
 	if err != nil {
 		return err
 	}
 
 	handler, err := mux.NewMux(baseCtx, mux.NewConfigMux(config.AllowOrigin,
-		config.Schema, config.PortServer), userService, productService, logger)
+		config.Schema, config.PortServer, pathToImages, time.Duration(config.StreamHeartbeat)*time.Second,
+		config.TrustProxyHeaders),
+		userService, productService, categoryService, chatService, orderService, reviewService,
+		moderationService, eventHub, logger)
 	if err != nil {
 		return err
 	}
@@ -71,13 +262,75 @@ func (s *Server) Run(config *config.Config) error {
 		MaxHeaderBytes: http.DefaultMaxHeaderBytes,
 		ReadTimeout:    basicTimeout,
 		WriteTimeout:   basicTimeout,
+		// only stream uses context of request, it is closed on signal, so shutdown doesn't wait for it
+		BaseContext: func(net.Listener) context.Context {
+			return signalCtx
+		},
 	}
 
+	serveErr := make(chan error, 1)
+
+	go func() {
+		serveErr <- s.httpServer.ListenAndServe()
+	}()
+
 	logger.Infof("Start server:%s", config.PortServer)
 
-	return s.httpServer.ListenAndServe() //nolint:wrapcheck
+	select {
+	case err := <-serveErr:
+		return err //nolint:wrapcheck
+	case <-signalCtx.Done():
+	}
+
+	logger.Infof("Shutdown server:%s", config.PortServer)
+
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancelShutdown()
+
+	return s.Shutdown(shutdownCtx)
 }
 
 func (s *Server) Shutdown(ctx context.Context) error {
 	return s.httpServer.Shutdown(ctx) //nolint:wrapcheck
 }
+
+// newPremoderation builds pipeline of premoderation rules enabled in config.
+func newPremoderation(config *config.Config, productStorage *productrepo.ProductStorage,
+) (*premoderation.Pipeline, error) {
+	var rules []premoderation.IRule
+
+	if config.BannedWords != "" || config.BannedRegexps != "" {
+		stopListRule, err := premoderation.NewStopListRule(strings.Split(config.BannedWords, ","),
+			strings.Split(config.BannedRegexps, ";"))
+		if err != nil {
+			return nil, err
+		}
+
+		rules = append(rules, stopListRule)
+	}
+
+	if config.CheckContacts {
+		rules = append(rules, premoderation.NewContactsRule())
+	}
+
+	if config.PriceOutlierLow != 0 || config.PriceOutlierHigh != 0 {
+		rules = append(rules, premoderation.NewPriceOutlierRule(productStorage, uint64(config.PriceOutlierLow),
+			uint64(config.PriceOutlierHigh), uint64(config.PriceOutlierSample)))
+	}
+
+	if config.ListingsPerHour != 0 {
+		rules = append(rules, premoderation.NewRateLimitRule(productStorage, uint64(config.ListingsPerHour)))
+	}
+
+	if config.DuplicateAction != "" {
+		duplicateRule, err := premoderation.NewDuplicateRule(productStorage, config.DuplicateAction,
+			uint64(config.DuplicatePercent), config.DuplicateAcross)
+		if err != nil {
+			return nil, err
+		}
+
+		rules = append(rules, duplicateRule)
+	}
+
+	return premoderation.NewPipeline(rules...)
+}
@@ -0,0 +1,134 @@
+package delivery
+
+import (
+	"fmt"
+	"github.com/SanExpett/marketplace-backend/internal/server/delivery"
+	"github.com/SanExpett/marketplace-backend/pkg/event_hub"
+	"github.com/SanExpett/marketplace-backend/pkg/models"
+	myerrors "github.com/SanExpett/marketplace-backend/pkg/my_errors"
+	"github.com/SanExpett/marketplace-backend/pkg/my_logger"
+	"go.uber.org/zap"
+	"net/http"
+	"time"
+)
+
+var _ IEventSubscriber = (*event_hub.EventHub)(nil)
+
+type IEventSubscriber interface {
+	Subscribe(userID uint64) (<-chan *models.Event, func())
+}
+
+type StreamHandler struct {
+	hub               IEventSubscriber
+	heartbeatInterval time.Duration
+	logger            *zap.SugaredLogger
+}
+
+func NewStreamHandler(hub IEventSubscriber, heartbeatInterval time.Duration) (*StreamHandler, error) {
+	logger, err := my_logger.Get()
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return &StreamHandler{
+		hub:               hub,
+		heartbeatInterval: heartbeatInterval,
+		logger:            logger,
+	}, nil
+}
+
+func writeEvent(w http.ResponseWriter, event *models.Event) error {
+	body := event.Body
+	if body == nil {
+		body = []byte("null")
+	}
+
+	_, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, body)
+
+	return err //nolint:wrapcheck
+}
+
+// StreamHandler godoc
+//
+//	@Summary    stream of events
+//	@Description  server-sent events for current user: new messages, offers and status changes of favorite products.
+//	@Description  Event data is json body of event, it is null when body is too big, then entity should be fetched.
+//	@Tags stream
+//	@Produce    text/event-stream
+//	@Success    200  {object} models.Event
+//	@Failure    405  {string} string
+//	@Failure    500  {string} string
+//	@Failure    222  {object} delivery.ErrorResponse "Error"
+//	@Router      /stream [get]
+func (s *StreamHandler) StreamHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, `Method not allowed`, http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	ctx := r.Context()
+
+	userID, err := delivery.GetUserIDFromCookie(r)
+	if err != nil {
+		delivery.HandleErr(w, s.logger, err)
+
+		return
+	}
+
+	// stream lives longer than write timeout of server
+	controller := http.NewResponseController(w)
+	if err := controller.SetWriteDeadline(time.Time{}); err != nil {
+		s.logger.Errorln(err)
+		http.Error(w, `Streaming is not supported`, http.StatusInternalServerError)
+
+		return
+	}
+
+	events, unsubscribe := s.hub.Subscribe(userID)
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	if err := controller.Flush(); err != nil {
+		s.logger.Errorln(err)
+
+		return
+	}
+
+	s.logger.Infof("in StreamHandler: user %d connected to stream", userID)
+
+	heartbeat := time.NewTicker(s.heartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			s.logger.Infof("in StreamHandler: user %d disconnected from stream", userID)
+
+			return
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+
+			err = writeEvent(w, event)
+		case <-heartbeat.C:
+			_, err = fmt.Fprint(w, ": ping\n\n")
+		}
+
+		if err == nil {
+			err = controller.Flush()
+		}
+
+		if err != nil {
+			s.logger.Infof("in StreamHandler: stream of user %d is closed: %+v", userID, err)
+
+			return
+		}
+	}
+}
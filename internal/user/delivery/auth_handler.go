@@ -32,6 +32,8 @@ var _ IUserService = (*userusecases.UserService)(nil)
 type IUserService interface {
 	AddUser(ctx context.Context, r io.Reader) (*models.User, error)
 	GetUser(ctx context.Context, login string, password string) (*models.UserWithoutPassword, error)
+	GetProfile(ctx context.Context, userID uint64) (*models.Profile, error)
+	CheckNotBanned(ctx context.Context, userID uint64) error
 }
 
 type UserHandler struct {
@@ -0,0 +1,46 @@
+package delivery
+
+import (
+	"github.com/SanExpett/marketplace-backend/internal/server/delivery"
+	"github.com/SanExpett/marketplace-backend/pkg/utils"
+	"net/http"
+)
+
+// GetProfileHandler godoc
+//
+//	@Summary    get profile
+//	@Description  get public profile of user with rating and count of reviews about him as saler
+//	@Tags profile
+//	@Produce    json
+//	@Param      id  query uint64 true  "user id"
+//	@Success    200  {object} ProfileResponse
+//	@Failure    405  {string} string
+//	@Failure    500  {string} string
+//	@Failure    222  {object} delivery.ErrorResponse "Error"
+//	@Router      /profile/get [get]
+func (u *UserHandler) GetProfileHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, `Method not allowed`, http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	ctx := r.Context()
+
+	userID, err := utils.ParseUint64FromRequest(r, "id")
+	if err != nil {
+		delivery.HandleErr(w, u.logger, err)
+
+		return
+	}
+
+	profile, err := u.service.GetProfile(ctx, userID)
+	if err != nil {
+		delivery.HandleErr(w, u.logger, err)
+
+		return
+	}
+
+	delivery.SendOkResponse(w, u.logger, NewProfileResponse(delivery.StatusResponseSuccessful, profile))
+	u.logger.Infof("in GetProfileHandler: get profile of user %d", userID)
+}
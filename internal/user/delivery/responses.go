@@ -0,0 +1,15 @@
+package delivery
+
+import "github.com/SanExpett/marketplace-backend/pkg/models"
+
+type ProfileResponse struct {
+	Status int             `json:"status"`
+	Body   *models.Profile `json:"body"`
+}
+
+func NewProfileResponse(status int, body *models.Profile) *ProfileResponse {
+	return &ProfileResponse{
+		Status: status,
+		Body:   body,
+	}
+}
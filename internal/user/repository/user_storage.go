@@ -19,6 +19,8 @@ var (
 	ErrLoginBusy     = myerrors.NewError("Такой логин уже занят")
 	ErrLoginNotExist = myerrors.NewError("Такой логин не существует")
 	ErrWrongPassword = myerrors.NewError("Некорректный пароль")
+	ErrUserNotFound  = myerrors.NewError("Этот пользователь не найден")
+	ErrUserBanned    = myerrors.NewError("Пользователь заблокирован модератором")
 
 	NameSeqUser = pgx.Identifier{"public", "user_id_seq"} //nolint:gochecknoglobals
 )
@@ -114,21 +116,24 @@ func (u *UserStorage) isLoginBusy(ctx context.Context, tx pgx.Tx, login string)
 	return true, nil
 }
 
-func (u *UserStorage) getUserByLogin(ctx context.Context, tx pgx.Tx, login string) (*models.User, error) {
-	SQLGetUserByLogin := `SELECT id, login, password FROM public."user" WHERE login=$1;`
+// getUserByLogin also reports whether user is banned by moderation.
+func (u *UserStorage) getUserByLogin(ctx context.Context, tx pgx.Tx, login string) (*models.User, bool, error) {
+	SQLGetUserByLogin := `SELECT id, login, password, banned_at IS NOT NULL FROM public."user" WHERE login=$1;`
 	userLine := tx.QueryRow(ctx, SQLGetUserByLogin, login)
 
 	user := models.User{ //nolint:exhaustruct
 		Login: login,
 	}
 
-	if err := userLine.Scan(&user.ID, &user.Login, &user.Password); err != nil {
+	var isBanned bool
+
+	if err := userLine.Scan(&user.ID, &user.Login, &user.Password, &isBanned); err != nil {
 		u.logger.Errorln(err)
 
-		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+		return nil, false, fmt.Errorf(myerrors.ErrTemplate, err)
 	}
 
-	return &user, nil
+	return &user, isBanned, nil
 }
 
 func (u *UserStorage) GetUser(ctx context.Context, login string, password string) (*models.UserWithoutPassword, error) {
@@ -145,7 +150,9 @@ func (u *UserStorage) GetUser(ctx context.Context, login string, password string
 			return ErrLoginNotExist
 		}
 
-		user, err = u.getUserByLogin(ctx, tx, login)
+		var isBanned bool
+
+		user, isBanned, err = u.getUserByLogin(ctx, tx, login)
 		if err != nil {
 			return fmt.Errorf(myerrors.ErrTemplate, err)
 		}
@@ -159,6 +166,10 @@ func (u *UserStorage) GetUser(ctx context.Context, login string, password string
 			return ErrWrongPassword
 		}
 
+		if isBanned {
+			return ErrUserBanned
+		}
+
 		return nil
 	})
 
@@ -171,3 +182,47 @@ func (u *UserStorage) GetUser(ctx context.Context, login string, password string
 
 	return userWithoutPass, nil
 }
+
+// CheckNotBanned returns ErrUserBanned for user banned by moderation.
+func (u *UserStorage) CheckNotBanned(ctx context.Context, userID uint64) error {
+	SQLIsBanned := `SELECT EXISTS(SELECT 1 FROM public."user" WHERE id=$1 AND banned_at IS NOT NULL)`
+
+	var isBanned bool
+
+	if err := u.pool.QueryRow(ctx, SQLIsBanned, userID).Scan(&isBanned); err != nil {
+		u.logger.Errorf("error with userID=%d: %+v", userID, err)
+
+		return fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	if isBanned {
+		return fmt.Errorf(myerrors.ErrTemplate, ErrUserBanned)
+	}
+
+	return nil
+}
+
+// GetProfile returns public information about user with rating aggregated from reviews about him as saler.
+func (u *UserStorage) GetProfile(ctx context.Context, userID uint64) (*models.Profile, error) {
+	SQLSelectProfile := `SELECT login, rating_sum, reviews_count, created_at FROM public."user" WHERE id=$1`
+
+	profile := &models.Profile{ID: userID} //nolint:exhaustruct
+
+	var ratingSum uint64
+
+	err := u.pool.QueryRow(ctx, SQLSelectProfile, userID).Scan(&profile.Login, &ratingSum, &profile.ReviewsCount,
+		&profile.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf(myerrors.ErrTemplate, ErrUserNotFound)
+		}
+
+		u.logger.Errorf("error with userID=%d: %+v", userID, err)
+
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	profile.Rating = models.Rating(ratingSum, profile.ReviewsCount)
+
+	return profile, nil
+}
@@ -17,6 +17,8 @@ var _ IUserStorage = (*userrepo.UserStorage)(nil)
 type IUserStorage interface {
 	AddUser(ctx context.Context, preUser *models.UserWithoutID) (*models.User, error)
 	GetUser(ctx context.Context, login string, password string) (*models.UserWithoutPassword, error)
+	GetProfile(ctx context.Context, userID uint64) (*models.Profile, error)
+	CheckNotBanned(ctx context.Context, userID uint64) error
 }
 
 type UserService struct {
@@ -67,3 +69,23 @@ func (u *UserService) GetUser(ctx context.Context, login string, password string
 
 	return user, nil
 }
+
+func (u *UserService) GetProfile(ctx context.Context, userID uint64) (*models.Profile, error) {
+	profile, err := u.storage.GetProfile(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	profile.Sanitize()
+
+	return profile, nil
+}
+
+// CheckNotBanned is called on every change of data: ban stops sessions which were started before it.
+func (u *UserService) CheckNotBanned(ctx context.Context, userID uint64) error {
+	if err := u.storage.CheckNotBanned(ctx, userID); err != nil {
+		return fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return nil
+}
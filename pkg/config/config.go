@@ -1,6 +1,9 @@
 package config
 
-import "os"
+import (
+	"os"
+	"strconv"
+)
 
 const (
 	standardAllowOrigin        = "localhost:3000"
@@ -10,6 +13,44 @@ const (
 	standardPathToRoot         = "."
 	standardOutputLogPath      = "stdout /var/log/backend/logs.json"
 	standardErrorOutputLogPath = "stderr /var/log/backend/err_logs.json"
+	standardIngestImages       = false
+	standardPathToImages       = "static/images"
+	standardImageMaxSize       = 5 * 1024 * 1024
+	standardImageFetchTimeout  = 10
+	standardCursorSecret       = "cursor-secret"
+	standardMaxPageLimit       = 100
+	standardEstimateThreshold  = 10000
+	standardViewBufferSize     = 10000
+	standardViewFlushInterval  = 10
+	standardViewDedupeWindow   = 30
+	standardTrustProxyHeaders  = false
+	standardPriceDropDays      = 14
+	standardBaseCurrency       = "RUB"
+	standardCurrencyRates      = ""
+	standardStreamBufferSize   = 64
+	standardStreamHeartbeat    = 15
+	standardOfferTTL           = 48
+	standardOfferExpiry        = 60
+	standardAcceptedOfferTTL   = 72
+	standardPaymentProvider    = "fake"
+	standardFakePaymentMode    = "success"
+	standardFakeWebhookDelay   = 2
+	standardReportThreshold    = 5
+	standardBannedWords        = ""
+	standardBannedRegexps      = ""
+	standardCheckContacts      = true
+	standardPriceOutlierLow    = 20
+	standardPriceOutlierHigh   = 1000
+	standardPriceOutlierSample = 20
+	standardListingsPerHour    = 10
+	standardDuplicateAction    = "review"
+	standardDuplicatePercent   = 80
+	standardDuplicateAcross    = true
+	standardPromotedSlots      = "1,6,11"
+	standardPromotionMaxDays   = 30
+	standardPromotionDayPrice  = 10000
+	standardMaxSavedSearches   = 50
+	standardSavedSearchMatch   = 30
 
 	envAllowOrigin        = "ALLOW_ORIGIN"
 	envSchema             = "SCHEMA"
@@ -18,6 +59,44 @@ const (
 	envPathToRoot         = "PATH_TO_ROOT"
 	envOutputLogPath      = "OUTPUT_LOG_PATH"
 	envErrorOutputLogPath = "ERROR_OUTPUT_LOG_PATH"
+	envIngestImages       = "INGEST_IMAGES"
+	envPathToImages       = "PATH_TO_IMAGES"
+	envImageMaxSize       = "IMAGE_MAX_SIZE"
+	envImageFetchTimeout  = "IMAGE_FETCH_TIMEOUT"
+	envCursorSecret       = "CURSOR_SECRET"
+	envMaxPageLimit       = "MAX_PAGE_LIMIT"
+	envEstimateThreshold  = "ESTIMATE_COUNT_THRESHOLD"
+	envViewBufferSize     = "VIEW_BUFFER_SIZE"
+	envViewFlushInterval  = "VIEW_FLUSH_INTERVAL"
+	envViewDedupeWindow   = "VIEW_DEDUPE_WINDOW"
+	envTrustProxyHeaders  = "TRUST_PROXY_HEADERS"
+	envPriceDropDays      = "PRICE_DROP_DAYS"
+	envBaseCurrency       = "BASE_CURRENCY"
+	envCurrencyRates      = "CURRENCY_RATES"
+	envStreamBufferSize   = "STREAM_BUFFER_SIZE"
+	envStreamHeartbeat    = "STREAM_HEARTBEAT_INTERVAL"
+	envOfferTTL           = "OFFER_TTL"
+	envOfferExpiry        = "OFFER_EXPIRY_INTERVAL"
+	envAcceptedOfferTTL   = "ACCEPTED_OFFER_TTL"
+	envPaymentProvider    = "PAYMENT_PROVIDER"
+	envFakePaymentMode    = "FAKE_PAYMENT_MODE"
+	envFakeWebhookDelay   = "FAKE_PAYMENT_WEBHOOK_DELAY"
+	envReportThreshold    = "REPORT_HIDE_THRESHOLD"
+	envBannedWords        = "PREMODERATION_BANNED_WORDS"
+	envBannedRegexps      = "PREMODERATION_BANNED_REGEXPS"
+	envCheckContacts      = "PREMODERATION_CHECK_CONTACTS"
+	envPriceOutlierLow    = "PRICE_OUTLIER_LOW_PERCENT"
+	envPriceOutlierHigh   = "PRICE_OUTLIER_HIGH_PERCENT"
+	envPriceOutlierSample = "PRICE_OUTLIER_MIN_SAMPLE"
+	envListingsPerHour    = "LISTINGS_PER_HOUR"
+	envDuplicateAction    = "DUPLICATE_ACTION"
+	envDuplicatePercent   = "DUPLICATE_SIMILARITY_PERCENT"
+	envDuplicateAcross    = "DUPLICATE_ACROSS_SALERS"
+	envPromotedSlots      = "PROMOTED_SLOTS"
+	envPromotionMaxDays   = "PROMOTION_MAX_DAYS"
+	envPromotionDayPrice  = "PROMOTION_DAY_PRICE"
+	envMaxSavedSearches   = "MAX_SAVED_SEARCHES"
+	envSavedSearchMatch   = "SAVED_SEARCH_MATCH_INTERVAL"
 )
 
 type Config struct {
@@ -28,17 +107,129 @@ type Config struct {
 	PathToRoot         string
 	OutputLogPath      string
 	ErrorOutputLogPath string
+	IngestImages       bool
+	PathToImages       string
+	ImageMaxSize       int64
+	// ImageFetchTimeout in seconds
+	ImageFetchTimeout int64
+	CursorSecret      string
+	MaxPageLimit      int64
+	// EstimateCountThreshold is number of rows after which total count of products is estimated
+	EstimateCountThreshold int64
+	ViewBufferSize         int64
+	// ViewFlushInterval in seconds
+	ViewFlushInterval int64
+	// ViewDedupeWindow in minutes, views of one viewer during it are counted once
+	ViewDedupeWindow int64
+	// TrustProxyHeaders takes address of client from X-Real-IP and X-Forwarded-For, enable it only behind
+	// reverse proxy which sets these headers
+	TrustProxyHeaders bool
+	// PriceDropDays is period during which fallen price is shown in feed
+	PriceDropDays int64
+	// BaseCurrency is used for prices without currency and as target of conversion
+	BaseCurrency string
+	// CurrencyRates like "USD:92.5,EUR:100.1" are prices of currencies in base currency, used for sorting by price
+	CurrencyRates string
+	// StreamBufferSize is number of events kept for slow stream client, newer events are dropped
+	StreamBufferSize int64
+	// StreamHeartbeat is interval in seconds, keeps idle stream connections alive through proxies
+	StreamHeartbeat int64
+	// OfferTTL in hours, offers and counter offers without answer expire after it
+	OfferTTL int64
+	// OfferExpiryInterval in seconds, how often outdated offers are expired
+	OfferExpiryInterval int64
+	// AcceptedOfferTTL in hours, accepted offer reserves product for buyer during it
+	AcceptedOfferTTL int64
+	// PaymentProvider is name of provider, only "fake" is supported now
+	PaymentProvider string
+	// FakePaymentMode is one of success, failure, async, async_failure
+	FakePaymentMode string
+	// FakeWebhookDelay in seconds, delay of webhook in async modes of fake provider
+	FakeWebhookDelay int64
+	// ReportThreshold is number of open reports after which product is hidden until moderator's decision,
+	// 0 disables automatic hiding
+	ReportThreshold int64
+	// BannedWords like "word1,word2" reject product which contains any of them
+	BannedWords string
+	// BannedRegexps like "regexp1;regexp2" send product which matches any of them to manual review
+	BannedRegexps string
+	// CheckContacts sends product with phone number or link to manual review
+	CheckContacts bool
+	// PriceOutlierLow and PriceOutlierHigh are bounds of normal price in percents of median price of category,
+	// product with price out of them is sent to manual review, 0 disables bound
+	PriceOutlierLow  int64
+	PriceOutlierHigh int64
+	// PriceOutlierSample is number of active products in category needed to check price
+	PriceOutlierSample int64
+	// ListingsPerHour is limit of new products of one saler per hour, 0 disables limit
+	ListingsPerHour int64
+	// DuplicateAction is what to do with duplicate of existing product: reject, warn or review,
+	// empty value disables search of duplicates
+	DuplicateAction string
+	// DuplicatePercent is minimal similarity of texts of duplicates in percents
+	DuplicatePercent int64
+	// DuplicateAcross enables search of duplicates among products of other salers
+	DuplicateAcross bool
+	// PromotedSlots like "1,6,11" are positions on page of default feed taken by promoted products,
+	// empty value disables them
+	PromotedSlots string
+	// PromotionMaxDays is the longest promotion which seller can buy or moderator can grant
+	PromotionMaxDays int64
+	// PromotionDayPrice is price of one started day of promotion in minor units of base currency
+	PromotionDayPrice int64
+	// MaxSavedSearches is how many searches one user can save, 0 disables saved searches
+	MaxSavedSearches int64
+	// SavedSearchMatch in seconds, how often new products are matched with saved searches and digests are sent
+	SavedSearchMatch int64
 }
 
 func New() *Config {
 	return &Config{
-		AllowOrigin:        getEnvStr(envAllowOrigin, standardAllowOrigin),
-		Schema:             getEnvStr(envSchema, standardSchema),
-		PortServer:         getEnvStr(envPortBackend, standardPort),
-		URLDataBase:        getEnvStr(envURLDataBase, standardURLDataBase),
-		PathToRoot:         getEnvStr(envPathToRoot, standardPathToRoot),
-		OutputLogPath:      getEnvStr(envOutputLogPath, standardOutputLogPath),
-		ErrorOutputLogPath: getEnvStr(envErrorOutputLogPath, standardErrorOutputLogPath),
+		AllowOrigin:            getEnvStr(envAllowOrigin, standardAllowOrigin),
+		Schema:                 getEnvStr(envSchema, standardSchema),
+		PortServer:             getEnvStr(envPortBackend, standardPort),
+		URLDataBase:            getEnvStr(envURLDataBase, standardURLDataBase),
+		PathToRoot:             getEnvStr(envPathToRoot, standardPathToRoot),
+		OutputLogPath:          getEnvStr(envOutputLogPath, standardOutputLogPath),
+		ErrorOutputLogPath:     getEnvStr(envErrorOutputLogPath, standardErrorOutputLogPath),
+		IngestImages:           getEnvBool(envIngestImages, standardIngestImages),
+		PathToImages:           getEnvStr(envPathToImages, standardPathToImages),
+		ImageMaxSize:           getEnvPositiveInt64(envImageMaxSize, standardImageMaxSize),
+		ImageFetchTimeout:      getEnvPositiveInt64(envImageFetchTimeout, standardImageFetchTimeout),
+		CursorSecret:           getEnvStr(envCursorSecret, standardCursorSecret),
+		MaxPageLimit:           getEnvPositiveInt64(envMaxPageLimit, standardMaxPageLimit),
+		EstimateCountThreshold: getEnvNonNegativeInt64(envEstimateThreshold, standardEstimateThreshold),
+		ViewBufferSize:         getEnvNonNegativeInt64(envViewBufferSize, standardViewBufferSize),
+		ViewFlushInterval:      getEnvPositiveInt64(envViewFlushInterval, standardViewFlushInterval),
+		ViewDedupeWindow:       getEnvNonNegativeInt64(envViewDedupeWindow, standardViewDedupeWindow),
+		TrustProxyHeaders:      getEnvBool(envTrustProxyHeaders, standardTrustProxyHeaders),
+		PriceDropDays:          getEnvNonNegativeInt64(envPriceDropDays, standardPriceDropDays),
+		BaseCurrency:           getEnvStr(envBaseCurrency, standardBaseCurrency),
+		CurrencyRates:          getEnvStr(envCurrencyRates, standardCurrencyRates),
+		StreamBufferSize:       getEnvNonNegativeInt64(envStreamBufferSize, standardStreamBufferSize),
+		StreamHeartbeat:        getEnvPositiveInt64(envStreamHeartbeat, standardStreamHeartbeat),
+		OfferTTL:               getEnvPositiveInt64(envOfferTTL, standardOfferTTL),
+		OfferExpiryInterval:    getEnvPositiveInt64(envOfferExpiry, standardOfferExpiry),
+		AcceptedOfferTTL:       getEnvPositiveInt64(envAcceptedOfferTTL, standardAcceptedOfferTTL),
+		PaymentProvider:        getEnvStr(envPaymentProvider, standardPaymentProvider),
+		FakePaymentMode:        getEnvStr(envFakePaymentMode, standardFakePaymentMode),
+		FakeWebhookDelay:       getEnvNonNegativeInt64(envFakeWebhookDelay, standardFakeWebhookDelay),
+		ReportThreshold:        getEnvNonNegativeInt64(envReportThreshold, standardReportThreshold),
+		BannedWords:            getEnvStr(envBannedWords, standardBannedWords),
+		BannedRegexps:          getEnvStr(envBannedRegexps, standardBannedRegexps),
+		CheckContacts:          getEnvBool(envCheckContacts, standardCheckContacts),
+		PriceOutlierLow:        getEnvNonNegativeInt64(envPriceOutlierLow, standardPriceOutlierLow),
+		PriceOutlierHigh:       getEnvNonNegativeInt64(envPriceOutlierHigh, standardPriceOutlierHigh),
+		PriceOutlierSample:     getEnvNonNegativeInt64(envPriceOutlierSample, standardPriceOutlierSample),
+		ListingsPerHour:        getEnvNonNegativeInt64(envListingsPerHour, standardListingsPerHour),
+		DuplicateAction:        getEnvStr(envDuplicateAction, standardDuplicateAction),
+		DuplicatePercent:       getEnvNonNegativeInt64(envDuplicatePercent, standardDuplicatePercent),
+		DuplicateAcross:        getEnvBool(envDuplicateAcross, standardDuplicateAcross),
+		PromotedSlots:          getEnvStr(envPromotedSlots, standardPromotedSlots),
+		PromotionMaxDays:       getEnvPositiveInt64(envPromotionMaxDays, standardPromotionMaxDays),
+		PromotionDayPrice:      getEnvPositiveInt64(envPromotionDayPrice, standardPromotionDayPrice),
+		MaxSavedSearches:       getEnvNonNegativeInt64(envMaxSavedSearches, standardMaxSavedSearches),
+		SavedSearchMatch:       getEnvPositiveInt64(envSavedSearchMatch, standardSavedSearchMatch),
 	}
 }
 
@@ -50,3 +241,51 @@ func getEnvStr(name string, defaultValue string) string {
 
 	return result
 }
+
+func getEnvBool(name string, defaultValue bool) bool {
+	result, ok := os.LookupEnv(name)
+	if !ok {
+		return defaultValue
+	}
+
+	value, err := strconv.ParseBool(result)
+	if err != nil {
+		return defaultValue
+	}
+
+	return value
+}
+
+func getEnvInt64(name string, defaultValue int64) int64 {
+	result, ok := os.LookupEnv(name)
+	if !ok {
+		return defaultValue
+	}
+
+	value, err := strconv.ParseInt(result, 10, 64)
+	if err != nil {
+		return defaultValue
+	}
+
+	return value
+}
+
+// getEnvPositiveInt64 is for intervals and other values which can't be zero.
+func getEnvPositiveInt64(name string, defaultValue int64) int64 {
+	value := getEnvInt64(name, defaultValue)
+	if value <= 0 {
+		return defaultValue
+	}
+
+	return value
+}
+
+// getEnvNonNegativeInt64 is for sizes and limits which are used as uint64.
+func getEnvNonNegativeInt64(name string, defaultValue int64) int64 {
+	value := getEnvInt64(name, defaultValue)
+	if value < 0 {
+		return defaultValue
+	}
+
+	return value
+}
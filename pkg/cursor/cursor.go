@@ -0,0 +1,62 @@
+package cursor
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	myerrors "github.com/SanExpett/marketplace-backend/pkg/my_errors"
+)
+
+const separator = "."
+
+var ErrInvalidCursor = myerrors.NewError("Некорректный курсор")
+
+func sign(payload []byte, secret []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(payload)
+
+	return mac.Sum(nil)
+}
+
+// Encode returns opaque cursor: base64 payload and its HMAC signature.
+func Encode(payload any, secret []byte) (string, error) {
+	rawPayload, err := json.Marshal(payload)
+	if err != nil {
+		return "", fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(rawPayload) + separator +
+		base64.RawURLEncoding.EncodeToString(sign(rawPayload, secret)), nil
+}
+
+// Decode checks signature of cursor and unmarshals its payload.
+func Decode(rawCursor string, secret []byte, payload any) error {
+	encodedPayload, encodedSignature, ok := strings.Cut(rawCursor, separator)
+	if !ok {
+		return fmt.Errorf(myerrors.ErrTemplate, ErrInvalidCursor)
+	}
+
+	rawPayload, err := base64.RawURLEncoding.DecodeString(encodedPayload)
+	if err != nil {
+		return fmt.Errorf(myerrors.ErrTemplate, ErrInvalidCursor)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(encodedSignature)
+	if err != nil {
+		return fmt.Errorf(myerrors.ErrTemplate, ErrInvalidCursor)
+	}
+
+	if !hmac.Equal(signature, sign(rawPayload, secret)) {
+		return fmt.Errorf(myerrors.ErrTemplate, ErrInvalidCursor)
+	}
+
+	if err := json.Unmarshal(rawPayload, payload); err != nil {
+		return fmt.Errorf(myerrors.ErrTemplate, ErrInvalidCursor)
+	}
+
+	return nil
+}
@@ -0,0 +1,76 @@
+package cursor
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+)
+
+type testPayload struct {
+	ID        uint64 `json:"id"`
+	CreatedAt string `json:"created_at"`
+}
+
+func TestEncodeDecode(t *testing.T) {
+	secret := []byte("secret")
+	payload := testPayload{ID: 42, CreatedAt: "2024-04-01T10:00:00Z"}
+
+	rawCursor, err := Encode(payload, secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded testPayload
+	if err := Decode(rawCursor, secret, &decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	if decoded != payload {
+		t.Fatalf("decoded %+v, want %+v", decoded, payload)
+	}
+}
+
+func TestDecodeRejectsInvalidCursor(t *testing.T) {
+	secret := []byte("secret")
+
+	rawCursor, err := Encode(testPayload{ID: 42}, secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	encodedPayload, encodedSignature, _ := strings.Cut(rawCursor, separator)
+	forgedPayload := base64.RawURLEncoding.EncodeToString([]byte(`{"id":1}`))
+
+	tests := map[string]struct {
+		rawCursor string
+		secret    []byte
+	}{
+		"other secret":        {rawCursor, []byte("other")},
+		"forged payload":      {forgedPayload + separator + encodedSignature, secret},
+		"no separator":        {encodedPayload + encodedSignature, secret},
+		"broken payload":      {"!!!" + separator + encodedSignature, secret},
+		"broken signature":    {encodedPayload + separator + "!!!", secret},
+		"truncated signature": {encodedPayload + separator + encodedSignature[:10], secret},
+		"empty":               {"", secret},
+	}
+
+	for name, test := range tests {
+		var decoded testPayload
+		if err := Decode(test.rawCursor, test.secret, &decoded); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("%s: expected ErrInvalidCursor, got %v", name, err)
+		}
+	}
+}
+
+func TestDecodeRejectsSignedGarbage(t *testing.T) {
+	secret := []byte("secret")
+	rawPayload := []byte("not json")
+	rawCursor := base64.RawURLEncoding.EncodeToString(rawPayload) + separator +
+		base64.RawURLEncoding.EncodeToString(sign(rawPayload, secret))
+
+	var decoded testPayload
+	if err := Decode(rawCursor, secret, &decoded); !errors.Is(err, ErrInvalidCursor) {
+		t.Fatalf("expected ErrInvalidCursor, got %v", err)
+	}
+}
@@ -0,0 +1,195 @@
+package event_hub
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/SanExpett/marketplace-backend/pkg/models"
+	myerrors "github.com/SanExpett/marketplace-backend/pkg/my_errors"
+	"github.com/SanExpett/marketplace-backend/pkg/my_logger"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+	"sync"
+	"time"
+)
+
+const (
+	NotifyChannel = "marketplace_events"
+	// maxNotifyPayload is limit of postgres on payload of NOTIFY
+	maxNotifyPayload = 8000
+	reconnectDelay   = 5 * time.Second
+)
+
+type notification struct {
+	Recipients []uint64      `json:"recipients"`
+	Event      *models.Event `json:"event"`
+}
+
+// EventHub delivers events to subscribed users. Events are published through postgres NOTIFY and
+// dispatched to local subscribers on LISTEN, so users connected to any replica receive them.
+type EventHub struct {
+	pool       *pgxpool.Pool
+	bufferSize uint64
+	mu         sync.RWMutex
+	// subscribers are channels of stream connections of each user
+	subscribers map[uint64]map[chan *models.Event]struct{}
+	logger      *zap.SugaredLogger
+}
+
+// NewEventHub creates hub. pool can be nil, then events are delivered only to subscribers of this process.
+func NewEventHub(pool *pgxpool.Pool, bufferSize uint64) (*EventHub, error) {
+	logger, err := my_logger.Get()
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return &EventHub{ //nolint:exhaustruct
+		pool:        pool,
+		bufferSize:  bufferSize,
+		subscribers: make(map[uint64]map[chan *models.Event]struct{}),
+		logger:      logger,
+	}, nil
+}
+
+// Subscribe returns channel with events for user. Call of unsubscribe closes the channel.
+func (e *EventHub) Subscribe(userID uint64) (<-chan *models.Event, func()) {
+	events := make(chan *models.Event, e.bufferSize)
+
+	e.mu.Lock()
+	if e.subscribers[userID] == nil {
+		e.subscribers[userID] = make(map[chan *models.Event]struct{})
+	}
+
+	e.subscribers[userID][events] = struct{}{}
+	e.mu.Unlock()
+
+	var once sync.Once
+
+	unsubscribe := func() {
+		once.Do(func() {
+			e.mu.Lock()
+			defer e.mu.Unlock()
+
+			delete(e.subscribers[userID], events)
+
+			if len(e.subscribers[userID]) == 0 {
+				delete(e.subscribers, userID)
+			}
+
+			close(events)
+		})
+	}
+
+	return events, unsubscribe
+}
+
+// dispatch never blocks: events for subscriber with full buffer are dropped.
+func (e *EventHub) dispatch(n *notification) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	for _, userID := range n.Recipients {
+		for events := range e.subscribers[userID] {
+			select {
+			case events <- n.Event:
+			default:
+				e.logger.Warnf("event %s for user %d is dropped: buffer is full", n.Event.Type, userID)
+			}
+		}
+	}
+}
+
+// Publish sends event to recipients. Event body is dropped if payload exceeds limit of NOTIFY.
+func (e *EventHub) Publish(ctx context.Context, event *models.Event, recipients ...uint64) error {
+	if len(recipients) == 0 {
+		return nil
+	}
+
+	n := &notification{Recipients: recipients, Event: event}
+
+	if e.pool == nil {
+		e.dispatch(n)
+
+		return nil
+	}
+
+	payload, err := json.Marshal(n)
+	if err != nil {
+		e.logger.Errorln(err)
+
+		return fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	if len(payload) >= maxNotifyPayload {
+		n.Event = &models.Event{Type: event.Type} //nolint:exhaustruct
+
+		payload, err = json.Marshal(n)
+		if err != nil {
+			e.logger.Errorln(err)
+
+			return fmt.Errorf(myerrors.ErrTemplate, err)
+		}
+	}
+
+	_, err = e.pool.Exec(ctx, `SELECT pg_notify($1, $2)`, NotifyChannel, string(payload))
+	if err != nil {
+		e.logger.Errorln(err)
+
+		return fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return nil
+}
+
+func (e *EventHub) listen(ctx context.Context) error {
+	conn, err := e.pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+	defer conn.Release()
+
+	_, err = conn.Exec(ctx, "LISTEN "+pgx.Identifier{NotifyChannel}.Sanitize())
+	if err != nil {
+		return fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	for {
+		pgNotification, err := conn.Conn().WaitForNotification(ctx)
+		if err != nil {
+			return fmt.Errorf(myerrors.ErrTemplate, err)
+		}
+
+		n := &notification{} //nolint:exhaustruct
+		if err := json.Unmarshal([]byte(pgNotification.Payload), n); err != nil || n.Event == nil {
+			e.logger.Errorf("wrong notification %s: %+v", pgNotification.Payload, err)
+
+			continue
+		}
+
+		e.dispatch(n)
+	}
+}
+
+// Run listens notifications of all replicas until ctx is done. Connection is restored after errors,
+// events published while connection is lost are not delivered.
+func (e *EventHub) Run(ctx context.Context) {
+	if e.pool == nil {
+		return
+	}
+
+	for {
+		err := e.listen(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+
+		e.logger.Errorf("error in listening of events, reconnect in %s: %+v", reconnectDelay, err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(reconnectDelay):
+		}
+	}
+}
@@ -0,0 +1,318 @@
+package image_loader
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/jpeg" // register decoder for image.DecodeConfig
+	_ "image/png"  // register decoder for image.DecodeConfig
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	myerrors "github.com/SanExpett/marketplace-backend/pkg/my_errors"
+	"github.com/SanExpett/marketplace-backend/pkg/my_logger"
+	"github.com/SanExpett/marketplace-backend/pkg/utils"
+	"go.uber.org/zap"
+)
+
+const (
+	maxRedirects = 3
+
+	// hashWidth and hashHeight are size of grid of difference hash, it gives 64 bits
+	hashWidth  = 9
+	hashHeight = 8
+	maxPixels  = 50_000_000
+
+	URLPrefixImages = "/static/images/"
+)
+
+var (
+	ErrImageURL         = myerrors.NewError("Некорректный адрес изображения")
+	ErrImageUnavailable = myerrors.NewError("Не удалось загрузить изображение")
+	ErrImageTooLarge    = myerrors.NewError("Изображение слишком большое")
+	ErrImageFormat      = myerrors.NewError("Изображение должно быть в формате png или jpeg")
+	ErrForbiddenAddress = myerrors.NewError("Адрес изображения указывает на запрещённую сеть")
+
+	allowedContentTypes = map[string]string{ //nolint:gochecknoglobals
+		"image/png":  ".png",
+		"image/jpeg": ".jpg",
+	}
+
+	// forbiddenPrefixes are special-purpose ranges from IANA registries: local and private networks,
+	// shared address space of carriers, benchmarking, documentation, reserved ranges and translation
+	// prefixes which can lead into them.
+	forbiddenPrefixes = []netip.Prefix{ //nolint:gochecknoglobals
+		netip.MustParsePrefix("0.0.0.0/8"),
+		netip.MustParsePrefix("10.0.0.0/8"),
+		netip.MustParsePrefix("100.64.0.0/10"),
+		netip.MustParsePrefix("127.0.0.0/8"),
+		netip.MustParsePrefix("169.254.0.0/16"),
+		netip.MustParsePrefix("172.16.0.0/12"),
+		netip.MustParsePrefix("192.0.0.0/24"),
+		netip.MustParsePrefix("192.0.2.0/24"),
+		netip.MustParsePrefix("192.88.99.0/24"),
+		netip.MustParsePrefix("192.168.0.0/16"),
+		netip.MustParsePrefix("198.18.0.0/15"),
+		netip.MustParsePrefix("198.51.100.0/24"),
+		netip.MustParsePrefix("203.0.113.0/24"),
+		netip.MustParsePrefix("224.0.0.0/4"),
+		netip.MustParsePrefix("240.0.0.0/4"),
+		netip.MustParsePrefix("::/128"),
+		netip.MustParsePrefix("::1/128"),
+		netip.MustParsePrefix("64:ff9b::/96"),
+		netip.MustParsePrefix("64:ff9b:1::/48"),
+		netip.MustParsePrefix("100::/64"),
+		netip.MustParsePrefix("2001::/23"),
+		netip.MustParsePrefix("2001:db8::/32"),
+		netip.MustParsePrefix("2002::/16"),
+		netip.MustParsePrefix("fc00::/7"),
+		netip.MustParsePrefix("fe80::/10"),
+		netip.MustParsePrefix("ff00::/8"),
+	}
+)
+
+// ImageLoader downloads remote images, checks them and copies them into local storage.
+type ImageLoader struct {
+	client       *http.Client
+	maxSize      int64
+	pathToImages string
+	// isAllowedAddr tells whether address may be dialed, it is checked after DNS resolution
+	isAllowedAddr func(addr netip.Addr) bool
+	logger        *zap.SugaredLogger
+}
+
+func NewImageLoader(pathToImages string, maxSize int64, timeout time.Duration) (*ImageLoader, error) {
+	logger, err := my_logger.Get()
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	if err := os.MkdirAll(pathToImages, os.ModePerm); err != nil {
+		logger.Errorln(err)
+
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	imageLoader := &ImageLoader{ //nolint:exhaustruct
+		maxSize:       maxSize,
+		pathToImages:  pathToImages,
+		isAllowedAddr: isPublicAddr,
+		logger:        logger,
+	}
+
+	dialer := &net.Dialer{ //nolint:exhaustruct
+		Timeout: timeout,
+		Control: imageLoader.controlAddress,
+	}
+
+	transport := &http.Transport{ //nolint:exhaustruct
+		Proxy:                 nil,
+		DialContext:           dialer.DialContext,
+		TLSHandshakeTimeout:   timeout,
+		ResponseHeaderTimeout: timeout,
+	}
+
+	imageLoader.client = &http.Client{ //nolint:exhaustruct
+		Transport: transport,
+		Timeout:   timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) > maxRedirects {
+				return fmt.Errorf(myerrors.ErrTemplate, ErrImageUnavailable)
+			}
+
+			return checkScheme(req.URL)
+		},
+	}
+
+	return imageLoader, nil
+}
+
+func checkScheme(imageURL *url.URL) error {
+	if imageURL.Scheme != "http" && imageURL.Scheme != "https" {
+		return fmt.Errorf(myerrors.ErrTemplate, ErrImageURL)
+	}
+
+	return nil
+}
+
+// isPublicAddr checks IPv4-mapped IPv6 addresses as IPv4 ones.
+func isPublicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+
+	for _, prefix := range forbiddenPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// controlAddress is called after DNS resolution, so it also covers
+// hostnames that resolve into private ranges and redirects to them.
+func (i *ImageLoader) controlAddress(_ string, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil || !i.isAllowedAddr(addrPort.Addr().WithZone("")) {
+		return fmt.Errorf(myerrors.ErrTemplate, ErrForbiddenAddress)
+	}
+
+	return nil
+}
+
+func (i *ImageLoader) fetch(ctx context.Context, rawURL string) ([]byte, error) {
+	imageURL, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, ErrImageURL)
+	}
+
+	if err := checkScheme(imageURL); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, imageURL.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, ErrImageURL)
+	}
+
+	resp, err := i.client.Do(req)
+	if err != nil {
+		i.logger.Errorf("in fetch: url=%s err=%+v", rawURL, err)
+
+		if errors.Is(err, ErrForbiddenAddress) {
+			return nil, fmt.Errorf(myerrors.ErrTemplate, ErrForbiddenAddress)
+		}
+
+		return nil, fmt.Errorf(myerrors.ErrTemplate, ErrImageUnavailable)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		i.logger.Errorf("in fetch: url=%s status=%d", rawURL, resp.StatusCode)
+
+		return nil, fmt.Errorf(myerrors.ErrTemplate, ErrImageUnavailable)
+	}
+
+	if resp.ContentLength > i.maxSize {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, ErrImageTooLarge)
+	}
+
+	content, err := io.ReadAll(io.LimitReader(resp.Body, i.maxSize+1))
+	if err != nil {
+		i.logger.Errorf("in fetch: url=%s err=%+v", rawURL, err)
+
+		return nil, fmt.Errorf(myerrors.ErrTemplate, ErrImageUnavailable)
+	}
+
+	if int64(len(content)) > i.maxSize {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, ErrImageTooLarge)
+	}
+
+	return content, nil
+}
+
+// checkImage sniffs the real content type and makes sure the image can be decoded.
+func checkImage(content []byte) (string, image.Image, error) {
+	extension, ok := allowedContentTypes[http.DetectContentType(content)]
+	if !ok {
+		return "", nil, fmt.Errorf(myerrors.ErrTemplate, ErrImageFormat)
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(content))
+	if err != nil {
+		return "", nil, fmt.Errorf(myerrors.ErrTemplate, ErrImageFormat)
+	}
+
+	// small file can still be decoded into huge bitmap
+	if config.Width*config.Height > maxPixels {
+		return "", nil, fmt.Errorf(myerrors.ErrTemplate, ErrImageTooLarge)
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(content))
+	if err != nil {
+		return "", nil, fmt.Errorf(myerrors.ErrTemplate, ErrImageFormat)
+	}
+
+	return extension, img, nil
+}
+
+// perceptualHash is difference hash of image: image is shrunk to grid of average brightness and every bit
+// tells whether cell is brighter than its right neighbour. Resized and recompressed copies get the same hash.
+func perceptualHash(img image.Image) uint64 {
+	bounds := img.Bounds()
+
+	var grid [hashHeight][hashWidth]float64
+
+	for row := 0; row < hashHeight; row++ {
+		minY := bounds.Min.Y + row*bounds.Dy()/hashHeight
+		maxY := max(bounds.Min.Y+(row+1)*bounds.Dy()/hashHeight, minY+1)
+
+		for column := 0; column < hashWidth; column++ {
+			minX := bounds.Min.X + column*bounds.Dx()/hashWidth
+			maxX := max(bounds.Min.X+(column+1)*bounds.Dx()/hashWidth, minX+1)
+
+			var sum float64
+
+			for y := minY; y < maxY; y++ {
+				for x := minX; x < maxX; x++ {
+					sum += float64(color.GrayModel.Convert(img.At(x, y)).(color.Gray).Y) //nolint:forcetypeassert
+				}
+			}
+
+			grid[row][column] = sum / float64((maxY-minY)*(maxX-minX))
+		}
+	}
+
+	var hash uint64
+
+	for row := 0; row < hashHeight; row++ {
+		for column := 0; column < hashWidth-1; column++ {
+			hash <<= 1
+			if grid[row][column] > grid[row][column+1] {
+				hash |= 1
+			}
+		}
+	}
+
+	return hash
+}
+
+// Load fetches the image by rawURL and returns its url in local storage and its perceptual hash.
+func (i *ImageLoader) Load(ctx context.Context, rawURL string) (string, uint64, error) {
+	content, err := i.fetch(ctx, rawURL)
+	if err != nil {
+		return "", 0, err
+	}
+
+	extension, img, err := checkImage(content)
+	if err != nil {
+		return "", 0, err
+	}
+
+	hash, err := utils.Hash256(content)
+	if err != nil {
+		i.logger.Errorln(err)
+
+		return "", 0, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	fileName := hash + extension
+
+	err = os.WriteFile(filepath.Join(i.pathToImages, fileName), content, 0o644) //nolint:gosec,gomnd
+	if err != nil {
+		i.logger.Errorln(err)
+
+		return "", 0, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return URLPrefixImages + fileName, perceptualHash(img), nil
+}
@@ -0,0 +1,241 @@
+package image_loader
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SanExpett/marketplace-backend/pkg/my_logger"
+)
+
+func TestMain(m *testing.M) {
+	if _, err := my_logger.New([]string{os.DevNull}, []string{os.DevNull}); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	os.Exit(m.Run())
+}
+
+func encodePNG(t *testing.T, width int, height int) []byte {
+	t.Helper()
+
+	img := image.NewGray(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		for y := 0; y < height; y++ {
+			img.SetGray(x, y, color.Gray{Y: uint8(255 * (width - x) / width)})
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+// newTestLoader allows loopback addresses, so loader can reach httptest server.
+func newTestLoader(t *testing.T, maxSize int64) *ImageLoader {
+	t.Helper()
+
+	loader, err := NewImageLoader(t.TempDir(), maxSize, 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loader.isAllowedAddr = func(addr netip.Addr) bool {
+		return addr.IsLoopback() || isPublicAddr(addr)
+	}
+
+	return loader
+}
+
+func TestLoadSuccess(t *testing.T) {
+	content := encodePNG(t, 32, 16)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		_, _ = w.Write(content)
+	}))
+	defer server.Close()
+
+	loader := newTestLoader(t, 1<<20)
+
+	imageURL, hash, err := loader.Load(context.Background(), server.URL+"/image.png")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !strings.HasPrefix(imageURL, URLPrefixImages) || !strings.HasSuffix(imageURL, ".png") {
+		t.Fatalf("unexpected url %q", imageURL)
+	}
+
+	if hash == 0 {
+		t.Fatal("hash of gradient image must not be zero")
+	}
+
+	stored, err := os.ReadFile(filepath.Join(loader.pathToImages, strings.TrimPrefix(imageURL, URLPrefixImages)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(stored, content) {
+		t.Fatal("stored image differs from served one")
+	}
+}
+
+func TestLoadRejectsNotImage(t *testing.T) {
+	// content type is sniffed from body, header of server is not trusted
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		_, _ = w.Write([]byte("<html><body>not an image</body></html>"))
+	}))
+	defer server.Close()
+
+	_, _, err := newTestLoader(t, 1<<20).Load(context.Background(), server.URL)
+	if !errors.Is(err, ErrImageFormat) {
+		t.Fatalf("expected ErrImageFormat, got %v", err)
+	}
+}
+
+func TestLoadRejectsOversizedBody(t *testing.T) {
+	content := encodePNG(t, 256, 256)
+
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+	}{
+		{
+			name: "content length",
+			handler: func(w http.ResponseWriter, _ *http.Request) {
+				w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+				_, _ = w.Write(content)
+			},
+		},
+		{
+			name: "chunked",
+			handler: func(w http.ResponseWriter, _ *http.Request) {
+				for _, chunk := range [][]byte{content[:len(content)/2], content[len(content)/2:]} {
+					_, _ = w.Write(chunk)
+					w.(http.Flusher).Flush() //nolint:forcetypeassert
+				}
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := httptest.NewServer(test.handler)
+			defer server.Close()
+
+			_, _, err := newTestLoader(t, int64(len(content)-1)).Load(context.Background(), server.URL)
+			if !errors.Is(err, ErrImageTooLarge) {
+				t.Fatalf("expected ErrImageTooLarge, got %v", err)
+			}
+		})
+	}
+}
+
+func TestLoadRedirectLimit(t *testing.T) {
+	content := encodePNG(t, 8, 8)
+
+	// /hop/N redirects N more times before serving image
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hops, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/hop/"))
+		if err != nil {
+			http.NotFound(w, r)
+
+			return
+		}
+
+		if hops > 0 {
+			http.Redirect(w, r, "/hop/"+strconv.Itoa(hops-1), http.StatusFound)
+
+			return
+		}
+
+		_, _ = w.Write(content)
+	}))
+	defer server.Close()
+
+	loader := newTestLoader(t, 1<<20)
+
+	if _, _, err := loader.Load(context.Background(), server.URL+"/hop/"+strconv.Itoa(maxRedirects)); err != nil {
+		t.Fatalf("redirects within limit must be followed: %v", err)
+	}
+
+	_, _, err := loader.Load(context.Background(), server.URL+"/hop/"+strconv.Itoa(maxRedirects+1))
+	if !errors.Is(err, ErrImageUnavailable) {
+		t.Fatalf("expected ErrImageUnavailable, got %v", err)
+	}
+}
+
+func TestLoadRefusesLoopback(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		t.Error("request must not reach loopback server")
+	}))
+	defer server.Close()
+
+	loader, err := NewImageLoader(t.TempDir(), 1<<20, 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, _, err = loader.Load(context.Background(), server.URL)
+	if !errors.Is(err, ErrForbiddenAddress) {
+		t.Fatalf("expected ErrForbiddenAddress, got %v", err)
+	}
+}
+
+func TestIsPublicAddr(t *testing.T) {
+	tests := []struct {
+		addr   string
+		public bool
+	}{
+		{"8.8.8.8", true},
+		{"1.1.1.1", true},
+		{"2606:4700:4700::1111", true},
+		{"127.0.0.1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"100.64.0.1", false},
+		{"100.127.255.255", false},
+		{"0.0.0.0", false},
+		{"0.1.2.3", false},
+		{"198.18.0.1", false},
+		{"198.19.255.255", false},
+		{"192.0.0.8", false},
+		{"240.0.0.1", false},
+		{"255.255.255.255", false},
+		{"224.0.0.1", false},
+		{"::1", false},
+		{"::", false},
+		{"fc00::1", false},
+		{"fe80::1", false},
+		{"64:ff9b::a00:1", false},
+		{"::ffff:127.0.0.1", false},
+		{"::ffff:100.64.0.1", false},
+	}
+
+	for _, test := range tests {
+		if got := isPublicAddr(netip.MustParseAddr(test.addr)); got != test.public {
+			t.Errorf("isPublicAddr(%s) = %v, want %v", test.addr, got, test.public)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"context"
+	"github.com/SanExpett/marketplace-backend/internal/server/delivery"
+	"net/http"
+	"slices"
+
+	"go.uber.org/zap"
+)
+
+type IBanChecker interface {
+	CheckNotBanned(ctx context.Context, userID uint64) error
+}
+
+// RejectBanned stops changes made by user banned by moderation, also in session started before ban.
+// Reading and allowedPaths (e.g. logout) stay available for banned user.
+func RejectBanned(next http.Handler, banChecker IBanChecker, logger *zap.SugaredLogger, allowedPaths ...string,
+) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isReadMethod(r.Method) || slices.Contains(allowedPaths, r.URL.Path) {
+			next.ServeHTTP(w, r)
+
+			return
+		}
+
+		if _, err := r.Cookie(delivery.CookieAuthName); err != nil {
+			next.ServeHTTP(w, r)
+
+			return
+		}
+
+		// invalid cookie is reported by handler which needs user
+		userID, err := delivery.GetUserIDFromCookie(r)
+		if err != nil {
+			next.ServeHTTP(w, r)
+
+			return
+		}
+
+		if err := banChecker.CheckNotBanned(r.Context(), userID); err != nil {
+			delivery.HandleErr(w, logger, err)
+
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func isReadMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
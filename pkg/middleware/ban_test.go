@@ -0,0 +1,105 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/SanExpett/marketplace-backend/internal/server/delivery"
+	"github.com/SanExpett/marketplace-backend/pkg/jwt"
+	myerrors "github.com/SanExpett/marketplace-backend/pkg/my_errors"
+	"github.com/SanExpett/marketplace-backend/pkg/my_logger"
+	"go.uber.org/zap"
+)
+
+func TestMain(m *testing.M) {
+	if _, err := my_logger.New([]string{os.DevNull}, []string{os.DevNull}); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	os.Exit(m.Run())
+}
+
+var errBanned = myerrors.NewError("banned")
+
+type fakeBanChecker struct {
+	bannedUserID uint64
+}
+
+func (f *fakeBanChecker) CheckNotBanned(_ context.Context, userID uint64) error {
+	if userID == f.bannedUserID {
+		return errBanned
+	}
+
+	return nil
+}
+
+func TestRejectBanned(t *testing.T) {
+	logger, err := my_logger.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		userID   uint64
+		rejected bool
+	}{
+		{"banned user changes", http.MethodPost, "/api/v1/product/add", 7, true},
+		{"banned user reads", http.MethodGet, "/api/v1/product/get", 7, false},
+		{"banned user logs out", http.MethodPost, "/api/v1/logout", 7, false},
+		{"other user changes", http.MethodPost, "/api/v1/product/add", 8, false},
+		{"guest changes", http.MethodPost, "/api/v1/signin", 0, false},
+	}
+
+	for _, test := range tests {
+		if got := isRejected(t, logger, test.method, test.path, test.userID); got != test.rejected {
+			t.Errorf("%s: rejected = %v, want %v", test.name, got, test.rejected)
+		}
+	}
+}
+
+func isRejected(t *testing.T, logger *zap.SugaredLogger, method string, path string, userID uint64) bool {
+	t.Helper()
+
+	var isCalled bool
+
+	handler := RejectBanned(http.HandlerFunc(func(_ http.ResponseWriter, _ *http.Request) {
+		isCalled = true
+	}), &fakeBanChecker{bannedUserID: 7}, logger, "/api/v1/logout")
+
+	r := httptest.NewRequest(method, path, nil)
+
+	if userID != 0 {
+		rawJwt, err := jwt.GenerateJwtToken(&jwt.UserJwtPayload{
+			UserID: userID, Expire: time.Now().Add(time.Hour).Unix(), Login: "login",
+		}, jwt.Secret, logger)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		r.AddCookie(&http.Cookie{Name: delivery.CookieAuthName, Value: rawJwt}) //nolint:exhaustruct
+	}
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	if isCalled {
+		return false
+	}
+
+	var response delivery.ErrorResponse
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatal(err)
+	}
+
+	return response.Status == delivery.StatusErrBadRequest
+}
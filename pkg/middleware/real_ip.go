@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// RealIP replaces RemoteAddr of request with address of client sent by reverse proxy. It must be used only
+// behind proxy which sets X-Real-IP or X-Forwarded-For, otherwise clients can forge their address.
+func RealIP(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if addr, ok := clientAddr(r.Header); ok {
+			r.RemoteAddr = net.JoinHostPort(addr.String(), "0")
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// clientAddr prefers X-Real-IP. In X-Forwarded-For the last address is taken: it is appended by our proxy,
+// previous ones are sent by client.
+func clientAddr(header http.Header) (netip.Addr, bool) {
+	if addr, err := netip.ParseAddr(strings.TrimSpace(header.Get("X-Real-IP"))); err == nil {
+		return addr.Unmap(), true
+	}
+
+	forwardedFor := header.Values("X-Forwarded-For")
+	if len(forwardedFor) == 0 {
+		return netip.Addr{}, false
+	}
+
+	hops := strings.Split(forwardedFor[len(forwardedFor)-1], ",")
+
+	addr, err := netip.ParseAddr(strings.TrimSpace(hops[len(hops)-1]))
+	if err != nil {
+		return netip.Addr{}, false
+	}
+
+	return addr.Unmap(), true
+}
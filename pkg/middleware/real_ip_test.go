@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRealIP(t *testing.T) {
+	tests := []struct {
+		name   string
+		header http.Header
+		want   string
+	}{
+		{"without headers", http.Header{}, "192.0.2.1:1234"},
+		{"real ip", http.Header{"X-Real-Ip": {"203.0.113.7"}}, "203.0.113.7:0"},
+		{"real ip before forwarded for", http.Header{
+			"X-Real-Ip": {"203.0.113.7"}, "X-Forwarded-For": {"198.51.100.2"},
+		}, "203.0.113.7:0"},
+		{"last forwarded for", http.Header{"X-Forwarded-For": {"10.0.0.1, 198.51.100.2"}}, "198.51.100.2:0"},
+		{"last forwarded for header", http.Header{
+			"X-Forwarded-For": {"10.0.0.1", "2001:db8::1"},
+		}, "[2001:db8::1]:0"},
+		{"garbage", http.Header{"X-Real-Ip": {"unknown"}, "X-Forwarded-For": {"unknown"}}, "192.0.2.1:1234"},
+	}
+
+	for _, test := range tests {
+		var remoteAddr string
+
+		handler := RealIP(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+			remoteAddr = r.RemoteAddr
+		}))
+
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.RemoteAddr = "192.0.2.1:1234"
+		r.Header = test.header
+
+		handler.ServeHTTP(httptest.NewRecorder(), r)
+
+		if remoteAddr != test.want {
+			t.Errorf("%s: RemoteAddr = %q, want %q", test.name, remoteAddr, test.want)
+		}
+	}
+}
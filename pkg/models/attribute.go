@@ -0,0 +1,52 @@
+package models
+
+import "github.com/microcosm-cc/bluemonday"
+
+const (
+	AttributeTypeInt    = "int"
+	AttributeTypeString = "string"
+	AttributeTypeEnum   = "enum"
+	AttributeTypeBool   = "bool"
+
+	AttributeFilterEq  = "eq"
+	AttributeFilterGte = "gte"
+	AttributeFilterLte = "lte"
+)
+
+// CategoryAttribute describes one structured attribute of products in category.
+type CategoryAttribute struct {
+	ID         uint64   `json:"id"`
+	CategoryID uint64   `json:"category_id"`
+	Name       string   `json:"name"`
+	Type       string   `json:"type"`
+	EnumValues []string `json:"enum_values"`
+	MinValue   *int64   `json:"min_value"`
+	MaxValue   *int64   `json:"max_value"`
+	Required   bool     `json:"required"`
+}
+
+// AttributeFilter is parsed from query params like attr.year_gte=2015.
+type AttributeFilter struct {
+	Name  string
+	Op    string
+	Value string
+}
+
+type FacetValue struct {
+	Value string `json:"value"`
+	Count uint64 `json:"count"`
+}
+
+type Facet struct {
+	Name   string        `json:"name"`
+	Values []*FacetValue `json:"values"`
+}
+
+func (f *Facet) Sanitize() {
+	sanitizer := bluemonday.UGCPolicy()
+
+	f.Name = sanitizer.Sanitize(f.Name)
+	for _, value := range f.Values {
+		value.Value = sanitizer.Sanitize(value.Value)
+	}
+}
@@ -0,0 +1,22 @@
+package models
+
+import "github.com/microcosm-cc/bluemonday"
+
+type Category struct {
+	ID       uint64      `json:"id"         valid:"required"`
+	ParentID uint64      `json:"parent_id"` // 0 for root categories
+	Slug     string      `json:"slug"       valid:"required"`
+	Name     string      `json:"name"       valid:"required"`
+	Children []*Category `json:"children"`
+}
+
+func (c *Category) Sanitize() {
+	sanitizer := bluemonday.UGCPolicy()
+
+	c.Slug = sanitizer.Sanitize(c.Slug)
+	c.Name = sanitizer.Sanitize(c.Name)
+
+	for _, child := range c.Children {
+		child.Sanitize()
+	}
+}
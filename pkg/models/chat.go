@@ -0,0 +1,60 @@
+package models
+
+import (
+	"github.com/microcosm-cc/bluemonday"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Conversation is chat of buyer with seller about product.
+type Conversation struct {
+	ID            uint64    `json:"id"`
+	ProductID     uint64    `json:"product_id"`
+	ProductTitle  string    `json:"product_title"`
+	BuyerID       uint64    `json:"buyer_id"`
+	SalerID       uint64    `json:"saler_id"`
+	LastMessage   *Message  `json:"last_message"`
+	UnreadCount   uint64    `json:"unread_count"`
+	CreatedAt     time.Time `json:"created_at"`
+	LastMessageAt time.Time `json:"last_message_at"`
+}
+
+// Message is read when ReadAt is set, it is set by receiver of message.
+type Message struct {
+	ID             uint64     `json:"id"`
+	ConversationID uint64     `json:"conversation_id"`
+	SenderID       uint64     `json:"sender_id"`
+	Text           string     `json:"text"`
+	IsMy           bool       `json:"is_my"`
+	CreatedAt      time.Time  `json:"created_at"`
+	ReadAt         *time.Time `json:"read_at"`
+}
+
+type PreMessage struct {
+	Text string `json:"text" valid:"required, length(1|4000)~Сообщение должно быть длинной от 1 до 4000 символов"` //nolint:nolintlint
+}
+
+func (p *PreMessage) Trim() {
+	p.Text = strings.TrimFunc(p.Text, unicode.IsSpace)
+}
+
+func (m *Message) Sanitize() {
+	sanitizer := bluemonday.UGCPolicy()
+
+	m.Text = sanitizer.Sanitize(m.Text)
+}
+
+func (c *Conversation) Sanitize() {
+	sanitizer := bluemonday.UGCPolicy()
+
+	c.ProductTitle = sanitizer.Sanitize(c.ProductTitle)
+
+	if c.LastMessage != nil {
+		c.LastMessage.Sanitize()
+	}
+}
+
+type UnreadCount struct {
+	Count uint64 `json:"count"`
+}
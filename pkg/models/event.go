@@ -0,0 +1,36 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	myerrors "github.com/SanExpett/marketplace-backend/pkg/my_errors"
+)
+
+const (
+	EventTypeNewMessage    = "new_message"
+	EventTypeOffer         = "offer"
+	EventTypeProductStatus = "product_status"
+	EventTypeSavedSearch   = "saved_search"
+)
+
+// Event is pushed to users through stream. Body is omitted when it is too big to be sent between
+// replicas, then client should fetch the entity by itself.
+type Event struct {
+	Type string          `json:"type"`
+	Body json.RawMessage `json:"body,omitempty"`
+}
+
+func NewEvent(eventType string, body any) (*Event, error) {
+	rawBody, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return &Event{Type: eventType, Body: rawBody}, nil
+}
+
+// ProductStatusChange is sent to users who have product in favorites.
+type ProductStatusChange struct {
+	ProductID uint64 `json:"product_id"`
+	Status    string `json:"status"`
+}
@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// ProductFilter describes which products are selected by feed, facets and counts.
+// Nil and zero fields mean that filter is not applied.
+type ProductFilter struct {
+	// Currency selects products priced in it, MinPrice and MaxPrice are in its minor units
+	Currency string
+	MinPrice *uint64
+	MaxPrice *uint64
+	SalerID  uint64
+	// Mine selects products of current user in any status
+	Mine bool
+	// Favorites selects products which current user added to favorites
+	Favorites bool
+	// FavoritesOf is id of user whose favorites are selected, it is set by usecases for Favorites
+	FavoritesOf   uint64
+	CategoryID    uint64
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	// Statuses are active only by default
+	Statuses    []string
+	HasImage    *bool
+	SearchQuery string
+	Attributes  []*AttributeFilter
+	// Near selects products not further than RadiusKm from it
+	Near     *GeoPoint
+	RadiusKm float64
+	// ExcludePromoted drops products with running promotion from feed, they are shown in promoted slots.
+	// It is set by usecases and is applied only to products list and its count
+	ExcludePromoted bool
+}
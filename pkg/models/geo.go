@@ -0,0 +1,103 @@
+package models
+
+import (
+	"fmt"
+	myerrors "github.com/SanExpett/marketplace-backend/pkg/my_errors"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	EarthRadiusKm = 6371.0
+
+	maxLatitude  = 90.0
+	maxLongitude = 180.0
+)
+
+var ErrWrongGeoPoint = myerrors.NewError("Некорректные координаты: ожидается широта от -90 до 90 и долгота от -180 до 180")
+
+type GeoPoint struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// ParseGeoPoint parses point like "55.75,37.61".
+func ParseGeoPoint(rawPoint string) (*GeoPoint, error) {
+	rawLatitude, rawLongitude, ok := strings.Cut(rawPoint, ",")
+	if !ok {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, ErrWrongGeoPoint)
+	}
+
+	latitude, errLatitude := strconv.ParseFloat(strings.TrimSpace(rawLatitude), 64)
+	longitude, errLongitude := strconv.ParseFloat(strings.TrimSpace(rawLongitude), 64)
+
+	if errLatitude != nil || errLongitude != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, ErrWrongGeoPoint)
+	}
+
+	point := &GeoPoint{Latitude: latitude, Longitude: longitude}
+
+	if err := point.Validate(); err != nil {
+		return nil, err
+	}
+
+	return point, nil
+}
+
+func ValidateCoordinates(latitude float64, longitude float64) error {
+	if math.IsNaN(latitude) || math.IsNaN(longitude) ||
+		math.Abs(latitude) > maxLatitude || math.Abs(longitude) > maxLongitude {
+		return fmt.Errorf(myerrors.ErrTemplate, ErrWrongGeoPoint)
+	}
+
+	return nil
+}
+
+func (g *GeoPoint) Validate() error {
+	return ValidateCoordinates(g.Latitude, g.Longitude)
+}
+
+// BoundingBox contains all points not further than radius from center.
+// MinLongitude > MaxLongitude means that box crosses antimeridian.
+type BoundingBox struct {
+	MinLatitude  float64
+	MaxLatitude  float64
+	MinLongitude float64
+	MaxLongitude float64
+}
+
+func (g *GeoPoint) BoundingBox(radiusKm float64) BoundingBox {
+	angularRadius := radiusKm / EarthRadiusKm
+	deltaLatitude := angularRadius * 180 / math.Pi
+
+	box := BoundingBox{
+		MinLatitude:  g.Latitude - deltaLatitude,
+		MaxLatitude:  g.Latitude + deltaLatitude,
+		MinLongitude: -maxLongitude,
+		MaxLongitude: maxLongitude,
+	}
+
+	// circle contains pole, so all longitudes are inside
+	if box.MinLatitude <= -maxLatitude || box.MaxLatitude >= maxLatitude {
+		box.MinLatitude = math.Max(box.MinLatitude, -maxLatitude)
+		box.MaxLatitude = math.Min(box.MaxLatitude, maxLatitude)
+
+		return box
+	}
+
+	deltaLongitude := math.Asin(math.Sin(angularRadius)/math.Cos(g.Latitude*math.Pi/180)) * 180 / math.Pi
+
+	box.MinLongitude = g.Longitude - deltaLongitude
+	box.MaxLongitude = g.Longitude + deltaLongitude
+
+	if box.MinLongitude < -maxLongitude {
+		box.MinLongitude += 2 * maxLongitude
+	}
+
+	if box.MaxLongitude > maxLongitude {
+		box.MaxLongitude -= 2 * maxLongitude
+	}
+
+	return box
+}
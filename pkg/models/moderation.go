@@ -0,0 +1,109 @@
+package models
+
+import (
+	"github.com/microcosm-cc/bluemonday"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	ReportReasonScam          = "scam"
+	ReportReasonProhibited    = "prohibited"
+	ReportReasonWrongCategory = "wrong_category"
+	ReportReasonDuplicate     = "duplicate"
+	ReportReasonSpam          = "spam"
+	ReportReasonOffensive     = "offensive"
+	ReportReasonOther         = "other"
+)
+
+const (
+	ModerationActionHide     = "hide"
+	ModerationActionRestore  = "restore"
+	ModerationActionBan      = "ban"
+	ModerationActionAutoHide = "auto_hide"
+)
+
+// IsReportReason reports whether reason is one of ReportReason* consts.
+func IsReportReason(reason string) bool {
+	return slices.Contains([]string{
+		ReportReasonScam, ReportReasonProhibited, ReportReasonWrongCategory, ReportReasonDuplicate,
+		ReportReasonSpam, ReportReasonOffensive, ReportReasonOther,
+	}, reason)
+}
+
+type Report struct {
+	ID         uint64    `json:"id"`
+	ProductID  uint64    `json:"product_id"`
+	ReporterID uint64    `json:"reporter_id"`
+	Reason     string    `json:"reason"`
+	Comment    string    `json:"comment"`
+	IsResolved bool      `json:"is_resolved"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type PreReport struct {
+	Reason  string `json:"reason"  valid:"required~Не указана причина жалобы"`                              //nolint:nolintlint
+	Comment string `json:"comment" valid:"length(0|1000)~Комментарий должен быть не длиннее 1000 символов"` //nolint:nolintlint
+}
+
+func (p *PreReport) Trim() {
+	p.Reason = strings.TrimFunc(p.Reason, unicode.IsSpace)
+	p.Comment = strings.TrimFunc(p.Comment, unicode.IsSpace)
+}
+
+func (r *Report) Sanitize() {
+	sanitizer := bluemonday.UGCPolicy()
+
+	r.Comment = sanitizer.Sanitize(r.Comment)
+}
+
+// ModerationItem is product in moderation queue with summary of its open reports. Product sent to review
+// by premoderation has ReviewReason and may have no reports. WaitingSince is time of the first report
+// or of sending to review.
+type ModerationItem struct {
+	ProductID       uint64     `json:"product_id"`
+	SalerID         uint64     `json:"saler_id"`
+	Title           string     `json:"title"`
+	Status          string     `json:"status"`
+	ReportsCount    uint64     `json:"reports_count"`
+	Reasons         []string   `json:"reasons"`
+	ReviewReason    string     `json:"review_reason,omitempty"`
+	FirstReportedAt *time.Time `json:"first_reported_at,omitempty"`
+	LastReportedAt  *time.Time `json:"last_reported_at,omitempty"`
+	WaitingSince    time.Time  `json:"waiting_since"`
+}
+
+func (m *ModerationItem) Sanitize() {
+	sanitizer := bluemonday.UGCPolicy()
+
+	m.Title = sanitizer.Sanitize(m.Title)
+	m.ReviewReason = sanitizer.Sanitize(m.ReviewReason)
+}
+
+// ModerationAction is entry of audit trail. ModeratorID is nil for automatic actions.
+type ModerationAction struct {
+	ID          uint64    `json:"id"`
+	ModeratorID *uint64   `json:"moderator_id"`
+	Action      string    `json:"action"`
+	ProductID   *uint64   `json:"product_id,omitempty"`
+	UserID      *uint64   `json:"user_id,omitempty"`
+	Comment     string    `json:"comment"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// PreModerationAction is comment of moderator to action, it is saved to audit trail.
+type PreModerationAction struct {
+	Comment string `json:"comment" valid:"length(0|1000)~Комментарий должен быть не длиннее 1000 символов"` //nolint:nolintlint
+}
+
+func (p *PreModerationAction) Trim() {
+	p.Comment = strings.TrimFunc(p.Comment, unicode.IsSpace)
+}
+
+func (m *ModerationAction) Sanitize() {
+	sanitizer := bluemonday.UGCPolicy()
+
+	m.Comment = sanitizer.Sanitize(m.Comment)
+}
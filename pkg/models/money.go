@@ -0,0 +1,121 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	myerrors "github.com/SanExpett/marketplace-backend/pkg/my_errors"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const CurrencyRUB = "RUB"
+
+//nolint:gochecknoglobals
+var currencyExponents = map[string]int{
+	"RUB": 2, "USD": 2, "EUR": 2, "GBP": 2, "CHF": 2, "CNY": 2, "JPY": 0, "KRW": 0,
+	"KZT": 2, "BYN": 2, "UAH": 2, "AMD": 2, "GEL": 2, "AZN": 2, "UZS": 2, "KGS": 2, "TRY": 2,
+}
+
+const MessageErrWrongCurrencyRate = "Некорректный курс валюты %s"
+
+var (
+	ErrUnknownCurrency = myerrors.NewError("Неизвестная валюта, ожидается код ISO 4217")
+	ErrAmountTooBig    = myerrors.NewError("Слишком большая сумма")
+)
+
+// Money is amount in minor units of currency (kopecks for RUB) and ISO 4217 code of currency.
+type Money struct {
+	Amount   uint64 `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+func NormalizeCurrency(currency string) string {
+	return strings.ToUpper(strings.TrimSpace(currency))
+}
+
+func ValidateCurrency(currency string) error {
+	if _, ok := currencyExponents[currency]; !ok {
+		return fmt.Errorf(myerrors.ErrTemplate, ErrUnknownCurrency)
+	}
+
+	return nil
+}
+
+// ValidateAmount checks that amount fits into BIGINT column.
+func ValidateAmount(amount uint64) error {
+	if amount > math.MaxInt64 {
+		return fmt.Errorf(myerrors.ErrTemplate, ErrAmountTooBig)
+	}
+
+	return nil
+}
+
+func (m *Money) Validate() error {
+	if err := ValidateCurrency(m.Currency); err != nil {
+		return err
+	}
+
+	return ValidateAmount(m.Amount)
+}
+
+// UnmarshalJSON normalizes currency code, so "rub" and " RUB" are the same currency.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	type money Money
+
+	rawMoney := money{} //nolint:exhaustruct
+	if err := json.Unmarshal(data, &rawMoney); err != nil {
+		return err //nolint:wrapcheck
+	}
+
+	*m = Money(rawMoney)
+	m.Currency = NormalizeCurrency(m.Currency)
+
+	return nil
+}
+
+// CurrencyRates are prices of one major unit of currency in major units of base currency.
+type CurrencyRates map[string]float64
+
+// ParseCurrencyRates parses rates like "USD:92.5,EUR:100.1". Empty string gives no rates.
+func ParseCurrencyRates(rawRates string) (CurrencyRates, error) {
+	rates := make(CurrencyRates)
+
+	if strings.TrimSpace(rawRates) == "" {
+		return rates, nil
+	}
+
+	for _, rawRate := range strings.Split(rawRates, ",") {
+		rawCurrency, rawValue, ok := strings.Cut(rawRate, ":")
+		currency := NormalizeCurrency(rawCurrency)
+
+		if err := ValidateCurrency(currency); err != nil {
+			return nil, err
+		}
+
+		value, err := strconv.ParseFloat(strings.TrimSpace(rawValue), 64)
+		if !ok || err != nil || value <= 0 || math.IsInf(value, 0) || math.IsNaN(value) {
+			return nil, myerrors.NewError(MessageErrWrongCurrencyRate, currency)
+		}
+
+		rates[currency] = value
+	}
+
+	return rates, nil
+}
+
+// MinorUnitFactors gives multipliers which convert amount in minor units of currency
+// into minor units of base currency.
+func (c CurrencyRates) MinorUnitFactors(baseCurrency string) map[string]float64 {
+	factors := map[string]float64{baseCurrency: 1}
+
+	for currency, rate := range c {
+		if currency == baseCurrency {
+			continue
+		}
+
+		factors[currency] = rate * math.Pow10(currencyExponents[baseCurrency]-currencyExponents[currency])
+	}
+
+	return factors
+}
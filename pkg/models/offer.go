@@ -0,0 +1,72 @@
+package models
+
+import "time"
+
+const (
+	OfferStatusPending   = "pending"
+	OfferStatusAccepted  = "accepted"
+	OfferStatusDeclined  = "declined"
+	OfferStatusCountered = "countered"
+	OfferStatusExpired   = "expired"
+	OfferStatusWithdrawn = "withdrawn"
+	// OfferStatusClosed is accepted offer whose order was cancelled, it doesn't reserve product anymore
+	OfferStatusClosed = "closed"
+)
+
+const (
+	OfferActionAccept   = "accept"
+	OfferActionDecline  = "decline"
+	OfferActionCounter  = "counter"
+	OfferActionWithdraw = "withdraw"
+)
+
+// Offer is price proposed by buyer. Seller can answer with CounterPrice, then buyer accepts or declines it.
+// Open offer expires without answer at ExpiresAt, accepted one reserves product until ExpiresAt.
+type Offer struct {
+	ID           uint64    `json:"id"`
+	ProductID    uint64    `json:"product_id"`
+	BuyerID      uint64    `json:"buyer_id"`
+	SalerID      uint64    `json:"saler_id"`
+	Price        Money     `json:"price"`
+	CounterPrice *Money    `json:"counter_price,omitempty"`
+	Status       string    `json:"status"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+// IsOpen reports whether offer is waiting for answer.
+func (o *Offer) IsOpen() bool {
+	return o.Status == OfferStatusPending || o.Status == OfferStatusCountered
+}
+
+// PreOffer is price of offer or counter offer, empty currency means currency of product.
+type PreOffer struct {
+	Price Money `json:"price"`
+}
+
+type offerTransition struct {
+	status  string
+	action  string
+	isSaler bool
+}
+
+// offerTransitions maps status, action and role of user to new status of offer.
+//
+//nolint:gochecknoglobals
+var offerTransitions = map[offerTransition]string{
+	{OfferStatusPending, OfferActionAccept, true}:      OfferStatusAccepted,
+	{OfferStatusPending, OfferActionDecline, true}:     OfferStatusDeclined,
+	{OfferStatusPending, OfferActionCounter, true}:     OfferStatusCountered,
+	{OfferStatusPending, OfferActionWithdraw, false}:   OfferStatusWithdrawn,
+	{OfferStatusCountered, OfferActionAccept, false}:   OfferStatusAccepted,
+	{OfferStatusCountered, OfferActionDecline, false}:  OfferStatusDeclined,
+	{OfferStatusCountered, OfferActionWithdraw, false}: OfferStatusWithdrawn,
+}
+
+// NextOfferStatus returns status of offer after action of seller or buyer, false if action isn't allowed.
+func NextOfferStatus(status string, action string, isSaler bool) (string, bool) {
+	nextStatus, ok := offerTransitions[offerTransition{status: status, action: action, isSaler: isSaler}]
+
+	return nextStatus, ok
+}
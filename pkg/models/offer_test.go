@@ -0,0 +1,55 @@
+package models
+
+import "testing"
+
+func TestNextOfferStatus(t *testing.T) {
+	tests := []struct {
+		status  string
+		action  string
+		isSaler bool
+		want    string
+	}{
+		{OfferStatusPending, OfferActionAccept, true, OfferStatusAccepted},
+		{OfferStatusPending, OfferActionDecline, true, OfferStatusDeclined},
+		{OfferStatusPending, OfferActionCounter, true, OfferStatusCountered},
+		{OfferStatusPending, OfferActionWithdraw, false, OfferStatusWithdrawn},
+		{OfferStatusCountered, OfferActionAccept, false, OfferStatusAccepted},
+		{OfferStatusCountered, OfferActionDecline, false, OfferStatusDeclined},
+		{OfferStatusCountered, OfferActionWithdraw, false, OfferStatusWithdrawn},
+	}
+
+	for _, test := range tests {
+		got, ok := NextOfferStatus(test.status, test.action, test.isSaler)
+		if !ok || got != test.want {
+			t.Errorf("NextOfferStatus(%s, %s, %v) = %s, %v, want %s", test.status, test.action, test.isSaler,
+				got, ok, test.want)
+		}
+	}
+}
+
+func TestNextOfferStatusForbidden(t *testing.T) {
+	forbidden := []offerTransition{
+		// buyer can't answer own offer, seller can't answer own counter offer
+		{OfferStatusPending, OfferActionAccept, false},
+		{OfferStatusPending, OfferActionCounter, false},
+		{OfferStatusCountered, OfferActionAccept, true},
+		{OfferStatusCountered, OfferActionCounter, true},
+		// only buyer withdraws
+		{OfferStatusPending, OfferActionWithdraw, true},
+		// buyer can't counter counter offer
+		{OfferStatusCountered, OfferActionCounter, false},
+	}
+
+	// closed offers are final
+	for _, status := range []string{OfferStatusAccepted, OfferStatusDeclined, OfferStatusExpired, OfferStatusWithdrawn} {
+		for _, action := range []string{OfferActionAccept, OfferActionDecline, OfferActionCounter, OfferActionWithdraw} {
+			forbidden = append(forbidden, offerTransition{status, action, true}, offerTransition{status, action, false})
+		}
+	}
+
+	for _, transition := range forbidden {
+		if got, ok := NextOfferStatus(transition.status, transition.action, transition.isSaler); ok {
+			t.Errorf("NextOfferStatus(%+v) = %s, must be forbidden", transition, got)
+		}
+	}
+}
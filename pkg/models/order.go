@@ -0,0 +1,106 @@
+package models
+
+import "time"
+
+const (
+	OrderStatusCreated   = "created"
+	OrderStatusPaid      = "paid"
+	OrderStatusShipped   = "shipped"
+	OrderStatusCompleted = "completed"
+	OrderStatusCancelled = "cancelled"
+	// OrderStatusRefunding is cancelled paid order whose refund is not confirmed by payment provider yet
+	OrderStatusRefunding = "refunding"
+	OrderStatusRefunded  = "refunded"
+)
+
+// LiveOrderStatuses are statuses of orders which hold product for buyer.
+func LiveOrderStatuses() []string {
+	return []string{OrderStatusCreated, OrderStatusPaid, OrderStatusShipped, OrderStatusRefunding}
+}
+
+const (
+	OrderActionShip     = "ship"
+	OrderActionComplete = "complete"
+	OrderActionCancel   = "cancel"
+)
+
+const (
+	PaymentStatusNone      = "none"
+	PaymentStatusPending   = "pending"
+	PaymentStatusSucceeded = "succeeded"
+	PaymentStatusFailed    = "failed"
+)
+
+// Order holds product for buyer from creation until it is completed, cancelled or refunded.
+// OfferID is accepted offer whose price is paid, 0 for order by price of product.
+type Order struct {
+	ID            uint64    `json:"id"`
+	ProductID     uint64    `json:"product_id"`
+	BuyerID       uint64    `json:"buyer_id"`
+	SalerID       uint64    `json:"saler_id"`
+	Price         Money     `json:"price"`
+	Status        string    `json:"status"`
+	PaymentID     string    `json:"payment_id,omitempty"`
+	PaymentStatus string    `json:"payment_status"`
+	PaymentError  string    `json:"payment_error,omitempty"`
+	OfferID       uint64    `json:"offer_id,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+	// PaymentAttempt makes idempotency key of charge, so retry after failure is a new charge
+	PaymentAttempt uint64 `json:"-"`
+}
+
+// PaymentTarget is what is paid for: order of buyer or promotion bought by seller, the other id is 0.
+type PaymentTarget struct {
+	OrderID     uint64
+	PromotionID uint64
+}
+
+// Payment is result of charge reported by payment provider synchronously or by webhook.
+type Payment struct {
+	ID          string `json:"id"`
+	OrderID     uint64 `json:"order_id,omitempty"`
+	PromotionID uint64 `json:"promotion_id,omitempty"`
+	Status      string `json:"status"`
+	Error       string `json:"error,omitempty"`
+}
+
+type orderTransition struct {
+	status  string
+	action  string
+	isSaler bool
+}
+
+// orderTransitions maps status, action and role of user to new status of order.
+//
+//nolint:gochecknoglobals
+var orderTransitions = map[orderTransition]string{
+	{OrderStatusPaid, OrderActionShip, true}:         OrderStatusShipped,
+	{OrderStatusShipped, OrderActionComplete, false}: OrderStatusCompleted,
+	{OrderStatusCreated, OrderActionCancel, false}:   OrderStatusCancelled,
+	{OrderStatusCreated, OrderActionCancel, true}:    OrderStatusCancelled,
+	{OrderStatusPaid, OrderActionCancel, false}:      OrderStatusRefunding,
+	{OrderStatusPaid, OrderActionCancel, true}:       OrderStatusRefunding,
+}
+
+// NextOrderStatus returns status of order after action of seller or buyer, false if action isn't allowed.
+func NextOrderStatus(status string, action string, isSaler bool) (string, bool) {
+	nextStatus, ok := orderTransitions[orderTransition{status: status, action: action, isSaler: isSaler}]
+
+	return nextStatus, ok
+}
+
+// IsOrderActionDone reports whether order is already in status which action leads to,
+// so repeated action changes nothing. Cancel of refunding order is done, only its refund is repeated.
+func IsOrderActionDone(status string, action string) bool {
+	switch action {
+	case OrderActionShip:
+		return status == OrderStatusShipped
+	case OrderActionComplete:
+		return status == OrderStatusCompleted
+	case OrderActionCancel:
+		return status == OrderStatusCancelled || status == OrderStatusRefunding || status == OrderStatusRefunded
+	default:
+		return false
+	}
+}
@@ -0,0 +1,75 @@
+package models
+
+import "testing"
+
+func TestNextOrderStatus(t *testing.T) {
+	tests := []struct {
+		transition orderTransition
+		want       string
+	}{
+		{orderTransition{OrderStatusPaid, OrderActionShip, true}, OrderStatusShipped},
+		{orderTransition{OrderStatusShipped, OrderActionComplete, false}, OrderStatusCompleted},
+		{orderTransition{OrderStatusCreated, OrderActionCancel, false}, OrderStatusCancelled},
+		{orderTransition{OrderStatusCreated, OrderActionCancel, true}, OrderStatusCancelled},
+		// paid order is refunded on cancel
+		{orderTransition{OrderStatusPaid, OrderActionCancel, false}, OrderStatusRefunding},
+		{orderTransition{OrderStatusPaid, OrderActionCancel, true}, OrderStatusRefunding},
+	}
+
+	for _, test := range tests {
+		got, ok := NextOrderStatus(test.transition.status, test.transition.action, test.transition.isSaler)
+		if !ok || got != test.want {
+			t.Errorf("NextOrderStatus(%+v) = %s, %v, want %s", test.transition, got, ok, test.want)
+		}
+	}
+}
+
+func TestNextOrderStatusForbidden(t *testing.T) {
+	forbidden := []orderTransition{
+		// unpaid order isn't shipped, only seller ships and only buyer completes
+		{OrderStatusCreated, OrderActionShip, true},
+		{OrderStatusPaid, OrderActionShip, false},
+		{OrderStatusShipped, OrderActionComplete, true},
+		{OrderStatusPaid, OrderActionComplete, false},
+		// shipped order can't be cancelled
+		{OrderStatusShipped, OrderActionCancel, false},
+		{OrderStatusShipped, OrderActionCancel, true},
+	}
+
+	// finished orders are final, refunding order is finished by payment provider
+	for _, status := range []string{OrderStatusCompleted, OrderStatusCancelled, OrderStatusRefunding,
+		OrderStatusRefunded} {
+		for _, action := range []string{OrderActionShip, OrderActionComplete, OrderActionCancel} {
+			forbidden = append(forbidden, orderTransition{status, action, true}, orderTransition{status, action, false})
+		}
+	}
+
+	for _, transition := range forbidden {
+		if got, ok := NextOrderStatus(transition.status, transition.action, transition.isSaler); ok {
+			t.Errorf("NextOrderStatus(%+v) = %s, must be forbidden", transition, got)
+		}
+	}
+}
+
+func TestIsOrderActionDone(t *testing.T) {
+	tests := []struct {
+		status string
+		action string
+		want   bool
+	}{
+		{OrderStatusShipped, OrderActionShip, true},
+		{OrderStatusCompleted, OrderActionComplete, true},
+		{OrderStatusCancelled, OrderActionCancel, true},
+		{OrderStatusRefunding, OrderActionCancel, true},
+		{OrderStatusRefunded, OrderActionCancel, true},
+		{OrderStatusPaid, OrderActionShip, false},
+		{OrderStatusCompleted, OrderActionCancel, false},
+		{OrderStatusShipped, "unknown", false},
+	}
+
+	for _, test := range tests {
+		if got := IsOrderActionDone(test.status, test.action); got != test.want {
+			t.Errorf("IsOrderActionDone(%s, %s) = %v, want %v", test.status, test.action, got, test.want)
+		}
+	}
+}
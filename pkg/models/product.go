@@ -29,38 +29,160 @@ func init() {
 	})
 }
 
+const (
+	ProductStatusDraft    = "draft"
+	ProductStatusActive   = "active"
+	ProductStatusReserved = "reserved"
+	ProductStatusSold     = "sold"
+	// ProductStatusHidden is set by moderation, saler can't change it
+	ProductStatusHidden = "hidden"
+	// ProductStatusOnReview is set by premoderation, product waits for decision of moderator
+	ProductStatusOnReview = "on_review"
+)
+
+// PrivateProductStatuses are statuses of products which are visible only to saler.
+func PrivateProductStatuses() []string {
+	return []string{ProductStatusDraft, ProductStatusHidden, ProductStatusOnReview}
+}
+
 type Product struct {
-	ID          uint64    `json:"id"              valid:"required"`
-	SalerID     uint64    `json:"saler_id"        valid:"required"`
-	Title       string    `json:"title"           valid:"required, length(1|256)~Заголовок должен быть длинной от 1 до 256 символов"`   //nolint:nolintlint
-	Description string    `json:"description"     valid:"required, length(1|4000)~Описание должно быть длинной от 1 до 4000 симвволов"` //nolint:nolintlint
-	ImageUrl    string    `json:"image_url"       valid:"optional, length(1|256)~Заголовок должен быть длинной от 1 до 256 символов"`   //nolint:nolintlint
-	Price       uint64    `json:"price"           valid:"required"`
-	CreatedAt   time.Time `json:"created_at"      valid:"required"`
+	ID          uint64         `json:"id"              valid:"required"`
+	SalerID     uint64         `json:"saler_id"        valid:"required"`
+	CategoryID  uint64         `json:"category_id"     valid:"required"`
+	Title       string         `json:"title"           valid:"required, length(1|256)~Заголовок должен быть длинной от 1 до 256 символов"`   //nolint:nolintlint
+	Description string         `json:"description"     valid:"required, length(1|4000)~Описание должно быть длинной от 1 до 4000 симвволов"` //nolint:nolintlint
+	ImageUrl    string         `json:"image_url"       valid:"optional, length(1|256)~Заголовок должен быть длинной от 1 до 256 символов"`   //nolint:nolintlint
+	Price       Money          `json:"price"           valid:"-"`
+	Attributes  map[string]any `json:"attributes"      valid:"-"`
+	Latitude    *float64       `json:"latitude"        valid:"-"`
+	Longitude   *float64       `json:"longitude"       valid:"-"`
+	City        string         `json:"city"            valid:"-"`
+	Status      string         `json:"status"          valid:"required"`
+	CreatedAt   time.Time      `json:"created_at"      valid:"required"`
+	// Warnings of premoderation are returned to seller only in response to adding of product
+	Warnings []string `json:"warnings,omitempty" valid:"-"`
 }
 
 type ProductWithIsMy struct {
-	ID          uint64    `json:"id"              valid:"required"`
-	SalerID     uint64    `json:"saler_id"        valid:"required"`
-	Title       string    `json:"title"           valid:"required, length(1|256)~Заголовок должен быть длинной от 1 до 256 символов"`   //nolint:nolintlint
-	Description string    `json:"description"     valid:"required, length(1|4000)~Описание должно быть длинной от 1 до 4000 симвволов"` //nolint:nolintlint
-	ImageUrl    string    `json:"image_url"       valid:"optional, length(1|256)~Заголовок должен быть длинной от 1 до 256 символов"`   //nolint:nolintlint
-	Price       uint64    `json:"price"           valid:"required"`
-	IsMy        bool      `json:"is_my"           valid:"required"`
-	CreatedAt   time.Time `json:"created_at"      valid:"required"`
+	ID          uint64         `json:"id"              valid:"required"`
+	SalerID     uint64         `json:"saler_id"        valid:"required"`
+	CategoryID  uint64         `json:"category_id"     valid:"required"`
+	Title       string         `json:"title"           valid:"required, length(1|256)~Заголовок должен быть длинной от 1 до 256 символов"`   //nolint:nolintlint
+	Description string         `json:"description"     valid:"required, length(1|4000)~Описание должно быть длинной от 1 до 4000 симвволов"` //nolint:nolintlint
+	ImageUrl    string         `json:"image_url"       valid:"optional, length(1|256)~Заголовок должен быть длинной от 1 до 256 символов"`   //nolint:nolintlint
+	Price       Money          `json:"price"           valid:"-"`
+	Attributes  map[string]any `json:"attributes"      valid:"-"`
+	Latitude    *float64       `json:"latitude"        valid:"-"`
+	Longitude   *float64       `json:"longitude"       valid:"-"`
+	City        string         `json:"city"            valid:"-"`
+	Status      string         `json:"status"          valid:"required"`
+	IsMy        bool           `json:"is_my"           valid:"required"`
+	IsFavorite  bool           `json:"is_favorite"     valid:"-"`
+	CreatedAt   time.Time      `json:"created_at"      valid:"required"`
+	Snippet     string         `json:"snippet"         valid:"-"`
+	Rank        float32        `json:"-"               valid:"-"`
+	// FavoritesCount is shown only to seller of product
+	FavoritesCount uint64 `json:"favorites_count,omitempty" valid:"-"`
+	// PriceDropped is set when price fell during configured period, PreviousPrice is price before it
+	PriceDropped  bool   `json:"price_dropped"            valid:"-"`
+	PreviousPrice *Money `json:"previous_price,omitempty" valid:"-"`
+	// Distance in kilometers to point of near filter
+	Distance *float64 `json:"distance,omitempty" valid:"-"`
+	// SortPrice is price used for sorting, it is converted to base currency when rates are configured
+	SortPrice uint64 `json:"-" valid:"-"`
+	// Saler is login and rating of seller
+	Saler *Saler `json:"saler" valid:"-"`
+	// PromotionType is type of the best running promotion, Promoted is set when product is put into promoted slot
+	PromotionType string `json:"promotion_type,omitempty" valid:"-"`
+	Promoted      bool   `json:"promoted"                 valid:"-"`
+}
+
+// PageInfo describes page of products list. Offset is set only in legacy offset mode.
+type PageInfo struct {
+	Total            uint64 `json:"total"`
+	TotalIsEstimated bool   `json:"total_is_estimated"`
+	Limit            uint64 `json:"limit"`
+	Offset           uint64 `json:"offset,omitempty"`
+	Cursor           string `json:"cursor,omitempty"`
+	NextCursor       string `json:"next_cursor,omitempty"`
+	HasMore          bool   `json:"has_more"`
+}
+
+// ProductCursor is position in products feed after which next page starts. Price is sort price.
+type ProductCursor struct {
+	Sort      string    `json:"s"`
+	Price     uint64    `json:"p"`
+	CreatedAt time.Time `json:"c"`
+	Rank      float32   `json:"r"`
+	Distance  float64   `json:"d"`
+	ID        uint64    `json:"i"`
+	// Promoted is number of promoted products shown before cursor, they are not counted in keyset
+	Promoted uint64 `json:"pr,omitempty"`
+	// OrganicStart means that no organic product is shown before cursor, so keyset is not applied
+	OrganicStart bool `json:"os,omitempty"`
 }
 
 type PreProduct struct {
-	SalerID     uint64 `json:"saler_id"        valid:"required"`
-	Title       string `json:"title"           valid:"required, length(1|256)~Заголовок должен быть длинной от 1 до 256 символов"`         //nolint:nolintlint
-	Description string `json:"description"     valid:"required, length(1|4000)~Описание должно быть длинной от 1 до 4000 симвволов"`       //nolint:nolintlint
-	ImageUrl    string `json:"image_url"       valid:"imgurl, optional, length(1|256)~Заголовок должен быть длинной от 1 до 256 символов"` //nolint:nolintlint
-	Price       uint64 `json:"price"           valid:"required"`
+	SalerID     uint64         `json:"saler_id"        valid:"required"`
+	CategoryID  uint64         `json:"category_id"     valid:"required"`
+	Title       string         `json:"title"           valid:"required, length(1|256)~Заголовок должен быть длинной от 1 до 256 символов"`         //nolint:nolintlint
+	Description string         `json:"description"     valid:"required, length(1|4000)~Описание должно быть длинной от 1 до 4000 симвволов"`       //nolint:nolintlint
+	ImageUrl    string         `json:"image_url"       valid:"imgurl, optional, length(1|256)~Заголовок должен быть длинной от 1 до 256 символов"` //nolint:nolintlint
+	Price       Money          `json:"price"           valid:"-"`
+	Attributes  map[string]any `json:"attributes"      valid:"-"`
+	Latitude    *float64       `json:"latitude"        valid:"-"`
+	Longitude   *float64       `json:"longitude"       valid:"-"`
+	City        string         `json:"city"            valid:"optional, length(1|128)~Город должен быть длинной от 1 до 128 символов"` //nolint:nolintlint
+	// Status and ReviewReason are set by premoderation, empty status means active
+	Status       string `json:"-" valid:"-"`
+	ReviewReason string `json:"-" valid:"-"`
+	// ImageHash is perceptual hash of ingested image, zero when image is not ingested
+	ImageHash uint64 `json:"-" valid:"-"`
+	// ID is set for edit of existing product, so it isn't found as duplicate of itself
+	ID uint64 `json:"-" valid:"-"`
+}
+
+// PartialProduct is edit of product by seller, nil fields are not changed.
+type PartialProduct struct {
+	Title       *string `json:"title"`
+	Description *string `json:"description"`
+	ImageUrl    *string `json:"image_url"`
+	Price       *Money  `json:"price"`
+	Status      *string `json:"status"`
+	// ReviewReason is set by premoderation together with ProductStatusOnReview
+	ReviewReason string `json:"-"`
+	// ImageHash is perceptual hash of new ingested image
+	ImageHash uint64 `json:"-"`
+}
+
+func (p *PartialProduct) Trim() {
+	if p.Title != nil {
+		*p.Title = strings.TrimFunc(*p.Title, unicode.IsSpace)
+	}
+
+	if p.Description != nil {
+		*p.Description = strings.TrimFunc(*p.Description, unicode.IsSpace)
+	}
+}
+
+type PriceChange struct {
+	OldPrice  Money     `json:"old_price"`
+	NewPrice  Money     `json:"new_price"`
+	ChangedAt time.Time `json:"changed_at"`
 }
 
 func (p *PreProduct) Trim() {
 	p.Title = strings.TrimFunc(p.Title, unicode.IsSpace)
 	p.Description = strings.TrimFunc(p.Description, unicode.IsSpace)
+	p.City = strings.TrimFunc(p.City, unicode.IsSpace)
+}
+
+func sanitizeAttributes(sanitizer *bluemonday.Policy, attributes map[string]any) {
+	for name, value := range attributes {
+		if str, ok := value.(string); ok {
+			attributes[name] = sanitizer.Sanitize(str)
+		}
+	}
 }
 
 func (p *Product) Sanitize() {
@@ -68,6 +190,8 @@ func (p *Product) Sanitize() {
 
 	p.Title = sanitizer.Sanitize(p.Title)
 	p.Description = sanitizer.Sanitize(p.Description)
+	p.City = sanitizer.Sanitize(p.City)
+	sanitizeAttributes(sanitizer, p.Attributes)
 }
 
 func (p *ProductWithIsMy) Sanitize() {
@@ -75,4 +199,28 @@ func (p *ProductWithIsMy) Sanitize() {
 
 	p.Title = sanitizer.Sanitize(p.Title)
 	p.Description = sanitizer.Sanitize(p.Description)
+	p.Snippet = sanitizer.Sanitize(p.Snippet)
+	p.City = sanitizer.Sanitize(p.City)
+	sanitizeAttributes(sanitizer, p.Attributes)
+
+	if p.Saler != nil {
+		p.Saler.Sanitize()
+	}
+}
+
+type Suggestions struct {
+	Titles     []string    `json:"titles"`
+	Categories []*Category `json:"categories"`
+}
+
+func (s *Suggestions) Sanitize() {
+	sanitizer := bluemonday.UGCPolicy()
+
+	for i, title := range s.Titles {
+		s.Titles[i] = sanitizer.Sanitize(title)
+	}
+
+	for _, category := range s.Categories {
+		category.Sanitize()
+	}
 }
@@ -0,0 +1,77 @@
+package models
+
+import (
+	myerrors "github.com/SanExpett/marketplace-backend/pkg/my_errors"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const MessageErrWrongPromotedSlot = "Некорректная позиция продвигаемого объявления %s"
+
+const (
+	// PromotionTypeBump raises product to promoted slots of feed
+	PromotionTypeBump = "bump"
+	// PromotionTypeHighlight is shown in promoted slots before bumped products and is highlighted by clients
+	PromotionTypeHighlight = "highlight"
+	// PromotionTypeTopOfCategory is shown first in promoted slots, but only in feed of category
+	PromotionTypeTopOfCategory = "top_of_category"
+)
+
+// PromotionTypes are ordered by priority in promoted slots.
+func PromotionTypes() []string {
+	return []string{PromotionTypeTopOfCategory, PromotionTypeHighlight, PromotionTypeBump}
+}
+
+func IsPromotionType(promotionType string) bool {
+	return slices.Contains(PromotionTypes(), promotionType)
+}
+
+// Promotion keeps product in promoted slots of default feed from StartedAt until EndedAt. Promotion bought
+// by seller works after its payment succeeds, promotion granted by moderator is free and has payment status none.
+type Promotion struct {
+	ID            uint64    `json:"id"`
+	ProductID     uint64    `json:"product_id"`
+	SalerID       uint64    `json:"saler_id"`
+	Type          string    `json:"type"`
+	StartedAt     time.Time `json:"started_at"`
+	EndedAt       time.Time `json:"ended_at"`
+	Price         Money     `json:"price"`
+	PaymentID     string    `json:"payment_id,omitempty"`
+	PaymentStatus string    `json:"payment_status"`
+	PaymentError  string    `json:"payment_error,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// PrePromotion is promotion ordered by seller, empty StartedAt means now.
+type PrePromotion struct {
+	Type      string     `json:"type"`
+	StartedAt *time.Time `json:"started_at"`
+	EndedAt   time.Time  `json:"ended_at"`
+}
+
+// ParsePromotedSlots parses positions on page counted from 1 like "1,6,11" into sorted positions from 0.
+// Empty string gives no slots.
+func ParsePromotedSlots(rawSlots string) ([]uint64, error) {
+	var slots []uint64
+
+	if strings.TrimSpace(rawSlots) == "" {
+		return slots, nil
+	}
+
+	for _, rawSlot := range strings.Split(rawSlots, ",") {
+		slot, err := strconv.ParseUint(strings.TrimSpace(rawSlot), 10, 64)
+		if err != nil || slot == 0 {
+			return nil, myerrors.NewError(MessageErrWrongPromotedSlot, rawSlot)
+		}
+
+		if !slices.Contains(slots, slot-1) {
+			slots = append(slots, slot-1)
+		}
+	}
+
+	slices.Sort(slots)
+
+	return slots, nil
+}
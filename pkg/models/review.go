@@ -0,0 +1,92 @@
+package models
+
+import (
+	"github.com/microcosm-cc/bluemonday"
+	"math"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	MinRating = 1
+	MaxRating = 5
+)
+
+// Review is left by buyer about seller after completed order, one per order.
+type Review struct {
+	ID          uint64     `json:"id"`
+	OrderID     uint64     `json:"order_id"`
+	ProductID   uint64     `json:"product_id"`
+	AuthorID    uint64     `json:"author_id"`
+	AuthorLogin string     `json:"author_login"`
+	SalerID     uint64     `json:"saler_id"`
+	Rating      uint64     `json:"rating"`
+	Text        string     `json:"text"`
+	Reply       string     `json:"reply,omitempty"`
+	RepliedAt   *time.Time `json:"replied_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+type PreReview struct {
+	Rating uint64 `json:"rating"`
+	Text   string `json:"text"   valid:"length(0|2000)~Отзыв должен быть не длиннее 2000 символов"` //nolint:nolintlint
+}
+
+type PreReply struct {
+	Text string `json:"text" valid:"required, length(1|2000)~Ответ должен быть длинной от 1 до 2000 символов"` //nolint:nolintlint
+}
+
+func (p *PreReview) Trim() {
+	p.Text = strings.TrimFunc(p.Text, unicode.IsSpace)
+}
+
+func (p *PreReply) Trim() {
+	p.Text = strings.TrimFunc(p.Text, unicode.IsSpace)
+}
+
+func (r *Review) Sanitize() {
+	sanitizer := bluemonday.UGCPolicy()
+
+	r.AuthorLogin = sanitizer.Sanitize(r.AuthorLogin)
+	r.Text = sanitizer.Sanitize(r.Text)
+	r.Reply = sanitizer.Sanitize(r.Reply)
+}
+
+// Saler is seller of product with rating from reviews.
+type Saler struct {
+	ID           uint64  `json:"id"`
+	Login        string  `json:"login"`
+	Rating       float64 `json:"rating"`
+	ReviewsCount uint64  `json:"reviews_count"`
+}
+
+func (s *Saler) Sanitize() {
+	sanitizer := bluemonday.UGCPolicy()
+
+	s.Login = sanitizer.Sanitize(s.Login)
+}
+
+// Profile is public information about user.
+type Profile struct {
+	ID           uint64    `json:"id"`
+	Login        string    `json:"login"`
+	Rating       float64   `json:"rating"`
+	ReviewsCount uint64    `json:"reviews_count"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+func (p *Profile) Sanitize() {
+	sanitizer := bluemonday.UGCPolicy()
+
+	p.Login = sanitizer.Sanitize(p.Login)
+}
+
+// Rating is average of ratings rounded to hundredths, 0 if there are no reviews.
+func Rating(ratingSum uint64, reviewsCount uint64) float64 {
+	if reviewsCount == 0 {
+		return 0
+	}
+
+	return math.Round(float64(ratingSum)/float64(reviewsCount)*100) / 100 //nolint:gomnd
+}
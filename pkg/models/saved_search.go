@@ -0,0 +1,75 @@
+package models
+
+import (
+	"github.com/microcosm-cc/bluemonday"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	// SavedSearchNotifyInstant sends new products as soon as matcher finds them
+	SavedSearchNotifyInstant = "instant"
+	// SavedSearchNotifyHourly sends digest of new products at most once an hour
+	SavedSearchNotifyHourly = "hourly"
+	// SavedSearchNotifyDaily sends digest of new products at most once a day
+	SavedSearchNotifyDaily = "daily"
+)
+
+func SavedSearchNotifyModes() []string {
+	return []string{SavedSearchNotifyInstant, SavedSearchNotifyHourly, SavedSearchNotifyDaily}
+}
+
+func IsSavedSearchNotifyMode(mode string) bool {
+	return slices.Contains(SavedSearchNotifyModes(), mode)
+}
+
+// SavedSearch is feed filter saved by user. New active products which match it are sent to user
+// according to Notify mode.
+type SavedSearch struct {
+	ID     uint64 `json:"id"`
+	UserID uint64 `json:"user_id"`
+	Name   string `json:"name"`
+	// Query is query string of feed which was saved, client opens feed with it
+	Query          string     `json:"query"`
+	Notify         string     `json:"notify"`
+	LastNotifiedAt *time.Time `json:"last_notified_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+func (s *SavedSearch) Sanitize() {
+	sanitizer := bluemonday.UGCPolicy()
+
+	s.Name = sanitizer.Sanitize(s.Name)
+}
+
+// PreSavedSearch is name and notify mode of saved search, empty Notify means instant.
+// Filter and Query are taken from query params of request like in feed.
+type PreSavedSearch struct {
+	Name   string         `json:"name"`
+	Notify string         `json:"notify"`
+	Query  string         `json:"-"`
+	Filter *ProductFilter `json:"-"`
+}
+
+func (p *PreSavedSearch) Trim() {
+	p.Name = strings.TrimFunc(p.Name, unicode.IsSpace)
+}
+
+// SavedSearchDigest is pushed to user when saved search finds new products. ProductIDs are the newest
+// of them, Count is number of all found products.
+type SavedSearchDigest struct {
+	SavedSearchID uint64   `json:"saved_search_id"`
+	UserID        uint64   `json:"-"`
+	Name          string   `json:"name"`
+	Query         string   `json:"query"`
+	ProductIDs    []uint64 `json:"product_ids"`
+	Count         uint64   `json:"count"`
+}
+
+func (s *SavedSearchDigest) Sanitize() {
+	sanitizer := bluemonday.UGCPolicy()
+
+	s.Name = sanitizer.Sanitize(s.Name)
+}
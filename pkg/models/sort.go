@@ -0,0 +1,121 @@
+package models
+
+import (
+	"strings"
+
+	myerrors "github.com/SanExpett/marketplace-backend/pkg/my_errors"
+)
+
+type SortField string
+
+const (
+	SortFieldPrice     SortField = "price"
+	SortFieldCreatedAt SortField = "created_at"
+	SortFieldRelevance SortField = "relevance"
+	SortFieldDistance  SortField = "distance"
+
+	sortKeysSeparator = ","
+	sortDescPrefix    = "-"
+	maxSortKeys       = 3
+)
+
+const MessageErrUnknownSortKey = "Неизвестный ключ сортировки %s"
+
+var ErrTooManySortKeys = myerrors.NewError("Слишком много ключей сортировки")
+
+//nolint:gochecknoglobals
+var sortFields = map[SortField]bool{
+	SortFieldPrice:     true,
+	SortFieldCreatedAt: true,
+	SortFieldRelevance: true,
+	SortFieldDistance:  true,
+}
+
+// SortKey is one key of sorting, Desc is set by "-" prefix: "-price".
+type SortKey struct {
+	Field SortField
+	Desc  bool
+}
+
+func (s SortKey) String() string {
+	if s.Desc {
+		return sortDescPrefix + string(s.Field)
+	}
+
+	return string(s.Field)
+}
+
+// SortSpec is ordered list of sort keys. Storage always adds id as the last tiebreaker.
+type SortSpec []SortKey
+
+// ParseSortSpec parses comma separated keys like "-price,created_at". Empty string gives empty spec.
+func ParseSortSpec(rawSort string) (SortSpec, error) {
+	sortSpec := SortSpec{}
+
+	if strings.TrimSpace(rawSort) == "" {
+		return sortSpec, nil
+	}
+
+	seenFields := make(map[SortField]bool)
+
+	for _, rawKey := range strings.Split(rawSort, sortKeysSeparator) {
+		rawKey = strings.TrimSpace(rawKey)
+
+		fieldName, desc := strings.CutPrefix(rawKey, sortDescPrefix)
+		field := SortField(fieldName)
+
+		if !sortFields[field] {
+			return nil, myerrors.NewError(MessageErrUnknownSortKey, rawKey)
+		}
+
+		if seenFields[field] {
+			continue
+		}
+
+		seenFields[field] = true
+		sortSpec = append(sortSpec, SortKey{Field: field, Desc: desc})
+	}
+
+	if len(sortSpec) > maxSortKeys {
+		return nil, ErrTooManySortKeys
+	}
+
+	return sortSpec, nil
+}
+
+func (s SortSpec) String() string {
+	keys := make([]string, 0, len(s))
+	for _, key := range s {
+		keys = append(keys, key.String())
+	}
+
+	return strings.Join(keys, sortKeysSeparator)
+}
+
+func (s SortSpec) Has(field SortField) bool {
+	for _, key := range s {
+		if key.Field == field {
+			return true
+		}
+	}
+
+	return false
+}
+
+// LegacySortSpec maps deprecated numeric sort_type to sort spec, 0 and unknown values give empty spec.
+func LegacySortSpec(sortType uint64) SortSpec {
+	legacySortSpecs := map[uint64]SortSpec{
+		1: {{Field: SortFieldPrice, Desc: false}},
+		2: {{Field: SortFieldPrice, Desc: true}},
+		3: {{Field: SortFieldCreatedAt, Desc: false}},
+		4: {{Field: SortFieldCreatedAt, Desc: true}},
+		5: {{Field: SortFieldRelevance, Desc: true}},
+	}
+
+	sortSpec, ok := legacySortSpecs[sortType]
+	if !ok {
+		return SortSpec{}
+	}
+
+	return sortSpec
+}
@@ -0,0 +1,68 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseSortSpec(t *testing.T) {
+	tests := []struct {
+		rawSort string
+		want    SortSpec
+	}{
+		{"", SortSpec{}},
+		{"  ", SortSpec{}},
+		{"price", SortSpec{{Field: SortFieldPrice, Desc: false}}},
+		{"-price, created_at", SortSpec{{Field: SortFieldPrice, Desc: true}, {Field: SortFieldCreatedAt, Desc: false}}},
+		// repeated field keeps the first direction
+		{"-created_at,created_at", SortSpec{{Field: SortFieldCreatedAt, Desc: true}}},
+		{"distance,-relevance,price", SortSpec{
+			{Field: SortFieldDistance, Desc: false}, {Field: SortFieldRelevance, Desc: true},
+			{Field: SortFieldPrice, Desc: false},
+		}},
+	}
+
+	for _, test := range tests {
+		got, err := ParseSortSpec(test.rawSort)
+		if err != nil {
+			t.Errorf("ParseSortSpec(%q): unexpected error %v", test.rawSort, err)
+
+			continue
+		}
+
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("ParseSortSpec(%q) = %v, want %v", test.rawSort, got, test.want)
+		}
+	}
+}
+
+func TestParseSortSpecErrors(t *testing.T) {
+	for _, rawSort := range []string{"title", "price,", "--price", "+price", "price,created_at,relevance,distance"} {
+		if _, err := ParseSortSpec(rawSort); err == nil {
+			t.Errorf("ParseSortSpec(%q): expected error", rawSort)
+		}
+	}
+}
+
+func TestSortSpecString(t *testing.T) {
+	sortSpec, err := ParseSortSpec("-price,created_at")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := sortSpec.String(); got != "-price,created_at" {
+		t.Fatalf("String() = %q", got)
+	}
+}
+
+func TestLegacySortSpec(t *testing.T) {
+	if got := LegacySortSpec(2); !reflect.DeepEqual(got, SortSpec{{Field: SortFieldPrice, Desc: true}}) {
+		t.Fatalf("LegacySortSpec(2) = %v", got)
+	}
+
+	for _, sortType := range []uint64{0, 6} {
+		if got := LegacySortSpec(sortType); len(got) != 0 {
+			t.Errorf("LegacySortSpec(%d) = %v, want empty", sortType, got)
+		}
+	}
+}
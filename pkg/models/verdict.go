@@ -0,0 +1,35 @@
+package models
+
+import "strings"
+
+const (
+	VerdictAccept = "accept"
+	VerdictReject = "reject"
+	VerdictReview = "review"
+	// VerdictWarn accepts product, reasons are returned to seller as warnings
+	VerdictWarn = "warn"
+)
+
+// Verdict is result of premoderation of product. Reasons are empty for accepted product.
+type Verdict struct {
+	Outcome  string   `json:"outcome"`
+	Reasons  []string `json:"reasons,omitempty"`
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+func NewVerdict(outcome string, reasons ...string) *Verdict {
+	return &Verdict{Outcome: outcome, Reasons: reasons} //nolint:exhaustruct
+}
+
+func (v *Verdict) Reason() string {
+	return strings.Join(v.Reasons, "; ")
+}
+
+// Duplicate is existing product which is similar to checked one by text or by image.
+type Duplicate struct {
+	ProductID uint64
+	SalerID   uint64
+	// Similarity of normalized texts from 0 to 1
+	Similarity float64
+	SameImage  bool
+}
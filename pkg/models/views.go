@@ -0,0 +1,7 @@
+package models
+
+// DailyViews is number of unique views of product during one UTC day formatted as YYYY-MM-DD.
+type DailyViews struct {
+	Day   string `json:"day"`
+	Views uint64 `json:"views"`
+}
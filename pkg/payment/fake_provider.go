@@ -0,0 +1,184 @@
+package payment
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"github.com/SanExpett/marketplace-backend/pkg/models"
+	myerrors "github.com/SanExpett/marketplace-backend/pkg/my_errors"
+	"github.com/SanExpett/marketplace-backend/pkg/my_logger"
+	"go.uber.org/zap"
+	"sync"
+	"time"
+)
+
+const (
+	// FakeModeSuccess and FakeModeFailure answer charge synchronously
+	FakeModeSuccess = "success"
+	FakeModeFailure = "failure"
+	// FakeModeAsync and FakeModeAsyncFailure answer charge as pending and report result by webhook later
+	FakeModeAsync        = "async"
+	FakeModeAsyncFailure = "async_failure"
+
+	fakeDeclineMessage = "Платеж отклонен банком"
+	webhookTimeout     = 10 * time.Second
+)
+
+var (
+	ErrUnknownFakeMode   = myerrors.NewError("Неизвестный режим тестового платежного провайдера")
+	ErrPaymentNotFound   = myerrors.NewError("Платеж не найден")
+	ErrRefundNotPossible = myerrors.NewError("Вернуть можно только успешный платеж")
+)
+
+// WebhookHandler receives result of asynchronous charge.
+type WebhookHandler func(ctx context.Context, payment *models.Payment)
+
+// FakeProvider is in-process payment provider for development and tests. Charges with the same
+// idempotency key return the same payment.
+type FakeProvider struct {
+	mode           string
+	webhookDelay   time.Duration
+	webhookHandler WebhookHandler
+	mu             sync.Mutex
+	payments       map[string]*models.Payment
+	paymentsByID   map[string]*models.Payment
+	refunds        map[string]struct{}
+	logger         *zap.SugaredLogger
+}
+
+func NewFakeProvider(mode string, webhookDelay time.Duration) (*FakeProvider, error) {
+	logger, err := my_logger.Get()
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	switch mode {
+	case FakeModeSuccess, FakeModeFailure, FakeModeAsync, FakeModeAsyncFailure:
+	default:
+		return nil, fmt.Errorf(myerrors.ErrTemplate, ErrUnknownFakeMode)
+	}
+
+	return &FakeProvider{ //nolint:exhaustruct
+		mode:         mode,
+		webhookDelay: webhookDelay,
+		payments:     make(map[string]*models.Payment),
+		paymentsByID: make(map[string]*models.Payment),
+		refunds:      make(map[string]struct{}),
+		logger:       logger,
+	}, nil
+}
+
+// SetWebhookHandler must be called before first charge in async modes.
+func (f *FakeProvider) SetWebhookHandler(handler WebhookHandler) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.webhookHandler = handler
+}
+
+func newPaymentID() (string, error) {
+	bytes := make([]byte, 12) //nolint:gomnd
+
+	if _, err := rand.Read(bytes); err != nil {
+		return "", fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return "fake_" + hex.EncodeToString(bytes), nil
+}
+
+func (f *FakeProvider) Charge(_ context.Context, target models.PaymentTarget, amount models.Money,
+	idempotencyKey string,
+) (*models.Payment, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if payment, ok := f.payments[idempotencyKey]; ok {
+		result := *payment
+
+		return &result, nil
+	}
+
+	paymentID, err := newPaymentID()
+	if err != nil {
+		return nil, err
+	}
+
+	payment := &models.Payment{ //nolint:exhaustruct
+		ID:          paymentID,
+		OrderID:     target.OrderID,
+		PromotionID: target.PromotionID,
+		Status:      models.PaymentStatusSucceeded,
+	}
+
+	switch f.mode {
+	case FakeModeFailure:
+		payment.Status = models.PaymentStatusFailed
+		payment.Error = fakeDeclineMessage
+	case FakeModeAsync, FakeModeAsyncFailure:
+		payment.Status = models.PaymentStatusPending
+
+		go f.sendWebhook(*payment, f.mode == FakeModeAsyncFailure)
+	}
+
+	f.payments[idempotencyKey] = payment
+	f.paymentsByID[paymentID] = payment
+
+	f.logger.Infof("fake charge of %d %s for %+v: %+v", amount.Amount, amount.Currency, target, payment)
+
+	result := *payment
+
+	return &result, nil
+}
+
+func (f *FakeProvider) sendWebhook(payment models.Payment, failed bool) {
+	time.Sleep(f.webhookDelay)
+
+	payment.Status = models.PaymentStatusSucceeded
+	if failed {
+		payment.Status = models.PaymentStatusFailed
+		payment.Error = fakeDeclineMessage
+	}
+
+	f.mu.Lock()
+	*f.paymentsByID[payment.ID] = payment
+	handler := f.webhookHandler
+	f.mu.Unlock()
+
+	if handler == nil {
+		f.logger.Errorf("webhook of payment %s is lost: handler is not set", payment.ID)
+
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), webhookTimeout)
+	defer cancel()
+
+	handler(ctx, &payment)
+}
+
+// Refund is idempotent: refund with the same idempotency key is done once and succeeds again.
+func (f *FakeProvider) Refund(_ context.Context, paymentID string, amount models.Money, idempotencyKey string,
+) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.refunds[idempotencyKey]; ok {
+		return nil
+	}
+
+	payment, ok := f.paymentsByID[paymentID]
+	if !ok {
+		return fmt.Errorf(myerrors.ErrTemplate, ErrPaymentNotFound)
+	}
+
+	if payment.Status != models.PaymentStatusSucceeded {
+		return fmt.Errorf(myerrors.ErrTemplate, ErrRefundNotPossible)
+	}
+
+	f.refunds[idempotencyKey] = struct{}{}
+
+	f.logger.Infof("fake refund of %d %s for payment %s", amount.Amount, amount.Currency, paymentID)
+
+	return nil
+}
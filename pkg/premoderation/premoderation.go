@@ -0,0 +1,63 @@
+package premoderation
+
+import (
+	"context"
+	"fmt"
+	"github.com/SanExpett/marketplace-backend/pkg/models"
+	myerrors "github.com/SanExpett/marketplace-backend/pkg/my_errors"
+	"github.com/SanExpett/marketplace-backend/pkg/my_logger"
+	"go.uber.org/zap"
+)
+
+// IRule checks product before it gets to feed. isNew is false for edit of existing product.
+type IRule interface {
+	Check(ctx context.Context, preProduct *models.PreProduct, isNew bool) (*models.Verdict, error)
+}
+
+// Pipeline runs rules in order. The first rejection stops it, reasons of manual review and warnings are collected
+// from all rules.
+type Pipeline struct {
+	rules  []IRule
+	logger *zap.SugaredLogger
+}
+
+func NewPipeline(rules ...IRule) (*Pipeline, error) {
+	logger, err := my_logger.Get()
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return &Pipeline{rules: rules, logger: logger}, nil
+}
+
+func (p *Pipeline) Check(ctx context.Context, preProduct *models.PreProduct, isNew bool) (*models.Verdict, error) {
+	var reviewReasons, warnings []string
+
+	for _, rule := range p.rules {
+		verdict, err := rule.Check(ctx, preProduct, isNew)
+		if err != nil {
+			return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+		}
+
+		switch verdict.Outcome {
+		case models.VerdictReject:
+			p.logger.Infof("product %q of saler %d is rejected: %s", preProduct.Title, preProduct.SalerID,
+				verdict.Reason())
+
+			return verdict, nil
+		case models.VerdictReview:
+			reviewReasons = append(reviewReasons, verdict.Reasons...)
+		case models.VerdictWarn:
+			warnings = append(warnings, verdict.Reasons...)
+		}
+	}
+
+	verdict := models.NewVerdict(models.VerdictAccept)
+	if len(reviewReasons) != 0 {
+		verdict = models.NewVerdict(models.VerdictReview, reviewReasons...)
+	}
+
+	verdict.Warnings = warnings
+
+	return verdict, nil
+}
@@ -0,0 +1,306 @@
+package premoderation
+
+import (
+	"context"
+	"fmt"
+	"github.com/SanExpett/marketplace-backend/pkg/models"
+	myerrors "github.com/SanExpett/marketplace-backend/pkg/my_errors"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	MessageReasonBannedWord     = "запрещенное слово «%s»"
+	MessageReasonBannedPattern  = "текст похож на запрещенный шаблон %s"
+	MessageReasonPriceTooLow    = "цена намного ниже средней по категории"
+	MessageReasonPriceTooHigh   = "цена намного выше средней по категории"
+	MessageReasonTooManyPerHour = "можно разместить не больше %d объявлений в час"
+	MessageReasonDuplicateOwn   = "похоже на ваше объявление №%d"
+	MessageReasonDuplicate      = "похоже на объявление №%d другого продавца"
+	MessageReasonSameImageOwn   = "то же изображение, что в вашем объявлении №%d"
+	MessageReasonSameImage      = "то же изображение, что в объявлении №%d другого продавца"
+
+	ReasonPhone = "телефон в объявлении, для связи есть чат"
+	ReasonLink  = "ссылка в объявлении"
+
+	minPhoneDigits  = 10
+	maxPhoneDigits  = 15
+	percent         = 100
+	rateLimitPeriod = time.Hour
+)
+
+var ErrUnknownDuplicateAction = myerrors.NewError("Неизвестное действие для дубликатов объявлений")
+
+//nolint:gochecknoglobals
+var (
+	regexpPhone = regexp.MustCompile(`\+?\d[\d\s\-().]{8,}\d`)
+	regexpLink  = regexp.MustCompile(
+		`(?i)(https?://|www\.|t\.me/|wa\.me/|[a-z0-9\-]+\.(ru|com|net|org|info|biz|io|me|su)\b|[a-zа-я0-9\-]+\.рф)`)
+)
+
+func productText(preProduct *models.PreProduct) string {
+	return preProduct.Title + "\n" + preProduct.Description
+}
+
+// StopListRule rejects product with banned word and sends product matching banned regexp to manual review:
+// regexps catch disguised spellings and give false positives.
+type StopListRule struct {
+	words    map[string]struct{}
+	phrases  []string
+	patterns []*regexp.Regexp
+}
+
+// NewStopListRule compiles rule, words are compared case insensitive, words with spaces are matched as phrases.
+func NewStopListRule(words []string, patterns []string) (*StopListRule, error) {
+	rule := &StopListRule{words: make(map[string]struct{})} //nolint:exhaustruct
+
+	for _, word := range words {
+		word = strings.ToLower(strings.TrimSpace(word))
+
+		switch {
+		case word == "":
+		case strings.ContainsFunc(word, unicode.IsSpace):
+			rule.phrases = append(rule.phrases, word)
+		default:
+			rule.words[word] = struct{}{}
+		}
+	}
+
+	for _, pattern := range patterns {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+
+		compiled, err := regexp.Compile("(?i)" + pattern)
+		if err != nil {
+			return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+		}
+
+		rule.patterns = append(rule.patterns, compiled)
+	}
+
+	return rule, nil
+}
+
+func (s *StopListRule) Check(_ context.Context, preProduct *models.PreProduct, _ bool) (*models.Verdict, error) {
+	text := strings.ToLower(productText(preProduct))
+
+	for _, word := range strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if _, ok := s.words[word]; ok {
+			return models.NewVerdict(models.VerdictReject, fmt.Sprintf(MessageReasonBannedWord, word)), nil
+		}
+	}
+
+	normalizedText := strings.Join(strings.Fields(text), " ")
+	for _, phrase := range s.phrases {
+		if strings.Contains(normalizedText, phrase) {
+			return models.NewVerdict(models.VerdictReject, fmt.Sprintf(MessageReasonBannedWord, phrase)), nil
+		}
+	}
+
+	for _, pattern := range s.patterns {
+		if pattern.MatchString(text) {
+			return models.NewVerdict(models.VerdictReview,
+				fmt.Sprintf(MessageReasonBannedPattern, strings.TrimPrefix(pattern.String(), "(?i)"))), nil
+		}
+	}
+
+	return models.NewVerdict(models.VerdictAccept), nil
+}
+
+// ContactsRule sends product with phone number or link to manual review: contacts outside of chat
+// are common in scam listings.
+type ContactsRule struct{}
+
+func NewContactsRule() *ContactsRule {
+	return &ContactsRule{}
+}
+
+func isPhone(candidate string) bool {
+	digits := 0
+
+	for _, r := range candidate {
+		if unicode.IsDigit(r) {
+			digits++
+		}
+	}
+
+	return digits >= minPhoneDigits && digits <= maxPhoneDigits
+}
+
+func (c *ContactsRule) Check(_ context.Context, preProduct *models.PreProduct, _ bool) (*models.Verdict, error) {
+	text := productText(preProduct)
+
+	var reasons []string
+
+	for _, candidate := range regexpPhone.FindAllString(text, -1) {
+		if isPhone(candidate) {
+			reasons = append(reasons, ReasonPhone)
+
+			break
+		}
+	}
+
+	if regexpLink.MatchString(text) {
+		reasons = append(reasons, ReasonLink)
+	}
+
+	if len(reasons) != 0 {
+		return models.NewVerdict(models.VerdictReview, reasons...), nil
+	}
+
+	return models.NewVerdict(models.VerdictAccept), nil
+}
+
+type IPriceStatsStorage interface {
+	// GetPriceMedian returns median price of active products of category in currency and number of them.
+	GetPriceMedian(ctx context.Context, categoryID uint64, currency string) (uint64, uint64, error)
+}
+
+// PriceOutlierRule sends product with price far from median of its category to manual review.
+// Too low price is typical bait, too high one is often typo or money laundering.
+type PriceOutlierRule struct {
+	storage IPriceStatsStorage
+	// lowPercent and highPercent are bounds of normal price in percents of median
+	lowPercent  uint64
+	highPercent uint64
+	// minSample is number of products in category needed for median to be meaningful
+	minSample uint64
+}
+
+func NewPriceOutlierRule(storage IPriceStatsStorage, lowPercent uint64, highPercent uint64, minSample uint64,
+) *PriceOutlierRule {
+	return &PriceOutlierRule{
+		storage:     storage,
+		lowPercent:  lowPercent,
+		highPercent: highPercent,
+		minSample:   minSample,
+	}
+}
+
+func (p *PriceOutlierRule) Check(ctx context.Context, preProduct *models.PreProduct, _ bool,
+) (*models.Verdict, error) {
+	median, count, err := p.storage.GetPriceMedian(ctx, preProduct.CategoryID, preProduct.Price.Currency)
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	if count < p.minSample || median == 0 {
+		return models.NewVerdict(models.VerdictAccept), nil
+	}
+
+	price := preProduct.Price.Amount * percent
+
+	switch {
+	case price < median*p.lowPercent:
+		return models.NewVerdict(models.VerdictReview, MessageReasonPriceTooLow), nil
+	case p.highPercent != 0 && price > median*p.highPercent:
+		return models.NewVerdict(models.VerdictReview, MessageReasonPriceTooHigh), nil
+	}
+
+	return models.NewVerdict(models.VerdictAccept), nil
+}
+
+type IRecentProductsStorage interface {
+	// CountProductsSince returns number of products added by saler since time.
+	CountProductsSince(ctx context.Context, salerID uint64, since time.Time) (uint64, error)
+}
+
+// RateLimitRule rejects new product of saler who has added too many products during the last hour.
+// Edits are not limited.
+type RateLimitRule struct {
+	storage      IRecentProductsStorage
+	limitPerHour uint64
+}
+
+func NewRateLimitRule(storage IRecentProductsStorage, limitPerHour uint64) *RateLimitRule {
+	return &RateLimitRule{
+		storage:      storage,
+		limitPerHour: limitPerHour,
+	}
+}
+
+func (r *RateLimitRule) Check(ctx context.Context, preProduct *models.PreProduct, isNew bool,
+) (*models.Verdict, error) {
+	if !isNew {
+		return models.NewVerdict(models.VerdictAccept), nil
+	}
+
+	count, err := r.storage.CountProductsSince(ctx, preProduct.SalerID, time.Now().Add(-rateLimitPeriod))
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	if count >= r.limitPerHour {
+		return models.NewVerdict(models.VerdictReject, fmt.Sprintf(MessageReasonTooManyPerHour, r.limitPerHour)), nil
+	}
+
+	return models.NewVerdict(models.VerdictAccept), nil
+}
+
+type IDuplicatesStorage interface {
+	// FindDuplicate returns the most similar product of saler or of any saler when acrossSalers is set.
+	// Texts are similar when similarity is at least given one. Nil is returned when there is no duplicate.
+	FindDuplicate(ctx context.Context, preProduct *models.PreProduct, similarity float64, acrossSalers bool,
+	) (*models.Duplicate, error)
+}
+
+// DuplicateRule finds new or edited product which repeats existing one by text or by image. Depending on action
+// duplicate is rejected, accepted with warning or sent to manual review.
+type DuplicateRule struct {
+	storage IDuplicatesStorage
+	// action is outcome of verdict for duplicate: VerdictReject, VerdictWarn or VerdictReview
+	action string
+	// similarityPercent is minimal similarity of texts of duplicates
+	similarityPercent uint64
+	acrossSalers      bool
+}
+
+func NewDuplicateRule(storage IDuplicatesStorage, action string, similarityPercent uint64, acrossSalers bool,
+) (*DuplicateRule, error) {
+	if action != models.VerdictReject && action != models.VerdictWarn && action != models.VerdictReview {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, ErrUnknownDuplicateAction)
+	}
+
+	return &DuplicateRule{
+		storage:           storage,
+		action:            action,
+		similarityPercent: similarityPercent,
+		acrossSalers:      acrossSalers,
+	}, nil
+}
+
+func duplicateReason(duplicate *models.Duplicate, salerID uint64) string {
+	isOwn := duplicate.SalerID == salerID
+
+	switch {
+	case duplicate.SameImage && isOwn:
+		return fmt.Sprintf(MessageReasonSameImageOwn, duplicate.ProductID)
+	case duplicate.SameImage:
+		return fmt.Sprintf(MessageReasonSameImage, duplicate.ProductID)
+	case isOwn:
+		return fmt.Sprintf(MessageReasonDuplicateOwn, duplicate.ProductID)
+	default:
+		return fmt.Sprintf(MessageReasonDuplicate, duplicate.ProductID)
+	}
+}
+
+func (d *DuplicateRule) Check(ctx context.Context, preProduct *models.PreProduct, _ bool,
+) (*models.Verdict, error) {
+	duplicate, err := d.storage.FindDuplicate(ctx, preProduct, float64(d.similarityPercent)/percent,
+		d.acrossSalers)
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	if duplicate == nil {
+		return models.NewVerdict(models.VerdictAccept), nil
+	}
+
+	return models.NewVerdict(d.action, duplicateReason(duplicate, preProduct.SalerID)), nil
+}
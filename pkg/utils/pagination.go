@@ -0,0 +1,18 @@
+package utils
+
+import myerrors "github.com/SanExpett/marketplace-backend/pkg/my_errors"
+
+const MessageErrWrongLimit = "Некорректный limit: он должен быть не больше %d"
+
+// ValidateLimit replaces absent limit with defaultLimit and rejects limit bigger than configured maxLimit.
+func ValidateLimit(limit uint64, defaultLimit uint64, maxLimit uint64) (uint64, error) {
+	if limit == 0 {
+		limit = min(defaultLimit, maxLimit)
+	}
+
+	if limit > maxLimit {
+		return 0, myerrors.NewError(MessageErrWrongLimit, maxLimit)
+	}
+
+	return limit, nil
+}
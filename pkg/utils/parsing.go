@@ -4,13 +4,24 @@ import (
 	"fmt"
 	myerrors "github.com/SanExpett/marketplace-backend/pkg/my_errors"
 	mylogger "github.com/SanExpett/marketplace-backend/pkg/my_logger"
+	"math"
 	"net/http"
 	"strconv"
+	gotime "time" // time is taken by argon2 parameter in hashing.go
 )
 
 var MessageErrWrongNumberParam = "Получили некорректный числовой параметр. " + //nolint:gochecknoglobals
 	"Он должен быть целым"
 
+var MessageErrWrongFloatParam = "Получили некорректный числовой параметр. " + //nolint:gochecknoglobals
+	"Он должен быть числом"
+
+var MessageErrWrongTimeParam = "Получили некорректный параметр времени. " + //nolint:gochecknoglobals
+	"Он должен быть в формате RFC 3339"
+
+var MessageErrWrongBoolParam = "Получили некорректный логический параметр. " + //nolint:gochecknoglobals
+	"Он должен быть true или false"
+
 func ParseUint64FromRequest(r *http.Request, paramName string) (uint64, error) {
 	logger, err := mylogger.Get()
 	if err != nil {
@@ -34,3 +45,63 @@ func ParseUint64FromRequest(r *http.Request, paramName string) (uint64, error) {
 func ParseStringFromRequest(r *http.Request, paramName string) string {
 	return r.URL.Query().Get(paramName)
 }
+
+func ParseTimeFromRequest(r *http.Request, paramName string) (gotime.Time, error) {
+	logger, err := mylogger.Get()
+	if err != nil {
+		return gotime.Time{}, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	timeStr := r.URL.Query().Get(paramName)
+
+	parsedTime, err := gotime.Parse(gotime.RFC3339, timeStr)
+	if err != nil {
+		err := myerrors.NewError("%s %s=%s", MessageErrWrongTimeParam, paramName, timeStr)
+
+		logger.Errorln(err)
+
+		return gotime.Time{}, err
+	}
+
+	return parsedTime, nil
+}
+
+func ParseBoolFromRequest(r *http.Request, paramName string) (bool, error) {
+	logger, err := mylogger.Get()
+	if err != nil {
+		return false, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	boolStr := r.URL.Query().Get(paramName)
+
+	value, err := strconv.ParseBool(boolStr)
+	if err != nil {
+		err := myerrors.NewError("%s %s=%s", MessageErrWrongBoolParam, paramName, boolStr)
+
+		logger.Errorln(err)
+
+		return false, err
+	}
+
+	return value, nil
+}
+
+func ParseFloat64FromRequest(r *http.Request, paramName string) (float64, error) {
+	logger, err := mylogger.Get()
+	if err != nil {
+		return 0, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	numberStr := r.URL.Query().Get(paramName)
+
+	number, err := strconv.ParseFloat(numberStr, 64)
+	if err != nil || math.IsNaN(number) || math.IsInf(number, 0) {
+		err := myerrors.NewError("%s %s=%s", MessageErrWrongFloatParam, paramName, numberStr)
+
+		logger.Errorln(err)
+
+		return 0, err
+	}
+
+	return number, nil
+}
@@ -0,0 +1,181 @@
+package view_counter
+
+import (
+	"context"
+	"fmt"
+	myerrors "github.com/SanExpett/marketplace-backend/pkg/my_errors"
+	"github.com/SanExpett/marketplace-backend/pkg/my_logger"
+	"go.uber.org/zap"
+	"strings"
+	"time"
+)
+
+const (
+	day          = 24 * time.Hour
+	flushTimeout = 10 * time.Second
+	// maxFlushAttempts is number of failed flushes of day after which its views are written by one product
+	maxFlushAttempts = 3
+)
+
+//nolint:gochecknoglobals
+var botMarkers = []string{
+	"bot", "crawler", "spider", "slurp", "curl", "wget", "python-requests", "go-http-client",
+	"headless", "facebookexternalhit", "preview", "httpclient",
+}
+
+// IsBot reports whether request is made by known crawler or script. Empty user agent is treated as bot.
+func IsBot(userAgent string) bool {
+	userAgent = strings.ToLower(userAgent)
+	if userAgent == "" {
+		return true
+	}
+
+	for _, marker := range botMarkers {
+		if strings.Contains(userAgent, marker) {
+			return true
+		}
+	}
+
+	return false
+}
+
+type IViewStorage interface {
+	AddViews(ctx context.Context, day time.Time, views map[uint64]uint64) error
+}
+
+type view struct {
+	productID uint64
+	viewerKey string
+	at        time.Time
+}
+
+// ViewCounter collects views in memory and periodically flushes them to storage, so recording
+// a view never waits for database. Views of the same viewer are counted once per dedupe window.
+type ViewCounter struct {
+	views         chan view
+	storage       IViewStorage
+	flushInterval time.Duration
+	dedupeWindow  time.Duration
+	// lastViews and pendingViews are owned by Run goroutine
+	lastViews     map[string]time.Time
+	pendingViews  map[time.Time]map[uint64]uint64
+	failedFlushes map[time.Time]int
+	logger        *zap.SugaredLogger
+}
+
+func NewViewCounter(storage IViewStorage, bufferSize uint64, flushInterval time.Duration,
+	dedupeWindow time.Duration,
+) (*ViewCounter, error) {
+	logger, err := my_logger.Get()
+	if err != nil {
+		return nil, fmt.Errorf(myerrors.ErrTemplate, err)
+	}
+
+	return &ViewCounter{
+		views:         make(chan view, bufferSize),
+		storage:       storage,
+		flushInterval: flushInterval,
+		dedupeWindow:  dedupeWindow,
+		lastViews:     make(map[string]time.Time),
+		pendingViews:  make(map[time.Time]map[uint64]uint64),
+		failedFlushes: make(map[time.Time]int),
+		logger:        logger,
+	}, nil
+}
+
+// Record doesn't block: when buffer is full the view is dropped.
+func (v *ViewCounter) Record(productID uint64, viewerKey string) {
+	select {
+	case v.views <- view{productID: productID, viewerKey: viewerKey, at: time.Now().UTC()}:
+	default:
+		v.logger.Warnf("in Record: buffer of views is full, view of product %d is dropped", productID)
+	}
+}
+
+// Run aggregates and flushes views until ctx is done, then flushes the rest.
+func (v *ViewCounter) Run(ctx context.Context) {
+	ticker := time.NewTicker(v.flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case curView := <-v.views:
+			v.add(curView)
+		case <-ticker.C:
+			v.flush(ctx)
+		case <-ctx.Done():
+			v.drain()
+
+			flushCtx, cancel := context.WithTimeout(context.Background(), flushTimeout)
+			v.flush(flushCtx)
+			cancel()
+
+			return
+		}
+	}
+}
+
+func (v *ViewCounter) drain() {
+	for {
+		select {
+		case curView := <-v.views:
+			v.add(curView)
+		default:
+			return
+		}
+	}
+}
+
+func (v *ViewCounter) add(curView view) {
+	key := fmt.Sprintf("%d:%s", curView.productID, curView.viewerKey)
+
+	if lastView, ok := v.lastViews[key]; ok && curView.at.Sub(lastView) < v.dedupeWindow {
+		return
+	}
+
+	v.lastViews[key] = curView.at
+
+	viewDay := curView.at.Truncate(day)
+	if v.pendingViews[viewDay] == nil {
+		v.pendingViews[viewDay] = make(map[uint64]uint64)
+	}
+
+	v.pendingViews[viewDay][curView.productID]++
+}
+
+// flush keeps views of failed days to retry them next time. After maxFlushAttempts views of day are split
+// by products, so one broken product (e.g. deleted one) doesn't block the others, and failed ones are dropped.
+func (v *ViewCounter) flush(ctx context.Context) {
+	now := time.Now().UTC()
+
+	for key, lastView := range v.lastViews {
+		if now.Sub(lastView) >= v.dedupeWindow {
+			delete(v.lastViews, key)
+		}
+	}
+
+	for viewDay, views := range v.pendingViews {
+		if err := v.storage.AddViews(ctx, viewDay, views); err != nil {
+			v.logger.Errorln(err)
+
+			v.failedFlushes[viewDay]++
+			if v.failedFlushes[viewDay] < maxFlushAttempts {
+				continue
+			}
+
+			v.flushByProducts(ctx, viewDay, views)
+		}
+
+		delete(v.pendingViews, viewDay)
+		delete(v.failedFlushes, viewDay)
+	}
+}
+
+func (v *ViewCounter) flushByProducts(ctx context.Context, viewDay time.Time, views map[uint64]uint64) {
+	for productID, count := range views {
+		if err := v.storage.AddViews(ctx, viewDay, map[uint64]uint64{productID: count}); err != nil {
+			v.logger.Errorf("in flush: %d views of product %d at %s are dropped: %+v",
+				count, productID, viewDay.Format(time.DateOnly), err)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package view_counter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/SanExpett/marketplace-backend/pkg/my_logger"
+)
+
+func TestMain(m *testing.M) {
+	if _, err := my_logger.New([]string{os.DevNull}, []string{os.DevNull}); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	os.Exit(m.Run())
+}
+
+var errBrokenProduct = errors.New("product is deleted")
+
+// fakeViewStorage fails every batch which contains brokenProductID.
+type fakeViewStorage struct {
+	brokenProductID uint64
+	calls           int
+	views           map[uint64]uint64
+}
+
+func (f *fakeViewStorage) AddViews(_ context.Context, _ time.Time, views map[uint64]uint64) error {
+	f.calls++
+
+	if _, ok := views[f.brokenProductID]; ok {
+		return errBrokenProduct
+	}
+
+	for productID, count := range views {
+		f.views[productID] += count
+	}
+
+	return nil
+}
+
+func TestFlushSplitsFailedBatch(t *testing.T) {
+	storage := &fakeViewStorage{brokenProductID: 2, views: make(map[uint64]uint64)}
+
+	viewCounter, err := NewViewCounter(storage, 10, time.Second, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now().UTC()
+	for _, curView := range []view{
+		{productID: 1, viewerKey: "a", at: now},
+		{productID: 1, viewerKey: "a", at: now},
+		{productID: 1, viewerKey: "b", at: now},
+		{productID: 2, viewerKey: "a", at: now},
+	} {
+		viewCounter.add(curView)
+	}
+
+	for attempt := 1; attempt < maxFlushAttempts; attempt++ {
+		viewCounter.flush(context.Background())
+
+		if len(viewCounter.pendingViews) != 1 || len(storage.views) != 0 {
+			t.Fatalf("views must be kept for retry after %d failed flushes", attempt)
+		}
+	}
+
+	viewCounter.flush(context.Background())
+
+	if len(viewCounter.pendingViews) != 0 || len(viewCounter.failedFlushes) != 0 {
+		t.Fatal("views must be dropped after last attempt")
+	}
+
+	if storage.views[1] != 2 || storage.views[2] != 0 {
+		t.Fatalf("unexpected stored views %v", storage.views)
+	}
+
+	calls := storage.calls
+
+	viewCounter.flush(context.Background())
+
+	if storage.calls != calls {
+		t.Fatal("dropped views must not be flushed again")
+	}
+}
+
+func TestIsBot(t *testing.T) {
+	for userAgent, isBot := range map[string]bool{
+		"":                                true,
+		"Googlebot/2.1":                   true,
+		"curl/8.4.0":                      true,
+		"Mozilla/5.0 (X11; Linux x86_64)": false,
+	} {
+		if got := IsBot(userAgent); got != isBot {
+			t.Errorf("IsBot(%q) = %v, want %v", userAgent, got, isBot)
+		}
+	}
+}